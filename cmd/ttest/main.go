@@ -0,0 +1,57 @@
+// Ttest runs scripts against a T editor
+// started with -listen, see ttest.Client.Run.
+//
+// Usage:
+//
+//	ttest -sock path [script ...]
+//
+// With no scripts, the script is read from standard input.
+// Ttest exits with status 1 at the first failing command.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/eaburns/T/ttest"
+)
+
+var sock = flag.String("sock", "", "the Unix socket `path` given to T's -listen flag")
+
+func main() {
+	flag.Parse()
+	if *sock == "" {
+		fmt.Fprintln(os.Stderr, "usage: ttest -sock path [script ...]")
+		os.Exit(2)
+	}
+	c, err := ttest.Dial(*sock)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer c.Close()
+	if flag.NArg() == 0 {
+		if err := c.Run(os.Stdin); err != nil {
+			fail(c, "stdin", err)
+		}
+		return
+	}
+	for _, path := range flag.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			fail(c, path, err)
+		}
+		err = c.Run(f)
+		f.Close()
+		if err != nil {
+			fail(c, path, err)
+		}
+	}
+}
+
+func fail(c *ttest.Client, name string, err error) {
+	fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+	c.Close()
+	os.Exit(1)
+}
@@ -0,0 +1,231 @@
+package dap
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Client is a connection to a debug adapter process.
+type Client struct {
+	conn  *Conn
+	cmd   *exec.Cmd // nil if not started by Start
+	event func(Event)
+
+	once        sync.Once
+	initialized chan struct{} // closed on the initialized event
+}
+
+// A StackFrame is a frame of the stack of a stopped thread.
+type StackFrame struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Line   int    `json:"line"` // one-based
+	Source *struct {
+		Name string `json:"name"`
+		Path string `json:"path"`
+	} `json:"source"`
+}
+
+// Path returns the file path of the frame's source, or "" if none.
+func (f StackFrame) Path() string {
+	if f.Source == nil {
+		return ""
+	}
+	return f.Source.Path
+}
+
+// A StoppedEvent is the body of a stopped event.
+type StoppedEvent struct {
+	Reason      string `json:"reason"`
+	Description string `json:"description"`
+	ThreadID    int    `json:"threadId"`
+}
+
+// An OutputEvent is the body of an output event.
+type OutputEvent struct {
+	Category string `json:"category"`
+	Output   string `json:"output"`
+}
+
+// Start starts the debug adapter command in the directory
+// and initializes it.
+// Events from the adapter are passed to event,
+// called from a goroutine reading from the adapter.
+func Start(cmd []string, dir string, event func(Event)) (*Client, error) {
+	if len(cmd) == 0 {
+		return nil, errors.New("no command")
+	}
+	c := exec.Command(cmd[0], cmd[1:]...)
+	c.Dir = dir
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := c.StdoutPipe()
+	if err != nil {
+		stdin.Close()
+		return nil, err
+	}
+	if err := c.Start(); err != nil {
+		stdin.Close()
+		stdout.Close()
+		return nil, err
+	}
+	cl, err := NewClient(stdout, stdin, event)
+	if err != nil {
+		c.Process.Kill()
+		c.Wait()
+		return nil, err
+	}
+	cl.cmd = c
+	return cl, nil
+}
+
+// NewClient returns an initialized client of the adapter
+// reading from r and writing to w.
+// Events from the adapter are passed to event,
+// called from the goroutine reading from r.
+func NewClient(r io.Reader, w io.Writer, event func(Event)) (*Client, error) {
+	cl := &Client{event: event, initialized: make(chan struct{})}
+	cl.conn = NewConn(r, w, cl.handle)
+	args := map[string]interface{}{
+		"clientID":        "T",
+		"adapterID":       "T",
+		"linesStartAt1":   true,
+		"columnsStartAt1": true,
+		"pathFormat":      "path",
+	}
+	if err := cl.conn.Call("initialize", args, nil); err != nil {
+		return nil, err
+	}
+	return cl, nil
+}
+
+func (cl *Client) handle(ev Event) {
+	if ev.Name == "initialized" {
+		cl.once.Do(func() { close(cl.initialized) })
+	}
+	if cl.event != nil {
+		cl.event(ev)
+	}
+}
+
+// Launch launches the program with the adapter-specific arguments,
+// setting the breakpoints, a map from file path to one-based lines,
+// before the program runs.
+func (cl *Client) Launch(args map[string]interface{}, breaks map[string][]int) error {
+	// The adapter may not answer the launch request
+	// until configuration is done.
+	launched := make(chan error, 1)
+	go func() { launched <- cl.conn.Call("launch", args, nil) }()
+	select {
+	case err := <-launched:
+		if err != nil {
+			return err
+		}
+		<-cl.initialized
+	case <-cl.initialized:
+	}
+	paths := make([]string, 0, len(breaks))
+	for path := range breaks {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := cl.SetBreakpoints(path, breaks[path]); err != nil {
+			return err
+		}
+	}
+	if err := cl.conn.Call("configurationDone", nil, nil); err != nil {
+		return err
+	}
+	return <-launched
+}
+
+// SetBreakpoints replaces the breakpoints of the file
+// with breakpoints at the one-based lines.
+func (cl *Client) SetBreakpoints(path string, lines []int) error {
+	bps := make([]map[string]int, len(lines))
+	for i, l := range lines {
+		bps[i] = map[string]int{"line": l}
+	}
+	return cl.conn.Call("setBreakpoints", map[string]interface{}{
+		"source":      map[string]string{"path": path},
+		"breakpoints": bps,
+	}, nil)
+}
+
+// Continue resumes the threads of the program.
+func (cl *Client) Continue(thread int) error {
+	return cl.conn.Call("continue", map[string]int{"threadId": thread}, nil)
+}
+
+// Next steps the thread over the current line.
+func (cl *Client) Next(thread int) error {
+	return cl.conn.Call("next", map[string]int{"threadId": thread}, nil)
+}
+
+// StepIn steps the thread into the function called on the current line.
+func (cl *Client) StepIn(thread int) error {
+	return cl.conn.Call("stepIn", map[string]int{"threadId": thread}, nil)
+}
+
+// StepOut steps the thread out of the current function.
+func (cl *Client) StepOut(thread int) error {
+	return cl.conn.Call("stepOut", map[string]int{"threadId": thread}, nil)
+}
+
+// StackTrace returns the stack frames of the stopped thread,
+// innermost first.
+func (cl *Client) StackTrace(thread int) ([]StackFrame, error) {
+	var body struct {
+		StackFrames []StackFrame `json:"stackFrames"`
+	}
+	if err := cl.conn.Call("stackTrace", map[string]int{"threadId": thread}, &body); err != nil {
+		return nil, err
+	}
+	return body.StackFrames, nil
+}
+
+// Disconnect asks the adapter to terminate the program and exit
+// and, if it was started by Start, waits for it,
+// killing it if it has not exited after the timeout.
+func (cl *Client) Disconnect(timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		err := cl.conn.Call("disconnect", map[string]bool{"terminateDebuggee": true}, nil)
+		if cl.cmd != nil {
+			cl.cmd.Process.Kill()
+			cl.cmd.Wait()
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		if cl.cmd != nil {
+			cl.cmd.Process.Kill()
+		}
+		return errors.New("disconnect timed out")
+	}
+}
+
+// Stopped returns the body of a stopped event.
+func (ev Event) Stopped() StoppedEvent {
+	var s StoppedEvent
+	json.Unmarshal(ev.Body, &s)
+	return s
+}
+
+// Output returns the body of an output event.
+func (ev Event) Output() OutputEvent {
+	var o OutputEvent
+	json.Unmarshal(ev.Body, &o)
+	return o
+}
@@ -0,0 +1,175 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAdapter serves the test client,
+// replying to each request with the body for its command,
+// recording the commands and arguments of the requests.
+// It sends the initialized event after the launch request
+// and answers the launch request after configurationDone.
+func fakeAdapter(t *testing.T, r io.Reader, w io.Writer, bodies map[string]string, log *requestLog) {
+	br := bufio.NewReader(r)
+	var seq int
+	var mu sync.Mutex
+	send := func(s string) {
+		mu.Lock()
+		defer mu.Unlock()
+		seq++
+		s = fmt.Sprintf(`{"seq":%d,%s}`, seq, s)
+		fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(s), s)
+	}
+	reply := func(m incoming) {
+		body, ok := bodies[m.Command]
+		if !ok {
+			send(fmt.Sprintf(`"type":"response","request_seq":%d,"command":%q,"success":false,"message":"no %s"`, m.Seq, m.Command, m.Command))
+			return
+		}
+		send(fmt.Sprintf(`"type":"response","request_seq":%d,"command":%q,"success":true,"body":%s`, m.Seq, m.Command, body))
+	}
+	var launch incoming
+	for {
+		data, err := readMessage(br)
+		if err != nil {
+			return
+		}
+		var m struct {
+			incoming
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Errorf("bad message %q: %v", data, err)
+			return
+		}
+		if m.Type == "response" {
+			continue
+		}
+		log.add(m.Command + " " + string(m.Arguments))
+		switch m.Command {
+		case "launch":
+			launch = m.incoming
+			// A request from the adapter must be answered.
+			send(`"type":"request","command":"runInTerminal","arguments":{}`)
+			send(`"type":"event","event":"initialized"`)
+			continue
+		case "configurationDone":
+			reply(m.incoming)
+			reply(launch)
+			send(`"type":"event","event":"stopped","body":{"reason":"breakpoint","threadId":3}`)
+			continue
+		}
+		reply(m.incoming)
+	}
+}
+
+type requestLog struct {
+	mu   sync.Mutex
+	reqs []string
+}
+
+func (l *requestLog) add(r string) {
+	l.mu.Lock()
+	l.reqs = append(l.reqs, r)
+	l.mu.Unlock()
+}
+
+func (l *requestLog) get() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string{}, l.reqs...)
+}
+
+func TestClient(t *testing.T) {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+	var log requestLog
+	go fakeAdapter(t, sr, sw, map[string]string{
+		"initialize":        `{}`,
+		"launch":            `{}`,
+		"setBreakpoints":    `{"breakpoints":[{"verified":true,"line":4}]}`,
+		"configurationDone": `{}`,
+		"stackTrace":        `{"stackFrames":[{"id":1,"name":"main.f","line":4,"source":{"path":"/a/b.go"}},{"id":2,"name":"main.main","line":9}]}`,
+		"next":              `{}`,
+		"disconnect":        `{}`,
+	}, &log)
+	events := make(chan Event, 10)
+	cl, err := NewClient(cr, cw, func(ev Event) { events <- ev })
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	err = cl.Launch(map[string]interface{}{"program": "/a"}, map[string][]int{"/a/b.go": {4}})
+	if err != nil {
+		t.Fatalf("Launch failed: %v", err)
+	}
+	var stopped *StoppedEvent
+	for stopped == nil {
+		select {
+		case ev := <-events:
+			if ev.Name == "stopped" {
+				s := ev.Stopped()
+				stopped = &s
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no stopped event")
+		}
+	}
+	if *stopped != (StoppedEvent{Reason: "breakpoint", ThreadID: 3}) {
+		t.Errorf("stopped=%+v, want breakpoint on thread 3", *stopped)
+	}
+
+	frames, err := cl.StackTrace(3)
+	if err != nil {
+		t.Fatalf("StackTrace failed: %v", err)
+	}
+	if len(frames) != 2 || frames[0].Path() != "/a/b.go" || frames[0].Line != 4 || frames[1].Path() != "" {
+		t.Errorf("StackTrace=%+v, want /a/b.go:4 and no source", frames)
+	}
+	if err := cl.Next(3); err != nil {
+		t.Errorf("Next failed: %v", err)
+	}
+	if err := cl.StepOut(3); err == nil || err.Error() != "no stepOut" {
+		t.Errorf("StepOut=%v, want no stepOut", err)
+	}
+	if err := cl.Disconnect(5 * time.Second); err != nil {
+		t.Errorf("Disconnect failed: %v", err)
+	}
+
+	want := []string{
+		`initialize {"adapterID":"T","clientID":"T","columnsStartAt1":true,"linesStartAt1":true,"pathFormat":"path"}`,
+		`launch {"program":"/a"}`,
+		`setBreakpoints {"breakpoints":[{"line":4}],"source":{"path":"/a/b.go"}}`,
+		`configurationDone `,
+		`stackTrace {"threadId":3}`,
+		`next {"threadId":3}`,
+		`stepOut {"threadId":3}`,
+		`disconnect {"terminateDebuggee":true}`,
+	}
+	if got := log.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("requests=\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestResponseError(t *testing.T) {
+	tests := []struct {
+		m    incoming
+		want string
+	}{
+		{incoming{Command: "launch", Message: "bad", Body: json.RawMessage(`{"error":{"id":1,"format":"no program"}}`)}, "no program"},
+		{incoming{Command: "launch", Message: "bad"}, "bad"},
+		{incoming{Command: "launch"}, "launch failed"},
+	}
+	for _, test := range tests {
+		if err := responseError(test.m); err.Error() != test.want {
+			t.Errorf("responseError(%+v)=%q, want %q", test.m, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Package dap implements a client of the Debug Adapter Protocol.
+// See https://microsoft.github.io/debug-adapter-protocol/
+// for the protocol specification.
+//
+// The client supports launching a program, line breakpoints,
+// continuing and stepping, and stack traces.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A Conn is a connection using the base protocol
+// of the Debug Adapter Protocol:
+// each message is preceded by a Content-Length header.
+type Conn struct {
+	w     io.Writer
+	wmu   sync.Mutex
+	event func(Event)
+
+	mu      sync.Mutex
+	seq     int
+	pending map[int]chan response
+	err     error // the error that ended reading, if any
+}
+
+// An Event is an event sent by the debug adapter.
+type Event struct {
+	Name string
+	Body json.RawMessage
+}
+
+type message struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	Command    string      `json:"command,omitempty"`
+	Arguments  interface{} `json:"arguments,omitempty"`
+	RequestSeq int         `json:"request_seq,omitempty"`
+	Success    bool        `json:"success,omitempty"`
+	Message    string      `json:"message,omitempty"`
+}
+
+type incoming struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	Command    string          `json:"command"`
+	Event      string          `json:"event"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Body       json.RawMessage `json:"body"`
+}
+
+type response struct {
+	body json.RawMessage
+	err  error
+}
+
+// NewConn returns a new connection that writes messages to w
+// and reads them from r until r returns an error.
+// Events from the adapter are passed to event,
+// called from the reading goroutine.
+// Requests from the adapter are answered with failure.
+func NewConn(r io.Reader, w io.Writer, event func(Event)) *Conn {
+	c := &Conn{w: w, event: event, pending: make(map[int]chan response)}
+	go c.read(bufio.NewReader(r))
+	return c
+}
+
+// Call sends a request and waits for its response,
+// the body of which is unmarshaled into body if body is non-nil.
+func (c *Conn) Call(command string, args, body interface{}) error {
+	ch := make(chan response, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return err
+	}
+	c.seq++
+	seq := c.seq
+	c.pending[seq] = ch
+	c.mu.Unlock()
+
+	if err := c.write(message{Seq: seq, Type: "request", Command: command, Arguments: args}); err != nil {
+		c.mu.Lock()
+		delete(c.pending, seq)
+		c.mu.Unlock()
+		return err
+	}
+	resp := <-ch
+	if resp.err != nil {
+		return resp.err
+	}
+	if body == nil || len(resp.body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.body, body)
+}
+
+func (c *Conn) write(m message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(data)
+	return err
+}
+
+func (c *Conn) read(r *bufio.Reader) {
+	var err error
+	for err == nil {
+		var data []byte
+		if data, err = readMessage(r); err == nil {
+			c.dispatch(data)
+		}
+	}
+	c.mu.Lock()
+	c.err = err
+	for seq, ch := range c.pending {
+		ch <- response{err: err}
+		delete(c.pending, seq)
+	}
+	c.mu.Unlock()
+}
+
+func (c *Conn) dispatch(data []byte) {
+	var m incoming
+	if err := json.Unmarshal(data, &m); err != nil {
+		return
+	}
+	switch m.Type {
+	case "request":
+		// A reverse request, such as runInTerminal, is not supported.
+		// The reply is written concurrently,
+		// since the adapter may not read it until it has written more.
+		c.mu.Lock()
+		c.seq++
+		seq := c.seq
+		c.mu.Unlock()
+		go c.write(message{
+			Seq:        seq,
+			Type:       "response",
+			Command:    m.Command,
+			RequestSeq: m.Seq,
+			Message:    "not supported",
+		})
+	case "event":
+		if c.event != nil {
+			c.event(Event{Name: m.Event, Body: m.Body})
+		}
+	case "response":
+		c.mu.Lock()
+		ch, ok := c.pending[m.RequestSeq]
+		delete(c.pending, m.RequestSeq)
+		c.mu.Unlock()
+		if !ok {
+			return
+		}
+		if !m.Success {
+			ch <- response{err: responseError(m)}
+		} else {
+			ch <- response{body: m.Body}
+		}
+	}
+}
+
+// responseError returns the error of a failed response:
+// the formatted error of its body, if any, or its message.
+func responseError(m incoming) error {
+	var body struct {
+		Error *struct {
+			Format string `json:"format"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(m.Body, &body) == nil && body.Error != nil && body.Error.Format != "" {
+		return errors.New(body.Error.Format)
+	}
+	if m.Message != "" {
+		return errors.New(m.Message)
+	}
+	return errors.New(m.Command + " failed")
+}
+
+// readMessage reads the headers and content of a message.
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	h, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(h.Get("Content-Length")))
+	if err != nil || n < 0 {
+		return nil, errors.New("bad Content-Length")
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
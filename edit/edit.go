@@ -129,6 +129,11 @@
 // 		The digits 1-9 correspond to text matched by capturing groups
 // 		numbered from left-to-right by order of their open parenthesis.
 // 		The 0 is the entire match.
+// 		An unescaped & is also the entire match, and \& is a literal &.
+//
+// 		Matches may span lines; the regexp \n matches a newline.
+// 		As in sam, an empty match immediately following
+// 		the previous match is not a match.
 //
 // 	[ addr ] ( "x" | "y" ) "/" regexp "/" command.
 // 		Executes a command for each match of the regular expression in the address.
@@ -220,6 +225,10 @@ func (e NoCommandError) Error() string {
 	return "no command"
 }
 
+// ErrNoMatch is returned when a regular expression
+// of an address or command does not match.
+var ErrNoMatch = errors.New("no match")
+
 // TextLen is the length of Text; 0 if Text is nil.
 func (d Diff) TextLen() int64 {
 	if d.Text == nil {
@@ -426,6 +435,8 @@ func parseDelimited(t string, sub func(int) string, delim rune) (string, string)
 		switch r, t = next(t); {
 		case r == eof || r == '\n' || r == delim:
 			return s.String(), t
+		case r == '&' && sub != nil:
+			s.WriteString(sub(0))
 		case r == '\\' && sub != nil:
 			if r, t1 := next(t); '0' <= r && r <= '9' {
 				t = t1
@@ -518,6 +529,7 @@ func sub(a [2]int64, t string, ro rope.Rope) (Diffs, string, error) {
 		}
 		return rope.Slice(ro, ms[i], ms[i+1]).String()
 	}
+	prev := int64(-1) // end of the previous match
 	for a[0] <= a[1] {
 		if ms = re.FindInRope(ro, a[0], a[1]); ms == nil {
 			break
@@ -527,9 +539,13 @@ func sub(a [2]int64, t string, ro rope.Rope) (Diffs, string, error) {
 		}
 		if ms[1] == ms[0] {
 			a[0]++
+			if ms[0] == prev {
+				continue
+			}
 		} else {
 			a[0] = ms[1]
 		}
+		prev = ms[1]
 		n--
 		if n > 0 {
 			continue
@@ -545,7 +561,7 @@ func sub(a [2]int64, t string, ro rope.Rope) (Diffs, string, error) {
 		adj += ms[1] - ms[0] - int64(len(s))
 	}
 	if len(ds) == 0 {
-		return nil, "", errors.New("no match")
+		return nil, "", ErrNoMatch
 	}
 	return ds, t, nil
 }
@@ -945,7 +961,7 @@ func regexpAddr(ro rope.Rope, at int64, rev bool, t string) ([2]int64, string, e
 		}
 	}
 	if len(ms) == 0 {
-		return [2]int64{}, t, errors.New("no match")
+		return [2]int64{}, t, ErrNoMatch
 	}
 	return [2]int64{ms[0], ms[1]}, t, err
 }
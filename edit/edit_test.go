@@ -708,6 +708,25 @@ func TestEdit(t *testing.T) {
 				{edit: `,s4/line/LINE/`, err: "no match"},
 				{edit: `,s2/line/LINE/g`, want: "line1\nLINE2\nLINE3"},
 				{edit: `,s  2  /line/LINE/g`, want: "line1\nLINE2\nLINE3"},
+
+				// & is the entire match; \& is a literal &.
+				{edit: `,s/line/<&>/g`, want: "<line>1\n<line>2\n<line>3"},
+				{edit: `1s/line/\&/`, want: "&1\nline2\nline3"},
+				{edit: `1s/(l)(i)/\2\1&/`, want: "illine1\nline2\nline3"},
+				{edit: `1s/l(i)ne/&\1&/`, want: "lineiline1\nline2\nline3"},
+
+				// Matches and substitutions spanning lines.
+				{edit: `,s/1\nline/X/`, want: "lineX2\nline3"},
+				{edit: `,s/line(.)\n/\1 /g`, want: "1 2 line3"},
+				{edit: `1s/1/\n/`, want: "line\n\nline2\nline3"},
+				{edit: `,s/$/;/g`, want: "line1;\nline2;\nline3;"},
+				{edit: `,s/^/> /g`, want: "> line1\n> line2\n> line3"},
+				{edit: `,s2/^/> /g`, want: "line1\n> line2\n> line3"},
+				{edit: `,s/.$/[&]/g`, want: "line[1]\nline[2]\nline[3]"},
+
+				// An empty match immediately after a match is skipped.
+				{edit: `1s/e*/-/g`, want: "-l-i-n-1-\n-line2\nline3"},
+				{edit: `1s/[a-z]*/-/g`, want: "-1-\n-line2\nline3"},
 			},
 		},
 		{
@@ -0,0 +1,288 @@
+// Package headless drives a ui.Win without a window system.
+// Events are synthesized by method calls or read from a script,
+// and the window is drawn into an image.RGBA.
+// It is useful for image tests, benchmarks,
+// and scripting the editor where there is no display.
+package headless
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eaburns/T/clipboard"
+	"github.com/eaburns/T/ui"
+)
+
+// A Win is a ui.Win with no window system.
+// Events are sent to it with the methods of ui.Win
+// and the methods of Win,
+// and it is drawn by Image.
+type Win struct {
+	*ui.Win
+	img   *image.RGBA
+	dirty bool // whether all of img must be redrawn
+}
+
+// New returns a new headless window
+// of the given size in pixels and resolution in dots per inch.
+// Its clipboard is a memory clipboard.
+func New(size image.Point, dpi float32) *Win {
+	w := &Win{
+		Win:   ui.NewWin(dpi),
+		img:   image.NewRGBA(image.Rectangle{Max: size}),
+		dirty: true,
+	}
+	w.SetClipboard(clipboard.NewMem())
+	w.Win.Resize(size)
+	return w
+}
+
+// Resize resizes the window.
+func (w *Win) Resize(size image.Point) {
+	w.img = image.NewRGBA(image.Rectangle{Max: size})
+	w.dirty = true
+	w.Win.Resize(size)
+}
+
+// SetDPI sets the resolution of the window in dots per inch.
+func (w *Win) SetDPI(dpi float32) {
+	w.dirty = true
+	w.Win.SetDPI(dpi)
+}
+
+// Image draws the window and returns its image.
+// The image is reused by the next call to Image;
+// callers that keep it must copy it.
+func (w *Win) Image() *image.RGBA {
+	w.Win.Draw(w.dirty, w.img)
+	w.dirty = false
+	return w.img
+}
+
+// Type types the runes of a string.
+func (w *Win) Type(s string) {
+	for _, r := range s {
+		if r == '\r' {
+			r = '\n'
+		}
+		w.Rune(r)
+	}
+}
+
+// Step presses and releases a mouse button at a point,
+// moving the mouse there first.
+func (w *Win) Step(pt image.Point, button int) {
+	w.Move(pt)
+	w.Click(pt, button)
+	w.Click(pt, -button)
+}
+
+// keys are the named keys of the key script command.
+var keys = map[string]func(*Win){
+	"up":        func(w *Win) { w.Dir(0, -1) },
+	"down":      func(w *Win) { w.Dir(0, 1) },
+	"left":      func(w *Win) { w.Dir(-1, 0) },
+	"right":     func(w *Win) { w.Dir(1, 0) },
+	"pageup":    func(w *Win) { w.Dir(0, -2) },
+	"pagedown":  func(w *Win) { w.Dir(0, 2) },
+	"home":      func(w *Win) { w.Dir(0, math.MinInt16) },
+	"end":       func(w *Win) { w.Dir(0, math.MaxInt16) },
+	"enter":     func(w *Win) { w.Rune('\n') },
+	"tab":       func(w *Win) { w.Rune('\t') },
+	"backspace": func(w *Win) { w.Rune('\b') },
+	"delete":    func(w *Win) { w.Rune(0x7f) },
+	"esc":       func(w *Win) { w.Rune(0x1b) },
+	"copy":      func(w *Win) { w.Copy() },
+	"cut":       func(w *Win) { w.Cut() },
+	"paste":     func(w *Win) { w.Paste() },
+}
+
+// Run runs a script of commands, one per line.
+// Blank lines and lines beginning with # are ignored.
+// The commands are:
+//
+//	type text          type the rest of the line, or a Go-quoted string
+//	preedit text       show an input method composition, like type's text
+//	commit text        commit an input method composition, like type's text
+//	key name           press a key: up, down, left, right, pageup, pagedown,
+//	                   home, end, enter, tab, backspace, delete, esc,
+//	                   copy, cut, or paste
+//	mod m              press (m > 0) or release (m < 0) modifier |m|
+//	move x y           move the mouse
+//	press x y button   press a mouse button
+//	release x y button release a mouse button
+//	click x y [button] press and release a mouse button, by default 1
+//	wheel x y dx dy    roll the mouse wheel
+//	exec command       execute a command, failing on error
+//	tick [n]           tick n times, by default once
+//	sleep duration     sleep, for example, waiting for a command to finish
+//	size width height  resize the window
+//	dpi dpi            set the resolution of the window
+//	png file           draw the window and write its image to a PNG file
+//
+// Run returns the first error, prefixed by its line number.
+func (w *Win) Run(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := w.runLine(line); err != nil {
+			return fmt.Errorf("line %d: %v", n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func (w *Win) runLine(line string) error {
+	cmd, rest := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		cmd, rest = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	switch cmd {
+	case "type", "preedit", "commit":
+		if strings.HasPrefix(rest, `"`) || strings.HasPrefix(rest, "`") {
+			s, err := strconv.Unquote(rest)
+			if err != nil {
+				return fmt.Errorf("bad string %s", rest)
+			}
+			rest = s
+		}
+		switch cmd {
+		case "type":
+			w.Type(rest)
+		case "preedit":
+			w.Preedit(rest)
+		case "commit":
+			w.Commit(rest)
+		}
+	case "key":
+		f, ok := keys[rest]
+		if !ok {
+			return fmt.Errorf("unknown key %s", rest)
+		}
+		f(w)
+	case "mod":
+		m, err := ints(rest, 1, 1)
+		if err != nil {
+			return err
+		}
+		w.Mod(m[0])
+	case "move":
+		xy, err := ints(rest, 2, 2)
+		if err != nil {
+			return err
+		}
+		w.Move(image.Pt(xy[0], xy[1]))
+	case "press", "release":
+		xyb, err := ints(rest, 3, 3)
+		if err != nil {
+			return err
+		}
+		if cmd == "release" {
+			xyb[2] = -xyb[2]
+		}
+		w.Click(image.Pt(xyb[0], xyb[1]), xyb[2])
+	case "click":
+		xyb, err := ints(rest, 2, 3)
+		if err != nil {
+			return err
+		}
+		b := 1
+		if len(xyb) == 3 {
+			b = xyb[2]
+		}
+		w.Step(image.Pt(xyb[0], xyb[1]), b)
+	case "wheel":
+		v, err := ints(rest, 4, 4)
+		if err != nil {
+			return err
+		}
+		w.Wheel(image.Pt(v[0], v[1]), v[2], v[3])
+	case "exec":
+		return w.Exec(rest)
+	case "tick":
+		n := []int{1}
+		if rest != "" {
+			var err error
+			if n, err = ints(rest, 1, 1); err != nil {
+				return err
+			}
+		}
+		for i := 0; i < n[0]; i++ {
+			w.Tick()
+		}
+	case "sleep":
+		d, err := time.ParseDuration(rest)
+		if err != nil {
+			return fmt.Errorf("bad duration %s", rest)
+		}
+		time.Sleep(d)
+	case "size":
+		wh, err := ints(rest, 2, 2)
+		if err != nil {
+			return err
+		}
+		if wh[0] <= 0 || wh[1] <= 0 {
+			return errors.New("size must be positive")
+		}
+		w.Resize(image.Pt(wh[0], wh[1]))
+	case "dpi":
+		d, err := strconv.ParseFloat(rest, 32)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("bad dpi %s", rest)
+		}
+		w.SetDPI(float32(d))
+	case "png":
+		if rest == "" {
+			return errors.New("usage: png file")
+		}
+		return writePNG(rest, w.Image())
+	default:
+		return fmt.Errorf("unknown command %s", cmd)
+	}
+	return nil
+}
+
+// ints returns the space-separated integers of a string,
+// of which there must be between min and max.
+func ints(s string, min, max int) ([]int, error) {
+	fs := strings.Fields(s)
+	switch {
+	case (len(fs) < min || len(fs) > max) && min == max:
+		return nil, fmt.Errorf("want %d numbers, got %d", min, len(fs))
+	case len(fs) < min || len(fs) > max:
+		return nil, fmt.Errorf("want %d to %d numbers, got %d", min, max, len(fs))
+	}
+	var ns []int
+	for _, f := range fs {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %s", f)
+		}
+		ns = append(ns, n)
+	}
+	return ns, nil
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
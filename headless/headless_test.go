@@ -0,0 +1,121 @@
+package headless
+
+import (
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestWin(t *testing.T) (*Win, string) {
+	dir, err := ioutil.TempDir("", "T_headless_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	os.Setenv("HOME", dir)
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	os.Setenv("T_CONFIG", filepath.Join(dir, "config"))
+	return New(image.Pt(400, 300), 72), dir
+}
+
+func TestRun(t *testing.T) {
+	w, dir := newTestWin(t)
+	defer os.RemoveAll(dir)
+	defer w.Close()
+
+	before := image.NewRGBA(w.Image().Bounds())
+	copy(before.Pix, w.Image().Pix)
+
+	path := filepath.Join(dir, "out.png")
+	script := "# a comment\n\n" +
+		"exec NewRow\n" +
+		"type \"Hello,\\tWorld\\n\"\n" +
+		"key left\n" +
+		"tick 2\n" +
+		"png " + path + "\n"
+	if err := w.Run(strings.NewReader(script)); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open PNG: %v", err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("failed to decode PNG: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 400, 300) {
+		t.Errorf("PNG bounds=%v, want %v", img.Bounds(), image.Rect(0, 0, 400, 300))
+	}
+	if string(before.Pix) == string(w.Image().Pix) {
+		t.Errorf("image did not change after adding a row and typing")
+	}
+}
+
+func TestRun_Size(t *testing.T) {
+	w, dir := newTestWin(t)
+	defer os.RemoveAll(dir)
+	defer w.Close()
+	if err := w.Run(strings.NewReader("size 200 100\n")); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if b := w.Image().Bounds(); b != image.Rect(0, 0, 200, 100) {
+		t.Errorf("Image().Bounds()=%v, want %v", b, image.Rect(0, 0, 200, 100))
+	}
+}
+
+func TestRun_Error(t *testing.T) {
+	w, dir := newTestWin(t)
+	defer os.RemoveAll(dir)
+	defer w.Close()
+	tests := []struct {
+		script, err string
+	}{
+		{"tick\nnope\n", "line 2: unknown command nope"},
+		{"click 1\n", "line 1: want 2 to 3 numbers, got 1"},
+		{"move 1 x\n", "line 1: bad number x"},
+		{"size 0 10\n", "line 1: size must be positive"},
+		{"key f13\n", "line 1: unknown key f13"},
+		{"type \"abc\n", "line 1: bad string \"abc"},
+		{"sleep soon\n", "line 1: bad duration soon"},
+		{"png\n", "line 1: usage: png file"},
+	}
+	for _, test := range tests {
+		err := w.Run(strings.NewReader(test.script))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("Run(%q)=%v, want %q", test.script, err, test.err)
+		}
+	}
+}
+
+func TestRun_Preedit(t *testing.T) {
+	w, dir := newTestWin(t)
+	defer os.RemoveAll(dir)
+	defer w.Close()
+	if err := w.Run(strings.NewReader("exec NewRow\ntype Hello\n")); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	before := string(w.Image().Pix)
+	if err := w.Run(strings.NewReader("preedit ni\n")); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if string(w.Image().Pix) == before {
+		t.Errorf("image did not change after preedit")
+	}
+	if err := w.Run(strings.NewReader("preedit \"\"\n")); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if string(w.Image().Pix) != before {
+		t.Errorf("image changed after canceling preedit")
+	}
+	if err := w.Run(strings.NewReader("commit 你\n")); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if string(w.Image().Pix) == before {
+		t.Errorf("image did not change after commit")
+	}
+}
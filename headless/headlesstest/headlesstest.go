@@ -0,0 +1,215 @@
+// Package headlesstest provides golden image tests
+// of headless windows.
+// Scripts of events are run in a new headless window,
+// and its image is compared with a checked-in golden PNG file,
+// allowing small differences of color, such as from font rasterization.
+//
+// Run the tests with -headlesstest.update
+// to write the golden files from the current images.
+package headlesstest
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/T/headless"
+)
+
+var update = flag.Bool("headlesstest.update", false, "write golden files instead of comparing images with them")
+
+// Options are the options of golden image tests.
+// The zero Options is a 400×300 window at 72 DPI
+// that must match its golden images exactly.
+type Options struct {
+	// Size is the size of the window in pixels.
+	Size image.Point
+
+	// DPI is the resolution of the window in dots per inch.
+	DPI float32
+
+	// Tolerance is how much each color channel of a pixel,
+	// 0 to 255, may differ from the golden image.
+	Tolerance int
+
+	// MaxPixels is the number of pixels that may differ
+	// by more than Tolerance.
+	MaxPixels int
+}
+
+func (opts Options) size() image.Point {
+	if opts.Size == image.ZP {
+		return image.Pt(400, 300)
+	}
+	return opts.Size
+}
+
+func (opts Options) dpi() float32 {
+	if opts.DPI == 0 {
+		return 72
+	}
+	return opts.DPI
+}
+
+// RunScripts runs each script file dir/*.script in a new window,
+// see headless.Win.Run,
+// and compares the image of the window at the end of the script
+// with the golden file dir/name_golden.png.
+// The window's home and configuration directories
+// are a new temporary directory.
+func RunScripts(t *testing.T, dir string, opts Options) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.script"))
+	if err != nil {
+		t.Fatalf("failed to list scripts: %v", err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no scripts in %s", dir)
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".script")
+		t.Run(name, func(t *testing.T) {
+			img, err := runScript(path, opts)
+			if err != nil {
+				t.Fatalf("%s: %v", path, err)
+			}
+			Golden(t, img, filepath.Join(dir, name+"_golden.png"), opts)
+		})
+	}
+}
+
+func runScript(path string, opts Options) (*image.RGBA, error) {
+	script, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer script.Close()
+
+	home, err := ioutil.TempDir("", "T_headlesstest")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(home)
+	for k, v := range map[string]string{
+		"HOME":            home,
+		"XDG_CONFIG_HOME": home,
+		"T_CONFIG":        filepath.Join(home, "config"),
+	} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+
+	w := headless.New(opts.size(), opts.dpi())
+	defer w.Close()
+	if err := w.Run(script); err != nil {
+		return nil, err
+	}
+	return w.Image(), nil
+}
+
+// Golden compares the image with the golden PNG file.
+// If they differ by more than the options allow,
+// the test fails, and the image is written to name_new.png
+// and the differing pixels to name_diff.png,
+// next to the golden file name_golden.png.
+// With -headlesstest.update, the image is written to the golden file.
+func Golden(t testing.TB, img image.Image, golden string, opts Options) {
+	t.Helper()
+	base := strings.TrimSuffix(golden, "_golden.png")
+	base = strings.TrimSuffix(base, ".png")
+	if *update {
+		if err := writePNG(golden, img); err != nil {
+			t.Fatalf("failed to write golden file: %v", err)
+		}
+		return
+	}
+	want, err := readPNG(golden)
+	if err != nil {
+		writePNG(base+"_new.png", img)
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	n, diff := Diff(img, want, opts.Tolerance)
+	if n > opts.MaxPixels {
+		writePNG(base+"_new.png", img)
+		writePNG(base+"_diff.png", diff)
+		t.Errorf("%d pixels of %s_new.png differ from %s, want at most %d",
+			n, base, golden, opts.MaxPixels)
+	}
+}
+
+// Diff returns the number of pixels of the images
+// with a color channel that differs by more than the tolerance
+// and an image of the differences:
+// the differing pixels are red and the others are faded.
+// If the bounds of the images differ,
+// all pixels in either image but not in both differ.
+func Diff(a, b image.Image, tolerance int) (int, *image.RGBA) {
+	r := a.Bounds().Union(b.Bounds())
+	diff := image.NewRGBA(r)
+	var n int
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			pt := image.Pt(x, y)
+			if !pt.In(a.Bounds()) || !pt.In(b.Bounds()) {
+				n++
+				diff.SetRGBA(x, y, color.RGBA{R: 0xFF, A: 0xFF})
+				continue
+			}
+			ca := color.RGBAModel.Convert(a.At(x, y)).(color.RGBA)
+			cb := color.RGBAModel.Convert(b.At(x, y)).(color.RGBA)
+			if channelDiff(ca.R, cb.R) > tolerance ||
+				channelDiff(ca.G, cb.G) > tolerance ||
+				channelDiff(ca.B, cb.B) > tolerance ||
+				channelDiff(ca.A, cb.A) > tolerance {
+				n++
+				diff.SetRGBA(x, y, color.RGBA{R: 0xFF, A: 0xFF})
+				continue
+			}
+			diff.SetRGBA(x, y, color.RGBA{
+				R: 0xC0 + ca.R/4,
+				G: 0xC0 + ca.G/4,
+				B: 0xC0 + ca.B/4,
+				A: 0xFF,
+			})
+		}
+	}
+	return n, diff
+}
+
+func channelDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return img, nil
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
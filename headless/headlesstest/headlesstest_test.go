@@ -0,0 +1,115 @@
+package headlesstest
+
+import (
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	b := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	a.SetRGBA(0, 0, color.RGBA{R: 10, A: 0xFF})
+	b.SetRGBA(0, 0, color.RGBA{R: 12, A: 0xFF})
+	a.SetRGBA(1, 1, color.RGBA{G: 100, A: 0xFF})
+	b.SetRGBA(1, 1, color.RGBA{G: 200, A: 0xFF})
+
+	tests := []struct {
+		tolerance int
+		want      int
+	}{
+		{tolerance: 0, want: 2},
+		{tolerance: 2, want: 1},
+		{tolerance: 100, want: 0},
+	}
+	for _, test := range tests {
+		n, diff := Diff(a, b, test.tolerance)
+		if n != test.want {
+			t.Errorf("Diff(tolerance=%d)=%d, want %d", test.tolerance, n, test.want)
+		}
+		if diff.Bounds() != a.Bounds() {
+			t.Errorf("diff bounds=%v, want %v", diff.Bounds(), a.Bounds())
+		}
+	}
+
+	if n, _ := Diff(a, image.NewRGBA(image.Rect(0, 0, 3, 2)), 0xFF); n != 2 {
+		t.Errorf("Diff of different sizes=%d, want 2", n)
+	}
+}
+
+func TestRunScripts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "T_headlesstest_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hello.script")
+	script := "exec NewRow\ntype Hello, World\n"
+	if err := ioutil.WriteFile(path, []byte(script), 0666); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	opts := Options{Size: image.Pt(200, 100)}
+	img, err := runScript(path, opts)
+	if err != nil {
+		t.Fatalf("runScript failed: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 200, 100) {
+		t.Errorf("image bounds=%v, want %v", img.Bounds(), image.Rect(0, 0, 200, 100))
+	}
+	if err := writePNG(filepath.Join(dir, "hello_golden.png"), img); err != nil {
+		t.Fatalf("failed to write golden file: %v", err)
+	}
+	RunScripts(t, dir, opts)
+	if _, err := os.Stat(filepath.Join(dir, "hello_new.png")); err == nil {
+		t.Errorf("hello_new.png was written for a matching image")
+	}
+}
+
+func TestGolden_Mismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "T_headlesstest_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	golden := filepath.Join(dir, "x_golden.png")
+	if err := writePNG(golden, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatalf("failed to write golden file: %v", err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.SetRGBA(0, 0, color.RGBA{R: 0xFF, A: 0xFF})
+
+	var tb fakeTB
+	Golden(&tb, img, golden, Options{MaxPixels: 1})
+	if tb.failed {
+		t.Errorf("Golden failed with 1 of MaxPixels 1 differing")
+	}
+	Golden(&tb, img, golden, Options{})
+	if !tb.failed {
+		t.Errorf("Golden did not fail with 1 pixel differing")
+	}
+	for _, name := range []string{"x_new.png", "x_diff.png"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s was not written: %v", name, err)
+		}
+	}
+}
+
+// fakeTB is a testing.TB that records whether it failed.
+type fakeTB struct {
+	testing.TB
+	failed bool
+}
+
+func (tb *fakeTB) Helper()                       {}
+func (tb *fakeTB) Errorf(string, ...interface{}) { tb.failed = true }
+func (tb *fakeTB) Fatalf(string, ...interface{}) { tb.failed = true }
+
+// TestScripts runs the scripts of testdata.
+// The text is rasterized in pure Go, but the tolerance allows
+// for differences of floating point arithmetic between architectures.
+func TestScripts(t *testing.T) {
+	RunScripts(t, "testdata", Options{Tolerance: 8, MaxPixels: 20})
+}
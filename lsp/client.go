@@ -0,0 +1,235 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// A Client is a connection to a language server process.
+type Client struct {
+	conn *Conn
+	cmd  *exec.Cmd // nil if not started by Start
+
+	mu        sync.Mutex
+	diags     map[string][]Diagnostic // by URI
+	gen       int                     // incremented with each diagnostics change
+	published func()                  // called when diagnostics are published; nil if none
+}
+
+// Start starts the language server command in the directory
+// and initializes it with the directory as the workspace root.
+func Start(cmd []string, dir string) (*Client, error) {
+	if len(cmd) == 0 {
+		return nil, errors.New("no command")
+	}
+	c := exec.Command(cmd[0], cmd[1:]...)
+	c.Dir = dir
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := c.StdoutPipe()
+	if err != nil {
+		stdin.Close()
+		return nil, err
+	}
+	if err := c.Start(); err != nil {
+		stdin.Close()
+		stdout.Close()
+		return nil, err
+	}
+	cl, err := NewClient(stdout, stdin, dir)
+	if err != nil {
+		c.Process.Kill()
+		c.Wait()
+		return nil, err
+	}
+	cl.cmd = c
+	return cl, nil
+}
+
+// NewClient returns a client of the server
+// reading from r and writing to w,
+// initialized with the directory as the workspace root.
+func NewClient(r io.Reader, w io.Writer, dir string) (*Client, error) {
+	cl := &Client{diags: make(map[string][]Diagnostic)}
+	cl.conn = NewConn(r, w, cl.notify)
+	params := map[string]interface{}{
+		"processId": os.Getpid(),
+		"rootUri":   PathURI(dir),
+		"capabilities": map[string]interface{}{
+			"textDocument": map[string]interface{}{
+				"synchronization":    map[string]interface{}{"didSave": true},
+				"hover":              map[string]interface{}{"contentFormat": []string{"plaintext"}},
+				"completion":         map[string]interface{}{},
+				"definition":         map[string]interface{}{},
+				"references":         map[string]interface{}{},
+				"publishDiagnostics": map[string]interface{}{},
+			},
+		},
+	}
+	if err := cl.conn.Call("initialize", params, nil); err != nil {
+		return nil, err
+	}
+	if err := cl.conn.Notify("initialized", struct{}{}); err != nil {
+		return nil, err
+	}
+	return cl, nil
+}
+
+func (cl *Client) notify(method string, params json.RawMessage) {
+	if method != "textDocument/publishDiagnostics" {
+		return
+	}
+	var p publishDiagnostics
+	if json.Unmarshal(params, &p) != nil {
+		return
+	}
+	cl.mu.Lock()
+	cl.diags[p.URI] = p.Diagnostics
+	cl.gen++
+	f := cl.published
+	cl.mu.Unlock()
+	if f != nil {
+		f()
+	}
+}
+
+// SetNotify sets a function called, from the goroutine reading from the server,
+// each time diagnostics are published.
+func (cl *Client) SetNotify(f func()) {
+	cl.mu.Lock()
+	cl.published = f
+	cl.mu.Unlock()
+}
+
+// Diagnostics returns the last diagnostics published for the file
+// and a generation number that changes when any diagnostics are published.
+func (cl *Client) Diagnostics(path string) ([]Diagnostic, int) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.diags[PathURI(path)], cl.gen
+}
+
+// Open notifies the server that the file is open with the text.
+func (cl *Client) Open(path, languageID string, version int, text string) error {
+	return cl.conn.Notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": textDocumentItem{
+			URI:        PathURI(path),
+			LanguageID: languageID,
+			Version:    version,
+			Text:       text,
+		},
+	})
+}
+
+// Change notifies the server that the text of the open file changed.
+func (cl *Client) Change(path string, version int, text string) error {
+	return cl.conn.Notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   textDocumentID{URI: PathURI(path), Version: version},
+		"contentChanges": []contentChange{{Text: text}},
+	})
+}
+
+// Save notifies the server that the open file was saved.
+func (cl *Client) Save(path string) error {
+	return cl.conn.Notify("textDocument/didSave", map[string]interface{}{
+		"textDocument": textDocumentID{URI: PathURI(path)},
+	})
+}
+
+// Close notifies the server that the file is no longer open.
+func (cl *Client) Close(path string) error {
+	return cl.conn.Notify("textDocument/didClose", map[string]interface{}{
+		"textDocument": textDocumentID{URI: PathURI(path)},
+	})
+}
+
+// Hover returns the hover text at the position of the open file.
+func (cl *Client) Hover(path string, p Position) (string, error) {
+	var result *struct {
+		Contents json.RawMessage `json:"contents"`
+	}
+	if err := cl.conn.Call("textDocument/hover", position(path, p), &result); err != nil || result == nil {
+		return "", err
+	}
+	return hoverText(result.Contents), nil
+}
+
+// Definition returns the locations of the definition
+// of the symbol at the position of the open file.
+func (cl *Client) Definition(path string, p Position) ([]Location, error) {
+	var result json.RawMessage
+	if err := cl.conn.Call("textDocument/definition", position(path, p), &result); err != nil {
+		return nil, err
+	}
+	return locations(result), nil
+}
+
+// References returns the locations of the references
+// to the symbol at the position of the open file,
+// including its declaration.
+func (cl *Client) References(path string, p Position) ([]Location, error) {
+	params := map[string]interface{}{
+		"textDocument": textDocumentID{URI: PathURI(path)},
+		"position":     p,
+		"context":      map[string]bool{"includeDeclaration": true},
+	}
+	var locs []Location
+	if err := cl.conn.Call("textDocument/references", params, &locs); err != nil {
+		return nil, err
+	}
+	return locs, nil
+}
+
+// Completion returns the completions at the position of the open file.
+func (cl *Client) Completion(path string, p Position) ([]CompletionItem, error) {
+	var result json.RawMessage
+	if err := cl.conn.Call("textDocument/completion", position(path, p), &result); err != nil {
+		return nil, err
+	}
+	var list struct {
+		Items []CompletionItem `json:"items"`
+	}
+	if json.Unmarshal(result, &list) == nil && list.Items != nil {
+		return list.Items, nil
+	}
+	var items []CompletionItem
+	json.Unmarshal(result, &items)
+	return items, nil
+}
+
+// Shutdown asks the server to exit
+// and, if it was started by Start, waits for it,
+// killing it if it has not exited after the timeout.
+func (cl *Client) Shutdown(timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		err := cl.conn.Call("shutdown", nil, nil)
+		if err == nil {
+			err = cl.conn.Notify("exit", nil)
+		}
+		if cl.cmd != nil {
+			cl.cmd.Wait()
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		if cl.cmd != nil {
+			cl.cmd.Process.Kill()
+		}
+		return errors.New("shutdown timed out")
+	}
+}
+
+func position(path string, p Position) textDocumentPosition {
+	return textDocumentPosition{TextDocument: textDocumentID{URI: PathURI(path)}, Position: p}
+}
@@ -0,0 +1,163 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeServer serves the test client,
+// replying to each request with the result for its method
+// and publishing a diagnostic for each opened file.
+func fakeServer(t *testing.T, r io.Reader, w io.Writer, results map[string]string) {
+	br := bufio.NewReader(r)
+	send := func(s string) {
+		fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(s), s)
+	}
+	for {
+		data, err := readMessage(br)
+		if err != nil {
+			return
+		}
+		var m incoming
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Errorf("bad message %q: %v", data, err)
+			return
+		}
+		switch {
+		case m.Method == "textDocument/didOpen":
+			var p struct{ TextDocument textDocumentItem }
+			json.Unmarshal(m.Params, &p)
+			// A request from the server must be answered.
+			send(`{"jsonrpc":"2.0","id":"x","method":"workspace/configuration","params":{}}`)
+			send(`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"` +
+				p.TextDocument.URI + `","diagnostics":[{"range":{"start":{"line":1,"character":2},"end":{"line":1,"character":4}},"severity":2,"message":"` +
+				p.TextDocument.Text + `"}]}}`)
+		case m.ID != nil && m.Method != "":
+			result, ok := results[m.Method]
+			if !ok {
+				send(`{"jsonrpc":"2.0","id":` + string(*m.ID) + `,"error":{"code":-32601,"message":"no ` + m.Method + `"}}`)
+				continue
+			}
+			send(`{"jsonrpc":"2.0","id":` + string(*m.ID) + `,"result":` + result + `}`)
+		}
+	}
+}
+
+func newTestClient(t *testing.T, results map[string]string) *Client {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+	go fakeServer(t, sr, sw, results)
+	cl, err := NewClient(cr, cw, "/home/test")
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	return cl
+}
+
+func TestClient(t *testing.T) {
+	cl := newTestClient(t, map[string]string{
+		"initialize":              `{"capabilities":{}}`,
+		"textDocument/hover":      `{"contents":{"kind":"plaintext","value":"func F()"}}`,
+		"textDocument/definition": `[{"targetUri":"file:///a/b.go","targetSelectionRange":{"start":{"line":3,"character":5},"end":{"line":3,"character":6}}}]`,
+		"textDocument/completion": `{"isIncomplete":false,"items":[{"label":"Foo"},{"label":"Bar","insertText":"Bar()"}]}`,
+		"textDocument/references": `[{"uri":"file:///a/b.go","range":{"start":{"line":3,"character":5},"end":{"line":3,"character":6}}},{"uri":"file:///a/c.go","range":{"start":{"line":7,"character":1},"end":{"line":7,"character":2}}}]`,
+		"shutdown":                `null`,
+	})
+
+	if err := cl.Open("/a/b.go", "go", 1, "hello"); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	var ds []Diagnostic
+	for start := time.Now(); len(ds) == 0 && time.Since(start) < 5*time.Second; {
+		ds, _ = cl.Diagnostics("/a/b.go")
+		time.Sleep(time.Millisecond)
+	}
+	want := []Diagnostic{{Range: Range{Start: Position{1, 2}, End: Position{1, 4}}, Severity: Warning, Message: "hello"}}
+	if !reflect.DeepEqual(ds, want) {
+		t.Errorf("Diagnostics=%+v, want %+v", ds, want)
+	}
+
+	if h, err := cl.Hover("/a/b.go", Position{}); err != nil || h != "func F()" {
+		t.Errorf("Hover=%q,%v, want %q,nil", h, err, "func F()")
+	}
+	locs, err := cl.Definition("/a/b.go", Position{})
+	wantLocs := []Location{{URI: "file:///a/b.go", Range: Range{Start: Position{3, 5}, End: Position{3, 6}}}}
+	if err != nil || !reflect.DeepEqual(locs, wantLocs) {
+		t.Errorf("Definition=%+v,%v, want %+v,nil", locs, err, wantLocs)
+	}
+	if locs[0].Path() != "/a/b.go" {
+		t.Errorf("Path()=%q, want /a/b.go", locs[0].Path())
+	}
+	refs, err := cl.References("/a/b.go", Position{})
+	wantRefs := append(wantLocs, Location{URI: "file:///a/c.go", Range: Range{Start: Position{7, 1}, End: Position{7, 2}}})
+	if err != nil || !reflect.DeepEqual(refs, wantRefs) {
+		t.Errorf("References=%+v,%v, want %+v,nil", refs, err, wantRefs)
+	}
+	items, err := cl.Completion("/a/b.go", Position{})
+	if err != nil || len(items) != 2 || items[0].Text() != "Foo" || items[1].Text() != "Bar()" {
+		t.Errorf("Completion=%+v,%v, want Foo and Bar()", items, err)
+	}
+	if err := cl.Change("/a/b.go", 2, "bye"); err != nil {
+		t.Errorf("Change failed: %v", err)
+	}
+	if err := cl.conn.Call("unknown", nil, nil); err == nil || err.Error() != "no unknown" {
+		t.Errorf("Call(unknown)=%v, want no unknown", err)
+	}
+	if err := cl.Shutdown(5 * time.Second); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+}
+
+func TestHoverText(t *testing.T) {
+	tests := []struct {
+		contents, want string
+	}{
+		{`"x"`, "x"},
+		{`{"kind":"markdown","value":"y"}`, "y"},
+		{`{"language":"go","value":"z"}`, "z"},
+		{`["a",{"language":"go","value":"b"}]`, "a\nb"},
+		{`null`, ""},
+	}
+	for _, test := range tests {
+		if got := hoverText(json.RawMessage(test.contents)); got != test.want {
+			t.Errorf("hoverText(%s)=%q, want %q", test.contents, got, test.want)
+		}
+	}
+}
+
+func TestPos(t *testing.T) {
+	const text = "ab\nc😀d\n\nxyz"
+	tests := []struct {
+		offset int
+		pos    Position
+	}{
+		{0, Position{0, 0}},
+		{2, Position{0, 2}},
+		{3, Position{1, 0}},
+		{4, Position{1, 1}},
+		{8, Position{1, 3}},
+		{9, Position{1, 4}},
+		{10, Position{2, 0}},
+		{13, Position{3, 2}},
+		{14, Position{3, 3}},
+	}
+	for _, test := range tests {
+		if p := Pos(text, test.offset); p != test.pos {
+			t.Errorf("Pos(%d)=%v, want %v", test.offset, p, test.pos)
+		}
+		if o := Offset(text, test.pos); o != test.offset {
+			t.Errorf("Offset(%v)=%d, want %d", test.pos, o, test.offset)
+		}
+	}
+	if o := Offset(text, Position{0, 10}); o != 2 {
+		t.Errorf("Offset past the end of a line=%d, want 2", o)
+	}
+	if o := Offset(text, Position{10, 0}); o != len(text) {
+		t.Errorf("Offset past the last line=%d, want %d", o, len(text))
+	}
+}
@@ -0,0 +1,194 @@
+// Package lsp implements a client of the Language Server Protocol.
+// See https://microsoft.github.io/language-server-protocol/
+// for the protocol specification.
+//
+// The client supports document synchronization by full text,
+// diagnostics, hover, go-to-definition, references, and completion.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A Conn is a JSON-RPC 2.0 connection
+// using the base protocol of the Language Server Protocol:
+// each message is preceded by a Content-Length header.
+type Conn struct {
+	w      io.Writer
+	wmu    sync.Mutex
+	notify func(method string, params json.RawMessage)
+
+	mu      sync.Mutex
+	nextID  int
+	pending map[int]chan response
+	err     error // the error that ended reading, if any
+}
+
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  interface{}      `json:"params,omitempty"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *ResponseError   `json:"error,omitempty"`
+}
+
+type incoming struct {
+	ID     *json.RawMessage `json:"id"`
+	Method string           `json:"method"`
+	Params json.RawMessage  `json:"params"`
+	Result json.RawMessage  `json:"result"`
+	Error  *ResponseError   `json:"error"`
+}
+
+type response struct {
+	result json.RawMessage
+	err    error
+}
+
+// A ResponseError is an error returned by the server.
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string { return e.Message }
+
+// NewConn returns a new connection that writes messages to w
+// and reads them from r until r returns an error.
+// Notifications from the server are passed to notify,
+// called from the reading goroutine.
+// Requests from the server are answered with a null result.
+func NewConn(r io.Reader, w io.Writer, notify func(method string, params json.RawMessage)) *Conn {
+	c := &Conn{w: w, notify: notify, pending: make(map[int]chan response)}
+	go c.read(bufio.NewReader(r))
+	return c
+}
+
+// Call sends a request and waits for its response,
+// which is unmarshaled into result if result is non-nil.
+func (c *Conn) Call(method string, params, result interface{}) error {
+	ch := make(chan response, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return err
+	}
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	raw := json.RawMessage(strconv.Itoa(id))
+	if err := c.write(message{JSONRPC: "2.0", ID: &raw, Method: method, Params: params}); err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return err
+	}
+	resp := <-ch
+	if resp.err != nil {
+		return resp.err
+	}
+	if result == nil || len(resp.result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.result, result)
+}
+
+// Notify sends a notification.
+func (c *Conn) Notify(method string, params interface{}) error {
+	return c.write(message{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (c *Conn) write(m message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(data)
+	return err
+}
+
+func (c *Conn) read(r *bufio.Reader) {
+	var err error
+	for err == nil {
+		var data []byte
+		if data, err = readMessage(r); err == nil {
+			c.dispatch(data)
+		}
+	}
+	c.mu.Lock()
+	c.err = err
+	for id, ch := range c.pending {
+		ch <- response{err: err}
+		delete(c.pending, id)
+	}
+	c.mu.Unlock()
+}
+
+func (c *Conn) dispatch(data []byte) {
+	var m incoming
+	if err := json.Unmarshal(data, &m); err != nil {
+		return
+	}
+	switch {
+	case m.Method != "" && m.ID != nil:
+		// A request from the server.
+		// The reply is written concurrently,
+		// since the server may not read it until it has written more.
+		go c.write(message{JSONRPC: "2.0", ID: m.ID, Result: json.RawMessage("null")})
+	case m.Method != "":
+		if c.notify != nil {
+			c.notify(m.Method, m.Params)
+		}
+	case m.ID != nil:
+		id, err := strconv.Atoi(string(*m.ID))
+		if err != nil {
+			return
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[id]
+		delete(c.pending, id)
+		c.mu.Unlock()
+		if !ok {
+			return
+		}
+		if m.Error != nil {
+			ch <- response{err: m.Error}
+		} else {
+			ch <- response{result: m.Result}
+		}
+	}
+}
+
+// readMessage reads the headers and content of a message.
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	h, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(h.Get("Content-Length")))
+	if err != nil || n < 0 {
+		return nil, errors.New("bad Content-Length")
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
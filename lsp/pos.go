@@ -0,0 +1,58 @@
+package lsp
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Pos returns the position of a byte offset in the text.
+func Pos(text string, offset int) Position {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	var p Position
+	for i, r := range text[:offset] {
+		if r == '\n' {
+			p.Line++
+			p.Character = 0
+			continue
+		}
+		if i+utf8.RuneLen(r) > offset {
+			break
+		}
+		p.Character += utf16Len(r)
+	}
+	return p
+}
+
+// Offset returns the byte offset of a position in the text.
+// Positions past the end of a line are at the end of the line,
+// and positions past the last line are at the end of the text.
+func Offset(text string, p Position) int {
+	var i, line int
+	for line < p.Line {
+		j := strings.IndexByte(text[i:], '\n')
+		if j < 0 {
+			return len(text)
+		}
+		i += j + 1
+		line++
+	}
+	for n := 0; n < p.Character && i < len(text); {
+		r, w := utf8.DecodeRuneInString(text[i:])
+		if r == '\n' {
+			break
+		}
+		n += utf16Len(r)
+		i += w
+	}
+	return i
+}
+
+// utf16Len returns the number of UTF-16 code units encoding the rune.
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
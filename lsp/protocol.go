@@ -0,0 +1,174 @@
+package lsp
+
+import (
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// A Position is a zero-based line
+// and UTF-16 code unit offset in the line.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// A Range is a span of text from Start up to, not including, End.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// A Location is a range in a file.
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// Path returns the file path of the location's URI.
+func (l Location) Path() string { return URIPath(l.URI) }
+
+// Severities of diagnostics.
+const (
+	Error       = 1
+	Warning     = 2
+	Information = 3
+	Hint        = 4
+)
+
+// A Diagnostic is an error or warning about a range of a file.
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Message  string `json:"message"`
+}
+
+// SeverityName returns the name of the diagnostic's severity.
+func (d Diagnostic) SeverityName() string {
+	switch d.Severity {
+	case Warning:
+		return "warning"
+	case Information:
+		return "info"
+	case Hint:
+		return "hint"
+	default:
+		return "error"
+	}
+}
+
+// A CompletionItem is a candidate completion.
+type CompletionItem struct {
+	Label      string `json:"label"`
+	Detail     string `json:"detail,omitempty"`
+	InsertText string `json:"insertText,omitempty"`
+	TextEdit   *struct {
+		Range   Range  `json:"range"`
+		NewText string `json:"newText"`
+	} `json:"textEdit,omitempty"`
+}
+
+// Text returns the text inserted by the completion.
+func (c CompletionItem) Text() string {
+	switch {
+	case c.TextEdit != nil:
+		return c.TextEdit.NewText
+	case c.InsertText != "":
+		return c.InsertText
+	default:
+		return c.Label
+	}
+}
+
+type textDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+type textDocumentID struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version,omitempty"`
+}
+
+type textDocumentPosition struct {
+	TextDocument textDocumentID `json:"textDocument"`
+	Position     Position       `json:"position"`
+}
+
+type contentChange struct {
+	Text string `json:"text"`
+}
+
+type publishDiagnostics struct {
+	URI         string       `json:"uri"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// PathURI returns the file URI of an absolute path.
+func PathURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// URIPath returns the path of a file URI.
+func URIPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// hoverText returns the text of the contents of a hover result,
+// which is a MarkupContent, a MarkedString, or a list of MarkedStrings.
+func hoverText(data json.RawMessage) string {
+	var str string
+	if json.Unmarshal(data, &str) == nil {
+		return str
+	}
+	var v struct {
+		Value string `json:"value"`
+	}
+	if json.Unmarshal(data, &v) == nil && v.Value != "" {
+		return v.Value
+	}
+	var list []json.RawMessage
+	if json.Unmarshal(data, &list) != nil {
+		return ""
+	}
+	var ss []string
+	for _, l := range list {
+		if s := hoverText(l); s != "" {
+			ss = append(ss, s)
+		}
+	}
+	return strings.Join(ss, "\n")
+}
+
+// locations returns the locations of a definition result,
+// which is a Location, a list of Locations, or a list of LocationLinks.
+func locations(data json.RawMessage) []Location {
+	var l Location
+	if json.Unmarshal(data, &l) == nil && l.URI != "" {
+		return []Location{l}
+	}
+	var list []struct {
+		Location
+		TargetURI   string `json:"targetUri"`
+		TargetRange Range  `json:"targetSelectionRange"`
+	}
+	if json.Unmarshal(data, &list) != nil {
+		return nil
+	}
+	var ls []Location
+	for _, l := range list {
+		if l.TargetURI != "" {
+			l.Location = Location{URI: l.TargetURI, Range: l.TargetRange}
+		}
+		ls = append(ls, l.Location)
+	}
+	return ls
+}
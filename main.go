@@ -3,6 +3,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"image"
 	"image/draw"
@@ -10,9 +11,12 @@ import (
 	"math"
 	"os"
 	"runtime/pprof"
+	"sort"
 	"time"
 
+	"github.com/eaburns/T/headless"
 	"github.com/eaburns/T/ui"
+	"golang.org/x/exp/shiny/driver"
 	"golang.org/x/exp/shiny/driver/gldriver"
 	"golang.org/x/exp/shiny/screen"
 	"golang.org/x/image/math/f64"
@@ -21,27 +25,137 @@ import (
 	"golang.org/x/mobile/event/mouse"
 	"golang.org/x/mobile/event/paint"
 	"golang.org/x/mobile/event/size"
+	"golang.org/x/mobile/event/touch"
 )
 
-const tickRate = 20 * time.Millisecond
+const (
+	// maxRepeatBurst is the most repeats of a held key
+	// generated in a single tick.
+	maxRepeatBurst = 4
 
-var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to `file`")
+	// touchSlop is the distance in pixels that a touch can move
+	// and still be a tap or long press.
+	touchSlop = 10
+
+	// longPressDelay is the time a touch must be held
+	// without moving to be a long press.
+	longPressDelay = 500 * time.Millisecond
+)
+
+var (
+	cpuprofile   = flag.String("cpuprofile", "", "write cpu profile to `file`")
+	stickyKeys   = flag.Bool("stickykeys", false, "latch modifier keys pressed and released alone")
+	slowKeyDelay = flag.Duration("slowkeys", 0, "ignore key presses held for less than `duration`")
+	deadKeys     = flag.Bool("deadkeys", false, "combine typed accents with the following rune")
+	repeatDelay  = flag.Duration("repeatdelay", 0, "repeat held arrow keys after `duration`, instead of the system's repeat")
+	repeatRate   = flag.Duration("repeatrate", 50*time.Millisecond, "initial `duration` between repeats of held arrow keys")
+	repeatMin    = flag.Duration("repeatmin", 10*time.Millisecond, "minimum `duration` between repeats of held arrow keys")
+	repeatAccel  = flag.Float64("repeataccel", 0.9, "`factor` by which the duration between repeats shrinks with each repeat")
+	fullscreen   = flag.Bool("fullscreen", false, "start with the window fullscreen")
+	fps          = flag.Int("fps", 60, "draw at most `n` frames per second; 0 is unlimited")
+	listen       = flag.String("listen", "", "accept JSON-RPC connections to control the editor on the Unix socket `path`")
+	script       = flag.String("headless", "", "run the script `file` (- for standard input) without a window, then exit")
+	driverName   = flag.String("driver", "auto", "the window `driver`: gl, software, or auto to use software if gl fails")
+)
 
 func main() {
-	gldriver.Main(func(scr screen.Screen) {
-		flag.Parse()
-		if *cpuprofile != "" {
-			f, err := os.Create(*cpuprofile)
-			if err != nil {
-				log.Fatal("could not create CPU profile: ", err)
-			}
-			if err := pprof.StartCPUProfile(f); err != nil {
-				log.Fatal("could not start CPU profile: ", err)
-			}
-			defer pprof.StopCPUProfile()
+	flag.Parse()
+	if *script != "" {
+		startProfile()
+		err := runScript(*script, flag.Args())
+		pprof.StopCPUProfile()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	startProfile()
+	err := runDriver(*driverName, flag.Args())
+	pprof.StopCPUProfile()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runDriver runs a window with the named driver until it is closed.
+// The software driver is the platform's default shiny driver,
+// which does not use GL on Linux and Windows;
+// its buffer is blitted directly to the window.
+func runDriver(name string, paths []string) error {
+	switch name {
+	case "gl":
+		return runWindow(gldriver.Main, false, paths)
+	case "software":
+		return runWindow(driver.Main, true, paths)
+	case "auto":
+		err := runWindow(gldriver.Main, false, paths)
+		var werr windowError
+		if !errors.As(err, &werr) {
+			return err
+		}
+		log.Printf("%v; using the software driver", err)
+		return runWindow(driver.Main, true, paths)
+	default:
+		return errors.New("unknown driver " + name)
+	}
+}
+
+// A windowError is an error creating a window.
+type windowError struct{ err error }
+
+func (e windowError) Error() string { return "creating window: " + e.err.Error() }
+
+// runWindow runs a window with the main function of a driver
+// until it is closed.
+func runWindow(main func(func(screen.Screen)), blit bool, paths []string) error {
+	var err error
+	main(func(scr screen.Screen) {
+		var w *win
+		if w, err = newWindow(context.Background(), scr, blit, paths); err == nil {
+			<-w.done
 		}
-		<-newWindow(context.Background(), scr).done
 	})
+	return err
+}
+
+// startProfile starts the CPU profile if the -cpuprofile flag is set.
+func startProfile() {
+	if *cpuprofile == "" {
+		return
+	}
+	f, err := os.Create(*cpuprofile)
+	if err != nil {
+		log.Fatal("could not create CPU profile: ", err)
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		log.Fatal("could not start CPU profile: ", err)
+	}
+}
+
+// runScript runs a headless.Win script from a file,
+// or standard input if path is -,
+// with the paths opened as by the command line of a window.
+func runScript(path string, paths []string) error {
+	r := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	w := headless.New(image.Pt(1024, 768), 96)
+	defer w.Close()
+	w.SetStickyMods(*stickyKeys)
+	w.SetDeadKeys(*deadKeys)
+	w.OpenArgs(paths)
+	if *listen != "" {
+		if err := w.Listen(*listen); err != nil {
+			return err
+		}
+	}
+	return w.Run(r)
 }
 
 type win struct {
@@ -53,13 +167,22 @@ type win struct {
 	size image.Point
 	screen.Window
 
-	win *ui.Win
+	win  *ui.Win
+	blit bool          // whether to upload the buffer to the window instead of a texture
+	wake chan struct{} // receives when a tick is needed now
+	next chan nextTick // receives when the tick after a tick is needed
 }
 
-func newWindow(ctx context.Context, scr screen.Screen) *win {
-	window, err := scr.NewWindow(nil)
+// nextTick is when the tick after a tick is needed.
+type nextTick struct {
+	after time.Duration // the time from the tick to the next, or negative if none
+	rate  time.Duration // the shortest time from the tick to the next
+}
+
+func newWindow(ctx context.Context, scr screen.Screen, blit bool, paths []string) (*win, error) {
+	window, err := scr.NewWindow(&screen.NewWindowOptions{Title: "T"})
 	if err != nil {
-		panic(err)
+		return nil, windowError{err}
 	}
 	var e size.Event
 	for {
@@ -77,41 +200,103 @@ func newWindow(ctx context.Context, scr screen.Screen) *win {
 		dpi:    float32(e.PixelsPerPt) * 72.0,
 		size:   e.Size(),
 		Window: window,
+		blit:   blit,
+		wake:   make(chan struct{}, 1),
+		next:   make(chan nextTick, 1),
 	}
 	w.win = ui.NewWin(w.dpi)
+	w.win.SetWake(w.wakeUp)
+	w.win.SetStickyMods(*stickyKeys)
+	w.win.SetDeadKeys(*deadKeys)
 	w.win.Resize(w.size)
+	w.win.OpenArgs(paths)
+	w.win.SetFullscreen(*fullscreen)
+	if *listen != "" {
+		if err := w.win.Listen(*listen); err != nil {
+			w.win.OutputString(err.Error() + "\n")
+		}
+	}
 
 	go tick(w)
 	go poll(scr, w)
-	return w
+	return w, nil
 }
 
 func (w *win) Release() { w.cancel() }
 
 type done struct{}
 
+// wakeUp requests a tick as soon as the tick rate allows.
+// It is safe for concurrent calls and does not block.
+func (w *win) wakeUp() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// tick sends the time to the window when it needs a tick:
+// when it is woken, or after the duration that poll returns on next
+// for the previous tick, but at most once every rate it returns.
+// An idle window is not ticked.
 func tick(w *win) {
-	ticker := time.NewTicker(tickRate)
+	timer := time.NewTimer(0)
 	for {
 		select {
-		case <-ticker.C:
-			w.Send(time.Now())
+		case <-timer.C:
+		case <-w.wake:
+		case <-w.ctx.Done():
+			timer.Stop()
+			w.Send(done{})
+			return
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		last := time.Now()
+		w.Send(last)
+		var next nextTick
+		select {
+		case next = <-w.next:
 		case <-w.ctx.Done():
-			ticker.Stop()
 			w.Send(done{})
 			return
 		}
+		select {
+		case <-time.After(time.Until(last.Add(next.rate))):
+		case <-w.ctx.Done():
+			w.Send(done{})
+			return
+		}
+		if next.after >= 0 {
+			timer.Reset(time.Until(last.Add(next.after)))
+		}
 	}
 }
 
 func poll(scr screen.Screen, w *win) {
 	var mods [4]bool
+	slow := newSlowKeys(*slowKeyDelay)
+	rep := &keyRepeat{
+		delay:    *repeatDelay,
+		interval: *repeatRate,
+		min:      *repeatMin,
+		accel:    *repeatAccel,
+	}
 	dirty := true
+	title := "T"
+	full := false
 	buf, tex := bufTex(scr, w.size)
+	touches := newTouches()
+	pace := newPacer(*fps)
 
 	for {
 		switch e := w.NextEvent().(type) {
 		case done:
+			w.win.Close()
 			buf.Release()
 			tex.Release()
 			w.Window.Release()
@@ -119,22 +304,56 @@ func poll(scr screen.Screen, w *win) {
 			return
 
 		case time.Time:
+			for _, r := range slow.ready(e) {
+				if rep.accept(r, e) {
+					mods = keyEvent(w, mods, r)
+				}
+			}
+			for _, r := range rep.ready(e) {
+				mods = keyEvent(w, mods, r)
+			}
+			touches.tick(w, e)
 			if w.win.Tick() {
-				w.Send(paint.Event{})
+				pace.request(w)
+			}
+			if w.win.Exiting() {
+				w.cancel()
+			}
+			if t := w.win.Title(); t != title {
+				title = t
+				setTitle(w.Window, title)
+			}
+			if f := w.win.Fullscreen(); f != full {
+				full = f
+				setFullscreen(w.Window, full)
 			}
+			next := w.win.NextTick(e)
+			if len(slow.pending) > 0 || rep.held != nil || len(touches.seqs) > 0 {
+				next = 0
+			}
+			w.next <- nextTick{after: next, rate: w.win.TickRate()}
 
 		case lifecycle.Event:
 			if e.To == lifecycle.StageDead {
-				w.cancel()
+				if w.win.Exit() {
+					w.cancel()
+				} else {
+					pace.request(w)
+				}
 				continue
 			}
 			w.win.Focus(e.To == lifecycle.StageFocused)
+			w.wakeUp()
 
 		case size.Event:
 			if e.Size() == image.ZP {
 				w.cancel()
 				continue
 			}
+			if dpi := float32(e.PixelsPerPt) * 72.0; dpi != w.dpi {
+				w.dpi = dpi
+				w.win.SetDPI(dpi)
+			}
 			w.size = e.Size()
 			w.win.Resize(w.size)
 			dirty = true
@@ -143,13 +362,32 @@ func poll(scr screen.Screen, w *win) {
 				buf.Release()
 				buf, tex = bufTex(scr, w.size.Mul(2))
 			}
+			w.wakeUp()
 
 		case paint.Event:
+			if !e.External && !pace.pending {
+				// Already drawn by an earlier paint event.
+				continue
+			}
+			pace.painted(time.Now())
 			rect := image.Rectangle{Max: w.size}
 			img := buf.RGBA().SubImage(rect).(*image.RGBA)
-			w.win.Draw(dirty, img)
+			r := w.win.Draw(dirty, img)
 			dirty = false
-			tex.Upload(image.ZP, buf, buf.Bounds())
+			if w.blit {
+				if e.External {
+					// The window may have lost its contents.
+					r = rect
+				}
+				if !r.Empty() {
+					w.Upload(r.Min, buf, r)
+				}
+				w.Publish()
+				continue
+			}
+			if !r.Empty() {
+				tex.Upload(r.Min, buf, r)
+			}
 			w.Draw(f64.Aff3{
 				1, 0, 0,
 				0, 1, 0,
@@ -158,13 +396,76 @@ func poll(scr screen.Screen, w *win) {
 
 		case mouse.Event:
 			mouseEvent(w, e)
+			w.wakeUp()
+
+		case touch.Event:
+			touches.event(w, e, time.Now())
+			w.wakeUp()
 
 		case key.Event:
-			mods = keyEvent(w, mods, e)
+			now := time.Now()
+			if slow.accept(e, now) && rep.accept(e, now) {
+				mods = keyEvent(w, mods, e)
+			}
+			w.wakeUp()
 		}
 	}
 }
 
+// A pacer coalesces the paint events requested by ticks
+// and spaces the frames they draw evenly, at most fps a second,
+// so that continuous scrolling does not draw at uneven intervals.
+type pacer struct {
+	interval time.Duration // least time between frames
+	last     time.Time     // when the last frame was drawn
+	pending  bool          // whether a requested paint event is not yet handled
+}
+
+func newPacer(fps int) *pacer {
+	p := &pacer{}
+	if fps > 0 {
+		p.interval = time.Second / time.Duration(fps)
+	}
+	return p
+}
+
+// request sends a paint event, unless one is pending,
+// delayed until interval after the last frame.
+func (p *pacer) request(w *win) {
+	if p.pending {
+		return
+	}
+	p.pending = true
+	if d := time.Until(p.last.Add(p.interval)); d > 0 {
+		time.AfterFunc(d, func() { w.Send(paint.Event{}) })
+		return
+	}
+	w.Send(paint.Event{})
+}
+
+// painted records that a frame was drawn at the time.
+func (p *pacer) painted(now time.Time) {
+	p.pending = false
+	p.last = now
+}
+
+// setTitle sets the title of the window,
+// if its driver supports changing it after the window is created.
+func setTitle(w screen.Window, title string) {
+	if t, ok := w.(interface{ SetTitle(string) }); ok {
+		t.SetTitle(title)
+	}
+}
+
+// setFullscreen sets whether the window is fullscreen,
+// if its driver supports it.
+// The window then receives a size event for its new size.
+func setFullscreen(w screen.Window, fullscreen bool) {
+	if f, ok := w.(interface{ SetFullscreen(bool) }); ok {
+		f.SetFullscreen(fullscreen)
+	}
+}
+
 func mouseEvent(w *win, e mouse.Event) {
 	switch pt := image.Pt(int(e.X), int(e.Y)); {
 	case e.Button == mouse.ButtonWheelUp:
@@ -194,6 +495,104 @@ func mouseEvent(w *win, e mouse.Event) {
 	}
 }
 
+// touches maps touch events onto mouse events:
+//
+//	tap                 button 1 click, setting the cursor
+//	drag                button 1 drag, selecting text
+//	long press          button 3 press, to look; drag and lift to release
+//	two-finger pan      precise scrolling
+//	two-finger tap      button 2 click, to execute
+type touches struct {
+	seqs   map[touch.Sequence]image.Point // current touch points
+	start  image.Point                    // where the first touch began
+	began  time.Time                      // when the first touch began
+	moved  bool                           // whether a touch moved beyond touchSlop
+	multi  bool                           // whether there were two touches
+	button int                            // mouse button pressed or 0
+}
+
+func newTouches() *touches {
+	return &touches{seqs: make(map[touch.Sequence]image.Point)}
+}
+
+func (t *touches) event(w *win, e touch.Event, now time.Time) {
+	pt := image.Pt(int(e.X), int(e.Y))
+	switch e.Type {
+	case touch.TypeBegin:
+		if len(t.seqs) == 0 {
+			t.start, t.began, t.moved, t.multi = pt, now, false, false
+		} else {
+			t.multi = true
+			t.release(w, pt)
+		}
+		t.seqs[e.Sequence] = pt
+
+	case touch.TypeMove:
+		prev, ok := t.seqs[e.Sequence]
+		if !ok {
+			return
+		}
+		t.seqs[e.Sequence] = pt
+		d := pt.Sub(t.start)
+		if d.X*d.X+d.Y*d.Y > touchSlop*touchSlop {
+			t.moved = true
+		}
+		switch {
+		case len(t.seqs) > 1:
+			d := pt.Sub(prev)
+			w.win.WheelPx(pt, float64(-d.X), float64(d.Y))
+		case t.button > 0:
+			w.win.Move(pt)
+		case t.moved && !t.multi:
+			w.win.Move(t.start)
+			w.win.Click(t.start, 1)
+			t.button = 1
+			w.win.Move(pt)
+		}
+
+	case touch.TypeEnd:
+		if _, ok := t.seqs[e.Sequence]; !ok {
+			return
+		}
+		delete(t.seqs, e.Sequence)
+		if len(t.seqs) > 0 {
+			return
+		}
+		switch {
+		case t.button > 0:
+			w.win.Move(pt)
+			t.release(w, pt)
+		case t.multi && !t.moved:
+			w.win.Move(t.start)
+			w.win.Click(t.start, 2)
+			w.win.Click(t.start, -2)
+		case !t.multi && !t.moved:
+			w.win.Move(t.start)
+			w.win.Click(t.start, 1)
+			w.win.Click(t.start, -1)
+		}
+	}
+}
+
+// tick presses button 3 if a single touch has been held long enough.
+func (t *touches) tick(w *win, now time.Time) {
+	if len(t.seqs) != 1 || t.multi || t.moved || t.button > 0 ||
+		now.Sub(t.began) < longPressDelay {
+		return
+	}
+	w.win.Move(t.start)
+	w.win.Click(t.start, 3)
+	t.button = 3
+}
+
+// release releases the pressed mouse button, if any.
+func (t *touches) release(w *win, pt image.Point) {
+	if t.button > 0 {
+		w.win.Click(pt, -t.button)
+		t.button = 0
+	}
+}
+
 func keyEvent(w *win, mods [4]bool, e key.Event) [4]bool {
 	if e.Direction == key.DirNone {
 		e.Direction = key.DirPress
@@ -202,12 +601,24 @@ func keyEvent(w *win, mods [4]bool, e key.Event) [4]bool {
 		dirKey(w, e)
 		return mods
 	}
+	if f, ok := clipboardKeyCode[e.Code]; ok &&
+		e.Modifiers&(key.ModMeta|key.ModControl) != 0 &&
+		e.Modifiers&(key.ModShift|key.ModAlt) == 0 {
+		if e.Direction == key.DirPress {
+			if err := f(w.win); err != nil {
+				w.win.OutputString(err.Error() + "\n")
+			}
+		}
+		return mods
+	}
 
 	switch {
 	case e.Code == key.CodeDeleteBackspace:
 		e.Rune = '\b'
 	case e.Code == key.CodeDeleteForward:
 		e.Rune = 0x7f
+	case e.Code == key.CodeEscape:
+		e.Rune = 0x1b
 	case e.Rune == '\r':
 		e.Rune = '\n'
 	}
@@ -232,6 +643,14 @@ var dirKeyCode = map[key.Code]bool{
 	key.CodeEnd:        true,
 }
 
+// clipboardKeyCode are the keys that,
+// pressed with control or meta, copy, cut, and paste.
+var clipboardKeyCode = map[key.Code]func(*ui.Win) error{
+	key.CodeC: (*ui.Win).Copy,
+	key.CodeX: (*ui.Win).Cut,
+	key.CodeV: (*ui.Win).Paste,
+}
+
 func dirKey(w *win, e key.Event) {
 	switch e.Code {
 	case key.CodeUpArrow:
@@ -289,6 +708,132 @@ func modKey(w *win, mods [4]bool, e key.Event) [4]bool {
 	return mods
 }
 
+var modKeyCode = map[key.Code]bool{
+	key.CodeLeftShift:    true,
+	key.CodeRightShift:   true,
+	key.CodeLeftAlt:      true,
+	key.CodeRightAlt:     true,
+	key.CodeLeftControl:  true,
+	key.CodeRightControl: true,
+	key.CodeLeftGUI:      true,
+	key.CodeRightGUI:     true,
+}
+
+// slowKeys filters key events, dropping presses
+// of keys that are not held for at least a minimum duration.
+// Modifier keys are not filtered.
+type slowKeys struct {
+	delay    time.Duration
+	pending  map[key.Code]pendingKey
+	accepted map[key.Code]bool
+}
+
+type pendingKey struct {
+	e  key.Event
+	at time.Time
+}
+
+func newSlowKeys(delay time.Duration) *slowKeys {
+	return &slowKeys{
+		delay:    delay,
+		pending:  make(map[key.Code]pendingKey),
+		accepted: make(map[key.Code]bool),
+	}
+}
+
+// accept returns whether the event should be handled now.
+// Presses are held back until ready reports them.
+func (s *slowKeys) accept(e key.Event, now time.Time) bool {
+	if s.delay <= 0 || modKeyCode[e.Code] {
+		return true
+	}
+	switch e.Direction {
+	case key.DirPress:
+		s.pending[e.Code] = pendingKey{e: e, at: now}
+		return false
+	case key.DirNone: // auto-repeat
+		return s.accepted[e.Code]
+	default:
+		delete(s.pending, e.Code)
+		delete(s.accepted, e.Code)
+		return true
+	}
+}
+
+// ready returns the held-back presses that have been held long enough,
+// in the order that they were pressed.
+func (s *slowKeys) ready(now time.Time) []key.Event {
+	var es []pendingKey
+	for c, p := range s.pending {
+		if now.Sub(p.at) >= s.delay {
+			es = append(es, p)
+			delete(s.pending, c)
+			s.accepted[c] = true
+		}
+	}
+	sort.Slice(es, func(i, j int) bool { return es[i].at.Before(es[j].at) })
+	var ready []key.Event
+	for _, p := range es {
+		ready = append(ready, p.e)
+	}
+	return ready
+}
+
+// keyRepeat generates repeats of held directional keys,
+// replacing the system's auto-repeat.
+// After the key is held for delay, it repeats every interval,
+// and the interval is multiplied by accel after each repeat
+// down to a minimum of min.
+// If delay is 0, the system's auto-repeat is used.
+type keyRepeat struct {
+	delay, interval, min time.Duration
+	accel                float64
+
+	held *key.Event
+	next time.Time     // time of the next repeat
+	cur  time.Duration // current interval between repeats
+}
+
+// accept returns whether the event should be handled now.
+// System auto-repeats of directional keys are dropped.
+func (k *keyRepeat) accept(e key.Event, now time.Time) bool {
+	if k.delay <= 0 || !dirKeyCode[e.Code] {
+		return true
+	}
+	switch e.Direction {
+	case key.DirPress:
+		k.held = &e
+		k.next = now.Add(k.delay)
+		k.cur = k.interval
+		return true
+	case key.DirNone: // auto-repeat
+		return false
+	default:
+		if k.held != nil && k.held.Code == e.Code {
+			k.held = nil
+		}
+		return true
+	}
+}
+
+// ready returns the repeats of the held key that are due.
+func (k *keyRepeat) ready(now time.Time) []key.Event {
+	var ready []key.Event
+	for k.held != nil && !now.Before(k.next) {
+		if len(ready) == maxRepeatBurst {
+			// Don't try to catch up after a stall.
+			k.next = now.Add(k.cur)
+			break
+		}
+		ready = append(ready, *k.held)
+		k.next = k.next.Add(k.cur)
+		if k.cur = time.Duration(float64(k.cur) * k.accel); k.cur < k.min {
+			k.cur = k.min
+		}
+	}
+	return ready
+}
+
 func bufTex(scr screen.Screen, sz image.Point) (screen.Buffer, screen.Texture) {
 	buf, err := scr.NewBuffer(sz)
 	if err != nil {
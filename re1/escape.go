@@ -1,6 +1,9 @@
 package re1
 
-import "strings"
+import (
+	"strings"
+	"unicode"
+)
 
 // Escape returns the argument with any meta-characters escaped.
 func Escape(t string) string {
@@ -13,3 +16,28 @@ func Escape(t string) string {
 	}
 	return s.String()
 }
+
+// FoldCase returns the argument with each letter outside of a charclass
+// replaced by a charclass matching its upper and lower case.
+// Escaped runes and charclasses are unchanged.
+func FoldCase(t string) string {
+	var s strings.Builder
+	var esc, class bool
+	for _, r := range t {
+		switch {
+		case esc:
+			esc = false
+		case r == '\\':
+			esc = true
+		case class:
+			class = r != ']'
+		case r == '[':
+			class = true
+		case unicode.ToLower(r) != unicode.ToUpper(r):
+			s.WriteString("[" + string(unicode.ToLower(r)) + string(unicode.ToUpper(r)) + "]")
+			continue
+		}
+		s.WriteRune(r)
+	}
+	return s.String()
+}
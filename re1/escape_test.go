@@ -18,3 +18,25 @@ func TestEscape(t *testing.T) {
 		t.Errorf("got=%v, want=%v\n", got, want)
 	}
 }
+
+func TestFoldCase(t *testing.T) {
+	tests := []struct {
+		re, str string
+		want    []int64
+	}{
+		{re: "abc", str: "xAbC", want: []int64{1, 4, 0}},
+		{re: "[a-c]+", str: "ABcb", want: []int64{2, 4, 0}},
+		{re: `\nX`, str: "\nx", want: []int64{0, 2, 0}},
+		{re: "é1", str: "É1", want: []int64{0, 3, 0}},
+		{re: "x", str: "y", want: nil},
+	}
+	for _, test := range tests {
+		re, residual, err := New(FoldCase(test.re), Opts{})
+		if err != nil || residual != "" {
+			t.Fatalf("New(FoldCase(%q))=_,%q,%v, want _,\"\",nil", test.re, residual, err)
+		}
+		if got := re.Find(strings.NewReader(test.str)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("FoldCase(%q) on %q got=%v, want=%v\n", test.re, test.str, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package text
+
+import (
+	"image"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// FallbackFace returns a font.Face for a list of TTFs
+// of a given size at a given DPI.
+// Each rune is drawn with the first font that has a glyph for the rune.
+// If no font has a glyph for the rune, the first font is used.
+// Metrics are those of the first font.
+func FallbackFace(fonts []*truetype.Font, dpi float32, sizePt int) font.Face {
+	return FallbackFaceOptions(fonts, dpi, sizePt, Options{})
+}
+
+// Options are options of the rasterization of a face.
+type Options struct {
+	// Hinting is the hinting of the glyph outlines.
+	Hinting font.Hinting
+
+	// Subpixel is whether glyphs are anti-aliased
+	// using the subpixels of an LCD with horizontal RGB stripes.
+	// The masks of such a face are LCDMasks,
+	// which are drawn with DrawLCD.
+	Subpixel bool
+}
+
+// FallbackFaceOptions is like FallbackFace,
+// but the glyphs are rasterized with the given options.
+func FallbackFaceOptions(fonts []*truetype.Font, dpi float32, sizePt int, opts Options) font.Face {
+	face := newFallbackFace(fonts, dpi, sizePt, opts.Hinting)
+	if opts.Subpixel {
+		hi := newFallbackFace(fonts, 3*dpi, sizePt, opts.Hinting)
+		return &subpixelFace{Face: face, hi: hi}
+	}
+	return face
+}
+
+func newFallbackFace(fonts []*truetype.Font, dpi float32, sizePt int, hinting font.Hinting) font.Face {
+	opts := &truetype.Options{
+		Size:    float64(sizePt),
+		DPI:     float64(dpi * (72.0 / 96.0)),
+		Hinting: hinting,
+	}
+	if len(fonts) == 1 {
+		return truetype.NewFace(fonts[0], opts)
+	}
+	f := &fallbackFace{fonts: fonts}
+	for _, font := range fonts {
+		f.faces = append(f.faces, truetype.NewFace(font, opts))
+	}
+	return f
+}
+
+type fallbackFace struct {
+	fonts []*truetype.Font
+	faces []font.Face
+}
+
+func (f *fallbackFace) face(r rune) font.Face {
+	for i, font := range f.fonts {
+		if font.Index(r) != 0 {
+			return f.faces[i]
+		}
+	}
+	return f.faces[0]
+}
+
+func (f *fallbackFace) Close() error {
+	var err error
+	for _, face := range f.faces {
+		if e := face.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (f *fallbackFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	return f.face(r).Glyph(dot, r)
+}
+
+func (f *fallbackFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	return f.face(r).GlyphBounds(r)
+}
+
+func (f *fallbackFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	return f.face(r).GlyphAdvance(r)
+}
+
+func (f *fallbackFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	face := f.face(r0)
+	if face != f.face(r1) {
+		return 0
+	}
+	return face.Kern(r0, r1)
+}
+
+func (f *fallbackFace) Metrics() font.Metrics { return f.faces[0].Metrics() }
@@ -0,0 +1,41 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestFallbackFace(t *testing.T) {
+	regular, err := truetype.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("failed to parse font: %v", err)
+	}
+	regularFace := truetype.NewFace(regular, &truetype.Options{Size: 11})
+	empty := &truetype.Font{}
+	f := &fallbackFace{
+		fonts: []*truetype.Font{empty, regular},
+		faces: []font.Face{testFace{1}, regularFace},
+	}
+	if face := f.face('a'); face != regularFace {
+		t.Errorf("face('a')=%v, want the fallback face", face)
+	}
+	if face := f.face('世'); face != (testFace{1}) {
+		t.Errorf("face('世')=%v, want the first face", face)
+	}
+}
+
+func TestFallbackFaceSingleFont(t *testing.T) {
+	regular, err := truetype.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("failed to parse font: %v", err)
+	}
+	if _, ok := FallbackFace([]*truetype.Font{regular}, 96, 11).(*fallbackFace); ok {
+		t.Errorf("FallbackFace of a single font is a fallbackFace")
+	}
+	if _, ok := FallbackFace([]*truetype.Font{regular, regular}, 96, 11).(*fallbackFace); !ok {
+		t.Errorf("FallbackFace of two fonts is not a fallbackFace")
+	}
+}
@@ -0,0 +1,151 @@
+package text
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// An LCDMask is a glyph mask with the coverage
+// of the red, green, and blue subpixels of each pixel
+// in its R, G, and B channels.
+// Its A channel is the greatest of the three.
+type LCDMask struct {
+	*image.RGBA
+}
+
+// lcdFilter are the weights of neighboring subpixels
+// in the coverage of a subpixel, reducing color fringes.
+var lcdFilter = [...]int{1, 2, 3, 2, 1}
+
+// subpixelFace is a face whose glyphs are LCDMasks.
+// Its glyphs are rasterized by hi, at three times the resolution,
+// and the other methods are those of the embedded face.
+type subpixelFace struct {
+	font.Face
+	hi font.Face
+}
+
+func (f *subpixelFace) Close() error {
+	err := f.Face.Close()
+	if e := f.hi.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+func (f *subpixelFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	adv, ok := f.Face.GlyphAdvance(r)
+	hdr, hm, hmp, _, hok := f.hi.Glyph(fixed.Point26_6{X: 3 * dot.X, Y: 3 * dot.Y}, r)
+	if !hok {
+		return f.Face.Glyph(dot, r)
+	}
+	alpha, isAlpha := hm.(*image.Alpha)
+	// coverage returns the coverage, 0-765,
+	// of the subpixel column sx in the three rows of pixel row y.
+	coverage := func(sx, y int) int {
+		if sx < hdr.Min.X || sx >= hdr.Max.X {
+			return 0
+		}
+		var sum int
+		for sy := 3 * y; sy < 3*y+3; sy++ {
+			if sy < hdr.Min.Y || sy >= hdr.Max.Y {
+				continue
+			}
+			mx, my := hmp.X+sx-hdr.Min.X, hmp.Y+sy-hdr.Min.Y
+			if isAlpha {
+				sum += int(alpha.AlphaAt(mx, my).A)
+			} else {
+				_, _, _, a := hm.At(mx, my).RGBA()
+				sum += int(a >> 8)
+			}
+		}
+		return sum
+	}
+	dr := image.Rect(floorDiv(hdr.Min.X, 3)-1, floorDiv(hdr.Min.Y, 3), ceilDiv(hdr.Max.X, 3)+1, ceilDiv(hdr.Max.Y, 3))
+	m := image.NewRGBA(image.Rect(0, 0, dr.Dx(), dr.Dy()))
+	for y := dr.Min.Y; y < dr.Max.Y; y++ {
+		for x := dr.Min.X; x < dr.Max.X; x++ {
+			var c [3]int
+			for i := range c {
+				for k, w := range lcdFilter {
+					c[i] += w * coverage(3*x+i+k-len(lcdFilter)/2, y)
+				}
+				c[i] /= 9 * 3
+			}
+			a := c[0]
+			if c[1] > a {
+				a = c[1]
+			}
+			if c[2] > a {
+				a = c[2]
+			}
+			m.SetRGBA(x-dr.Min.X, y-dr.Min.Y, color.RGBA{R: uint8(c[0]), G: uint8(c[1]), B: uint8(c[2]), A: uint8(a)})
+		}
+	}
+	return dr, LCDMask{m}, image.Point{}, adv, ok
+}
+
+// DrawLCD draws the color to the rectangle of dst
+// through the LCDMask at the point mp,
+// blending each channel by the coverage of its subpixel.
+func DrawLCD(dst draw.Image, r image.Rectangle, c color.Color, mask LCDMask, mp image.Point) {
+	cr, cg, cb, _ := c.RGBA()
+	blend := func(d, s uint32, a uint8) uint16 {
+		return uint16((d*(0xFF-uint32(a)) + s*uint32(a)) / 0xFF)
+	}
+	clip := r.Intersect(dst.Bounds())
+	if rgba, ok := dst.(*image.RGBA); ok {
+		// Avoid the allocations of At and Set.
+		for y := clip.Min.Y; y < clip.Max.Y; y++ {
+			for x := clip.Min.X; x < clip.Max.X; x++ {
+				m := mask.RGBAAt(mp.X+x-r.Min.X, mp.Y+y-r.Min.Y)
+				if m.A == 0 {
+					continue
+				}
+				d := rgba.RGBAAt(x, y)
+				rgba.SetRGBA(x, y, color.RGBA{
+					R: uint8(blend(uint32(d.R)*0x101, cr, m.R) >> 8),
+					G: uint8(blend(uint32(d.G)*0x101, cg, m.G) >> 8),
+					B: uint8(blend(uint32(d.B)*0x101, cb, m.B) >> 8),
+					A: d.A,
+				})
+			}
+		}
+		return
+	}
+	for y := clip.Min.Y; y < clip.Max.Y; y++ {
+		for x := clip.Min.X; x < clip.Max.X; x++ {
+			m := mask.RGBAAt(mp.X+x-r.Min.X, mp.Y+y-r.Min.Y)
+			if m.A == 0 {
+				continue
+			}
+			dr, dg, db, da := dst.At(x, y).RGBA()
+			dst.Set(x, y, color.RGBA64{
+				R: blend(dr, cr, m.R),
+				G: blend(dg, cg, m.G),
+				B: blend(db, cb, m.B),
+				A: uint16(da),
+			})
+		}
+	}
+}
+
+// floorDiv returns x/n rounded toward negative infinity.
+func floorDiv(x, n int) int {
+	if x < 0 {
+		return -ceilDiv(-x, n)
+	}
+	return x / n
+}
+
+// ceilDiv returns x/n rounded toward positive infinity.
+func ceilDiv(x, n int) int {
+	if x < 0 {
+		return -floorDiv(-x, n)
+	}
+	return (x + n - 1) / n
+}
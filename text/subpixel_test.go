@@ -0,0 +1,70 @@
+package text
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestSubpixelFace(t *testing.T) {
+	regular, err := truetype.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("failed to parse font: %v", err)
+	}
+	face := FallbackFaceOptions([]*truetype.Font{regular}, 96, 11, Options{Subpixel: true})
+	dot := fixed.P(10, 20)
+	dr, m, mp, adv, ok := face.Glyph(dot, 'l')
+	if !ok {
+		t.Fatalf("Glyph('l') not ok")
+	}
+	if want, _ := face.GlyphAdvance('l'); adv != want {
+		t.Errorf("advance=%v, want %v", adv, want)
+	}
+	lcd, ok := m.(LCDMask)
+	if !ok {
+		t.Fatalf("mask is a %T, want an LCDMask", m)
+	}
+	if !dr.Overlaps(image.Rect(10, 10, 14, 20)) {
+		t.Errorf("glyph bounds %v, want them near the dot %v", dr, dot)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	draw.Draw(img, img.Bounds(), image.White, image.ZP, draw.Src)
+	DrawLCD(img, dr, color.Black, lcd, mp)
+	var dark, fringe bool
+	for y := dr.Min.Y; y < dr.Max.Y; y++ {
+		for x := dr.Min.X; x < dr.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			dark = dark || c.R < 0x80 && c.G < 0x80 && c.B < 0x80
+			fringe = fringe || c.R != c.B
+			if c.A != 0xFF {
+				t.Fatalf("pixel (%d, %d)=%v, want opaque", x, y, c)
+			}
+		}
+	}
+	if !dark || !fringe {
+		t.Errorf("dark=%v, fringe=%v, want true, true", dark, fringe)
+	}
+}
+
+func TestDiv(t *testing.T) {
+	tests := []struct{ x, floor, ceil int }{
+		{0, 0, 0},
+		{1, 0, 1},
+		{3, 1, 1},
+		{4, 1, 2},
+		{-1, -1, 0},
+		{-3, -1, -1},
+		{-4, -2, -1},
+	}
+	for _, test := range tests {
+		if f, c := floorDiv(test.x, 3), ceilDiv(test.x, 3); f != test.floor || c != test.ceil {
+			t.Errorf("floorDiv(%d, 3), ceilDiv(%d, 3)=%d, %d, want %d, %d", test.x, test.x, f, c, test.floor, test.ceil)
+		}
+	}
+}
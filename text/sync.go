@@ -0,0 +1,156 @@
+package text
+
+import (
+	"image"
+	"image/draw"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// SyncFace returns a face that is safe for concurrent use.
+// Each call of a method of the face holds its lock.
+//
+// The mask returned by Glyph may be reused by the next call,
+// so a mask drawn concurrently with the use of the face by others
+// must be drawn between Lock and Unlock.
+func SyncFace(face font.Face) font.Face {
+	if _, ok := face.(*syncFace); ok {
+		return face
+	}
+	return &syncFace{face: face}
+}
+
+type syncFace struct {
+	mu   sync.Mutex
+	face font.Face
+
+	glyphsMu sync.RWMutex
+	glyphs   map[glyphKey]cachedGlyph
+}
+
+// maxCachedGlyphs is the most glyphs cached by a face
+// before its cache is cleared.
+const maxCachedGlyphs = 1 << 14
+
+// A glyphKey is a rune at the fractional part of a dot.
+type glyphKey struct {
+	r    rune
+	x, y fixed.Int26_6
+}
+
+// A cachedGlyph is the result of Glyph
+// with the rectangle relative to the integer part of the dot
+// and a mask that is not reused.
+type cachedGlyph struct {
+	dr   image.Rectangle
+	mask image.Image
+	adv  fixed.Int26_6
+	ok   bool
+}
+
+// Lock locks the face if it was returned by SyncFace
+// and returns the face to use until Unlock,
+// whose masks remain valid until then.
+// Other faces are returned as they are.
+func Lock(face font.Face) font.Face {
+	f, ok := face.(*syncFace)
+	if !ok {
+		return face
+	}
+	f.mu.Lock()
+	return f.face
+}
+
+// Unlock unlocks a face locked by Lock.
+func Unlock(face font.Face) {
+	if f, ok := face.(*syncFace); ok {
+		f.mu.Unlock()
+	}
+}
+
+// Glyph is like the Glyph method of the face,
+// but the mask remains valid after the next use of the face.
+//
+// The glyphs of a face returned by SyncFace are cached,
+// so a glyph is only rasterized under the lock of the face
+// the first time it is drawn at a fractional dot,
+// and masks of cached glyphs can be drawn concurrently.
+// The masks of other faces may be reused by the next call,
+// as those of their Glyph method.
+func Glyph(face font.Face, dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	f, ok := face.(*syncFace)
+	if !ok {
+		return face.Glyph(dot, r)
+	}
+	key := glyphKey{r: r, x: dot.X & 63, y: dot.Y & 63}
+	at := image.Pt(dot.X.Floor(), dot.Y.Floor())
+	f.glyphsMu.RLock()
+	g, ok := f.glyphs[key]
+	f.glyphsMu.RUnlock()
+	if !ok {
+		f.mu.Lock()
+		dr, m, mp, adv, ok := f.face.Glyph(dot, r)
+		g = cachedGlyph{dr: dr.Sub(at), mask: copyMask(m, mp, dr.Size()), adv: adv, ok: ok}
+		f.mu.Unlock()
+		f.glyphsMu.Lock()
+		if f.glyphs == nil || len(f.glyphs) >= maxCachedGlyphs {
+			f.glyphs = make(map[glyphKey]cachedGlyph)
+		}
+		f.glyphs[key] = g
+		f.glyphsMu.Unlock()
+	}
+	return g.dr.Add(at), g.mask, image.Point{}, g.adv, g.ok
+}
+
+// copyMask returns a copy of the part of size of the mask at mp,
+// with its bounds at the origin.
+// LCDMasks are copied as LCDMasks and others as *image.Alpha.
+func copyMask(m image.Image, mp image.Point, size image.Point) image.Image {
+	r := image.Rectangle{Max: size}
+	if lcd, ok := m.(LCDMask); ok {
+		c := image.NewRGBA(r)
+		draw.Draw(c, r, lcd.RGBA, mp, draw.Src)
+		return LCDMask{c}
+	}
+	c := image.NewAlpha(r)
+	draw.Draw(c, r, m, mp, draw.Src)
+	return c
+}
+
+func (f *syncFace) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.face.Close()
+}
+
+func (f *syncFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.face.Glyph(dot, r)
+}
+
+func (f *syncFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.face.GlyphBounds(r)
+}
+
+func (f *syncFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.face.GlyphAdvance(r)
+}
+
+func (f *syncFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.face.Kern(r0, r1)
+}
+
+func (f *syncFace) Metrics() font.Metrics {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.face.Metrics()
+}
@@ -0,0 +1,96 @@
+package text
+
+import (
+	"image"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestSyncFace(t *testing.T) {
+	regular, err := truetype.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("failed to parse font: %v", err)
+	}
+	face := SyncFace(truetype.NewFace(regular, &truetype.Options{Size: 11}))
+	if SyncFace(face) != face {
+		t.Errorf("SyncFace of a SyncFace is a new face")
+	}
+	if f := (testFace{1}); Lock(f) != f {
+		t.Errorf("Lock(testFace) is not the face")
+	}
+
+	// Each goroutine checks that the mask of its rune
+	// is not overwritten by the others while the face is locked.
+	var wg sync.WaitGroup
+	for _, r := range "aWi." {
+		wg.Add(1)
+		go func(r rune) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				f := Lock(face)
+				dr, m, mp, _, _ := f.Glyph(fixed.P(0, 10), r)
+				want := coverage(m, dr, mp)
+				runtime.Gosched()
+				if got := coverage(m, dr, mp); got != want {
+					t.Errorf("coverage of %q=%d, then %d", r, want, got)
+				}
+				Unlock(face)
+				face.Kern('a', r)
+			}
+		}(r)
+	}
+	wg.Wait()
+}
+
+func coverage(m image.Image, dr image.Rectangle, mp image.Point) int {
+	var sum int
+	for y := 0; y < dr.Dy(); y++ {
+		for x := 0; x < dr.Dx(); x++ {
+			_, _, _, a := m.At(mp.X+x, mp.Y+y).RGBA()
+			sum += int(a >> 8)
+		}
+	}
+	return sum
+}
+
+func TestGlyph(t *testing.T) {
+	regular, err := truetype.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("failed to parse font: %v", err)
+	}
+	plain := truetype.NewFace(regular, &truetype.Options{Size: 11})
+	face := SyncFace(truetype.NewFace(regular, &truetype.Options{Size: 11}))
+	for _, dot := range []fixed.Point26_6{fixed.P(0, 10), fixed.P(3, 12), {X: 100, Y: 650}} {
+		wantDr, wantM, wantMp, wantAdv, _ := plain.Glyph(dot, 'W')
+		want := coverage(wantM, wantDr, wantMp)
+		for i := 0; i < 2; i++ {
+			dr, m, mp, adv, ok := Glyph(face, dot, 'W')
+			if !ok || dr != wantDr || adv != wantAdv || coverage(m, dr, mp) != want {
+				t.Errorf("Glyph(%v)=%v,%v,%v, want %v,%v,%v", dot, dr, adv, coverage(m, dr, mp), wantDr, wantAdv, want)
+			}
+		}
+	}
+
+	// The masks are not reused by later calls.
+	dr, m, mp, _, _ := Glyph(face, fixed.P(0, 10), 'a')
+	want := coverage(m, dr, mp)
+	var wg sync.WaitGroup
+	for _, r := range "Wi." {
+		wg.Add(1)
+		go func(r rune) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				Glyph(face, fixed.Point26_6{X: fixed.Int26_6(i), Y: 640}, r)
+			}
+		}(r)
+	}
+	wg.Wait()
+	if got := coverage(m, dr, mp); got != want {
+		t.Errorf("coverage of a=%d, then %d", want, got)
+	}
+}
@@ -13,6 +13,9 @@ import (
 type Style struct {
 	// FG and BG are the foreground and background colors of the text.
 	FG, BG color.Color
+	// Underline is the color of a line drawn under the text,
+	// or nil for no line.
+	Underline color.Color
 	// Face is the font face, describing the font and size.
 	font.Face
 }
@@ -26,6 +29,9 @@ func (sty Style) Merge(other Style) Style {
 	if other.BG == nil {
 		other.BG = sty.BG
 	}
+	if other.Underline == nil {
+		other.Underline = sty.Underline
+	}
 	if other.Face == nil {
 		other.Face = sty.Face
 	}
@@ -33,13 +39,14 @@ func (sty Style) Merge(other Style) Style {
 }
 
 // Face returns a font.Face for a TTF of a given size at a given DPI.
+// The face is safe for concurrent use; see SyncFace.
 func Face(ttf []byte, dpi float32, sizePt int) font.Face {
 	f, err := truetype.Parse(gomedium.TTF)
 	if err != nil {
 		panic(err.Error())
 	}
-	return truetype.NewFace(f, &truetype.Options{
+	return SyncFace(truetype.NewFace(f, &truetype.Options{
 		Size: float64(sizePt),
 		DPI:  float64(dpi * (72.0 / 96.0)),
-	})
+	}))
 }
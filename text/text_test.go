@@ -65,6 +65,16 @@ func TestStyleMerge(t *testing.T) {
 			b:    Style{BG: color.Black},
 			want: Style{BG: color.Black, Face: face1},
 		},
+		{
+			a:    Style{FG: color.White},
+			b:    Style{Underline: color.Black},
+			want: Style{FG: color.White, Underline: color.Black},
+		},
+		{
+			a:    Style{Underline: color.White},
+			b:    Style{Underline: color.Black},
+			want: Style{Underline: color.Black},
+		},
 		{
 			a:    Style{FG: color.White, BG: color.Black, Face: face1},
 			b:    Style{FG: color.Black, BG: color.White, Face: face2},
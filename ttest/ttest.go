@@ -0,0 +1,333 @@
+// Package ttest drives a running T editor
+// through the JSON-RPC socket of its -listen flag.
+// It is for integration tests of the editor and of extensions:
+// they open files, type, execute commands,
+// and check the text of sheets and images of the window.
+//
+// A Client connects to an editor started with -listen by Dial,
+// or to a new headless editor in the calling process by Start.
+package ttest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eaburns/T/headless"
+)
+
+// A Client is a connection to an editor.
+// Its methods name a sheet by its title;
+// if it is empty, the focused sheet is used.
+type Client struct {
+	c    net.Conn
+	r    *bufio.Reader
+	id   int
+	stop func() // stops the editor of Start, or nil
+}
+
+// A Sheet is the state of a sheet.
+type Sheet struct {
+	Title string   `json:"title"`
+	Text  string   `json:"text"`
+	Dot   [2]int64 `json:"dot"`
+}
+
+// Dial connects to the editor listening on the Unix socket at path.
+func Dial(path string) (*Client, error) {
+	c, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{c: c, r: bufio.NewReader(c)}, nil
+}
+
+// Start starts a headless editor with a window of the given size
+// and connects to it.
+// The editor runs in the calling process until the Client is closed.
+// It reads its configuration from the environment as usual,
+// so tests should set HOME, XDG_CONFIG_HOME, and T_CONFIG.
+func Start(size image.Point) (*Client, error) {
+	dir, err := ioutil.TempDir("", "ttest")
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "sock")
+	w := headless.New(size, 72)
+	if err := w.Listen(path); err != nil {
+		w.Close()
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	wake := make(chan struct{}, 1)
+	w.SetWake(func() {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	})
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		defer w.Close()
+		for {
+			select {
+			case <-done:
+				return
+			case <-wake:
+			case <-time.After(w.TickRate()):
+			}
+			w.Tick()
+		}
+	}()
+	stop := func() {
+		close(done)
+		<-stopped
+		os.RemoveAll(dir)
+	}
+	c, err := Dial(path)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	c.stop = stop
+	return c, nil
+}
+
+// Close closes the connection,
+// and if the editor was started by Start, stops it.
+func (c *Client) Close() error {
+	err := c.c.Close()
+	if c.stop != nil {
+		c.stop()
+	}
+	return err
+}
+
+type request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int         `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+type response struct {
+	ID     *int            `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Call calls a method of the editor, see ui.Win.Listen,
+// and unmarshals its result into result, unless it is nil.
+// Event notifications received while waiting are ignored.
+func (c *Client) Call(method string, params, result interface{}) error {
+	c.id++
+	req := request{JSONRPC: "2.0", ID: c.id, Method: method, Params: params}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if _, err := c.c.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	for {
+		line, err := c.r.ReadBytes('\n')
+		if err != nil {
+			return err
+		}
+		var resp response
+		if err := json.Unmarshal(line, &resp); err != nil {
+			return fmt.Errorf("bad response %s: %v", line, err)
+		}
+		if resp.ID == nil || *resp.ID != c.id {
+			continue
+		}
+		if resp.Error != nil {
+			return errors.New(resp.Error.Message)
+		}
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	}
+}
+
+type params struct {
+	Sheet   string `json:"sheet,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Edit    string `json:"edit,omitempty"`
+	Addr    string `json:"addr,omitempty"`
+	Command string `json:"command,omitempty"`
+	Text    string `json:"text,omitempty"`
+}
+
+// Sheets returns the titles of the sheets.
+func (c *Client) Sheets() ([]string, error) {
+	var titles []string
+	err := c.Call("sheets", nil, &titles)
+	return titles, err
+}
+
+// Open opens or focuses the sheet of a file.
+func (c *Client) Open(path string) error {
+	return c.Call("open", params{Path: path}, nil)
+}
+
+// Read returns the state of a sheet.
+func (c *Client) Read(sheet string) (Sheet, error) {
+	var s Sheet
+	err := c.Call("read", params{Sheet: sheet}, &s)
+	return s, err
+}
+
+// Edit performs an Edit language command on the body of a sheet
+// and returns the new dot.
+func (c *Client) Edit(sheet, edit string) ([2]int64, error) {
+	var dot [2]int64
+	err := c.Call("edit", params{Sheet: sheet, Edit: edit}, &dot)
+	return dot, err
+}
+
+// Dot sets dot of the body of a sheet to an Edit language address
+// and returns it.
+func (c *Client) Dot(sheet, addr string) ([2]int64, error) {
+	var dot [2]int64
+	err := c.Call("dot", params{Sheet: sheet, Addr: addr}, &dot)
+	return dot, err
+}
+
+// Exec executes a command as if clicked in a sheet.
+func (c *Client) Exec(sheet, command string) error {
+	return c.Call("exec", params{Sheet: sheet, Command: command}, nil)
+}
+
+// Type types the text in the focused text box.
+func (c *Client) Type(text string) error {
+	return c.Call("type", params{Text: text}, nil)
+}
+
+// Image returns an image of the window.
+func (c *Client) Image() (image.Image, error) {
+	var data []byte
+	if err := c.Call("image", nil, &data); err != nil {
+		return nil, err
+	}
+	return png.Decode(bytes.NewReader(data))
+}
+
+// Run runs a script of commands, one per line,
+// on the focused sheet.
+// Blank lines and lines beginning with # are ignored.
+// The commands are:
+//
+//	open path          open or focus the sheet of a file
+//	type text          type the rest of the line, or a Go-quoted string
+//	exec command       execute a command
+//	edit command       perform an Edit language command
+//	dot addr           set dot to an Edit language address
+//	want text          fail unless the body is the rest of the line,
+//	                   or a Go-quoted string
+//	png file           write an image of the window to a PNG file
+//
+// Run returns the first error, prefixed by its line number.
+func (c *Client) Run(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := c.runLine(line); err != nil {
+			return fmt.Errorf("line %d: %v", n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func (c *Client) runLine(line string) error {
+	cmd, rest := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		cmd, rest = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	switch cmd {
+	case "open":
+		return c.Open(rest)
+	case "type":
+		s, err := unquote(rest)
+		if err != nil {
+			return err
+		}
+		return c.Type(s)
+	case "exec":
+		return c.Exec("", rest)
+	case "edit":
+		_, err := c.Edit("", rest)
+		return err
+	case "dot":
+		_, err := c.Dot("", rest)
+		return err
+	case "want":
+		want, err := unquote(rest)
+		if err != nil {
+			return err
+		}
+		s, err := c.Read("")
+		if err != nil {
+			return err
+		}
+		if s.Text != want {
+			return fmt.Errorf("%s is %q, want %q", s.Title, s.Text, want)
+		}
+		return nil
+	case "png":
+		if rest == "" {
+			return errors.New("usage: png file")
+		}
+		img, err := c.Image()
+		if err != nil {
+			return err
+		}
+		return writePNG(rest, img)
+	default:
+		return fmt.Errorf("unknown command %s", cmd)
+	}
+}
+
+// unquote returns the string unquoted if it is a Go-quoted string,
+// and otherwise the string.
+func unquote(s string) (string, error) {
+	if !strings.HasPrefix(s, `"`) && !strings.HasPrefix(s, "`") {
+		return s, nil
+	}
+	u, err := strconv.Unquote(s)
+	if err != nil {
+		return "", fmt.Errorf("bad string %s", s)
+	}
+	return u, nil
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
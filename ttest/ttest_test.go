@@ -0,0 +1,118 @@
+package ttest
+
+import (
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func startTest(t *testing.T) (*Client, string) {
+	dir, err := ioutil.TempDir("", "T_ttest_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	os.Setenv("HOME", dir)
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	os.Setenv("T_CONFIG", filepath.Join(dir, "config"))
+	c, err := Start(image.Pt(400, 300))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("Start failed: %v", err)
+	}
+	return c, dir
+}
+
+func TestClient(t *testing.T) {
+	c, dir := startTest(t)
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	path := filepath.Join(dir, "a.txt")
+	if err := ioutil.WriteFile(path, []byte("Hello, World\n"), 0666); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := c.Open(path); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	titles, err := c.Sheets()
+	if err != nil || len(titles) != 1 || titles[0] != path {
+		t.Fatalf("Sheets()=%v,%v, want [%s],nil", titles, err, path)
+	}
+	if dot, err := c.Dot("", "/World/"); err != nil || dot != [2]int64{7, 12} {
+		t.Errorf("Dot()=%v,%v, want [7 12],nil", dot, err)
+	}
+	if err := c.Type("T"); err != nil {
+		t.Fatalf("Type failed: %v", err)
+	}
+	if _, err := c.Edit(path, "0,$ x/l+/ c/L/"); err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+	s, err := c.Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if s.Title != path || s.Text != "HeLo, T\n" {
+		t.Errorf("Read()=%+v, want title %s and text %q", s, path, "HeLo, T\n")
+	}
+	if err := c.Exec("", "Put"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if data, err := ioutil.ReadFile(path); err != nil || string(data) != "HeLo, T\n" {
+		t.Errorf("file=%q,%v, want %q,nil", data, err, "HeLo, T\n")
+	}
+	if _, err := c.Read("nope"); err == nil || err.Error() != "no sheet nope" {
+		t.Errorf("Read(nope)=%v, want no sheet nope", err)
+	}
+
+	img, err := c.Image()
+	if err != nil {
+		t.Fatalf("Image failed: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 400, 300) {
+		t.Errorf("image bounds=%v, want %v", img.Bounds(), image.Rect(0, 0, 400, 300))
+	}
+}
+
+func TestRun(t *testing.T) {
+	c, dir := startTest(t)
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	path := filepath.Join(dir, "a.txt")
+	if err := ioutil.WriteFile(path, []byte("abc\n"), 0666); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	png := filepath.Join(dir, "a.png")
+	script := "# a comment\n\n" +
+		"open " + path + "\n" +
+		"dot /b/\n" +
+		"type \"x\\ty\"\n" +
+		"edit $ a/z/\n" +
+		"want \"ax\\tyc\\nz\"\n" +
+		"png " + png + "\n"
+	if err := c.Run(strings.NewReader(script)); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if _, err := os.Stat(png); err != nil {
+		t.Errorf("png was not written: %v", err)
+	}
+
+	tests := []struct {
+		script, err string
+	}{
+		{"\nnope\n", "line 2: unknown command nope"},
+		{"want abc\n", `line 1: ` + path + ` is "ax\tyc\nz", want "abc"`},
+		{"type \"abc\n", "line 1: bad string \"abc"},
+		{"edit /nope/\n", "line 1: no match"},
+		{"png\n", "line 1: usage: png file"},
+	}
+	for _, test := range tests {
+		err := c.Run(strings.NewReader(test.script))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("Run(%q)=%v, want %q", test.script, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package ui
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// altFileName is the name of a file
+// containing a project's counterpart file rules.
+// Each non-empty line not beginning with # is a rule:
+// a regular expression (using regexp package syntax)
+// and a replacement (using regexp.Expand syntax),
+// separated by whitespace.
+// The rules of the nearest altFileName
+// in the directory of a file or its parents
+// are used before altFiles.
+const altFileName = ".talt"
+
+// alt opens the counterpart of the sheet's file,
+// in the column adjacent to c.
+// The counterpart is the first existing file named
+// by a matching counterpart rule.
+func alt(c *Col, s *Sheet) error {
+	path := s.Title()
+	for _, r := range altRules(c.win, filepath.Dir(path)) {
+		re, err := regexp.Compile(r.regexp)
+		if err != nil {
+			return err
+		}
+		m := re.FindStringSubmatchIndex(path)
+		if m == nil {
+			continue
+		}
+		alt := string(re.ExpandString(nil, r.repl, path, m))
+		if focusSheet(c.win, alt) {
+			return nil
+		}
+		if _, err := os.Stat(alt); err != nil {
+			continue
+		}
+		return openSheet(adjacentCol(c), alt)
+	}
+	return errors.New(msg("no counterpart for %s", path))
+}
+
+type altRule struct {
+	regexp, repl string
+}
+
+// altRules returns the counterpart rules for files in the directory.
+// Bad rules are reported in the Output sheet and skipped.
+func altRules(w *Win, dir string) []altRule {
+	var rules []altRule
+	for {
+		f, err := os.Open(filepath.Join(dir, altFileName))
+		if err == nil {
+			rules = readAltRules(w, f)
+			f.Close()
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return append(rules, altFiles...)
+}
+
+func readAltRules(w *Win, f *os.File) []altRule {
+	var rules []altRule
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fs := strings.Fields(line)
+		if len(fs) != 2 {
+			w.OutputString(msg("%s: bad rule: %s", f.Name(), line) + "\n")
+			continue
+		}
+		rules = append(rules, altRule{fs[0], fs[1]})
+	}
+	return rules
+}
+
+// adjacentCol returns the column to the right of c,
+// or to the left if c is the rightmost column.
+// If c is the only column, a new column is added.
+func adjacentCol(c *Col) *Col {
+	w := c.win
+	i := colIndex(c)
+	switch {
+	case len(w.cols) == 1:
+		return w.Add()
+	case i < len(w.cols)-1:
+		return w.cols[i+1]
+	default:
+		return w.cols[i-1]
+	}
+}
+
+// openSheet adds a sheet for the file at the path to the column.
+func openSheet(c *Col, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	s := NewSheet(c.win, path)
+	if err := get(s, f); err != nil {
+		return err
+	}
+	c.Add(s)
+	return nil
+}
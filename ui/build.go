@@ -0,0 +1,196 @@
+package ui
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+)
+
+// A build is a command run for a +Build or +Watch sheet.
+//
+// The output of the command is appended to the body as it is written,
+// followed by its exit status if it fails.
+type build struct {
+	cmd *exec.Cmd
+
+	mu  sync.Mutex
+	out strings.Builder // output not yet inserted
+}
+
+// runBuild implements the Mk and Build commands: Mk [command].
+// The command, by default the first of buildTools
+// with a file in the directory of the sheet or a parent,
+// is run by sh in that directory,
+// and its output is shown in the +Build sheet of the directory.
+// A build already running in the sheet is killed,
+// and the sheet is reused.
+// Looking anywhere on a line of the output
+// with a compiler error location of the form path:line
+// opens the file at the line.
+func runBuild(c *Col, s *Sheet, args string) error {
+	dir, err := abs(s, ".")
+	if err != nil {
+		return err
+	}
+	if args == "" {
+		var tool string
+		if dir, tool = buildTool(dir); tool == "" {
+			return errors.New(msg("no build tool for %s", dir))
+		}
+		args = tool
+	}
+	_, err = runInSheet(c, filepath.Join(dir, "+Build"), dir, args)
+	return err
+}
+
+// runInSheet runs the command by sh in the directory,
+// showing its output in the sheet with the title,
+// which is created if it does not exist.
+// A command already running in the sheet is killed,
+// and the sheet is reused.
+func runInSheet(c *Col, title, dir, command string) (*Sheet, error) {
+	b := findSheet(c.win, title)
+	if b == nil {
+		b = NewSheet(c.win, title)
+		c.Add(b)
+	}
+	if b.build != nil {
+		b.build.kill()
+		b.build = nil
+	}
+	focusSheet(c.win, title)
+	b.body.SetText(rope.New("$ " + command + "\n"))
+	setDot(b.body, 1, b.body.text.Len(), b.body.text.Len())
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = dir
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	if err := cmd.Start(); err != nil {
+		return b, err
+	}
+	bl := &build{cmd: cmd}
+	b.build = bl
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var buf [4096]byte
+		var d outputDecoder
+		for {
+			n, err := pr.Read(buf[:])
+			bl.mu.Lock()
+			bl.out.Write(d.decode(buf[:n], err != nil))
+			bl.mu.Unlock()
+			wake(b.win)
+			if err != nil {
+				return
+			}
+		}
+	}()
+	go func() {
+		err := cmd.Wait()
+		// The exit status follows all of the output,
+		// so wait for the reader to reach the end of the pipe.
+		pw.Close()
+		<-done
+		bl.mu.Lock()
+		if err != nil {
+			bl.out.WriteString(err.Error() + "\n")
+		}
+		bl.mu.Unlock()
+		wake(b.win)
+		cmdFinished(b.win, command, start, err)
+	}()
+	return b, nil
+}
+
+// buildTool returns the nearest of the directory and its parents
+// containing the file of one of buildTools, and the tool's command.
+// If there is none, it returns the directory and "".
+func buildTool(dir string) (string, string) {
+	for d := dir; ; d = filepath.Dir(d) {
+		for _, t := range buildTools {
+			if _, err := os.Stat(filepath.Join(d, t.file)); err == nil {
+				return d, t.cmd
+			}
+		}
+		if filepath.Dir(d) == d {
+			return dir, ""
+		}
+	}
+}
+
+// kill kills the build command.
+func (bl *build) kill() {
+	if bl.cmd.Process != nil {
+		bl.cmd.Process.Kill()
+	}
+}
+
+// buildOutput appends pending build output to the body.
+// It returns whether any output was appended.
+func buildOutput(s *Sheet) bool {
+	bl := s.build
+	bl.mu.Lock()
+	out := bl.out.String()
+	bl.out.Reset()
+	bl.mu.Unlock()
+	if len(out) == 0 {
+		return false
+	}
+	appendOutput(s.body, out)
+	return true
+}
+
+// appendOutput appends output to the body,
+// keeping dot at the end if it was at the end.
+func appendOutput(b *TextBox, out string) {
+	end := b.text.Len()
+	follow := b.dots[1].At == [2]int64{end, end}
+	b.Change(edit.Diffs{{At: [2]int64{end, end}, Text: rope.New(out)}})
+	if follow {
+		end = b.text.Len()
+		setDot(b, 1, end, end)
+		showAddr(b, end)
+	}
+}
+
+// errorLocation matches a compiler error location
+// of the form path:line or path:line:column.
+var errorLocation = regexp.MustCompile(`([^\s:]+):([0-9]+)(:[0-9]+)?`)
+
+// buildClickText returns the path:line address
+// of the first compiler error location
+// on the +Build, +Watch, or +Debug output line containing the address,
+// other than the $ command line,
+// and whether there is one.
+func buildClickText(s *Sheet, addr [2]int64) (string, bool) {
+	if base := filepath.Base(s.Title()); addr[0] < addr[1] || base != "+Build" && base != "+Watch" && base != "+Debug" {
+		return "", false
+	}
+	text := s.body.text.String()
+	start := strings.LastIndexByte(text[:addr[0]], '\n') + 1
+	if start == 0 && strings.HasPrefix(text, "$ ") {
+		return "", false // the command line
+	}
+	line := text[start:]
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	m := errorLocation.FindStringSubmatch(line)
+	if m == nil {
+		return "", false
+	}
+	return m[1] + ":" + m[2], true
+}
@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestCmd_Build(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	write(filepath.Join(dir, "a.go"), "package a\n\nfunc F() {}\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, filepath.Join(dir, "a.go"))
+	)
+	s.body.SetText(rope.New("package a\n\nfunc F() {}\n"))
+	c.Add(s)
+	for i := 0; i < 2; i++ {
+		if err := execCmd(c, s, "Mk echo ./a.go:3:6: oops; exit 1"); err != nil {
+			t.Fatalf("execCmd failed: %v", err)
+		}
+	}
+	b := findSheet(w, filepath.Join(dir, "+Build"))
+	if b == nil {
+		t.Fatalf("no +Build sheet")
+	}
+	var n int
+	for _, r := range c.rows {
+		if r == b {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("%d +Build rows, want 1", n)
+	}
+	want := "$ echo ./a.go:3:6: oops; exit 1\n./a.go:3:6: oops\nexit status 1\n"
+	for start := time.Now(); b.body.text.String() != want && time.Since(start) < 5*time.Second; {
+		b.Tick()
+		time.Sleep(time.Millisecond)
+	}
+	if got := b.body.text.String(); got != want {
+		t.Fatalf("+Build=%q, want %q", got, want)
+	}
+
+	at := int64(strings.LastIndex(want, "oops"))
+	txt, ok := buildClickText(b, [2]int64{at, at})
+	if !ok || txt != "./a.go:3" {
+		t.Fatalf("buildClickText=%q,%v, want ./a.go:3,true", txt, ok)
+	}
+	if _, ok := buildClickText(b, [2]int64{0, 0}); ok {
+		t.Errorf("buildClickText on the command line is ok")
+	}
+	if err := lookText(c, b, txt); err != nil {
+		t.Fatalf("lookText failed: %v", err)
+	}
+	if c.Row != Row(s) {
+		t.Fatalf("focused row is not a.go")
+	}
+	if got, want := s.body.dots[1].At, [2]int64{11, 23}; got != want {
+		t.Errorf("dot=%v, want %v", got, want)
+	}
+}
+
+func TestBuildTool(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	mkSubDir(dir, "sub")
+	touch(dir, "go.mod")
+
+	if d, tool := buildTool(filepath.Join(dir, "sub")); d != dir || tool != "go build ./... && go vet ./..." {
+		t.Errorf("buildTool=%q,%q, want %q,go build", d, tool, dir)
+	}
+	touch(filepath.Join(dir, "sub"), "Makefile")
+	if d, tool := buildTool(filepath.Join(dir, "sub")); d != filepath.Join(dir, "sub") || tool != "make" {
+		t.Errorf("buildTool=%q,%q, want %q,make", d, tool, filepath.Join(dir, "sub"))
+	}
+}
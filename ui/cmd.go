@@ -5,48 +5,250 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 	"unicode/utf8"
 
+	"github.com/eaburns/T/dap"
 	"github.com/eaburns/T/edit"
 	"github.com/eaburns/T/re1"
 	"github.com/eaburns/T/rope"
 )
 
+// builtinCmds are the names of the commands handled by execCmd.
+var builtinCmds = map[string]bool{
+	"Del": true, "Undel": true, "NewCol": true, "NewRow": true,
+	"New": true, "Quiet": true, "Resume": true, "Extensions": true,
+	"Swap": true, "Rotate": true, "Move": true, "Get": true, "Open": true,
+	"Sort": true, "Hidden": true, "Replace": true, "Mark": true,
+	"Back": true, "Forward": true, "Grep": true, "Gdiff": true,
+	"Gblame": true, "Gstatus": true, "Gcommit": true, "Mk": true,
+	"Build": true, "Diff": true, "Hunk+": true, "Hunk-": true,
+	"Watch": true, "Debug": true, "Cont": true, "Next": true,
+	"Step": true, "Out": true, "Stop": true, "Record": true,
+	"Replay": true, "Scripts": true, "Break": true, "Put": true,
+	"Put!": true, "Putall": true, "Exit": true, "Fullscreen": true,
+	"Set": true, "Show": true, "Theme": true, "Recover": true,
+	"Copy": true, "Cut": true, "Paste": true, "Wrap": true,
+	"Elastic": true, "Vi": true, "Repl": true, "Win": true, "Check": true,
+	"Comment": true, "Promote": true, "Demote": true, "Fold": true,
+	"Unfold": true, "Alt": true, "Imports": true, "Hover": true,
+	"Def": true, "Refs": true, "Snip": true, "Complete": true,
+	"Diags": true, "Play": true, "Font": true, "Journal": true,
+	"Tab": true,
+}
+
 // execCmd handles 2-click text.
 // c is non-nil
 // s may be nil
 func execCmd(c *Col, s *Sheet, text string) error {
-	switch cmd, _ := splitCmd(text); cmd {
+	if strings.TrimSpace(text) != "" {
+		logEvent(c.win, s, "exec", strings.TrimSpace(text))
+	}
+	// A command to confirm must be executed again next.
+	confirm := c.win.confirm
+	defer func() {
+		if c.win.confirm == confirm {
+			c.win.confirm = ""
+		}
+	}()
+	switch cmd, args := splitCmd(text); cmd {
 	case "Del":
 		if s == nil {
+			if len(c.win.cols) > 1 {
+				for _, r := range c.rows {
+					if s := getSheet(r); s != nil {
+						trashSheet(c.win, s)
+					}
+				}
+				for _, r := range c.rows[1:] {
+					r.Close()
+				}
+			}
 			c.win.Del(c)
 			return nil
 		}
 		for _, r := range c.rows {
 			if getSheet(r) == s {
+				trashSheet(c.win, s)
 				c.Del(r)
+				r.Close()
 			}
 		}
 
+	case "Undel":
+		return undel(c)
+
 	case "NewCol":
 		c.win.Add()
 
 	case "NewRow":
 		c.Add(NewSheet(c.win, ""))
 
+	case "New":
+		return newRow(c, args)
+
+	case "Quiet":
+		setQuiet(c.win, true)
+
+	case "Resume":
+		setQuiet(c.win, false)
+
+	case "Extensions":
+		c.win.OutputString(extensionStatus(c.win))
+
+	case "Swap":
+		if s != nil {
+			return swapRow(c, s, args)
+		}
+
+	case "Rotate":
+		return rotateRows(c, args)
+
+	case "Move":
+		if s != nil {
+			return moveToCol(c, s, args)
+		}
+
 	case "Get":
+		if args != "" {
+			return openFiles(c, s, args)
+		}
 		if s != nil {
 			return s.Get()
 		}
 
+	case "Open":
+		return openFiles(c, s, args)
+
+	case "Sort":
+		return sortDir(s, args)
+
+	case "Hidden":
+		return toggleHidden(s)
+
+	case "Replace":
+		return replace(c, s, args)
+
+	case "Mark":
+		if s != nil {
+			return setMark(s, args)
+		}
+
+	case "Back":
+		return goBack(c.win)
+
+	case "Forward":
+		return goForward(c.win)
+
+	case "Grep":
+		return grep(c, s, args)
+
+	case "Gdiff":
+		return gdiff(c, s, args)
+
+	case "Gblame":
+		if s != nil {
+			return gblame(c, s)
+		}
+
+	case "Gstatus":
+		return gstatus(c, s)
+
+	case "Gcommit":
+		return gcommit(c, s, args)
+
+	case "Mk", "Build":
+		return runBuild(c, s, args)
+
+	case "Diff":
+		if s != nil {
+			return sideDiff(c, s, args)
+		}
+
+	case "Hunk+":
+		if s != nil {
+			return nextHunk(s, 1)
+		}
+
+	case "Hunk-":
+		if s != nil {
+			return nextHunk(s, -1)
+		}
+
+	case "Watch":
+		if s != nil {
+			return runWatch(c, s, args)
+		}
+
+	case "Debug":
+		if s != nil {
+			return runDebug(c, s, args)
+		}
+
+	case "Cont":
+		return debugStep(c.win, (*dap.Client).Continue)
+
+	case "Next":
+		return debugStep(c.win, (*dap.Client).Next)
+
+	case "Step":
+		return debugStep(c.win, (*dap.Client).StepIn)
+
+	case "Out":
+		return debugStep(c.win, (*dap.Client).StepOut)
+
+	case "Stop":
+		stopDebug(c.win)
+
+	case "Record":
+		toggleRecording(c.win)
+
+	case "Replay":
+		return replayMacro(c.win, args)
+
+	case "Scripts":
+		return reloadScripts(c.win)
+
+	case "Break":
+		if s != nil {
+			toggleBreak(s, s.body.dots[1].At[0])
+		}
+
 	case "Put":
 		if s != nil {
 			return s.Put()
 		}
 
+	case "Put!":
+		if s != nil {
+			return putConflict(s)
+		}
+
+	case "Putall":
+		return putAll(c.win)
+
+	case "Exit":
+		return exit(c.win)
+
+	case "Fullscreen":
+		c.win.SetFullscreen(!c.win.Fullscreen())
+
+	case "Set":
+		return setOption(c, s, args)
+
+	case "Show":
+		return show(s, args)
+
+	case "Theme":
+		return setWinTheme(c.win, args)
+
+	case "Recover":
+		return recoverFiles(c)
+
 	case "Copy":
 		if s != nil {
 			return s.body.Copy()
@@ -62,27 +264,207 @@ func execCmd(c *Col, s *Sheet, text string) error {
 			return s.body.Paste()
 		}
 
+	case "Wrap":
+		if s != nil {
+			setWrap(s.body, s.body.nowrap)
+		}
+
+	case "Elastic":
+		if s != nil {
+			setElastic(s.body, !s.body.elastic)
+		}
+
+	case "Vi":
+		if s != nil {
+			setVi(s.body, s.body.vi == nil)
+		}
+
+	case "Repl":
+		dir, err := abs(s, ".")
+		if err != nil {
+			return err
+		}
+		r, err := newRepl(c.win, dir, args)
+		if err != nil {
+			return err
+		}
+		c.Add(r)
+
+	case "Win":
+		dir, err := abs(s, ".")
+		if err != nil {
+			return err
+		}
+		sh, err := newShell(c.win, dir, args)
+		if err != nil {
+			return err
+		}
+		c.Add(sh)
+
+	case "Check":
+		if s != nil {
+			toggleCheck(s.body)
+		}
+
+	case "Comment":
+		if s != nil {
+			return toggleComment(s)
+		}
+
+	case "Promote":
+		if s != nil {
+			shiftHeadings(s.body, headingMark(s.Title()), -1)
+		}
+
+	case "Demote":
+		if s != nil {
+			shiftHeadings(s.body, headingMark(s.Title()), 1)
+		}
+
+	case "Fold":
+		if s != nil {
+			return fold(s.body, headingMark(s.Title()), args)
+		}
+
+	case "Unfold":
+		if s != nil {
+			setFolds(s.body, nil)
+		}
+
+	case "Alt":
+		if s != nil {
+			return alt(c, s)
+		}
+
+	case "Imports":
+		if s != nil {
+			return imports(s.body, s.Title(), args)
+		}
+
+	case "Hover":
+		if s != nil {
+			return hover(s)
+		}
+
+	case "Def":
+		if s != nil {
+			return recordJump(c.win, func() error { return definition(c, s, args) })
+		}
+
+	case "Refs":
+		if s != nil {
+			return references(c, s, args)
+		}
+
+	case "Snip":
+		if s != nil {
+			return expandSnippet(s, args)
+		}
+
+	case "Complete":
+		if s != nil {
+			return popupComplete(s)
+		}
+
+	case "Diags":
+		if s != nil {
+			return diagnostics(s)
+		}
+
+	case "Play":
+		if s != nil {
+			at := s.body.dots[1].At
+			if at[0] == at[1] {
+				at = [2]int64{0, s.body.text.Len()}
+			}
+			snippet := rope.Slice(s.body.text, at[0], at[1]).String()
+			go func() {
+				if err := play(c.win, snippet); err != nil {
+					c.win.OutputString(err.Error() + "\n")
+				}
+			}()
+		}
+
+	case "Font":
+		if s != nil {
+			return setFont(s, args)
+		}
+
+	case "Journal":
+		return journal(c, time.Now())
+
+	case "Tab":
+		if s != nil {
+			return setTabs(s.body, args)
+		}
+
 	default:
 		if text == "" {
 			return nil
 		}
+		if addr := strings.TrimSpace(text); s != nil && len(addr) > 1 && (addr[0] == ':' || addr[0] == '\'') {
+			return recordJump(c.win, func() error { return jumpAddr(s, addr) })
+		}
+		if t := strings.TrimSpace(text); strings.HasPrefix(t, "|") {
+			if s == nil {
+				return nil
+			}
+			return pipeDot(s, t[1:])
+		}
 		if isDir, err := openDir(c, s, text); isDir {
 			return err
 		}
+		if sc, ok := c.win.scripts[cmd]; ok {
+			return runScript(c, s, sc, args)
+		}
+		cmd := exec.Command("sh", "-c", text)
+		if s != nil {
+			if dir, err := abs(s, "."); err == nil && filepath.IsAbs(dir) {
+				cmd.Dir = dir
+			}
+			cmd.Env = append(os.Environ(), sheetEnv(s)...)
+		}
 		go func() {
-			if err := shellCmd(c.win, text); err != nil {
+			start := time.Now()
+			err := shellCmd(c.win, cmd)
+			if err != nil {
 				c.win.OutputString(err.Error())
 			}
+			cmdFinished(c.win, text, start, err)
 		}()
 		return nil
 	}
 	return nil
 }
 
-func shellCmd(w *Win, text string) error {
-	// TODO: set 2-click shell command CWD to the sheet's directory.
-	// If executed from outside of a sheet, then don't set it specifically.
-	cmd := exec.Command("sh", "-c", text)
+// maxDotEnv is the longest dot set in the environment of commands;
+// a longer dot is left unset.
+const maxDotEnv = 64 << 10
+
+// sheetEnv returns the environment variables
+// set for commands executed in the sheet:
+// % and file are the title of the sheet,
+// winid is the ID of the sheet,
+// and dot is the text of dot of the body.
+// Commands of sh use $file, since sh cannot expand $%.
+func sheetEnv(s *Sheet) []string {
+	env := []string{
+		"%=" + s.Title(),
+		"file=" + s.Title(),
+		"winid=" + strconv.Itoa(s.id),
+	}
+	if at := s.body.dots[1].At; at[1]-at[0] <= maxDotEnv {
+		env = append(env, "dot="+rope.Slice(s.body.text, at[0], at[1]).String())
+	}
+	return env
+}
+
+// shellCmd runs the command,
+// writing its standard output and standard error to the Output sheet.
+// Commands executed in a sheet run in the sheet's directory
+// with the environment of sheetEnv;
+// others run in the current directory of T.
+func shellCmd(w *Win, cmd *exec.Cmd) error {
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
 		return err
@@ -108,10 +490,11 @@ func shellCmd(w *Win, text string) error {
 func pipeOutput(wg *sync.WaitGroup, w *Win, pipe io.Reader) {
 	defer wg.Done()
 	var buf [4096]byte
+	var d outputDecoder
 	for {
 		n, err := pipe.Read(buf[:])
-		if n > 0 {
-			w.OutputBytes(buf[:n])
+		if out := d.decode(buf[:n], err != nil); len(out) > 0 {
+			w.OutputBytes(out)
 		}
 		if err != nil {
 			return
@@ -124,10 +507,15 @@ func lookText(c *Col, s *Sheet, text string) error {
 		return nil
 	}
 
+	if ok, err := plumb(s, text); ok {
+		return err
+	}
+	if ok, err := lookFileAddr(c, s, text); ok {
+		return err
+	}
 	path, err := abs(s, text)
 	if err != nil {
-		setLook(c, s, text)
-		return nil
+		return setLook(c, s, text)
 	}
 
 	if focusSheet(c.win, path) {
@@ -139,10 +527,14 @@ func lookText(c *Col, s *Sheet, text string) error {
 
 	f, err := os.Open(path)
 	if err != nil {
-		setLook(c, s, text)
-		return nil
+		return setLook(c, s, text)
 	}
 	defer f.Close()
+	if isDirSheet(s) {
+		if st, err := f.Stat(); err == nil && st.IsDir() {
+			return lookDir(s, path, f)
+		}
+	}
 	s = NewSheet(c.win, path)
 	if err := get(s, f); err != nil {
 		return err
@@ -151,6 +543,41 @@ func lookText(c *Col, s *Sheet, text string) error {
 	return nil
 }
 
+// journal opens the Journal note file for the day of now,
+// creating it with journalHeader if it doesn't exist.
+func journal(c *Col, now time.Time) error {
+	dir := journalDir
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		dir = filepath.Join(home, "journal")
+	}
+	path := filepath.Join(dir, now.Format("2006-01-02")+".md")
+	if focusSheet(c.win, path) {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	switch f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666); {
+	case os.IsExist(err):
+		// Open the existing note.
+	case err != nil:
+		return err
+	default:
+		_, err := f.WriteString(now.Format(journalHeader))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return openSheet(c, path)
+}
+
 func focusSheet(w *Win, title string) bool {
 	for _, c := range w.cols {
 		for _, r := range c.rows {
@@ -166,8 +593,31 @@ func focusSheet(w *Win, title string) bool {
 	return false
 }
 
-func setLook(c *Col, s *Sheet, text string) {
+// jumpAddr sets dot of the body to the :addr edit address,
+// evaluated from dot, or to the 'name mark, and focuses the body.
+// The address may be any compound address of the edit language,
+// such as :123, :$, or :/func/,/^}/.
+func jumpAddr(s *Sheet, addr string) error {
+	if _, err := lookAddr(s.body, addr); err != nil {
+		return err
+	}
+	if s.TextBox != s.body {
+		s.TextBox.Focus(false)
+		s.TextBox = s.body
+		s.TextBox.Focus(true)
+	}
+	return nil
+}
+
+func setLook(c *Col, s *Sheet, text string) error {
+	if s == nil {
+		return nil
+	}
+	if ok, err := lookAddr(s.body, text); ok {
+		return err
+	}
 	// TODO: 3-clicking a non-file should highlight matches in the sheet.
+	return nil
 }
 
 func openDir(c *Col, s *Sheet, path string) (bool, error) {
@@ -203,7 +653,7 @@ func openDir(c *Col, s *Sheet, path string) (bool, error) {
 }
 
 func addDir(s *Sheet, f *os.File, rel string) error {
-	r, err := readFromDir(rel, f)
+	r, err := readFromDir(s, rel, f)
 	if err != nil {
 		return err
 	}
@@ -1,13 +1,61 @@
 package ui
 
 import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/eaburns/T/edit"
 	"github.com/eaburns/T/rope"
+	"golang.org/x/image/font/gofont/gomono"
 )
 
+func TestBuiltinCmds(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "cmd.go", nil, 0)
+	if err != nil {
+		t.Fatalf("failed to parse cmd.go: %v", err)
+	}
+	cases := make(map[string]bool)
+	for _, d := range f.Decls {
+		fn, ok := d.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != "execCmd" {
+			continue
+		}
+		var sw *ast.SwitchStmt
+		for _, st := range fn.Body.List {
+			if s, ok := st.(*ast.SwitchStmt); ok && sw == nil {
+				sw = s
+			}
+		}
+		for _, st := range sw.Body.List {
+			for _, e := range st.(*ast.CaseClause).List {
+				name, err := strconv.Unquote(e.(*ast.BasicLit).Value)
+				if err != nil {
+					t.Fatalf("bad case %v", e)
+				}
+				cases[name] = true
+			}
+		}
+	}
+	for name := range cases {
+		if !builtinCmds[name] {
+			t.Errorf("%s is handled by execCmd but not in builtinCmds", name)
+		}
+	}
+	for name := range builtinCmds {
+		if !cases[name] {
+			t.Errorf("%s is in builtinCmds but not handled by execCmd", name)
+		}
+	}
+}
+
 func TestCmd_empty(t *testing.T) {
 	var (
 		w = newTestWin()
@@ -309,3 +357,446 @@ func TestLook_focusExistingSheet(t *testing.T) {
 		t.Errorf("sheet not focused, wanted it to be focused")
 	}
 }
+
+func TestCmd_Tab(t *testing.T) {
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, "")
+	)
+	c.Add(s)
+	if err := execCmd(c, s, "Tab 4 spaces"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if s.body.tabWidth != 4 || !s.body.tabSpaces {
+		t.Errorf("tabWidth=%d, tabSpaces=%v, want 4, true", s.body.tabWidth, s.body.tabSpaces)
+	}
+	for _, r := range "a\tb\t" {
+		s.body.Rune(r)
+	}
+	if got, want := s.body.text.String(), "a   b   "; got != want {
+		t.Errorf("text=%q, want %q", got, want)
+	}
+
+	if err := execCmd(c, s, "Tab tabs"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	s.body.Rune('\t')
+	if got, want := s.body.text.String(), "a   b   \t"; got != want {
+		t.Errorf("text=%q, want %q", got, want)
+	}
+
+	if err := execCmd(c, s, "Tab 0"); err == nil {
+		t.Errorf("execCmd(Tab 0) succeeded, want an error")
+	}
+}
+
+func TestCmd_Font(t *testing.T) {
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, "")
+	)
+	c.Add(s)
+	if err := execCmd(c, s, "Font"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if s.font != fixedFont {
+		t.Errorf("font is not fixedFont after Font")
+	}
+	if err := execCmd(c, s, "Font"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if s.font != nil {
+		t.Errorf("font is not the default font after Font Font")
+	}
+
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mono.ttf")
+	if err := ioutil.WriteFile(path, gomono.TTF, 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := execCmd(c, s, "Font "+path+" 14"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if s.font == nil || s.fontSize != 14 {
+		t.Errorf("font=%p, fontSize=%d, want non-nil, 14", s.font, s.fontSize)
+	}
+	if s.body.style.Face == w.face || s.tag.style.Face != s.body.style.Face {
+		t.Errorf("sheet face was not changed")
+	}
+
+	if err := execCmd(c, s, "Font "+filepath.Join(dir, "missing.ttf")); err == nil {
+		t.Errorf("Font missing.ttf=nil, want error")
+	}
+}
+
+func TestCmd_Open(t *testing.T) {
+	defer func(n int) { maxOpenFiles = n }(maxOpenFiles)
+	maxOpenFiles = 2
+
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	mkSubDir(dir, "a")
+	touch(dir, "x.go")
+	touch(dir, "y.c")
+	touch(dir, "a/z.go")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, dir+"/")
+	)
+	c.Add(s)
+	if err := execCmd(c, s, "Open {*.c,a/*.go}"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if len(c.rows) != 4 {
+		t.Fatalf("len(c.rows)=%d, want 4", len(c.rows))
+	}
+	for i, want := range []string{"y.c", "a/z.go"} {
+		if title := getSheet(c.rows[i+2]).Title(); title != filepath.Join(dir, want) {
+			t.Errorf("row %d title=%q, want %q", i+2, title, filepath.Join(dir, want))
+		}
+	}
+
+	// More than maxOpenFiles must be confirmed.
+	if err := execCmd(c, s, "Get **/*.go *.c"); err == nil {
+		t.Errorf("execCmd succeeded, want an error")
+	}
+	if len(c.rows) != 4 {
+		t.Fatalf("len(c.rows)=%d, want 4", len(c.rows))
+	}
+	if err := execCmd(c, s, "Get **/*.go *.c"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if len(c.rows) != 5 {
+		t.Fatalf("len(c.rows)=%d, want 5", len(c.rows))
+	}
+
+	// Another command between dismisses the confirmation.
+	if err := execCmd(c, s, "Get **/*.go *.c"); err == nil {
+		t.Errorf("execCmd succeeded, want an error")
+	}
+	if err := execCmd(c, s, "Wrap"); err != nil {
+		t.Fatalf("Wrap failed: %v", err)
+	}
+	if err := execCmd(c, s, "Get **/*.go *.c"); err == nil {
+		t.Errorf("execCmd after Wrap succeeded, want an error")
+	}
+	if len(c.rows) != 5 {
+		t.Fatalf("len(c.rows)=%d, want 5", len(c.rows))
+	}
+
+	if err := execCmd(c, s, "Open *.none"); err == nil {
+		t.Errorf("execCmd succeeded, want an error")
+	}
+}
+
+func TestCmd_Replace(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	write(filepath.Join(dir, "a.go"), "foo\nbar\nfoo bar\n")
+	write(filepath.Join(dir, "b.go"), "bar\n")
+	write(filepath.Join(dir, "c.txt"), "foo\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, dir+"/")
+		b = NewSheet(w, filepath.Join(dir, "b.go"))
+	)
+	c.Add(s)
+	c.Add(b)
+	if err := b.Get(); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	b.body.Change(edit.Diffs{{At: [2]int64{0, 0}, Text: rope.New("foo ")}})
+
+	// The first execution shows a preview.
+	if err := execCmd(c, s, "Replace /fo+/baz/ *.go"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	p := findSheet(w, filepath.Join(dir, "+Replace"))
+	if p == nil {
+		t.Fatalf("no +Replace sheet")
+	}
+	want := filepath.Join(dir, "a.go") + ":1\n" +
+		"-foo\n" +
+		"+baz\n" +
+		filepath.Join(dir, "a.go") + ":3\n" +
+		"-foo bar\n" +
+		"+baz bar\n" +
+		filepath.Join(dir, "b.go") + ":1\n" +
+		"-foo bar\n" +
+		"+baz bar\n" +
+		"2 files change; execute again to replace\n"
+	if str := p.body.text.String(); str != want {
+		t.Errorf("preview=%q, want %q", str, want)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "a.go")); string(data) != "foo\nbar\nfoo bar\n" {
+		t.Errorf("a.go=%q before confirming", data)
+	}
+
+	// The second execution makes the changes,
+	// in the sheet if the file is open.
+	if err := execCmd(c, s, "Replace /fo+/baz/ *.go"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "a.go")); string(data) != "baz\nbar\nbaz bar\n" {
+		t.Errorf("a.go=%q, want %q", data, "baz\nbar\nbaz bar\n")
+	}
+	if str := b.body.text.String(); str != "baz bar\n" {
+		t.Errorf("b.go body=%q, want %q", str, "baz bar\n")
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "c.txt")); string(data) != "foo\n" {
+		t.Errorf("c.txt=%q, want %q", data, "foo\n")
+	}
+	if got, want := w.outputBuffer.String(), "replaced in 2 files\n"+filepath.Join(dir, "a.go")+"\n"+filepath.Join(dir, "b.go")+"\n"; got != want {
+		t.Errorf("output=%q, want %q", got, want)
+	}
+
+	// A file that changed since the preview is not replaced.
+	write(filepath.Join(dir, "c.txt"), "foo\n")
+	if err := execCmd(c, s, "Replace /foo/x/ *.txt"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	write(filepath.Join(dir, "c.txt"), "foo foo\n")
+	err := execCmd(c, s, "Replace /foo/x/ *.txt")
+	if want := filepath.Join(dir, "c.txt") + " changed since the preview; execute Replace again"; err == nil || err.Error() != want {
+		t.Errorf("execCmd=%v, want %q", err, want)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "c.txt")); string(data) != "foo foo\n" {
+		t.Errorf("c.txt=%q, want %q", data, "foo foo\n")
+	}
+
+	// With no patterns, the open file sheets are changed.
+	for i := 0; i < 2; i++ {
+		if err := execCmd(c, s, "Replace ,baz,qux,"); err != nil {
+			t.Fatalf("execCmd failed: %v", err)
+		}
+	}
+	if str := b.body.text.String(); str != "qux bar\n" {
+		t.Errorf("b.go body=%q, want %q", str, "qux bar\n")
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "a.go")); string(data) != "baz\nbar\nbaz bar\n" {
+		t.Errorf("a.go=%q, want %q", data, "baz\nbar\nbaz bar\n")
+	}
+
+	if err := execCmd(c, s, "Replace /none/x/ *.go"); err == nil {
+		t.Errorf("execCmd succeeded with no matches, want an error")
+	}
+	if err := execCmd(c, s, "Replace /foo"); err == nil {
+		t.Errorf("execCmd succeeded with no replacement, want an error")
+	}
+}
+
+func TestCmd_Undel(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file")
+	write(path, "hello")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, path)
+	)
+	c.Add(s)
+	if err := s.Get(); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+
+	// Unmodified sheets are not kept.
+	if err := execCmd(c, s, "Del"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if err := execCmd(c, nil, "Undel"); err == nil {
+		t.Errorf("Undel succeeded with no deleted sheets")
+	}
+
+	s = NewSheet(w, path)
+	c.Add(s)
+	if err := s.Get(); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	s.body.Change(edit.Diffs{{At: [2]int64{5, 5}, Text: rope.New(", World")}})
+	setDot(s.body, 1, 1, 2)
+	if err := execCmd(c, s, "Del"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if len(c.rows) != 1 {
+		t.Fatalf("len(c.rows)=%d, want 1", len(c.rows))
+	}
+	if err := execCmd(c, nil, "Undel"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if len(c.rows) != 2 {
+		t.Fatalf("len(c.rows)=%d, want 2", len(c.rows))
+	}
+	s = getSheet(c.rows[1])
+	if s.Title() != path || s.body.text.String() != "hello, World" {
+		t.Errorf("restored %q with %q, want %q with %q", s.Title(), s.body.text.String(), path, "hello, World")
+	}
+	if d := s.body.dots[1].At; d != [2]int64{1, 2} {
+		t.Errorf("restored dot=%v, want [1 2]", d)
+	}
+	if !isModified(s) {
+		t.Errorf("restored sheet is not modified")
+	}
+	if err := s.Put(); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if isModified(s) {
+		t.Errorf("sheet is modified after Put")
+	}
+}
+
+func TestCmd_Journal(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	defer func(d string) { journalDir = d }(journalDir)
+	journalDir = filepath.Join(dir, "notes")
+
+	var (
+		w   = newTestWin()
+		c   = w.cols[0]
+		now = time.Date(2020, time.March, 4, 12, 0, 0, 0, time.UTC)
+	)
+	if err := journal(c, now); err != nil {
+		t.Fatalf("journal failed: %v", err)
+	}
+	path := filepath.Join(dir, "notes", "2020-03-04.md")
+	s, ok := c.rows[len(c.rows)-1].(*Sheet)
+	if !ok || s.Title() != path {
+		t.Fatalf("last row is not a sheet titled %q", path)
+	}
+	const want = "# Wednesday, March 4, 2020\n\n"
+	if got := s.body.text.String(); got != want {
+		t.Errorf("body=%q, want %q", got, want)
+	}
+
+	write(path, "notes")
+	c.Del(s)
+	if err := journal(c, now); err != nil {
+		t.Fatalf("journal failed: %v", err)
+	}
+	s = c.rows[len(c.rows)-1].(*Sheet)
+	if got := s.body.text.String(); got != "notes" {
+		t.Errorf("body=%q, want %q", got, "notes")
+	}
+}
+
+func TestCmd_Alt(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	touch(dir, "a.go")
+	touch(dir, "a_test.go")
+	touch(dir, "b.x")
+	touch(dir, "b.y")
+	write(filepath.Join(dir, altFileName), "# comment\n^(.*)\\.x$ ${1}.y\nbad\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, filepath.Join(dir, "a.go"))
+	)
+	c.Add(s)
+	if err := execCmd(c, s, "Alt"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if len(w.cols) != 2 {
+		t.Fatalf("len(w.cols)=%d, want 2", len(w.cols))
+	}
+	c2 := w.cols[1]
+	alt := c2.rows[len(c2.rows)-1].(*Sheet)
+	if title, want := alt.Title(), filepath.Join(dir, "a_test.go"); title != want {
+		t.Errorf("counterpart title=%q, want %q", title, want)
+	}
+
+	// The counterpart is already open.
+	if err := execCmd(c2, alt, "Alt"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if len(c.rows) != 2 || len(c2.rows) != 2 {
+		t.Errorf("len(c.rows)=%d, len(c2.rows)=%d, want 2, 2", len(c.rows), len(c2.rows))
+	}
+
+	// A project rule.
+	s = NewSheet(w, filepath.Join(dir, "b.x"))
+	c.Add(s)
+	if err := execCmd(c, s, "Alt"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	alt = c2.rows[len(c2.rows)-1].(*Sheet)
+	if title, want := alt.Title(), filepath.Join(dir, "b.y"); title != want {
+		t.Errorf("counterpart title=%q, want %q", title, want)
+	}
+	if got, want := w.outputBuffer.String(), filepath.Join(dir, altFileName)+": bad rule: bad\n"; !strings.Contains(got, want) {
+		t.Errorf("output=%q, want %q", got, want)
+	}
+
+	s = NewSheet(w, filepath.Join(dir, "none.go"))
+	c.Add(s)
+	if err := execCmd(c, s, "Alt"); err == nil {
+		t.Errorf("execCmd succeeded, want an error")
+	}
+}
+
+func TestWinExec(t *testing.T) {
+	w := newTestWin()
+	s := NewSheet(w, "/a/b.txt")
+	w.cols[0].Add(s)
+	if err := w.Exec("Set tabwidth 3"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if s.body.tabWidth != 3 {
+		t.Errorf("tabWidth=%d, want 3", s.body.tabWidth)
+	}
+	if err := w.Exec("Set tabwidth x"); err == nil {
+		t.Errorf("Exec(Set tabwidth x) succeeded, want error")
+	}
+	if w.outputBuffer.Len() != 0 {
+		t.Errorf("output=%q, want empty", w.outputBuffer.String())
+	}
+}
+
+func TestCmd_shellEnv(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.txt")
+
+	w := newTestWin()
+	c := w.cols[0]
+	s := NewSheet(w, path)
+	c.Add(s)
+	s.body.SetText(rope.New("hello world"))
+	setDot(s.body, 1, 6, 11)
+	if err := execCmd(c, s, `echo "$(pwd -P) $file $winid $dot" > out`); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := real + " " + path + " " + strconv.Itoa(s.id) + " world\n"
+	out := filepath.Join(dir, "out")
+	var got string
+	for start := time.Now(); got != want && time.Since(start) < 5*time.Second; {
+		data, _ := ioutil.ReadFile(out)
+		got = string(data)
+		time.Sleep(time.Millisecond)
+	}
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if o := NewSheet(w, path); o.id == s.id {
+		t.Errorf("two sheets with winid %d", s.id)
+	}
+	env := sheetEnv(s)
+	if env[0] != "%="+path {
+		t.Errorf("sheetEnv(s)[0]=%q, want %q", env[0], "%="+path)
+	}
+}
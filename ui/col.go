@@ -3,6 +3,7 @@ package ui
 import (
 	"image"
 	"image/draw"
+	"sync"
 	"unicode"
 
 	"github.com/eaburns/T/rope"
@@ -16,7 +17,10 @@ type Col struct {
 	rows     []Row
 	heights  []float64 // frac of height
 	resizing int       // row index being resized or -1
+	drawn    []int     // y of the rows when last drawn
 	Row                // focus
+
+	imgs []image.RGBA // the images of the rows, reused by Draw
 }
 
 // NewCol returns a new column.
@@ -104,24 +108,97 @@ func (c *Col) Draw(dirty bool, drawImage draw.Image) {
 	if c.size != img.Bounds().Size() {
 		c.Resize(img.Bounds().Size())
 	}
+	if rowsMoved(c) || dirty {
+		// The frames and handle moved.
+		addChanged(c.win, img.Bounds())
+	}
+	if len(c.imgs) != len(c.rows) {
+		c.imgs = make([]image.RGBA, len(c.rows))
+	}
 
-	for i, o := range c.rows {
+	drawColHandle(c, img)
+	for i := range c.rows[:len(c.rows)-1] {
 		r := img.Bounds()
-		r.Min.Y = img.Bounds().Min.Y + y0(c, i)
-		r.Max.Y = img.Bounds().Min.Y + y1(c, i)
-		if i == 0 {
-			r0 := r
-			r0.Max.X = drawColHandle(c, img)
-			o.Draw(dirty, img.SubImage(r0).(*image.RGBA))
-		} else {
-			o.Draw(dirty, img.SubImage(r).(*image.RGBA))
+		r.Min.Y = img.Bounds().Min.Y + y1(c, i)
+		r.Max.Y = r.Min.Y + framePx
+		fillRect(img, frameBG, r)
+	}
+	// Starting goroutines allocates,
+	// so rows are only drawn concurrently if more than one changed.
+	if dirty || changedRows(c) > 1 {
+		drawRowsConcurrently(c, dirty, img)
+		return
+	}
+	for i, o := range c.rows {
+		o.Draw(dirty, subImage(&c.imgs[i], img, rowRect(c, img, i)))
+	}
+}
+
+// drawRowsConcurrently draws the rows concurrently
+// to disjoint parts of img.
+func drawRowsConcurrently(c *Col, dirty bool, img *image.RGBA) {
+	var wg sync.WaitGroup
+	for i, o := range c.rows {
+		wg.Add(1)
+		go func(i int, o Row) {
+			defer wg.Done()
+			o.Draw(dirty, subImage(&c.imgs[i], img, rowRect(c, img, i)))
+		}(i, o)
+	}
+	wg.Wait()
+}
+
+// rowRect returns the rectangle of img to which the ith row is drawn.
+func rowRect(c *Col, img *image.RGBA, i int) image.Rectangle {
+	r := img.Bounds()
+	r.Min.Y = img.Bounds().Min.Y + y0(c, i)
+	r.Max.Y = img.Bounds().Min.Y + y1(c, i)
+	if i == 0 {
+		r.Max.X = c.HandleBounds().Add(img.Bounds().Min).Min.X
+	}
+	return r
+}
+
+// changedRows returns the number of rows
+// that may draw something if drawn without dirty.
+func changedRows(c *Col) int {
+	var n int
+	for _, r := range c.rows {
+		if rowChanged(r) {
+			n++
 		}
-		if i < len(c.rows)-1 {
-			r.Min.Y = r.Max.Y
-			r.Max.Y += framePx
-			fillRect(img, frameBG, r)
+	}
+	return n
+}
+
+// rowChanged returns whether the row may draw something
+// if drawn without dirty.
+// Rows other than text boxes and sheets are assumed to have changed.
+func rowChanged(r Row) bool {
+	switch r := r.(type) {
+	case *TextBox:
+		return r.dirty
+	case *Sheet:
+		return r.tag.dirty || r.body.dirty
+	default:
+		return true
+	}
+}
+
+// rowsMoved returns whether the y of the top of any row changed
+// since it was last called, and records the current ys.
+func rowsMoved(c *Col) bool {
+	moved := len(c.drawn) != len(c.rows)
+	if moved {
+		c.drawn = make([]int, len(c.rows))
+	}
+	for i := range c.rows {
+		if y := y0(c, i); c.drawn[i] != y {
+			c.drawn[i] = y
+			moved = true
 		}
 	}
+	return moved
 }
 
 func drawColHandle(c *Col, img *image.RGBA) int {
@@ -151,6 +228,8 @@ func (c *Col) Resize(size image.Point) {
 		for i := 1; i < nr; i++ {
 			c.heights[i] = c.heights[i-1] + 1.0/float64(nr)
 		}
+	} else {
+		stackRows(c)
 	}
 	c.heights[len(c.heights)-1] = 1.0
 
@@ -171,6 +250,45 @@ func (c *Col) Resize(size image.Point) {
 	}
 }
 
+// stackRows ensures that there is room below each row
+// for the tags of all following rows.
+// Rows that would otherwise be pushed off the bottom of the column
+// are collapsed to tag-only stubs stacked at the bottom.
+func stackRows(c *Col) {
+	dy := dy(c)
+	stub := c.win.lineHeight + framePx
+	for i := len(c.rows) - 2; i >= 0; i-- {
+		max := int(dy) - (len(c.rows)-1-i)*stub
+		if y1(c, i) > max {
+			c.heights[i] = clampFrac(float64(max) / dy)
+		}
+	}
+}
+
+// collapsed returns whether the ith row is collapsed to just its tag.
+func collapsed(c *Col, i int) bool {
+	return i > 0 && y1(c, i)-y0(c, i) <= c.win.lineHeight
+}
+
+// restoreRow grows a collapsed row to half of the column height,
+// or as much as it can get if there isn't room for that.
+// Rows above it are shrunk, collapsing them if needed.
+func restoreRow(c *Col, i int) {
+	dy := dy(c)
+	stub := c.win.lineHeight + framePx
+	top := y1(c, i) - int(dy)/2
+	if min := (i - 1) * stub; top < min {
+		top = min
+	}
+	for j := i - 1; j >= 0; j-- {
+		max := top - framePx - (i-1-j)*stub
+		if y1(c, j) > max {
+			c.heights[j] = clampFrac(float64(max) / dy)
+		}
+	}
+	c.Resize(c.size)
+}
+
 // Move handles mouse move events.
 func (c *Col) Move(pt image.Point) {
 	if c.resizing >= 0 {
@@ -298,6 +416,17 @@ func (c *Col) Wheel(pt image.Point, x, y int) {
 	}
 }
 
+// WheelPx handles precise scrolling events.
+func (c *Col) WheelPx(pt image.Point, x, y float64) {
+	for i, r := range c.rows {
+		if pt.Y < y1(c, i) {
+			pt.Y -= y0(c, i)
+			r.WheelPx(pt, x, y)
+			return
+		}
+	}
+}
+
 // Click handles click events.
 func (c *Col) Click(pt image.Point, button int) {
 	if c.resizing >= 0 && button == -1 {
@@ -315,6 +444,10 @@ func (c *Col) Click(pt image.Point, button int) {
 			handle := handler.HandleBounds().Add(image.Pt(0, y0(c, i)))
 			if pt.In(handle) {
 				setColFocus(c, r)
+				if collapsed(c, i) {
+					restoreRow(c, i)
+					return
+				}
 				c.resizing = i - 1
 				return
 			}
@@ -339,7 +472,15 @@ func (c *Col) Click(pt image.Point, button int) {
 		case -2:
 			err = execCmd(c, s, txt)
 		case -3:
-			err = lookText(c, s, txt)
+			if s != nil && tb == s.body {
+				for _, f := range clickTexts {
+					if t, ok := f(s, addr); ok {
+						txt = t
+						break
+					}
+				}
+			}
+			err = recordJump(c.win, func() error { return lookText(c, s, txt) })
 		}
 		if err != nil {
 			c.win.OutputString(err.Error() + "\n")
@@ -347,6 +488,16 @@ func (c *Col) Click(pt image.Point, button int) {
 	}
 }
 
+// clickTexts return the text looked at
+// when a line of the body of a results sheet is 3-clicked,
+// and whether the sheet and line have one.
+var clickTexts = []func(*Sheet, [2]int64) (string, bool){
+	grepClickText,
+	buildClickText,
+	gdiffClickText,
+	gstatusClickText,
+}
+
 func setColFocusPt(c *Col, pt image.Point) {
 	for i, o := range c.rows {
 		if pt.Y < y1(c, i) {
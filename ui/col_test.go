@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"image"
+	"testing"
+)
+
+func TestResizeStacksCollapsedRows(t *testing.T) {
+	w := newTestWin()
+	c := w.cols[0]
+	c.Resize(image.Pt(100, 200))
+	for i := 0; i < 5; i++ {
+		c.Add(NewSheet(w, ""))
+	}
+	for i := 1; i < len(c.rows); i++ {
+		if h := y1(c, i) - y0(c, i); h < H {
+			t.Errorf("row %d height=%d, want >= %d", i, h, H)
+		}
+	}
+	if y := y1(c, len(c.rows)-1); y != 200 {
+		t.Errorf("last row y1=%d, want 200", y)
+	}
+	if !collapsed(c, len(c.rows)-1) {
+		t.Errorf("last row is not collapsed")
+	}
+}
+
+func TestClickRestoresCollapsedRow(t *testing.T) {
+	w := newTestWin()
+	c := w.cols[0]
+	c.Resize(image.Pt(100, 200))
+	for i := 0; i < 5; i++ {
+		c.Add(NewSheet(w, ""))
+	}
+	i := len(c.rows) - 1
+	if !collapsed(c, i) {
+		t.Fatalf("last row is not collapsed")
+	}
+
+	c.Click(image.Pt(99, y0(c, i)+1), 1)
+	c.Click(image.Pt(99, y0(c, i)+1), -1)
+
+	if collapsed(c, i) {
+		t.Errorf("last row is still collapsed")
+	}
+	if h := y1(c, i) - y0(c, i); h < 100-framePx {
+		t.Errorf("restored row height=%d, want >= %d", h, 100-framePx)
+	}
+	for j := 1; j < len(c.rows); j++ {
+		if h := y1(c, j) - y0(c, j); h < H-framePx {
+			t.Errorf("row %d height=%d, want >= %d", j, h, H-framePx)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/eaburns/T/rope"
+)
+
+// toggleComment implements the Comment command.
+// If every non-blank line spanned by dot is commented
+// with the comment prefix of the file's type,
+// the prefix and a following space are removed from the lines.
+// Otherwise, the prefix and a space are inserted
+// after the indentation of each non-blank line.
+func toggleComment(s *Sheet) error {
+	b := s.body
+	if b.comment == "" {
+		return errors.New(msg("no comment syntax for %s", s.Title()))
+	}
+	uncomment := true
+	for _, l := range dotLines(b) {
+		line := rope.Slice(b.text, l[0], l[1]).String()
+		if trimmed := strings.TrimLeft(line, " \t"); trimmed != "" && !strings.HasPrefix(trimmed, b.comment) {
+			uncomment = false
+			break
+		}
+	}
+	changeLines(b, func(line string) string {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			return line
+		}
+		indent := line[:len(line)-len(trimmed)]
+		if uncomment {
+			trimmed = strings.TrimPrefix(trimmed, b.comment)
+			return indent + strings.TrimPrefix(trimmed, " ")
+		}
+		return indent + b.comment + " " + trimmed
+	})
+	return nil
+}
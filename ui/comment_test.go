@@ -0,0 +1,35 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestToggleComment(t *testing.T) {
+	s := NewSheet(testWin, "a.go")
+	setFileType(s)
+	s.body.SetText(rope.New("func f() {\n\tx()\n\n\t// y()\n}\n"))
+	setDot(s.body, 1, 11, 20)
+	if err := toggleComment(s); err != nil {
+		t.Fatalf("toggleComment failed: %v", err)
+	}
+	want := "func f() {\n\t// x()\n\n\t// // y()\n}\n"
+	if got := s.body.text.String(); got != want {
+		t.Errorf("text=%q, want %q", got, want)
+	}
+	setDot(s.body, 1, 11, 28)
+	if err := toggleComment(s); err != nil {
+		t.Fatalf("toggleComment failed: %v", err)
+	}
+	want = "func f() {\n\tx()\n\n\t// y()\n}\n"
+	if got := s.body.text.String(); got != want {
+		t.Errorf("text=%q, want %q", got, want)
+	}
+
+	s = NewSheet(testWin, "a.txt")
+	setFileType(s)
+	if err := toggleComment(s); err == nil {
+		t.Errorf("toggleComment(a.txt) succeeded, want error")
+	}
+}
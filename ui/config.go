@@ -1,16 +1,34 @@
 package ui
 
 import (
+	"errors"
 	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/eaburns/T/syntax"
 	"github.com/eaburns/T/syntax/dirsyntax"
 	"github.com/eaburns/T/syntax/gosyntax"
+	"github.com/eaburns/T/text"
 	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
 	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/text/encoding/charmap"
 )
 
 const (
+	// underlinePx is the pixel-height of the line
+	// drawn under underlined text.
+	underlinePx = 2
+
+	// minFontSize is the smallest font size in points.
+	minFontSize = 4
+)
+
+var (
 	// framePx is the pixel-width of the lines
 	// drawn between columns and rows.
 	framePx = 1
@@ -20,51 +38,813 @@ const (
 	// and its text.
 	textPadPx = 7
 
-	// cursorWidthPx is the pixel-width of the cursor.
+	// cursorWidthPx is the pixel-width of the bar cursor
+	// and the pixel-height of the underline cursor.
 	cursorWidthPx = 4
 
+	// cursorShape is the shape of the cursor: bar, block, or underline.
+	// A block cursor is translucent, so the text under it shows.
+	cursorShape = "bar"
+
+	// cursorBlink is whether the cursor blinks.
+	cursorBlink = true
+
+	// blinkDuration is the time the blinking cursor is shown or hidden.
+	blinkDuration = 500 * time.Millisecond
+
+	// hollowCursor is whether the cursor of the focused text box
+	// is drawn as an outline while the window is not focused.
+	// Otherwise it is not drawn.
+	hollowCursor = true
+
 	// colText is the default column background text.
 	colText = "Del NewCol NewRow\n"
 
 	// tagText is the default tag text.
 	tagText = " Del Cut Paste"
-)
 
-var (
+	// tagFile is the name of files whose text is appended
+	// to the tags of new sheets of the files in their directory,
+	// so commands used there are one click away.
+	tagFile = ".tag"
+
+	// configFile is the configuration file read at startup.
+	// It can be set with the T_CONFIG environment variable.
+	// If it is empty, T/config in os.UserConfigDir is used.
+	configFile = ""
+
 	// defaultFont is the default font.
 	defaultFont, _ = truetype.Parse(goregular.TTF)
 
+	// fixedFont is the fixed-width font
+	// toggled with defaultFont by the Font command.
+	fixedFont, _ = truetype.Parse(gomono.TTF)
+
+	// ligatures is whether sequences of runes in ligatureRunes
+	// are drawn as a single glyph.
+	// Ligatures do not change the width of the text.
+	ligatures = false
+
+	// ligatureRunes maps sequences of runes
+	// to the rune drawn in their place if ligatures is true.
+	// The sequences are at most maxLigatureLen bytes.
+	ligatureRunes = map[string]rune{
+		"->":  '→',
+		"<-":  '←',
+		"=>":  '⇒',
+		"<=":  '≤',
+		">=":  '≥',
+		"!=":  '≠',
+		"...": '…',
+	}
+
 	// defaultFontSize is the default font size in points.
 	defaultFontSize = 11
 
+	// subpixelText is whether text is anti-aliased
+	// using the subpixels of an LCD with horizontal RGB stripes,
+	// which is sharper than grayscale anti-aliasing
+	// on standard-DPI monitors.
+	subpixelText = false
+
+	// fontHinting is the hinting of glyph outlines.
+	fontHinting = font.HintingNone
+
+	// fallbackFontPaths are paths of TTF files
+	// used to draw runes that have no glyph in defaultFont.
+	// Paths that do not exist or cannot be parsed are skipped.
+	// They can be set with the T_FONTS environment variable,
+	// a list of paths separated by os.PathListSeparator.
+	fallbackFontPaths = []string{
+		"/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf",
+		"/usr/share/fonts/truetype/noto/NotoSansCJK-Regular.ttc",
+		"/usr/share/fonts/opentype/noto/NotoSansCJK-Regular.ttc",
+		"/usr/share/fonts/truetype/droid/DroidSansFallbackFull.ttf",
+		"/usr/share/fonts/truetype/noto/NotoEmoji-Regular.ttf",
+		"/Library/Fonts/Arial Unicode.ttf",
+		"/System/Library/Fonts/Supplemental/Arial Unicode.ttf",
+		`C:\Windows\Fonts\seguisym.ttf`,
+		`C:\Windows\Fonts\msyh.ttc`,
+	}
+
+	// fonts are defaultFont followed by the parsed fallbackFontPaths.
+	fonts []*truetype.Font
+
 	// fg is the text foreground color.
-	fg = color.RGBA{R: 0x10, G: 0x28, B: 0x34, A: 0xFF}
+	fg color.Color = color.RGBA{R: 0x10, G: 0x28, B: 0x34, A: 0xFF}
 
 	// frameBG is the lines drawn between columns and rows.
 	frameBG = fg
 
 	// colBG is the column background color.
-	colBG = color.White
+	colBG color.Color = color.White
 
 	// tagBG is the tag background color.
-	tagBG = color.RGBA{R: 0xCF, G: 0xE0, B: 0xF7, A: 0xFF}
+	tagBG color.Color = color.RGBA{R: 0xCF, G: 0xE0, B: 0xF7, A: 0xFF}
 
 	// bodyBG is a body background color.
-	bodyBG = color.RGBA{R: 0xFA, G: 0xF0, B: 0xE6, A: 0xFF}
+	bodyBG color.Color = color.RGBA{R: 0xFA, G: 0xF0, B: 0xE6, A: 0xFF}
 
 	// hiBG1, hiBG2, and hiBG2 are the background colors
 	// of 1-, 2-, and 3-click highlighted text.
-	hiBG1 = color.RGBA{R: 0xDF, G: 0xC6, B: 0xDF, A: 0xFF}
-	hiBG2 = color.RGBA{R: 0xF6, G: 0xC3, B: 0xC6, A: 0xFF}
-	hiBG3 = color.RGBA{R: 0xD0, G: 0xEA, B: 0xC8, A: 0xFF}
+	hiBG1 color.Color = color.RGBA{R: 0xDF, G: 0xC6, B: 0xDF, A: 0xFF}
+	hiBG2 color.Color = color.RGBA{R: 0xF6, G: 0xC3, B: 0xC6, A: 0xFF}
+	hiBG3 color.Color = color.RGBA{R: 0xD0, G: 0xEA, B: 0xC8, A: 0xFF}
+
+	// themes are the named color themes.
+	// The theme can be selected with the T_THEME environment variable
+	// or changed with the Theme command.
+	themes = map[string]theme{
+		"default": lightTheme,
+		"light":   lightTheme,
+		"dark": {
+			fg:              color.RGBA{R: 0xD8, G: 0xDE, B: 0xE9, A: 0xFF},
+			frameBG:         color.RGBA{R: 0x4C, G: 0x56, B: 0x6A, A: 0xFF},
+			colBG:           color.RGBA{R: 0x1B, G: 0x1F, B: 0x27, A: 0xFF},
+			tagBG:           color.RGBA{R: 0x2E, G: 0x34, B: 0x40, A: 0xFF},
+			bodyBG:          color.RGBA{R: 0x23, G: 0x28, B: 0x31, A: 0xFF},
+			hiBG1:           color.RGBA{R: 0x4C, G: 0x3F, B: 0x63, A: 0xFF},
+			hiBG2:           color.RGBA{R: 0x6B, G: 0x3A, B: 0x3F, A: 0xFF},
+			hiBG3:           color.RGBA{R: 0x3B, G: 0x5A, B: 0x3A, A: 0xFF},
+			trailingSpaceBG: color.RGBA{R: 0x7A, G: 0x2E, B: 0x2E, A: 0xFF},
+			occurrenceBG:    color.RGBA{R: 0x4A, G: 0x42, B: 0x30, A: 0xFF},
+			debugLineBG:     color.RGBA{R: 0x2F, G: 0x4A, B: 0x5A, A: 0xFF},
+			diffRemovedBG:   color.RGBA{R: 0x5A, G: 0x2A, B: 0x2A, A: 0xFF},
+			diffAddedBG:     color.RGBA{R: 0x2A, G: 0x4A, B: 0x2A, A: 0xFF},
+			diffChangedBG:   color.RGBA{R: 0x4A, G: 0x44, B: 0x26, A: 0xFF},
+		},
+		// high-contrast has a contrast ratio of at least 7:1
+		// between the foreground and every background,
+		// meeting WCAG level AAA.
+		"high-contrast": {
+			fg:      color.Black,
+			frameBG: color.Black,
+			colBG:   color.White,
+			tagBG:   color.RGBA{R: 0xE0, G: 0xE0, B: 0xE0, A: 0xFF},
+			bodyBG:  color.White,
+			hiBG1:   color.RGBA{R: 0xFF, G: 0xE0, B: 0x33, A: 0xFF},
+			hiBG2:   color.RGBA{R: 0xFF, G: 0xA0, B: 0xA0, A: 0xFF},
+			hiBG3:   color.RGBA{R: 0x99, G: 0xEE, B: 0x99, A: 0xFF},
+		},
+	}
+
+	// lightTheme is the default theme, the initial colors.
+	lightTheme = theme{
+		fg:              fg,
+		frameBG:         frameBG,
+		colBG:           colBG,
+		tagBG:           tagBG,
+		bodyBG:          bodyBG,
+		hiBG1:           hiBG1,
+		hiBG2:           hiBG2,
+		hiBG3:           hiBG3,
+		trailingSpaceBG: trailingSpaceBG,
+		occurrenceBG:    occurrenceBG,
+		debugLineBG:     debugLineBG,
+		diffRemovedBG:   diffRemovedBG,
+		diffAddedBG:     diffAddedBG,
+		diffChangedBG:   diffChangedBG,
+	}
+
+	// wordRunes are the runes other than letters and numbers
+	// that are part of a word selected by double-clicking.
+	// For example, adding -./ selects whole file paths.
+	// They can be set with the T_WORD_RUNES environment variable.
+	wordRunes = "_"
+
+	// clickDelims are the pairs of opening and closing delimiters
+	// between which text is selected by double-clicking
+	// just after the opening or just before the closing delimiter.
+	clickDelims = [][2]rune{
+		{'(', ')'},
+		{'{', '}'},
+		{'[', ']'},
+		{'<', '>'},
+		{'«', '»'},
+		{'\'', '\''},
+		{'"', '"'},
+		{'`', '`'},
+		{'“', '”'},
+	}
+
+	// reducedMotion disables cursor blinking and other animations.
+	// It can be enabled by setting the T_REDUCED_MOTION environment variable.
+	reducedMotion = false
+
+	// repls maps the names of interpreters for REPL sheets
+	// to the command that runs the interpreter
+	// and a regular expression (using regexp package syntax)
+	// matching the interpreter's prompt.
+	repls = map[string]struct {
+		cmd    []string
+		prompt string
+	}{
+		"python": {[]string{"python3", "-i", "-q", "-u"}, `>>> |\.\.\. `},
+		"node":   {[]string{"node", "-i"}, `> |\.\.\. `},
+		"gore":   {[]string{"gore"}, `gore> |\.\.\. `},
+	}
+
+	// winShell is the command of the interactive shell of Win sheets,
+	// and winPrompt is a regular expression (using regexp package syntax)
+	// matching its prompt.
+	// The shell is run with PS1 set to "$ ".
+	winShell  = []string{"sh", "-i"}
+	winPrompt = `[$#] `
+
+	// languageServers are the language servers of file types:
+	// a file regular expression (using regexp package syntax),
+	// the language identifier of the files,
+	// and the command that runs the server.
+	// The first match is used.
+	// When a file is read by Get, it is opened in the server of its type,
+	// which is started for the file's root directory if it is not running.
+	// Servers whose command is not found are not started.
+	languageServers = []struct {
+		regexp   string
+		language string
+		cmd      []string
+	}{
+		{`.*\.go$`, "go", []string{"gopls"}},
+		{`.*\.py$`, "python", []string{"pylsp"}},
+		{`.*\.rs$`, "rust", []string{"rust-analyzer"}},
+		{`.*\.(c|h)$`, "c", []string{"clangd"}},
+		{`.*\.(cc|cpp|hpp)$`, "cpp", []string{"clangd"}},
+	}
+
+	// lspRootFiles are the files marking the root directory
+	// of a file for its language server.
+	// The root is the nearest directory containing one of them,
+	// or the file's directory if there is none.
+	lspRootFiles = []string{"go.mod", "Cargo.toml", ".git"}
+
+	// lspTimeout is the time after which
+	// a request to a language server fails.
+	lspTimeout = 5 * time.Second
+
+	// debugAdapters are the debug adapters of file types
+	// for the Debug command:
+	// a file regular expression (using regexp package syntax),
+	// the command that runs the adapter,
+	// and the arguments of its launch request,
+	// to which the Debug command adds the program and its arguments.
+	// The first match is used.
+	debugAdapters = []struct {
+		regexp string
+		cmd    []string
+		launch map[string]interface{}
+	}{
+		{`.*\.go$`, []string{"dlv", "dap"}, map[string]interface{}{"mode": "debug"}},
+		{`.*\.(c|h|cc|cpp|hpp|rs)$`, []string{"gdb", "-i", "dap"}, nil},
+	}
+
+	// debugTimeout is the time after which
+	// a stopped debug adapter is killed.
+	debugTimeout = 5 * time.Second
+
+	// watchDelay is the time without changes to watched files
+	// after which the command of the Watch command is rerun.
+	watchDelay = 200 * time.Millisecond
+
+	// journalDir is the directory of Journal note files.
+	// It can be set with the T_JOURNAL environment variable.
+	// If it is empty, $HOME/journal is used.
+	journalDir = ""
+
+	// journalHeader is the header of a new Journal note file.
+	// It is formatted by time.Time.Format with the note's date.
+	journalHeader = "# Monday, January 2, 2006\n\n"
+
+	// extensionsFile is the manifest of extensions,
+	// helper processes run with the editor.
+	// It can be set with the T_EXTENSIONS environment variable.
+	// If it is empty, T/extensions in os.UserConfigDir is used.
+	extensionsFile = ""
+
+	// searchHistoryFile is the file of the search history,
+	// read when the window is created and written when it is closed.
+	// It can be set with the T_SEARCHES environment variable.
+	// If it is empty, T/searches in os.UserConfigDir is used.
+	searchHistoryFile = ""
+
+	// snippetsDir is the directory of the snippet files of the Snip command.
+	// It can be set with the T_SNIPPETS environment variable.
+	// If it is empty, T/snippets in os.UserConfigDir is used.
+	snippetsDir = ""
+
+	// scriptsFile is the file defining the scripts,
+	// commands written in a small language of Edit commands.
+	// It can be set with the T_SCRIPTS environment variable.
+	// If it is empty, T/scripts in os.UserConfigDir is used.
+	scriptsFile = ""
+
+	// recoveryDir is the directory of the recovery files
+	// to which modified files are saved every autosaveInterval.
+	// Each window saves to its own subdirectory,
+	// named by the ID of its process.
+	// It can be set with the T_RECOVER environment variable.
+	// If it is empty, T/recover in os.UserCacheDir is used.
+	recoveryDir = ""
+
+	// autosaveInterval is the time between saves of modified files
+	// to recovery files. If it is 0, they are not saved.
+	autosaveInterval = 30 * time.Second
+
+	// maxSearches is the number of patterns kept in the search history.
+	maxSearches = 100
+
+	// maxJumps is the number of positions kept in the jump list.
+	maxJumps = 100
+
+	// extMinBackoff and extMaxBackoff bound the delay
+	// before restarting an extension that exited.
+	extMinBackoff = time.Second
+	extMaxBackoff = time.Minute
+
+	// locale is the locale of user-visible messages.
+	// It is selected by the T_LANG, LC_ALL, LC_MESSAGES,
+	// or LANG environment variable.
+	// If it has no messages, messages are in English.
+	locale = ""
+
+	// messages maps locales to translations of user-visible messages.
+	// The keys of a translation are the English fmt format strings
+	// and the values are the translated format strings.
+	// Command names are not translated.
+	messages = map[string]map[string]string{
+		"de": {
+			"no counterpart for %s":                      "kein Gegenstück für %s",
+			"%s: bad rule: %s":                           "%s: ungültige Regel: %s",
+			"no files match %s":                          "keine Dateien passen zu %s",
+			"%d files match; execute again to open them": "%d Dateien passen; zum Öffnen erneut ausführen",
+			"%s is not imported":                         "%s ist nicht importiert",
+			"no deleted sheets":                          "keine gelöschten Blätter",
+			"unknown theme %s":                           "unbekanntes Farbschema %s",
+			"syntax highlighting":                        "Syntaxhervorhebung",
+			"trailing space highlighting":                "Hervorhebung von Leerzeichen am Zeilenende",
+			"imports on Put":                             "Imports bei Put",
+			"formatting on Put":                          "Formatierung bei Put",
+			"wrapping (%d-byte line)":                    "Zeilenumbruch (Zeile mit %d Bytes)",
+			"%s: %d bytes; disabled %s":                  "%s: %d Bytes; deaktiviert: %s",
+			"usage: Open pattern ...":                    "Aufruf: Open Muster ...",
+			"usage: Imports [+path|-path ...]":           "Aufruf: Imports [+Pfad|-Pfad ...]",
+			"usage: Fold [level]":                        "Aufruf: Fold [Ebene]",
+			"usage: Repl %s":                             "Aufruf: Repl %s",
+			"usage: Font [path] [size]":                  "Aufruf: Font [Pfad] [Größe]",
+			"usage: Tab [width] [spaces|tabs]":           "Aufruf: Tab [Breite] [spaces|tabs]",
+			"usage: Swap [n]":                            "Aufruf: Swap [n]",
+			"usage: Rotate [-]":                          "Aufruf: Rotate [-]",
+			"usage: Theme [name]":                        "Aufruf: Theme [Name]",
+			"no comment syntax for %s":                   "keine Kommentarsyntax für %s",
+			"usage: Set [-g] option value":               "Aufruf: Set [-g] Option Wert",
+			"usage: Show options":                        "Aufruf: Show options",
+			"unknown option %s":                          "unbekannte Option %s",
+			"%s wants on or off":                         "%s erwartet on oder off",
+			"%s wants a positive number":                 "%s erwartet eine positive Zahl",
+			"usage: Move column":                         "Aufruf: Move Spalte",
+			"line %d: want name and command":             "Zeile %d: Name und Befehl erwartet",
+			"line %d: duplicate extension %s":            "Zeile %d: Erweiterung %s doppelt",
+			"line %d: want key and value":                "Zeile %d: Schlüssel und Wert erwartet",
+			"line %d: unknown key %s":                    "Zeile %d: unbekannter Schlüssel %s",
+			"line %d: bad number %s":                     "Zeile %d: ungültige Zahl %s",
+			"line %d: bad color %s":                      "Zeile %d: ungültige Farbe %s",
+			"line %d: %s":                                "Zeile %d: %s",
+			"no extensions":                              "keine Erweiterungen",
+			"%s: %s (%d restarts)":                       "%s: %s (%d Neustarts)",
+			"no match for %s":                            "kein Treffer für %s",
+			"usage: Replace /regexp/text/ [pattern ...]": "Aufruf: Replace /regexp/text/ [Muster ...]",
+			"no matches":                                 "keine Treffer",
+			"%d files change; execute again to replace":  "%d Dateien ändern sich; erneut ausführen, um zu ersetzen",
+			"usage: Grep regexp [path ...]":              "Aufruf: Grep regexp [Pfad ...]",
+			"more than %d matches":                       "mehr als %d Treffer",
+			"no marks":                                   "keine Marken",
+			"no mark %s":                                 "keine Marke %s",
+			"usage: Mark [name]":                         "Aufruf: Mark [Name]",
+			"no previous position":                       "keine vorherige Position",
+			"no next position":                           "keine nächste Position",
+			"replaced in %d files":                       "in %d Dateien ersetzt",
+			"language server":                            "Sprachserver",
+			"no build tool for %s":                       "kein Build-Werkzeug für %s",
+			"(unsaved)":                                  "(ungespeichert)",
+			"usage: Watch [-p path]... command":          "Aufruf: Watch [-p Pfad]... Befehl",
+			"no differences":                             "keine Unterschiede",
+			"%s is not a file":                           "%s ist keine Datei",
+			"nothing to commit":                          "nichts zu committen",
+			"usage: Gcommit message":                     "Aufruf: Gcommit Nachricht",
+			"no language server for %s":                  "kein Sprachserver für %s",
+			"language server %s is not running":          "Sprachserver %s läuft nicht",
+			"language server timed out":                  "Zeitüberschreitung des Sprachservers",
+			"no information":                             "keine Informationen",
+			"no definition":                              "keine Definition",
+			"no file %s":                                 "keine Datei %s",
+			"no completions":                             "keine Vervollständigungen",
+			"no diagnostics":                             "keine Diagnosen",
+			"no identifier":                              "kein Bezeichner",
+			"no definition of %s":                        "keine Definition von %s",
+			"no references":                              "keine Verweise",
+			"no references to %s":                        "keine Verweise auf %s",
+			"no debug adapter for %s":                    "kein Debug-Adapter für %s",
+			"debugging %s":                               "debugge %s",
+			"stopped: %s":                                "angehalten: %s",
+			"program exited":                             "Programm beendet",
+			"stopped debugging":                          "Debuggen beendet",
+			"no debug session":                           "keine Debug-Sitzung",
+			"the debug adapter is not running":           "der Debug-Adapter läuft nicht",
+			"no snippet name":                            "kein Snippet-Name",
+			"no snippet %s":                              "kein Snippet %s",
+			"modified: %s; execute again to exit":        "geändert: %s; zum Beenden erneut ausführen",
+			"no recovery files":                          "keine Wiederherstellungsdateien",
+			"recovered %s":                               "%s wiederhergestellt",
+			"%s: not a recovery file":                    "%s: keine Wiederherstellungsdatei",
+			"%s finished":                                "%s beendet",
+			"%s failed: %s":                              "%s fehlgeschlagen: %s",
+			"%d unsaved files can be recovered; execute Recover to restore them": "%d ungespeicherte Dateien können mit Recover wiederhergestellt werden",
+			"%s changed on disk; execute Put! to overwrite it":                   "%s wurde auf der Platte geändert; Put! überschreibt die Datei",
+			"%s changed since the preview; execute Replace again":                "%s wurde seit der Vorschau geändert; Replace erneut ausführen",
+			"line %d: want cursorshape bar|block|underline":                      "Zeile %d: cursorshape bar|block|underline erwartet",
+			"line %d: bad duration %s":                                           "Zeile %d: ungültige Dauer %s",
+			"line %d: want hinting none|vertical|full":                           "Zeile %d: hinting none|vertical|full erwartet",
+			"line %d: want highlight name underline|background":                  "Zeile %d: highlight Name underline|background erwartet",
+			"already replaying":                                                  "Wiedergabe läuft bereits",
+			"cannot replay while recording":                                      "keine Wiedergabe während der Aufnahme",
+			"no macro recorded":                                                  "kein Makro aufgenommen",
+			"usage: Replay [n]":                                                  "Aufruf: Replay [n]",
+			"line %d: def inside def %s":                                         "Zeile %d: def innerhalb von def %s",
+			"line %d: want def name":                                             "Zeile %d: def Name erwartet",
+			"line %d: duplicate script %s":                                       "Zeile %d: doppeltes Skript %s",
+			"line %d: end without def":                                           "Zeile %d: end ohne def",
+			"line %d: unknown statement %s":                                      "Zeile %d: unbekannte Anweisung %s",
+			"line %d: def %s without end":                                        "Zeile %d: def %s ohne end",
+			"line %d: script %s has the name of a built-in command":              "Zeile %d: Skript %s hat den Namen eines eingebauten Befehls",
+			"line %d: else without if":                                           "Zeile %d: else ohne if",
+			"line %d: bad variable name %s":                                      "Zeile %d: ungültiger Variablenname %s",
+			"while ran %d times":                                                 "while lief %d Mal",
+			"%s: scripts nested too deeply":                                      "%s: Skripte zu tief verschachtelt",
+			"%s: line %d: %s":                                                    "%s: Zeile %d: %s",
+			"%s wants a sheet":                                                   "%s benötigt ein Blatt",
+			"unknown method %s":                                                  "unbekannte Methode %s",
+			"no focused sheet":                                                   "kein Blatt fokussiert",
+			"no pipe command":                                                    "kein Pipe-Befehl",
+			"|%s killed":                                                         "|%s abgebrochen",
+			"|%s: text changed while running":                                    "|%s: Text wurde während der Ausführung geändert",
+			"no sheet %s":                                                        "kein Blatt %s",
+			"Sort: not a directory":                                              "Sort: kein Verzeichnis",
+			"Sort: want name or time":                                            "Sort: name oder time erwartet",
+			"Hidden: not a directory":                                            "Hidden: kein Verzeichnis",
+			"New: want kind:argument; kinds: %s":                                 "New: Art:Argument erwartet; Arten: %s",
+			"New: unknown kind %s":                                               "New: unbekannte Art %s",
+		},
+	}
+
+	// legacyEncoding decodes bytes of command output
+	// that are not valid UTF-8.
+	// If it is nil, such bytes are shown as U+FFFD.
+	legacyEncoding = charmap.Windows1252
+
+	// modButtons are substitutes for mouse buttons
+	// for mice with fewer than three buttons.
+	// A press of button with the modifier key held
+	// (1 is shift, 2 is alt, and 3 is control or meta)
+	// acts as a press of the button as.
+	// The first match is used.
+	modButtons = []struct {
+		mod, button, as int
+	}{
+		{mod: 2, button: 1, as: 2},
+		{mod: 3, button: 1, as: 3},
+	}
+
+	// goimportsCmd is the command that organizes the imports
+	// of Go source read from its standard input
+	// for the Imports command and on Put.
+	goimportsCmd = []string{"goimports"}
+
+	// largeFileSize is the size in bytes above which
+	// syntax and trailing space highlighting are disabled,
+	// Go imports are not organized or the text formatted on Put,
+	// and no language server is used
+	// for a file read by Get.
+	largeFileSize int64 = 4 << 20
+
+	// longLineLen is the length in bytes of a line
+	// above which wrapping is disabled
+	// for a file read by Get.
+	longLineLen int64 = 16 << 10
+
+	// deadKeys maps the accents that are dead keys,
+	// if dead keys are enabled with Win.SetDeadKeys,
+	// to the combining marks that they add to the next rune.
+	deadKeys = map[rune]rune{
+		'´': '\u0301', // acute
+		'`': '\u0300', // grave
+		'^': '\u0302', // circumflex
+		'~': '\u0303', // tilde
+		'¨': '\u0308', // diaeresis
+		'"': '\u0308', // diaeresis
+		'¸': '\u0327', // cedilla
+		'˚': '\u030A', // ring
+		'ˇ': '\u030C', // caron
+	}
+
+	// maxKills is the number of killed texts
+	// kept in the kill ring for yanking.
+	maxKills = 20
+
+	// maxTrash is the number of deleted modified sheets
+	// kept to be restored by the Undel command.
+	maxTrash = 20
 
-	// syntaxHighlighting maps file regular (using regexp package syntax)
-	// to functions from dpi to the Highlighter for that file.
-	syntaxHighlighting = []struct {
+	// maxOpenFiles is the number of files
+	// above which the Open command must be executed twice
+	// to open the files matching its patterns.
+	maxOpenFiles = 10
+
+	// plumbRules route text that is looked at to external programs
+	// instead of opening or searching for it.
+	// The first rule whose regexp matches the text is used.
+	// Its command is run with the text, or the absolute path
+	// if the text names a file, as the final argument.
+	// A nil command is the opener of the operating system:
+	// xdg-open, open on macOS, or start on Windows.
+	plumbRules = []struct {
 		regexp string
-		tok    func(float32) syntax.Tokenizer
+		cmd    []string
+	}{
+		{`^(https?|ftp)://`, nil},
+		{`^mailto:`, nil},
+		{`\.(pdf|PDF|png|PNG|jpe?g|JPE?G|gif|GIF|svg)$`, nil},
+	}
+
+	// notifyAfter is the time a command executed from a tag
+	// must run for its completion to be notified by notifyCmd
+	// while the window is not focused.
+	// If it is 0, completions are not notified.
+	notifyAfter = 10 * time.Second
+
+	// notifyCmd is the command showing a desktop notification,
+	// run with the message as its final argument.
+	notifyCmd = []string{"notify-send", "T"}
+
+	// maxArgCols is the most columns
+	// among which the files of the command line are opened.
+	maxArgCols = 2
+
+	// gitCmd is the git command
+	// of the Gdiff, Gblame, Gstatus, and Gcommit commands.
+	gitCmd = []string{"git"}
+
+	// buildTools are the build tools of the Mk and Build commands:
+	// a file marking a directory built by the tool,
+	// and the command, run by sh, that builds it.
+	// The first tool with a file in the sheet's directory
+	// or its nearest parent is used.
+	buildTools = []struct {
+		file string
+		cmd  string
 	}{
-		{`.*\.go$`, gosyntax.NewTokenizer},
-		{`.*/$`, dirsyntax.NewTokenizer},
+		{"mkfile", "mk"},
+		{"Makefile", "make"},
+		{"go.mod", "go build ./... && go vet ./..."},
+		{"Cargo.toml", "cargo build"},
+	}
+
+	// maxPopupItems is the maximum number of completions
+	// shown at once in the completion popup.
+	maxPopupItems = 10
+
+	// maxGrepResults is the maximum number of matching lines
+	// shown by the Grep command.
+	maxGrepResults = 1000
+
+	// maxFinderFiles is the maximum number of files
+	// listed in a +Open sheet.
+	maxFinderFiles = 10000
+
+	// maxFinderResults is the maximum number of matching files
+	// shown in a +Open sheet.
+	maxFinderResults = 100
+
+	// playTimeout is the time after which
+	// a program run by the Play command is killed.
+	playTimeout = 10 * time.Second
+
+	// altFiles are the default rules mapping a file
+	// to its counterparts for the Alt command:
+	// a regular expression (using regexp package syntax)
+	// and a replacement (using regexp.Expand syntax).
+	altFiles = []altRule{
+		{`^(.*)_test\.go$`, `${1}.go`},
+		{`^(.*)\.go$`, `${1}_test.go`},
+		{`^(.*)\.c$`, `${1}.h`},
+		{`^(.*)\.h$`, `${1}.c`},
+		{`^(.*)\.h$`, `${1}.cc`},
+		{`^(.*)\.cc$`, `${1}.h`},
+		{`^(.*)\.cpp$`, `${1}.hpp`},
+		{`^(.*)\.hpp$`, `${1}.cpp`},
+	}
+
+	// defaultTabWidth is the default width of a tab in spaces.
+	defaultTabWidth = 8
+
+	// elasticTabPad is the minimum number of spaces
+	// between columns of elastic tabstops.
+	elasticTabPad = 2
+
+	// trailingSpaceBG is the background color of highlighted trailing whitespace.
+	trailingSpaceBG color.Color = color.RGBA{R: 0xFF, G: 0xC8, B: 0xC8, A: 0xFF}
+
+	// occurrenceBG is the background color of the other occurrences
+	// of a word selected by double-clicking.
+	occurrenceBG color.Color = color.RGBA{R: 0xF0, G: 0xE4, B: 0xC8, A: 0xFF}
+
+	// errorUnderline and warningUnderline are the colors of the underlines
+	// of errors and other diagnostics of a language server.
+	errorUnderline   color.Color = color.RGBA{R: 0xE0, G: 0x20, B: 0x20, A: 0xFF}
+	warningUnderline color.Color = color.RGBA{R: 0xE0, G: 0xA0, B: 0x20, A: 0xFF}
+
+	// breakpointBG is the color of the left padding
+	// of lines with debugger breakpoints.
+	breakpointBG color.Color = color.RGBA{R: 0xD0, G: 0x30, B: 0x30, A: 0xFF}
+
+	// debugLineBG is the background color of the line
+	// where the debugged program stopped.
+	debugLineBG color.Color = color.RGBA{R: 0xC8, G: 0xE8, B: 0xF0, A: 0xFF}
+
+	// diffRemovedBG, diffAddedBG, and diffChangedBG
+	// are the background colors of removed, added, and changed lines
+	// of a +Diff sheet.
+	diffRemovedBG color.Color = color.RGBA{R: 0xF8, G: 0xD0, B: 0xD0, A: 0xFF}
+	diffAddedBG   color.Color = color.RGBA{R: 0xD0, G: 0xF0, B: 0xD0, A: 0xFF}
+	diffChangedBG color.Color = color.RGBA{R: 0xF0, G: 0xE8, B: 0xC0, A: 0xFF}
+
+	// highlightStyles are the styles of passive highlights by name:
+	// match for the matches of Look,
+	// occurrence for the occurrences of a double-clicked word,
+	// trailing for trailing whitespace,
+	// and debugline for the line where the debugged program stopped.
+	// Each is drawn as a background or an underline of its color.
+	highlightStyles = map[string]highlightStyle{
+		"match":      {color: &hiBG3},
+		"occurrence": {color: &occurrenceBG},
+		"trailing":   {color: &trailingSpaceBG},
+		"debugline":  {color: &debugLineBG},
+	}
+
+	// defaultFileSettings are the settings of files
+	// that match none of fileTypes.
+	defaultFileSettings = fileSettings{
+		tabWidth:  defaultTabWidth,
+		showSpace: true,
+	}
+
+	// fileTypes maps file regular expressions (using regexp package syntax)
+	// to the settings for files of that type. The first match is used.
+	// A file whose path does not match the regexp of a type
+	// also matches if its text begins with a #! line
+	// whose interpreter matches the shebang regular expression.
+	// The interpreter is the base name of the first word of the line,
+	// or the second word if the first is env.
+	// The settings are set when a file is read by Get
+	// and when the sheet's title changes.
+	// Go files are not also formatted by gofmt,
+	// since goimports formats the text.
+	fileTypes = []struct {
+		regexp  string
+		shebang string
+		fileSettings
+	}{
+		{`.*\.go$`, ``, fileSettings{tabWidth: 8, showSpace: true, trimSpace: true, imports: true, comment: "//", lexer: gosyntax.NewTokenizer}},
+		{`.*/$`, ``, fileSettings{tabWidth: defaultTabWidth, lexer: dirsyntax.NewTokenizer}},
+		{`(^|.*/)Makefile$`, ``, fileSettings{tabWidth: 8, showSpace: true, comment: "#"}},
+		{`.*\.py$`, `^python[0-9.]*$`, fileSettings{tabWidth: 4, tabSpaces: true, showSpace: true, trimSpace: true, format: []string{"black", "-q", "-"}, comment: "#"}},
+		{`.*\.(sh|bash)$`, `^(ba|da|k|z)?sh$`, fileSettings{tabWidth: 8, showSpace: true, trimSpace: true, comment: "#"}},
+		{`.*\.rs$`, ``, fileSettings{tabWidth: 8, showSpace: true, format: []string{"rustfmt", "--emit", "stdout"}, comment: "//"}},
+		{`.*\.(c|h|cc|cpp|hpp)$`, ``, fileSettings{tabWidth: 8, showSpace: true, format: []string{"clang-format"}, comment: "//"}},
+		// Trailing spaces are line breaks in markdown.
+		{`.*\.(md|markdown)$`, ``, fileSettings{tabWidth: 4, tabSpaces: true}},
+		{`.*\.ya?ml$`, ``, fileSettings{tabWidth: 2, tabSpaces: true, showSpace: true, trimSpace: true, comment: "#"}},
+		{`.*\.(csv|tsv)$`, ``, fileSettings{tabWidth: 8, noWrap: true}},
 	}
 )
+
+// fileSettings are settings that vary by file type.
+type fileSettings struct {
+	tabWidth   int      // width of a tab in spaces
+	tabSpaces  bool     // whether the tab key inserts spaces
+	showSpace  bool     // whether trailing whitespace is highlighted
+	trimSpace  bool     // whether trailing whitespace is removed on Put
+	imports    bool     // whether Go imports are organized on Put
+	format     []string // command that formats the text on Put; nil for none
+	noWrap     bool     // whether long lines are not wrapped
+	autoIndent bool     // whether a typed newline copies the indentation of its line
+	comment    string   // prefix of line comments toggled by Comment; "" for none
+
+	// lexer returns the tokenizer of syntax highlighting for a dpi;
+	// nil for no highlighting.
+	lexer func(float32) syntax.Tokenizer
+}
+
+// loadFonts parses defaultFont and the fallbackFontPaths into fonts.
+func loadFonts() {
+	fonts = []*truetype.Font{defaultFont}
+	for _, path := range fallbackFontPaths {
+		ttf, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		f, err := truetype.Parse(ttf)
+		if err != nil {
+			continue
+		}
+		fonts = append(fonts, f)
+	}
+}
+
+// A highlightStyle is the style of a kind of passive highlight.
+type highlightStyle struct {
+	color     *color.Color // the color, which may be changed by the theme
+	underline bool         // whether the text is underlined instead of its background colored
+}
+
+// hiStyle returns the text style of the named highlightStyle.
+func hiStyle(name string) text.Style {
+	h := highlightStyles[name]
+	if h.underline {
+		return text.Style{Underline: *h.color}
+	}
+	return text.Style{BG: *h.color}
+}
+
+// A theme is a set of colors used to draw the UI.
+// The highlight colors after hiBG3 are those of lightTheme if nil.
+type theme struct {
+	fg, frameBG, colBG, tagBG, bodyBG          color.Color
+	hiBG1, hiBG2, hiBG3                        color.Color
+	trailingSpaceBG, occurrenceBG, debugLineBG color.Color
+	diffRemovedBG, diffAddedBG, diffChangedBG  color.Color
+}
+
+func setTheme(name string) error {
+	t, ok := themes[name]
+	if !ok {
+		return errors.New(msg("unknown theme %s", name))
+	}
+	fg, frameBG, colBG, tagBG, bodyBG = t.fg, t.frameBG, t.colBG, t.tagBG, t.bodyBG
+	hiBG1, hiBG2, hiBG3 = t.hiBG1, t.hiBG2, t.hiBG3
+	or := func(c, def color.Color) color.Color {
+		if c == nil {
+			return def
+		}
+		return c
+	}
+	trailingSpaceBG = or(t.trailingSpaceBG, lightTheme.trailingSpaceBG)
+	occurrenceBG = or(t.occurrenceBG, lightTheme.occurrenceBG)
+	debugLineBG = or(t.debugLineBG, lightTheme.debugLineBG)
+	diffRemovedBG = or(t.diffRemovedBG, lightTheme.diffRemovedBG)
+	diffAddedBG = or(t.diffAddedBG, lightTheme.diffAddedBG)
+	diffChangedBG = or(t.diffChangedBG, lightTheme.diffChangedBG)
+	return nil
+}
+
+// configFromEnv sets configuration from environment variables.
+// The configFile is read first,
+// so the environment variables override it.
+func configFromEnv() error {
+	locale = envLocale()
+	if path := os.Getenv("T_CONFIG"); path != "" {
+		configFile = path
+	}
+	fileErr := readConfigFile()
+	if os.Getenv("T_REDUCED_MOTION") != "" {
+		reducedMotion = true
+	}
+	if paths := os.Getenv("T_FONTS"); paths != "" {
+		fallbackFontPaths = filepath.SplitList(paths)
+	}
+	if rs, ok := os.LookupEnv("T_WORD_RUNES"); ok {
+		wordRunes = rs
+	}
+	if path := os.Getenv("T_EXTENSIONS"); path != "" {
+		extensionsFile = path
+	}
+	if path := os.Getenv("T_SEARCHES"); path != "" {
+		searchHistoryFile = path
+	}
+	if dir := os.Getenv("T_SNIPPETS"); dir != "" {
+		snippetsDir = dir
+	}
+	if path := os.Getenv("T_SCRIPTS"); path != "" {
+		scriptsFile = path
+	}
+	if dir := os.Getenv("T_RECOVER"); dir != "" {
+		recoveryDir = dir
+	}
+	if dir := os.Getenv("T_JOURNAL"); dir != "" {
+		journalDir = dir
+	}
+	if name := os.Getenv("T_THEME"); name != "" {
+		if err := setTheme(name); err != nil {
+			return err
+		}
+	}
+	return fileErr
+}
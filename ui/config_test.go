@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestHighContrastTheme(t *testing.T) {
+	th := themes["high-contrast"]
+	bgs := map[string]color.Color{
+		"colBG":  th.colBG,
+		"tagBG":  th.tagBG,
+		"bodyBG": th.bodyBG,
+		"hiBG1":  th.hiBG1,
+		"hiBG2":  th.hiBG2,
+		"hiBG3":  th.hiBG3,
+	}
+	for name, bg := range bgs {
+		if r := contrast(th.fg, bg); r < 7 {
+			t.Errorf("contrast(fg, %s)=%.2f, want >= 7", name, r)
+		}
+	}
+}
+
+func TestDarkTheme(t *testing.T) {
+	th := themes["dark"]
+	bgs := map[string]color.Color{
+		"colBG":           th.colBG,
+		"tagBG":           th.tagBG,
+		"bodyBG":          th.bodyBG,
+		"hiBG1":           th.hiBG1,
+		"hiBG2":           th.hiBG2,
+		"hiBG3":           th.hiBG3,
+		"trailingSpaceBG": th.trailingSpaceBG,
+		"occurrenceBG":    th.occurrenceBG,
+		"debugLineBG":     th.debugLineBG,
+		"diffRemovedBG":   th.diffRemovedBG,
+		"diffAddedBG":     th.diffAddedBG,
+		"diffChangedBG":   th.diffChangedBG,
+	}
+	for name, bg := range bgs {
+		if r := contrast(th.fg, bg); r < 4.5 {
+			t.Errorf("contrast(fg, %s)=%.2f, want >= 4.5", name, r)
+		}
+	}
+}
+
+func TestSetThemeUnknown(t *testing.T) {
+	if err := setTheme("no such theme"); err == nil {
+		t.Errorf("setTheme succeeded, wanted an error")
+	}
+}
+
+// contrast returns the WCAG contrast ratio of two colors.
+func contrast(a, b color.Color) float64 {
+	la, lb := luminance(a), luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+func luminance(c color.Color) float64 {
+	r, g, b, _ := c.RGBA()
+	lin := func(v uint32) float64 {
+		f := float64(v) / 0xFFFF
+		if f <= 0.03928 {
+			return f / 12.92
+		}
+		return math.Pow((f+0.055)/1.055, 2.4)
+	}
+	return 0.2126*lin(r) + 0.7152*lin(g) + 0.0722*lin(b)
+}
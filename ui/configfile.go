@@ -0,0 +1,224 @@
+package ui
+
+import (
+	"bufio"
+	"errors"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+)
+
+// configColors are the colors that can be set in the configFile,
+// by the names of their variables.
+var configColors = map[string]*color.Color{
+	"fg":               &fg,
+	"frameBG":          &frameBG,
+	"colBG":            &colBG,
+	"tagBG":            &tagBG,
+	"bodyBG":           &bodyBG,
+	"hiBG1":            &hiBG1,
+	"hiBG2":            &hiBG2,
+	"hiBG3":            &hiBG3,
+	"trailingSpaceBG":  &trailingSpaceBG,
+	"occurrenceBG":     &occurrenceBG,
+	"errorUnderline":   &errorUnderline,
+	"warningUnderline": &warningUnderline,
+	"breakpointBG":     &breakpointBG,
+	"debugLineBG":      &debugLineBG,
+	"diffRemovedBG":    &diffRemovedBG,
+	"diffAddedBG":      &diffAddedBG,
+	"diffChangedBG":    &diffChangedBG,
+}
+
+// readConfigFile reads the configFile, if it exists.
+func readConfigFile() error {
+	path := configFile
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(dir, "T", "config")
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := readConfig(f); err != nil {
+		return errors.New(path + ": " + err.Error())
+	}
+	return nil
+}
+
+// readConfig sets configuration from the lines of a configuration file.
+// Each line is a key followed by its value.
+// The keys font and fixedfont set defaultFont and fixedFont
+// to the TTF file at the path of their value.
+// The keys fontsize, framepx, padpx, and cursorwidth set
+// defaultFontSize, framePx, textPadPx, and cursorWidthPx to a number.
+// The keys cursorshape, cursorblink, blinkrate, and hollowcursor
+// set cursorShape, cursorBlink, blinkDuration, and hollowCursor.
+// The keys subpixel and hinting set subpixelText and fontHinting.
+// The keys tagtext, coltext, and tagfile set tagText, colText, and tagFile.
+// The key highlight sets whether a highlightStyle is drawn
+// as an underline or a background.
+// The key theme sets the colors of the named theme,
+// and the names of configColors set a color to a #RRGGBB
+// or #RRGGBBAA hex value.
+// Empty lines and lines beginning with # are ignored.
+// Lines with errors are skipped, keeping the default,
+// and the first error is returned.
+func readConfig(r io.Reader) error {
+	var first error
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := setConfig(n, line); err != nil && first == nil {
+			first = err
+		}
+	}
+	if err := s.Err(); err != nil && first == nil {
+		first = err
+	}
+	return first
+}
+
+func setConfig(n int, line string) error {
+	key, val := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		key, val = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	if val == "" {
+		return errors.New(msg("line %d: want key and value", n))
+	}
+	switch key {
+	case "font", "fixedfont":
+		ttf, err := ioutil.ReadFile(val)
+		if err != nil {
+			return errors.New(msg("line %d: %s", n, err))
+		}
+		f, err := truetype.Parse(ttf)
+		if err != nil {
+			return errors.New(msg("line %d: %s", n, err))
+		}
+		if key == "font" {
+			defaultFont = f
+		} else {
+			fixedFont = f
+		}
+	case "fontsize", "framepx", "padpx", "cursorwidth":
+		v, err := strconv.Atoi(val)
+		if err != nil || v < 0 || key == "fontsize" && v < minFontSize || key == "cursorwidth" && v == 0 {
+			return errors.New(msg("line %d: bad number %s", n, val))
+		}
+		switch key {
+		case "fontsize":
+			defaultFontSize = v
+		case "framepx":
+			framePx = v
+		case "padpx":
+			textPadPx = v
+		case "cursorwidth":
+			cursorWidthPx = v
+		}
+	case "cursorshape":
+		if val != "bar" && val != "block" && val != "underline" {
+			return errors.New(msg("line %d: want cursorshape bar|block|underline", n))
+		}
+		cursorShape = val
+	case "cursorblink", "hollowcursor":
+		v, ok := parseOnOff(val)
+		if !ok {
+			return errors.New(msg("line %d: %s", n, msg("%s wants on or off", key)))
+		}
+		if key == "cursorblink" {
+			cursorBlink = v
+		} else {
+			hollowCursor = v
+		}
+	case "subpixel":
+		v, ok := parseOnOff(val)
+		if !ok {
+			return errors.New(msg("line %d: %s", n, msg("%s wants on or off", key)))
+		}
+		subpixelText = v
+	case "hinting":
+		switch val {
+		case "none":
+			fontHinting = font.HintingNone
+		case "vertical":
+			fontHinting = font.HintingVertical
+		case "full":
+			fontHinting = font.HintingFull
+		default:
+			return errors.New(msg("line %d: want hinting none|vertical|full", n))
+		}
+	case "blinkrate":
+		d, err := time.ParseDuration(val)
+		if err != nil || d <= 0 {
+			return errors.New(msg("line %d: bad duration %s", n, val))
+		}
+		blinkDuration = d
+	case "tagtext":
+		tagText = " " + val
+	case "coltext":
+		colText = val + "\n"
+	case "tagfile":
+		tagFile = val
+	case "highlight":
+		fs := strings.Fields(val)
+		h, ok := highlightStyles[fs[0]]
+		if !ok || len(fs) != 2 || fs[1] != "underline" && fs[1] != "background" {
+			return errors.New(msg("line %d: want highlight name underline|background", n))
+		}
+		h.underline = fs[1] == "underline"
+		highlightStyles[fs[0]] = h
+	case "theme":
+		if err := setTheme(val); err != nil {
+			return errors.New(msg("line %d: %s", n, err))
+		}
+	default:
+		c, ok := configColors[key]
+		if !ok {
+			return errors.New(msg("line %d: unknown key %s", n, key))
+		}
+		rgba, ok := parseColor(val)
+		if !ok {
+			return errors.New(msg("line %d: bad color %s", n, val))
+		}
+		*c = rgba
+	}
+	return nil
+}
+
+// parseColor returns the color of a #RRGGBB or #RRGGBBAA hex string.
+// The channels of the string are not alpha-premultiplied.
+func parseColor(s string) (color.RGBA, bool) {
+	if !strings.HasPrefix(s, "#") || len(s) != 7 && len(s) != 9 {
+		return color.RGBA{}, false
+	}
+	if len(s) == 7 {
+		s += "FF"
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{}, false
+	}
+	c := color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}
+	return color.RGBAModel.Convert(c).(color.RGBA), true
+}
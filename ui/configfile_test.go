@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadConfig(t *testing.T) {
+	defer func(size, frame, pad int, tag, col string, fg0, body color.Color) {
+		defaultFontSize, framePx, textPadPx, tagText, colText = size, frame, pad, tag, col
+		fg, bodyBG = fg0, body
+	}(defaultFontSize, framePx, textPadPx, tagText, colText, fg, bodyBG)
+
+	config := "# comment\n\n" +
+		"fontsize 14\n" +
+		"framepx 2\n" +
+		"padpx 5\n" +
+		"tagtext Del Put\n" +
+		"coltext Del NewCol\n" +
+		"fg #102030\n" +
+		"bodyBG #A0B0C080\n" +
+		"padpx x\n" +
+		"nokey 1\n"
+	err := readConfig(strings.NewReader(config))
+	if want := "line 10: bad number x"; err == nil || err.Error() != want {
+		t.Errorf("readConfig=%v, want %q", err, want)
+	}
+	if defaultFontSize != 14 || framePx != 2 || textPadPx != 5 {
+		t.Errorf("fontsize=%d, framepx=%d, padpx=%d, want 14, 2, 5", defaultFontSize, framePx, textPadPx)
+	}
+	if tagText != " Del Put" || colText != "Del NewCol\n" {
+		t.Errorf("tagText=%q, colText=%q, want %q, %q", tagText, colText, " Del Put", "Del NewCol\n")
+	}
+	if want := (color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xFF}); fg != want {
+		t.Errorf("fg=%v, want %v", fg, want)
+	}
+	if want := (color.RGBA{R: 0x50, G: 0x58, B: 0x60, A: 0x80}); bodyBG != want {
+		t.Errorf("bodyBG=%v, want %v", bodyBG, want)
+	}
+
+	for _, bad := range []string{"hinting some\n", "subpixel yes\n", "fontsize 1\n", "fg red\n", "fg #12345\n", "theme none\n", "font\n", "x y\n"} {
+		if err := readConfig(strings.NewReader(bad)); err == nil {
+			t.Errorf("readConfig(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		s    string
+		want color.RGBA
+	}{
+		{"#102030", color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xFF}},
+		{"#FF001040", color.RGBA{R: 0x40, G: 0x00, B: 0x04, A: 0x40}},
+		{"#FFFFFF00", color.RGBA{}},
+	}
+	for _, test := range tests {
+		c, ok := parseColor(test.s)
+		if !ok || c != test.want {
+			t.Errorf("parseColor(%q)=%v,%v, want %v,true", test.s, c, ok, test.want)
+		}
+		if c.R > c.A || c.G > c.A || c.B > c.A {
+			t.Errorf("parseColor(%q)=%v, which is not alpha-premultiplied", test.s, c)
+		}
+	}
+}
+
+func TestReadConfigFile(t *testing.T) {
+	defer func(f string, size int) { configFile, defaultFontSize = f, size }(configFile, defaultFontSize)
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	configFile = filepath.Join(dir, "config")
+	if err := readConfigFile(); err != nil {
+		t.Errorf("readConfigFile with no file failed: %v", err)
+	}
+	write(configFile, "fontsize 20\nx y\n")
+	err := readConfigFile()
+	if want := configFile + ": line 2: unknown key x"; err == nil || err.Error() != want {
+		t.Errorf("readConfigFile=%v, want %q", err, want)
+	}
+	if defaultFontSize != 20 {
+		t.Errorf("defaultFontSize=%d, want 20", defaultFontSize)
+	}
+}
+
+func TestReadConfig_Highlight(t *testing.T) {
+	defer func(h highlightStyle) { highlightStyles["match"] = h }(highlightStyles["match"])
+	if err := readConfig(strings.NewReader("highlight match underline\n")); err != nil {
+		t.Fatalf("readConfig failed: %v", err)
+	}
+	if s := hiStyle("match"); s.Underline != hiBG3 || s.BG != nil {
+		t.Errorf("match style=%v, want underline %v", s, hiBG3)
+	}
+	if s := hiStyle("occurrence"); s.BG != occurrenceBG || s.Underline != nil {
+		t.Errorf("occurrence style=%v, want background %v", s, occurrenceBG)
+	}
+	for _, bad := range []string{"highlight nope underline\n", "highlight match bold\n", "highlight match\n"} {
+		if err := readConfig(strings.NewReader(bad)); err == nil {
+			t.Errorf("readConfig(%q) succeeded, want error", bad)
+		}
+	}
+}
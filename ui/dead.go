@@ -0,0 +1,42 @@
+package ui
+
+import "golang.org/x/text/unicode/norm"
+
+// SetDeadKeys sets whether the accents of deadKeys are dead keys.
+//
+// A dead key types nothing itself;
+// instead it combines with the next rune typed,
+// so that, for example, ´ followed by e types é.
+// Typing a dead key followed by space or by itself
+// types the accent.
+// If the accent does not combine with the next rune,
+// both are typed.
+func (w *Win) SetDeadKeys(dead bool) {
+	w.deadKeys = dead
+	w.dead = 0
+}
+
+// deadKey returns the runes to type for a typed rune,
+// given any pending dead key.
+func deadKey(w *Win, r rune) []rune {
+	if !w.deadKeys || w.mods[2] || w.mods[3] {
+		return []rune{r}
+	}
+	dead := w.dead
+	w.dead = 0
+	if dead == 0 {
+		if _, ok := deadKeys[r]; ok {
+			w.dead = r
+			return nil
+		}
+		return []rune{r}
+	}
+	if r == ' ' || r == dead {
+		return []rune{dead}
+	}
+	c := []rune(norm.NFC.String(string([]rune{r, deadKeys[dead]})))
+	if len(c) == 1 {
+		return c
+	}
+	return []rune{dead, r}
+}
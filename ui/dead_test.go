@@ -0,0 +1,32 @@
+package ui
+
+import "testing"
+
+func TestDeadKeys(t *testing.T) {
+	tests := []struct {
+		typed string
+		want  string
+	}{
+		{typed: "abc", want: "abc"},
+		{typed: "´e", want: "é"},
+		{typed: "`a^o~n¨u", want: "àôñü"},
+		{typed: "\"A¸c", want: "Äç"},
+		{typed: "´ ", want: "´"},
+		{typed: "^^", want: "^"},
+		{typed: "´x", want: "´x"},
+		{typed: "´\n", want: "´\n"},
+	}
+	for _, test := range tests {
+		w := newTestWin()
+		w.SetDeadKeys(true)
+		s := NewSheet(w, "")
+		w.cols[0].Add(s)
+		s.TextBox = s.body
+		for _, r := range test.typed {
+			w.Rune(r)
+		}
+		if got := s.body.text.String(); got != test.want {
+			t.Errorf("typed %q, got %q, want %q", test.typed, got, test.want)
+		}
+	}
+}
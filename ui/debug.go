@@ -0,0 +1,387 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/eaburns/T/dap"
+	"github.com/eaburns/T/rope"
+	"github.com/eaburns/T/syntax"
+)
+
+// debugTagText is appended to the first line of the tag of a +Debug sheet.
+const debugTagText = " Cont Next Step Out Stop"
+
+// A debugger is a debug session run for a +Debug sheet.
+//
+// Events of the debug adapter are handled asynchronously
+// and shown by the sheet's Tick:
+// program output is appended to the body,
+// and when the program stops, the file of the stopped line
+// is opened with the line highlighted
+// and the stack is appended to the body.
+type debugger struct {
+	mu     sync.Mutex
+	client *dap.Client     // nil until the adapter has started
+	thread int             // the last stopped thread
+	out    strings.Builder // output not yet inserted
+	frames []dap.StackFrame
+	stop   bool // whether the program stopped and frames is not yet shown
+	resume bool // whether the program resumed since it was last shown
+	ended  bool // whether the session ended
+}
+
+// runDebug implements the Debug command: Debug [program [args]].
+// The debug adapter of the sheet's file type in debugAdapters
+// is started in the sheet's directory
+// and launches the program, by default the directory,
+// with the breakpoints of the open sheets.
+// The session is controlled by the commands of the tag
+// of the +Debug sheet of the directory,
+// in which the output of the program is shown.
+// A session already running in the window is stopped.
+func runDebug(c *Col, s *Sheet, args string) error {
+	adapter := -1
+	for i, a := range debugAdapters {
+		ok, err := regexp.MatchString(a.regexp, s.Title())
+		if err != nil {
+			return err
+		}
+		if ok {
+			adapter = i
+			break
+		}
+	}
+	if adapter < 0 {
+		return errors.New(msg("no debug adapter for %s", s.Title()))
+	}
+	a := debugAdapters[adapter]
+	if _, err := exec.LookPath(a.cmd[0]); err != nil {
+		return err
+	}
+	dir, err := abs(s, ".")
+	if err != nil {
+		return err
+	}
+	launch := make(map[string]interface{})
+	for k, v := range a.launch {
+		launch[k] = v
+	}
+	launch["program"] = dir
+	if fs := strings.Fields(args); len(fs) > 0 {
+		if launch["program"], err = abs(s, fs[0]); err != nil {
+			return err
+		}
+		launch["args"] = fs[1:]
+	}
+	stopDebug(c.win)
+
+	title := filepath.Join(dir, "+Debug")
+	d := findSheet(c.win, title)
+	if d == nil {
+		d = NewSheet(c.win, title)
+		c.Add(d)
+	}
+	setTagText(d, debugTagText, true)
+	focusSheet(c.win, title)
+	d.body.SetText(rope.New(msg("debugging %s", launch["program"]) + "\n"))
+	setDot(d.body, 1, d.body.text.Len(), d.body.text.Len())
+
+	dbg := &debugger{}
+	d.debug = dbg
+	breaks := allBreaks(c.win)
+	go func() {
+		cl, err := dap.Start(a.cmd, dir, dbg.event)
+		if err == nil {
+			dbg.mu.Lock()
+			dbg.client = cl
+			ended := dbg.ended
+			dbg.mu.Unlock()
+			if ended {
+				// Stopped while starting.
+				cl.Disconnect(debugTimeout)
+				return
+			}
+			err = cl.Launch(launch, breaks)
+		}
+		if err != nil {
+			dbg.mu.Lock()
+			dbg.out.WriteString(err.Error() + "\n")
+			dbg.ended = true
+			dbg.mu.Unlock()
+		}
+	}()
+	return nil
+}
+
+// event handles an event of the debug adapter.
+// It is called from the goroutine reading from the adapter,
+// so requests to the adapter are made from a new goroutine.
+func (dbg *debugger) event(ev dap.Event) {
+	dbg.mu.Lock()
+	defer dbg.mu.Unlock()
+	switch ev.Name {
+	case "output":
+		if o := ev.Output(); o.Category != "telemetry" {
+			dbg.out.WriteString(o.Output)
+		}
+	case "stopped":
+		st := ev.Stopped()
+		dbg.thread = st.ThreadID
+		dbg.out.WriteString(msg("stopped: %s", st.Reason) + "\n")
+		cl := dbg.client
+		if cl == nil {
+			break
+		}
+		go func() {
+			frames, err := cl.StackTrace(st.ThreadID)
+			dbg.mu.Lock()
+			defer dbg.mu.Unlock()
+			if err != nil {
+				dbg.out.WriteString(err.Error() + "\n")
+				return
+			}
+			dbg.frames, dbg.stop = frames, true
+		}()
+	case "continued":
+		dbg.resume = true
+	case "terminated", "exited":
+		if !dbg.ended {
+			dbg.out.WriteString(msg("program exited") + "\n")
+		}
+		dbg.ended = true
+	}
+}
+
+// debugUpdate shows the pending events of the sheet's debug session.
+// It returns whether the sheet must be redrawn.
+func debugUpdate(s *Sheet) bool {
+	dbg := s.debug
+	dbg.mu.Lock()
+	out := dbg.out.String()
+	dbg.out.Reset()
+	cl, frames, stop, resume, ended := dbg.client, dbg.frames, dbg.stop, dbg.resume, dbg.ended
+	dbg.stop, dbg.resume = false, false
+	dbg.mu.Unlock()
+
+	if resume || stop || ended {
+		clearDebugLines(s.win)
+	}
+	if stop {
+		var b strings.Builder
+		for _, f := range frames {
+			if f.Path() == "" {
+				fmt.Fprintf(&b, "\t%s\n", f.Name)
+			} else {
+				fmt.Fprintf(&b, "%s:%d: %s\n", f.Path(), f.Line, f.Name)
+			}
+		}
+		out += b.String()
+		if len(frames) > 0 && frames[0].Path() != "" {
+			showDebugLine(s, frames[0].Path(), frames[0].Line)
+		}
+	}
+	if ended {
+		s.debug = nil
+		if cl != nil {
+			go cl.Disconnect(debugTimeout)
+		}
+	}
+	if out == "" {
+		return resume || stop || ended
+	}
+	appendOutput(s.body, out)
+	return true
+}
+
+// showDebugLine opens the file at the one-based line
+// and highlights it as the line where the program stopped.
+func showDebugLine(s *Sheet, path string, line int) {
+	ok, err := lookFileAddr(s.win.Col, s, fmt.Sprintf("%s:%d", path, line))
+	switch {
+	case err != nil:
+		s.win.OutputString(err.Error() + "\n")
+		return
+	case !ok:
+		return
+	}
+	b := findSheet(s.win, path).body
+	at := b.dots[1].At[0]
+	hi := syntax.Highlight{At: [2]int64{at, lineEnd(b, at)}, Style: hiStyle("debugline")}
+	b.debugLine = []syntax.Highlight{hi}
+	dirtyLines(b)
+}
+
+// clearDebugLines removes the highlighting of the stopped line
+// from the bodies of the window's sheets.
+func clearDebugLines(w *Win) {
+	for _, c := range w.cols {
+		for _, r := range c.rows {
+			if s := getSheet(r); s != nil && s.body.debugLine != nil {
+				s.body.debugLine = nil
+				dirtyLines(s.body)
+			}
+		}
+	}
+}
+
+// debugSession returns the sheet of the window's debug session, or nil.
+func debugSession(w *Win) *Sheet {
+	for _, c := range w.cols {
+		for _, r := range c.rows {
+			if s := getSheet(r); s != nil && s.debug != nil {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// debugStep implements the Cont, Next, Step, and Out commands,
+// calling the step function of the adapter client
+// with the stopped thread of the window's debug session.
+func debugStep(w *Win, step func(*dap.Client, int) error) error {
+	s := debugSession(w)
+	if s == nil {
+		return errors.New(msg("no debug session"))
+	}
+	dbg := s.debug
+	dbg.mu.Lock()
+	cl, thread := dbg.client, dbg.thread
+	dbg.mu.Unlock()
+	if cl == nil {
+		return errors.New(msg("the debug adapter is not running"))
+	}
+	clearDebugLines(w)
+	go func() {
+		if err := step(cl, thread); err != nil {
+			dbg.mu.Lock()
+			dbg.out.WriteString(err.Error() + "\n")
+			dbg.mu.Unlock()
+		}
+	}()
+	return nil
+}
+
+// stopDebug implements the Stop command.
+// The window's debug session, if any, is stopped.
+func stopDebug(w *Win) {
+	if s := debugSession(w); s != nil {
+		endDebug(s)
+	}
+}
+
+// endDebug stops the debug session of the sheet.
+func endDebug(s *Sheet) {
+	dbg := s.debug
+	dbg.mu.Lock()
+	if !dbg.ended {
+		dbg.out.WriteString(msg("stopped debugging") + "\n")
+	}
+	dbg.ended = true
+	dbg.mu.Unlock()
+	debugUpdate(s)
+}
+
+// breakClick toggles the breakpoint of the line
+// whose left padding is clicked in the body of a file sheet.
+// The point is relative to the body.
+// It returns whether the click was handled.
+func breakClick(s *Sheet, pt image.Point, button int) bool {
+	if button != 1 || s.TextBox != s.body || pt.X >= textPadPx || pt.Y < 0 || !isFileSheet(s) {
+		return false
+	}
+	at, _ := atPoint(s.body, image.Pt(0, pt.Y))
+	toggleBreak(s, at)
+	return true
+}
+
+// toggleBreak implements the Break command.
+// The breakpoint of the line containing the address is toggled,
+// and the breakpoints of the file are sent to the debug session.
+// Lines with breakpoints are marked in the left padding of the body.
+func toggleBreak(s *Sheet, at int64) {
+	b := s.body
+	start := lineStart(b, at)
+	breaks := b.breaks[:0]
+	var removed bool
+	for _, br := range b.breaks {
+		if lineStart(b, br) == start {
+			removed = true
+			continue
+		}
+		breaks = append(breaks, br)
+	}
+	if !removed {
+		breaks = append(breaks, start)
+	}
+	b.breaks = breaks
+	dirtyLines(b)
+
+	d := debugSession(s.win)
+	if d == nil {
+		return
+	}
+	dbg := d.debug
+	dbg.mu.Lock()
+	cl := dbg.client
+	dbg.mu.Unlock()
+	if cl == nil {
+		return
+	}
+	path, lines := s.Title(), breakLines(b)
+	go func() {
+		if err := cl.SetBreakpoints(path, lines); err != nil {
+			dbg.mu.Lock()
+			dbg.out.WriteString(err.Error() + "\n")
+			dbg.mu.Unlock()
+		}
+	}()
+}
+
+// breakLines returns the sorted, one-based lines
+// with breakpoints in the body.
+func breakLines(b *TextBox) []int {
+	seen := make(map[int]bool)
+	var lines []int
+	for _, br := range b.breaks {
+		l := strings.Count(rope.Slice(b.text, 0, br).String(), "\n") + 1
+		if !seen[l] {
+			seen[l] = true
+			lines = append(lines, l)
+		}
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// allBreaks returns the breakpoint lines
+// of the file sheets of the window, by path.
+func allBreaks(w *Win) map[string][]int {
+	breaks := make(map[string][]int)
+	for _, c := range w.cols {
+		for _, r := range c.rows {
+			if s := getSheet(r); s != nil && len(s.body.breaks) > 0 && isFileSheet(s) {
+				breaks[s.Title()] = breakLines(s.body)
+			}
+		}
+	}
+	return breaks
+}
+
+// hasBreak returns whether a breakpoint is in the range of addresses.
+func hasBreak(b *TextBox, start, end int64) bool {
+	for _, br := range b.breaks {
+		if start <= br && br < end {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package ui
+
+import (
+	"encoding/json"
+	"image"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/T/dap"
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+)
+
+func TestBreakClick(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.go")
+	write(path, "a\nb\nc\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, path)
+	)
+	c.Add(s)
+	s.Resize(testSize)
+	if err := s.Get(); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	click := func(x, y int) {
+		s.Click(image.Pt(x, s.tagH+y), 1)
+		s.Click(image.Pt(x, s.tagH+y), -1)
+	}
+
+	click(1, H+1)
+	if got := breakLines(s.body); !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("after clicking line 2, breakLines=%v, want [2]", got)
+	}
+	if !hasBreak(s.body, 2, 4) || hasBreak(s.body, 0, 2) {
+		t.Errorf("hasBreak does not find the break at 2")
+	}
+	// Clicking the text does not toggle a breakpoint.
+	click(textPadPx+1, 1)
+	if got := breakLines(s.body); !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("after clicking text, breakLines=%v, want [2]", got)
+	}
+
+	// Breakpoints move with the text.
+	s.body.Change(edit.Diffs{{At: [2]int64{0, 0}, Text: rope.New("x\n")}})
+	if got := breakLines(s.body); !reflect.DeepEqual(got, []int{3}) {
+		t.Errorf("after inserting a line, breakLines=%v, want [3]", got)
+	}
+	click(1, 2*H+1)
+	if got := breakLines(s.body); len(got) != 0 {
+		t.Errorf("after clicking line 3, breakLines=%v, want []", got)
+	}
+
+	setDot(s.body, 1, 1, 1)
+	if err := execCmd(c, s, "Break"); err != nil {
+		t.Fatalf("Break failed: %v", err)
+	}
+	want := map[string][]int{path: {1}}
+	if got := allBreaks(w); !reflect.DeepEqual(got, want) {
+		t.Errorf("allBreaks=%v, want %v", got, want)
+	}
+}
+
+func TestDebugUpdate(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.go")
+	write(path, "package a\n\nfunc f() {\n}\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		d = NewSheet(w, filepath.Join(dir, "+Debug"))
+	)
+	c.Add(d)
+	dbg := &debugger{}
+	d.debug = dbg
+	var frames []dap.StackFrame
+	err := json.Unmarshal([]byte(`[{"name":"a.f","line":3,"source":{"path":"`+path+`"}},{"name":"runtime.main","line":1}]`), &frames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbg.out.WriteString("hello\n")
+	dbg.frames, dbg.stop = frames, true
+
+	if !debugUpdate(d) {
+		t.Errorf("debugUpdate returned false, want true")
+	}
+	want := "hello\n" + path + ":3: a.f\n\truntime.main\n"
+	if got := d.body.text.String(); got != want {
+		t.Errorf("+Debug body=%q, want %q", got, want)
+	}
+	s := findSheet(w, path)
+	if s == nil {
+		t.Fatalf("%s not opened", path)
+	}
+	if len(s.body.debugLine) != 1 || s.body.debugLine[0].At != [2]int64{11, 21} {
+		t.Errorf("debugLine=%v, want [11 21]", s.body.debugLine)
+	}
+
+	dbg.resume = true
+	debugUpdate(d)
+	if s.body.debugLine != nil {
+		t.Errorf("after resuming, debugLine=%v, want nil", s.body.debugLine)
+	}
+
+	if debugSession(w) != d {
+		t.Errorf("debugSession is not the +Debug sheet")
+	}
+	if err := execCmd(c, s, "Stop"); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if d.debug != nil || debugSession(w) != nil {
+		t.Errorf("Stop did not end the session")
+	}
+	if got := d.body.text.String(); !strings.HasSuffix(got, "stopped debugging\n") {
+		t.Errorf("+Debug body=%q, want stopped debugging", got)
+	}
+}
+
+func TestCmd_DebugNoSession(t *testing.T) {
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, "/a/b.txt")
+	)
+	c.Add(s)
+	err := execCmd(c, s, "Debug")
+	if want := "no debug adapter for /a/b.txt"; err == nil || err.Error() != want {
+		t.Errorf("Debug=%v, want %q", err, want)
+	}
+	for _, cmd := range []string{"Cont", "Next", "Step", "Out"} {
+		err := execCmd(c, s, cmd)
+		if want := "no debug session"; err == nil || err.Error() != want {
+			t.Errorf("execCmd(%q)=%v, want %q", cmd, err, want)
+		}
+	}
+	if err := execCmd(c, s, "Stop"); err != nil {
+		t.Errorf("Stop with no session failed: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+)
+
+// maxDiffEdits is the maximum number of line insertions and deletions
+// searched for by lineDiffs before it gives up on a minimal diff.
+const maxDiffEdits = 1000
+
+// lineDiffs returns diffs, in descending address order,
+// that change the text a into the text b
+// by replacing as few whole lines as it can.
+func lineDiffs(a, b string) edit.Diffs {
+	as, bs := splitAfterLines(a), splitAfterLines(b)
+	var pre int64
+	for len(as) > 0 && len(bs) > 0 && as[0] == bs[0] {
+		pre += int64(len(as[0]))
+		as, bs = as[1:], bs[1:]
+	}
+	for len(as) > 0 && len(bs) > 0 && as[len(as)-1] == bs[len(bs)-1] {
+		as, bs = as[:len(as)-1], bs[:len(bs)-1]
+	}
+	if len(as) == 0 && len(bs) == 0 {
+		return nil
+	}
+
+	offs := make([]int64, len(as)+1)
+	offs[0] = pre
+	for i, l := range as {
+		offs[i+1] = offs[i] + int64(len(l))
+	}
+	trace := editTrace(as, bs)
+	if trace == nil {
+		return edit.Diffs{{At: [2]int64{pre, offs[len(as)]}, Text: rope.New(strings.Join(bs, ""))}}
+	}
+
+	// Walk the trace backwards from the end of both texts,
+	// joining adjacent edits into a single diff.
+	var diffs edit.Diffs
+	x, y := len(as), len(bs)
+	x1, y1 := -1, -1 // end of the current run of edits, if any
+	flush := func() {
+		if x1 >= 0 {
+			diffs = append(diffs, edit.Diff{
+				At:   [2]int64{offs[x], offs[x1]},
+				Text: rope.New(strings.Join(bs[y:y1], "")),
+			})
+			x1, y1 = -1, -1
+		}
+	}
+	for d := len(trace) - 1; d > 0; d-- {
+		v, off := trace[d], len(trace[d])/2
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+			prevK = k + 1
+		}
+		prevX := v[off+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			flush()
+			x, y = x-1, y-1
+		}
+		if x1 < 0 {
+			x1, y1 = x, y
+		}
+		x, y = prevX, prevY
+	}
+	flush()
+	return diffs
+}
+
+// editTrace returns the furthest reaching paths
+// of each round of the Myers diff algorithm on the lines,
+// or nil if more than maxDiffEdits edits are needed.
+func editTrace(a, b []string) [][]int {
+	n, m := len(a), len(b)
+	max := n + m
+	if max > maxDiffEdits {
+		max = maxDiffEdits
+	}
+	off := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return trace
+			}
+		}
+	}
+	return nil
+}
+
+// splitAfterLines returns the lines of str,
+// each including its terminal newline, if any.
+func splitAfterLines(str string) []string {
+	lines := strings.SplitAfter(str, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
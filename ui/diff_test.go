@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+)
+
+func TestLineDiffs(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want [][2]int64
+	}{
+		{a: "", b: "", want: nil},
+		{a: "a\nb\n", b: "a\nb\n", want: nil},
+		{a: "", b: "a\n", want: [][2]int64{{0, 0}}},
+		{a: "a\n", b: "", want: [][2]int64{{0, 2}}},
+		{a: "a\nb\nc\n", b: "a\nx\nc\n", want: [][2]int64{{2, 4}}},
+		{a: "a\nc\n", b: "a\nb\nc\n", want: [][2]int64{{2, 2}}},
+		{a: "a\nb\nc\nd\ne\n", b: "x\nb\nc\ny\ne\n", want: [][2]int64{{6, 8}, {0, 2}}},
+		{a: "a\nb", b: "a\nb\n", want: [][2]int64{{2, 3}}},
+	}
+	for _, test := range tests {
+		diffs := lineDiffs(test.a, test.b)
+		var at [][2]int64
+		for _, d := range diffs {
+			at = append(at, d.At)
+		}
+		if !reflect.DeepEqual(at, test.want) {
+			t.Errorf("lineDiffs(%q, %q) at %v, want %v", test.a, test.b, at, test.want)
+		}
+		if got, _ := diffs.Apply(rope.New(test.a)); got.String() != test.b {
+			t.Errorf("lineDiffs(%q, %q) applied=%q", test.a, test.b, got.String())
+		}
+	}
+}
+
+func TestLineDiffs_TooManyEdits(t *testing.T) {
+	var a, b string
+	for i := 0; i < maxDiffEdits; i++ {
+		a += "a\n"
+		b += "b\n"
+	}
+	diffs := lineDiffs(a, b)
+	want := edit.Diffs{{At: [2]int64{0, int64(len(a))}, Text: rope.New(b)}}
+	if len(diffs) != 1 || diffs[0].At != want[0].At || diffs[0].Text.String() != b {
+		t.Errorf("lineDiffs=%v, want %v", diffs, want)
+	}
+}
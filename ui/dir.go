@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/eaburns/T/rope"
+)
+
+// dirRowTagText is added to the tag of a directory sheet.
+// Looking at .. goes up to the parent directory.
+const dirRowTagText = " .. Sort Hidden"
+
+// isDirSheet returns whether the sheet lists a directory.
+func isDirSheet(s *Sheet) bool {
+	return s != nil && strings.HasSuffix(s.Title(), string(os.PathSeparator))
+}
+
+// sortDir implements the Sort command of a directory sheet.
+// With the argument name or time, the listing is sorted
+// by name or by modification time, newest first;
+// with no argument, it switches between the two.
+// Directories are always listed before files.
+func sortDir(s *Sheet, args string) error {
+	if !isDirSheet(s) {
+		return errors.New(msg("Sort: not a directory"))
+	}
+	switch strings.TrimSpace(args) {
+	case "":
+		s.byTime = !s.byTime
+	case "name":
+		s.byTime = false
+	case "time":
+		s.byTime = true
+	default:
+		return errors.New(msg("Sort: want name or time"))
+	}
+	return s.Get()
+}
+
+// toggleHidden implements the Hidden command of a directory sheet.
+// It switches between listing and omitting files beginning with a dot.
+func toggleHidden(s *Sheet) error {
+	if !isDirSheet(s) {
+		return errors.New(msg("Hidden: not a directory"))
+	}
+	s.noHidden = !s.noHidden
+	return s.Get()
+}
+
+// lookDir shows the directory at path in the directory sheet,
+// as when descending into a subdirectory or going up to its parent.
+// The tag is reset as for a new sheet of the directory;
+// the Sort and Hidden settings are kept.
+func lookDir(s *Sheet, path string, f *os.File) error {
+	s.tag.SetText(rope.New(tagText + dirTagText(ensureTrailingSlash(path))))
+	s.SetTitle(path)
+	if err := get(s, f); err != nil {
+		return err
+	}
+	setDot(s.body, 1, 0, 0)
+	showAddr(s.body, 0)
+	return nil
+}
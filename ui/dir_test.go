@@ -0,0 +1,113 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDirSortHidden(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	touch(dir, "a")
+	touch(dir, "b")
+	touch(dir, ".c")
+	mkSubDir(dir, "1")
+	now := time.Now()
+	os.Chtimes(filepath.Join(dir, "a"), now, now.Add(-time.Hour))
+	os.Chtimes(filepath.Join(dir, "b"), now, now)
+	os.Chtimes(filepath.Join(dir, ".c"), now, now.Add(-2*time.Hour))
+
+	w := newTestWin()
+	c := w.cols[0]
+	s := NewSheet(w, dir)
+	c.Add(s)
+	if err := s.Get(); err != nil {
+		t.Fatalf("Get()=%v", err)
+	}
+	if tag := s.tag.text.String(); !strings.Contains(tag, dirRowTagText) {
+		t.Errorf("tag=%q, want it to contain %q", tag, dirRowTagText)
+	}
+
+	tests := []struct {
+		exec string
+		want string
+	}{
+		{exec: "Sort time", want: "1/\nb\na\n.c\n"},
+		{exec: "Hidden", want: "1/\nb\na\n"},
+		{exec: "Sort", want: "1/\na\nb\n"},
+		{exec: "Hidden", want: "1/\n.c\na\nb\n"},
+		{exec: "Sort name", want: "1/\n.c\na\nb\n"},
+	}
+	for _, test := range tests {
+		if err := execCmd(c, s, test.exec); err != nil {
+			t.Fatalf("execCmd(%q)=%v", test.exec, err)
+		}
+		if got := s.body.text.String(); got != test.want {
+			t.Errorf("after %s, body=%q, want %q", test.exec, got, test.want)
+		}
+	}
+
+	if err := execCmd(c, s, "Sort size"); err == nil {
+		t.Errorf("execCmd(Sort size)=nil, want error")
+	}
+	file := NewSheet(w, filepath.Join(dir, "a"))
+	if err := execCmd(c, file, "Hidden"); err == nil {
+		t.Errorf("execCmd(Hidden) in a file sheet=nil, want error")
+	}
+}
+
+func TestDirLook(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	touch(dir, "a")
+	mkSubDir(dir, "sub")
+	touch(filepath.Join(dir, "sub"), "b")
+
+	w := newTestWin()
+	c := w.cols[0]
+	s := NewSheet(w, dir)
+	c.Add(s)
+	s.noHidden = true
+	if err := s.Get(); err != nil {
+		t.Fatalf("Get()=%v", err)
+	}
+
+	if err := lookText(c, s, "sub/"); err != nil {
+		t.Fatalf("lookText(sub/)=%v", err)
+	}
+	if got, want := s.Title(), filepath.Join(dir, "sub")+"/"; got != want {
+		t.Errorf("title=%q, want %q", got, want)
+	}
+	if got, want := s.body.text.String(), "b\n"; got != want {
+		t.Errorf("body=%q, want %q", got, want)
+	}
+	if !s.noHidden {
+		t.Errorf("noHidden=false after descending, want true")
+	}
+
+	if err := lookText(c, s, ".."); err != nil {
+		t.Fatalf("lookText(..)=%v", err)
+	}
+	if got, want := s.Title(), dir+"/"; got != want {
+		t.Errorf("title=%q, want %q", got, want)
+	}
+	if got, want := s.body.text.String(), "sub/\na\n"; got != want {
+		t.Errorf("body=%q, want %q", got, want)
+	}
+	if n := strings.Count(s.tag.text.String(), dirRowTagText); n != 1 {
+		t.Errorf("tag=%q, want one %q", s.tag.text.String(), dirRowTagText)
+	}
+
+	if err := lookText(c, s, "a"); err != nil {
+		t.Fatalf("lookText(a)=%v", err)
+	}
+	if s.Title() != dir+"/" {
+		t.Errorf("title=%q after looking at a file, want %q", s.Title(), dir+"/")
+	}
+	if findSheet(w, filepath.Join(dir, "a")) == nil {
+		t.Errorf("no sheet for the file a")
+	}
+}
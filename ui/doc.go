@@ -0,0 +1,38 @@
+// Package ui is the user interface of the editor.
+//
+// A Win is the editor as a widget that programs can embed,
+// such as the T command, the headless package,
+// or a panel of another program.
+// The embedding program owns the window system:
+// it sends events to the Win, ticks it, and draws it.
+//
+// NewWin returns a Win for a display resolution,
+// and Close stops its background work when it is no longer used.
+//
+// Events are sent with the methods named for them:
+// Resize, Focus, Move, Click, Wheel, WheelPx, Mod, Dir, Rune,
+// Copy, Cut, Paste, Preedit, Commit, and Drop.
+// Coordinates are in pixels relative to the upper left of the Win.
+//
+// Tick runs the work of the editor that is not the direct result of an event,
+// such as showing the output of commands,
+// and it returns whether the Win must be drawn.
+// It must be called after events,
+// when the duration returned by NextTick has elapsed,
+// and when the function set by SetWake is called,
+// but no more often than every TickRate.
+//
+// Draw draws the Win into any draw.Image,
+// fastest into an *image.RGBA,
+// and returns the rectangle of the image that changed.
+// If the size of the image differs from the Win's, the Win is resized.
+//
+// After events and ticks, the embedding program may need to update its window
+// from Title, Fullscreen, and Exiting.
+// Without a system clipboard, SetClipboard sets another one.
+//
+// The methods of a Win must be called from a single goroutine,
+// except for OutputString and OutputBytes,
+// which are safe for concurrent calls.
+// The function set by SetWake is called from any goroutine.
+package ui
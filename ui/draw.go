@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"image"
+	"image/color"
+)
+
+// The functions of this file draw to *image.RGBA
+// without the allocations of image.NewUniform and SubImage,
+// so that drawing a frame does not allocate.
+
+// subImage sets dst to the part of img within r and returns dst.
+// It is like img.SubImage(r), but reuses dst.
+func subImage(dst, img *image.RGBA, r image.Rectangle) *image.RGBA {
+	r = r.Intersect(img.Rect)
+	if r.Empty() {
+		*dst = image.RGBA{}
+		return dst
+	}
+	i := img.PixOffset(r.Min.X, r.Min.Y)
+	*dst = image.RGBA{Pix: img.Pix[i:], Stride: img.Stride, Rect: r}
+	return dst
+}
+
+// fillRGBA sets the pixels of img within r to the color.
+func fillRGBA(img *image.RGBA, c color.Color, r image.Rectangle) {
+	r = r.Intersect(img.Rect)
+	if r.Empty() {
+		return
+	}
+	cr, cg, cb, ca := c.RGBA()
+	px := [4]uint8{uint8(cr >> 8), uint8(cg >> 8), uint8(cb >> 8), uint8(ca >> 8)}
+	i0 := img.PixOffset(r.Min.X, r.Min.Y)
+	row := img.Pix[i0 : i0+4*r.Dx()]
+	for i := 0; i < len(row); i += 4 {
+		copy(row[i:i+4], px[:])
+	}
+	for y := r.Min.Y + 1; y < r.Max.Y; y++ {
+		i := img.PixOffset(r.Min.X, y)
+		copy(img.Pix[i:i+len(row)], row)
+	}
+}
+
+// drawAlphaMask draws the color through the mask over img within r,
+// with the point mp of the mask aligned with r.Min.
+// It is like draw.DrawMask with draw.Over and an image.Uniform source,
+// and it computes the same pixels.
+func drawAlphaMask(img *image.RGBA, r image.Rectangle, c color.Color, mask *image.Alpha, mp image.Point) {
+	clip := r.Intersect(img.Rect).Intersect(mask.Rect.Add(r.Min.Sub(mp)))
+	mp = mp.Add(clip.Min.Sub(r.Min))
+	r = clip
+	const m = 1<<16 - 1
+	sr, sg, sb, sa := c.RGBA()
+	for y := 0; y < r.Dy(); y++ {
+		i := img.PixOffset(r.Min.X, r.Min.Y+y)
+		j := mask.PixOffset(mp.X, mp.Y+y)
+		for x := 0; x < r.Dx(); x, i, j = x+1, i+4, j+1 {
+			ma := uint32(mask.Pix[j])
+			if ma == 0 {
+				continue
+			}
+			ma |= ma << 8
+			a := (m - (sa * ma / m)) * 0x101
+			d := img.Pix[i : i+4 : i+4]
+			d[0] = uint8((uint32(d[0])*a + sr*ma) / m >> 8)
+			d[1] = uint8((uint32(d[1])*a + sg*ma) / m >> 8)
+			d[2] = uint8((uint32(d[2])*a + sb*ma) / m >> 8)
+			d[3] = uint8((uint32(d[3])*a + sa*ma) / m >> 8)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
+	"testing"
+)
+
+func TestDrawAlphaMask(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for n := 0; n < 100; n++ {
+		bounds := image.Rect(0, 0, 16, 16)
+		dst := image.NewRGBA(bounds)
+		rnd.Read(dst.Pix)
+		for i := 0; i < len(dst.Pix); i += 4 {
+			// Keep the destination premultiplied.
+			a := dst.Pix[i+3]
+			for k := 0; k < 3; k++ {
+				if dst.Pix[i+k] > a {
+					dst.Pix[i+k] = a
+				}
+			}
+		}
+		want := image.NewRGBA(bounds)
+		copy(want.Pix, dst.Pix)
+
+		mask := image.NewAlpha(image.Rect(0, 0, 10, 10))
+		rnd.Read(mask.Pix)
+		c := color.NRGBA{
+			R: uint8(rnd.Intn(256)),
+			G: uint8(rnd.Intn(256)),
+			B: uint8(rnd.Intn(256)),
+			A: uint8(rnd.Intn(256)),
+		}
+		r := image.Rect(rnd.Intn(12)-2, rnd.Intn(12)-2, 0, 0)
+		r.Max = r.Min.Add(image.Pt(10, 10))
+		mp := image.Pt(rnd.Intn(3), rnd.Intn(3))
+
+		drawAlphaMask(dst, r, c, mask, mp)
+		draw.DrawMask(want, r, image.NewUniform(c), image.ZP, mask, mp, draw.Over)
+		for i := range dst.Pix {
+			if dst.Pix[i] != want.Pix[i] {
+				x, y := i/4%16, i/4/16
+				t.Fatalf("%d: color %v, r=%v, mp=%v: pixel %d,%d=%v, want %v",
+					n, c, r, mp, x, y, dst.At(x, y), want.At(x, y))
+			}
+		}
+	}
+}
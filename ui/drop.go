@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"image"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// Drop opens the files and directories dropped on the window
+// at the point, each in a new sheet of the column under the point.
+// The paths may also be file URIs, as in a text/uri-list,
+// and lines of a text/uri-list beginning with # are ignored.
+// Errors are shown in the Output sheet.
+func (w *Win) Drop(pt image.Point, paths []string) {
+	setWinFocusPt(w, pt)
+	c := w.Col
+	for _, p := range paths {
+		if p = dropPath(p); p == "" {
+			continue
+		}
+		if focusSheet(w, p) || focusSheet(w, ensureTrailingSlash(p)) {
+			continue
+		}
+		if err := openSheet(c, p); err != nil {
+			w.OutputString(err.Error() + "\n")
+		}
+	}
+}
+
+// dropPath returns the absolute path of a dropped path or file URI,
+// or "" if it is neither.
+func dropPath(p string) string {
+	p = strings.TrimSpace(p)
+	if strings.HasPrefix(p, "file:") {
+		u, err := url.Parse(p)
+		if err != nil {
+			return ""
+		}
+		p = u.Path
+	}
+	if !filepath.IsAbs(p) {
+		return ""
+	}
+	return filepath.Clean(p)
+}
@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDropPath(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"/a/b.go", "/a/b.go"},
+		{"/a/../b.go\r", "/b.go"},
+		{"file:///a/b%20c.go", "/a/b c.go"},
+		{"file://host/a/b.go", "/a/b.go"},
+		{"# comment", ""},
+		{"b.go", ""},
+		{"http://example.com/b.go", ""},
+	}
+	for _, test := range tests {
+		if got := dropPath(test.path); got != test.want {
+			t.Errorf("dropPath(%q)=%q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestDrop(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.txt")
+	write(path, "a\n")
+
+	w := newTestWin()
+	w.Resize(image.Pt(800, 600))
+	c1 := w.Add()
+	w.Drop(image.Pt(10, 10), []string{"file://" + path})
+	s := findSheet(w, path)
+	if s == nil {
+		t.Fatalf("%s not opened", path)
+	}
+	if rowIndex(w.cols[0], s) < 0 || rowIndex(c1, s) >= 0 {
+		t.Errorf("sheet not opened in the first column")
+	}
+	if s.body.text.String() != "a\n" {
+		t.Errorf("body=%q, want %q", s.body.text.String(), "a\n")
+	}
+}
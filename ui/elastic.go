@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"bufio"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+	"golang.org/x/image/math/fixed"
+)
+
+// maxElasticLines is the maximum number of lines
+// scanned before and after the displayed text
+// when computing elastic tabstops.
+const maxElasticLines = 1000
+
+// A tabLine is a line of text split into tab-terminated cells.
+type tabLine struct {
+	cells []fixed.Int26_6 // width of each tab-terminated cell
+	tabs  []int64         // address of the tab terminating each cell
+}
+
+// elasticStops returns the x position of the tab stop
+// following each tab in and around the displayed lines,
+// keyed by the address of the tab.
+//
+// With elastic tabstops, the tab-terminated cells in the same column
+// of consecutive lines form a block, and all cells in a block
+// are as wide as the widest cell in the block plus padding.
+// A column of cells ends at the first line
+// with too few tabs to have a cell in that column.
+func elasticStops(b *TextBox) map[int64]fixed.Int26_6 {
+	lines := tabLines(b)
+	pad, _ := b.style.Face.GlyphAdvance(' ')
+	pad = pad.Mul(fixed.I(elasticTabPad))
+	stops := make(map[int64]fixed.Int26_6)
+	for i := range lines {
+		var x fixed.Int26_6
+		for j, w := range lines[i].cells {
+			for k := i - 1; k >= 0 && len(lines[k].cells) > j; k-- {
+				w = max(w, lines[k].cells[j])
+			}
+			for k := i + 1; k < len(lines) && len(lines[k].cells) > j; k++ {
+				w = max(w, lines[k].cells[j])
+			}
+			x += w + pad
+			stops[lines[i].tabs[j]] = x
+		}
+	}
+	return stops
+}
+
+// tabLines returns the displayed lines of text,
+// along with any lines containing tabs
+// immediately before and after them.
+func tabLines(b *TextBox) []tabLine {
+	start, err := edit.Addr([2]int64{b.at, b.at}, "-0", b.text)
+	if err != nil {
+		return nil
+	}
+	at := start[0]
+	for n := 0; n < maxElasticLines && at > 0; n++ {
+		prev, err := edit.Addr([2]int64{at - 1, at - 1}, "-0", b.text)
+		if err != nil || rope.IndexRune(rope.Slice(b.text, prev[0], at), '\t') < 0 {
+			break
+		}
+		at = prev[0]
+	}
+
+	var lines []tabLine
+	h := b.style.Face.Metrics().Height
+	visible := b.size.Y/h.Ceil() + 1
+	rs := bufio.NewReader(rope.NewReader(rope.Slice(b.text, at, b.text.Len())))
+	var prev rune
+	var x fixed.Int26_6
+	var l tabLine
+	for len(lines) < 2*maxElasticLines {
+		r, w, err := rs.ReadRune()
+		if err != nil {
+			lines = append(lines, l)
+			break
+		}
+		switch r {
+		case '\t':
+			l.cells = append(l.cells, x)
+			l.tabs = append(l.tabs, at)
+			x, prev = 0, 0
+		case '\n':
+			lines = append(lines, l)
+			if at >= b.at {
+				visible--
+			}
+			if visible <= 0 && len(l.tabs) == 0 {
+				return lines
+			}
+			l = tabLine{}
+			x, prev = 0, 0
+		default:
+			x += kern(b.style, prev, r)
+			adv, _ := b.style.Face.GlyphAdvance(r)
+			x += adv
+			prev = r
+		}
+		at += int64(w)
+	}
+	return lines
+}
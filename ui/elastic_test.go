@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"image"
+	"testing"
+
+	"github.com/eaburns/T/rope"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestElasticStops(t *testing.T) {
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New("a\tbb\tc\naaa\tb\tc\nx\n\ta\tb\n"))
+	setElastic(b, true)
+	b.lines()
+
+	pad := fixed.I(elasticTabPad * A)
+	tests := []struct {
+		at   int64
+		want fixed.Int26_6
+	}{
+		// The first column block is the first two lines; widest is aaa.
+		{at: 1, want: fixed.I(3*A) + pad},
+		{at: 10, want: fixed.I(3*A) + pad},
+		// The second column block; widest is bb.
+		{at: 4, want: fixed.I(5*A) + 2*pad},
+		{at: 12, want: fixed.I(5*A) + 2*pad},
+		// Line x has no tabs, so it ends the blocks.
+		{at: 17, want: pad},
+		{at: 19, want: fixed.I(A) + 2*pad},
+	}
+	for _, test := range tests {
+		if got, ok := b.stops[test.at]; !ok || got != test.want {
+			t.Errorf("stops[%d]=%v,%v, want %v", test.at, got, ok, test.want)
+		}
+	}
+	if at, _ := atPoint(b, image.Pt(3*A+pad.Floor()+1, 1)); at != 2 {
+		t.Errorf("atPoint(after first tab)=%d, want 2", at)
+	}
+
+	setElastic(b, false)
+	b.lines()
+	if b.stops != nil {
+		t.Errorf("stops=%v, want nil", b.stops)
+	}
+}
@@ -0,0 +1,44 @@
+package ui
+
+import "unicode/utf8"
+
+// An outputDecoder converts command output to UTF-8.
+//
+// Valid UTF-8 is unchanged.
+// Other bytes are decoded with legacyEncoding,
+// or replaced by U+FFFD if legacyEncoding is nil.
+// An incomplete UTF-8 sequence at the end of the output
+// is held until the next call to decode completes it.
+type outputDecoder struct {
+	pending []byte
+}
+
+// decode returns the UTF-8 text of the next bytes of output.
+// If final is true, there is no more output,
+// and any incomplete sequence is decoded.
+func (d *outputDecoder) decode(data []byte, final bool) []byte {
+	data = append(d.pending, data...)
+	d.pending = nil
+	out := make([]byte, 0, len(data))
+	var buf [utf8.UTFMax]byte
+	for len(data) > 0 {
+		r, w := utf8.DecodeRune(data)
+		if r == utf8.RuneError && w <= 1 {
+			if !final && !utf8.FullRune(data) {
+				d.pending = append(d.pending, data...)
+				break
+			}
+			r, w = legacyRune(data[0]), 1
+		}
+		out = append(out, buf[:utf8.EncodeRune(buf[:], r)]...)
+		data = data[w:]
+	}
+	return out
+}
+
+func legacyRune(b byte) rune {
+	if legacyEncoding == nil {
+		return utf8.RuneError
+	}
+	return legacyEncoding.DecodeByte(b)
+}
@@ -0,0 +1,36 @@
+package ui
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestOutputDecoder(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		legacy *charmap.Charmap
+		want   string
+	}{
+		{name: "ascii", chunks: []string{"hello"}, legacy: charmap.Windows1252, want: "hello"},
+		{name: "utf8", chunks: []string{"héllo ☺"}, legacy: charmap.Windows1252, want: "héllo ☺"},
+		{name: "split utf8", chunks: []string{"h\xc3", "\xa9llo \xe2\x98", "\xba"}, legacy: charmap.Windows1252, want: "héllo ☺"},
+		{name: "latin1", chunks: []string{"h\xe9llo"}, legacy: charmap.Windows1252, want: "héllo"},
+		{name: "cp1252", chunks: []string{"\x93quoted\x94 \x80"}, legacy: charmap.Windows1252, want: "“quoted” €"},
+		{name: "truncated at end", chunks: []string{"x\xe2\x98"}, legacy: charmap.Windows1252, want: "xâ˜"},
+		{name: "replace", chunks: []string{"h\xe9llo"}, legacy: nil, want: "h�llo"},
+	}
+	defer func(e *charmap.Charmap) { legacyEncoding = e }(legacyEncoding)
+	for _, test := range tests {
+		legacyEncoding = test.legacy
+		var d outputDecoder
+		var got string
+		for i, c := range test.chunks {
+			got += string(d.decode([]byte(c), i == len(test.chunks)-1))
+		}
+		if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"net"
+)
+
+// maxPendingEvents is the most events waiting to be written
+// to a connection; further events are dropped
+// so that a slow reader cannot block the window.
+const maxPendingEvents = 256
+
+// An editorEvent is sent as a JSON-RPC notification
+// to the connections of Listen that called the log method.
+type editorEvent struct {
+	// Type is one of:
+	//	get   a sheet was loaded from its file or directory
+	//	put   a sheet was written to its file
+	//	dot   dot of the body of the focused sheet moved
+	//	exec  a command was executed
+	Type  string    `json:"type"`
+	Sheet string    `json:"sheet"` // title of the sheet; "" for exec outside of a sheet
+	Winid int       `json:"winid,omitempty"`
+	Text  string    `json:"text,omitempty"` // the command of exec
+	Dot   *[2]int64 `json:"dot,omitempty"`  // dot of dot
+}
+
+type rpcNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  editorEvent `json:"params"`
+}
+
+// logEvent sends an event of the sheet to the connections
+// that called the log method; s may be nil.
+func logEvent(w *Win, s *Sheet, typ, text string) {
+	ev := editorEvent{Type: typ, Text: text}
+	if s != nil {
+		ev.Sheet = s.Title()
+		ev.Winid = s.id
+	}
+	sendEvent(w, ev)
+}
+
+// logDot sends a dot event if dot of the body of the focused sheet
+// moved since the last tick.
+func logDot(w *Win) {
+	if w.listener == nil {
+		return
+	}
+	s := getSheet(w.Col.Row)
+	if s == nil {
+		return
+	}
+	at := s.body.dots[1].At
+	if s == w.dotSheet && at == w.dotAt {
+		return
+	}
+	w.dotSheet, w.dotAt = s, at
+	sendEvent(w, editorEvent{Type: "dot", Sheet: s.Title(), Winid: s.id, Dot: &at})
+}
+
+func sendEvent(w *Win, ev editorEvent) {
+	ln := w.listener
+	if ln == nil {
+		return
+	}
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+	for _, ch := range ln.logs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// subscribe implements the log method:
+// events are written to the connection until it is closed.
+func subscribe(ln *listener, c net.Conn, cw *connWriter) {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+	if ln.logs[c] != nil {
+		return
+	}
+	ch := make(chan editorEvent, maxPendingEvents)
+	ln.logs[c] = ch
+	go func() {
+		for ev := range ch {
+			if cw.send(rpcNotification{JSONRPC: "2.0", Method: "event", Params: ev}) != nil {
+				c.Close()
+			}
+		}
+	}()
+}
@@ -0,0 +1,15 @@
+package ui
+
+import "github.com/eaburns/T/clipboard"
+
+// Exec executes a command as if it were clicked with button 2
+// in the focused row of the focused column.
+// Unlike a click, the command's error is returned,
+// not written to the Output sheet.
+func (w *Win) Exec(cmd string) error {
+	return execCmd(w.Col, getSheet(w.Col.Row), cmd)
+}
+
+// SetClipboard sets the clipboard used by the window,
+// for example, a memory clipboard when there is no system clipboard.
+func (w *Win) SetClipboard(c clipboard.Clipboard) { w.clipboard = c }
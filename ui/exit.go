@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+)
+
+// Exit requests that the window exit,
+// as when its system window is closed.
+// If no file sheets are modified,
+// or the request is repeated,
+// it returns true, and the window should be closed.
+// Otherwise the modified files are listed in the Output sheet
+// and it returns false.
+func (w *Win) Exit() bool {
+	if err := exit(w); err != nil {
+		w.OutputString(err.Error() + "\n")
+	}
+	return w.exiting
+}
+
+// Exiting returns whether the Exit command was executed
+// and the window should be closed.
+func (w *Win) Exiting() bool { return w.exiting }
+
+// exit implements the Exit command.
+// If file sheets are modified, the first execution lists them,
+// and executing the command again next exits, discarding the changes.
+// Executing Putall first saves them.
+func exit(w *Win) error {
+	var titles []string
+	for _, s := range modifiedSheets(w) {
+		titles = append(titles, s.Title())
+	}
+	// If other sheets were modified since the warning, warn again.
+	key := "Exit " + strings.Join(titles, " ")
+	if len(titles) > 0 && w.confirm != key {
+		w.confirm = key
+		return errors.New(msg("modified: %s; execute again to exit", strings.Join(titles, " ")))
+	}
+	w.confirm = ""
+	w.exiting = true
+	return nil
+}
+
+// putAll implements the Putall command,
+// writing the body of each modified file sheet to its file.
+func putAll(w *Win) error {
+	for _, s := range modifiedSheets(w) {
+		if err := s.Put(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// modifiedSheets returns the file sheets of the window
+// that are modified, in column and row order.
+func modifiedSheets(w *Win) []*Sheet {
+	var ss []*Sheet
+	for _, c := range w.cols {
+		for _, r := range c.rows {
+			if s := getSheet(r); s != nil && isFileSheet(s) && isModified(s) {
+				ss = append(ss, s)
+			}
+		}
+	}
+	return ss
+}
@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestCmd_Exit(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.txt")
+	write(path, "a\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, path)
+	)
+	c.Add(s)
+	if err := s.Get(); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	s.body.SetText(rope.New("b\n"))
+
+	err := execCmd(c, s, "Exit")
+	if want := "modified: " + path + "; execute again to exit"; err == nil || err.Error() != want {
+		t.Errorf("Exit=%v, want %q", err, want)
+	}
+	if w.Exiting() {
+		t.Fatalf("exiting with a modified file")
+	}
+	if err := execCmd(c, s, "Exit"); err != nil {
+		t.Fatalf("Exit again failed: %v", err)
+	}
+	if !w.Exiting() {
+		t.Errorf("not exiting after Exit again")
+	}
+}
+
+func TestCmd_Putall(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.txt")
+	write(path, "a\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, path)
+	)
+	c.Add(s)
+	if err := s.Get(); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	s.body.SetText(rope.New("b\n"))
+	if err := execCmd(c, s, "Putall"); err != nil {
+		t.Fatalf("Putall failed: %v", err)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "b\n" {
+		t.Errorf("a.txt=%q, want %q", data, "b\n")
+	}
+	if !w.Exit() {
+		t.Errorf("Exit()=false after Putall")
+	}
+}
+
+func TestCmd_ExitConfirm(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	pathA := filepath.Join(dir, "a.txt")
+	pathB := filepath.Join(dir, "b.txt")
+	write(pathA, "a\n")
+	write(pathB, "b\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		a = NewSheet(w, pathA)
+		b = NewSheet(w, pathB)
+	)
+	c.Add(b)
+	c.Add(a)
+	if err := a.Get(); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if err := b.Get(); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	a.body.SetText(rope.New("x\n"))
+
+	// Typing after the warning dismisses it.
+	if err := execCmd(c, a, "Exit"); err == nil {
+		t.Fatalf("Exit=nil, want a warning")
+	}
+	w.Rune('y')
+	if err := execCmd(c, a, "Exit"); err == nil || w.Exiting() {
+		t.Fatalf("Exit after typing=%v, want a warning", err)
+	}
+
+	// Another command after the warning dismisses it.
+	if err := execCmd(c, a, "Wrap"); err != nil {
+		t.Fatalf("Wrap failed: %v", err)
+	}
+	if err := execCmd(c, a, "Exit"); err == nil || w.Exiting() {
+		t.Fatalf("Exit after Wrap=%v, want a warning", err)
+	}
+
+	// Modifying another sheet after the warning warns again.
+	b.body.SetText(rope.New("z\n"))
+	err := execCmd(c, a, "Exit")
+	if want := "modified: " + pathB + " " + pathA + "; execute again to exit"; err == nil || err.Error() != want {
+		t.Fatalf("Exit after modifying b.txt=%v, want %q", err, want)
+	}
+	if w.Exiting() {
+		t.Fatalf("exiting after modifying b.txt")
+	}
+	if err := execCmd(c, a, "Exit"); err != nil || !w.Exiting() {
+		t.Errorf("Exit again=%v, exiting=%v, want nil, true", err, w.Exiting())
+	}
+}
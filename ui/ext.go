@@ -0,0 +1,209 @@
+package ui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// An extension is a helper process
+// started with the window and restarted if it exits.
+//
+// Extensions are declared in the extensionsFile manifest,
+// one per line, as a name followed by the command and its arguments,
+// separated by spaces.
+// Blank lines and lines beginning with # are ignored.
+// For example:
+//
+//	# Lint Go files on save.
+//	lint /home/me/bin/golint-daemon -watch .
+//
+// The output of an extension is written to the Output sheet.
+type extension struct {
+	name string
+	cmd  []string
+
+	mu       sync.Mutex
+	state    string // a description of the process state
+	restarts int
+}
+
+// readExtensions returns the extensions declared in the manifest.
+func readExtensions(r io.Reader) ([]*extension, error) {
+	var exts []*extension
+	names := make(map[string]bool)
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fs := strings.Fields(line)
+		if len(fs) < 2 {
+			return nil, errors.New(msg("line %d: want name and command", n))
+		}
+		if names[fs[0]] {
+			return nil, errors.New(msg("line %d: duplicate extension %s", n, fs[0]))
+		}
+		names[fs[0]] = true
+		exts = append(exts, &extension{name: fs[0], cmd: fs[1:], state: "stopped"})
+	}
+	return exts, s.Err()
+}
+
+// startExtensions starts the extensions of the extensionsFile manifest,
+// if it exists.
+// They are stopped when the window is closed.
+func startExtensions(w *Win) error {
+	path := extensionsFile
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(dir, "T", "extensions")
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	exts, err := readExtensions(f)
+	if err != nil {
+		return errors.New(path + ": " + err.Error())
+	}
+	w.exts = exts
+	w.stopExts = make(chan struct{})
+	for _, e := range exts {
+		w.extsDone.Add(1)
+		go superviseExtension(w, e, w.stopExts)
+	}
+	return nil
+}
+
+// stopExtensions kills the extension processes
+// and waits for them to exit.
+func stopExtensions(w *Win) {
+	if w.stopExts == nil {
+		return
+	}
+	close(w.stopExts)
+	w.extsDone.Wait()
+	w.stopExts = nil
+}
+
+// superviseExtension runs the extension until stop is closed,
+// restarting it after a delay each time it exits.
+// The delay starts at extMinBackoff and doubles
+// with each consecutive quick exit, up to extMaxBackoff.
+// While the window is quiet, the restart waits until it resumes.
+func superviseExtension(w *Win, e *extension, stop <-chan struct{}) {
+	defer w.extsDone.Done()
+	backoff := extMinBackoff
+	for {
+		start := time.Now()
+		cmd := exec.Command(e.cmd[0], e.cmd[1:]...)
+		done := make(chan error, 1)
+		if err := runExtension(w, cmd, done); err != nil {
+			done <- err
+		} else {
+			e.setState(fmt.Sprintf("running, pid %d", cmd.Process.Pid))
+		}
+		var err error
+		select {
+		case <-stop:
+			if cmd.Process != nil {
+				cmd.Process.Kill()
+				<-done
+			}
+			e.setState("stopped")
+			return
+		case err = <-done:
+		}
+		if time.Since(start) > extMaxBackoff {
+			backoff = extMinBackoff
+		}
+		if err == nil {
+			err = errors.New("exited")
+		}
+		e.mu.Lock()
+		e.restarts++
+		e.state = fmt.Sprintf("%s; restarting in %s", err, backoff)
+		e.mu.Unlock()
+		w.OutputString(e.name + ": " + err.Error() + "\n")
+		select {
+		case <-stop:
+			e.setState("stopped")
+			return
+		case <-time.After(backoff):
+		}
+		select {
+		case <-stop:
+			e.setState("stopped")
+			return
+		case <-resumed(w):
+		}
+		if backoff *= 2; backoff > extMaxBackoff {
+			backoff = extMaxBackoff
+		}
+	}
+}
+
+// runExtension starts the command, copying its output to the Output sheet,
+// and sends the result of waiting for it on done.
+func runExtension(w *Win, cmd *exec.Cmd, done chan<- error) error {
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		stderr.Close()
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		stderr.Close()
+		stdout.Close()
+		return err
+	}
+	go func() {
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go pipeOutput(&wg, w, stdout)
+		go pipeOutput(&wg, w, stderr)
+		wg.Wait()
+		done <- cmd.Wait()
+	}()
+	return nil
+}
+
+func (e *extension) setState(state string) {
+	e.mu.Lock()
+	e.state = state
+	e.mu.Unlock()
+}
+
+// extensionStatus returns a line for each extension
+// with its name, state, and number of restarts.
+func extensionStatus(w *Win) string {
+	if len(w.exts) == 0 {
+		return msg("no extensions") + "\n"
+	}
+	var s strings.Builder
+	for _, e := range w.exts {
+		e.mu.Lock()
+		s.WriteString(msg("%s: %s (%d restarts)", e.name, e.state, e.restarts) + "\n")
+		e.mu.Unlock()
+	}
+	return s.String()
+}
@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadExtensions(t *testing.T) {
+	exts, err := readExtensions(strings.NewReader("# comment\n\nlint golint -x .\n  fmt gofmt\n"))
+	if err != nil {
+		t.Fatalf("readExtensions failed: %v", err)
+	}
+	var got [][]string
+	for _, e := range exts {
+		got = append(got, append([]string{e.name}, e.cmd...))
+	}
+	want := [][]string{{"lint", "golint", "-x", "."}, {"fmt", "gofmt"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"name\n", "a x\nb y\na z\n"} {
+		if _, err := readExtensions(strings.NewReader(bad)); err == nil {
+			t.Errorf("readExtensions(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestSuperviseExtensions(t *testing.T) {
+	defer func(f string, min time.Duration) { extensionsFile, extMinBackoff = f, min }(extensionsFile, extMinBackoff)
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	extensionsFile = filepath.Join(dir, "extensions")
+	extMinBackoff = time.Millisecond
+	write(extensionsFile, "fail false\nsleep sleep 100\n")
+
+	w := newTestWin()
+	if err := startExtensions(w); err != nil {
+		t.Fatalf("startExtensions failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w.exts[0].mu.Lock()
+		n := w.exts[0].restarts
+		w.exts[0].mu.Unlock()
+		if n >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("fail extension restarted %d times, want at least 2", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if s := extensionStatus(w); !strings.Contains(s, "sleep: running") {
+		t.Errorf("status=%q, want sleep running", s)
+	}
+
+	w.Close()
+	if s := extensionStatus(w); !strings.HasPrefix(s, "fail: stopped (") ||
+		!strings.HasSuffix(s, "\nsleep: stopped (0 restarts)\n") {
+		t.Errorf("status=%q, want stopped", s)
+	}
+}
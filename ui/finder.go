@@ -0,0 +1,187 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+)
+
+// A finder is the file list of a +Open sheet.
+//
+// The first line of the body is a query,
+// and the following lines are the files under the directory
+// that fuzzily match it, best first.
+// Typing return on the query line opens the best match.
+type finder struct {
+	dir   string
+	files []string // paths relative to dir
+	query string   // the query of the listed matches
+}
+
+// newFinder returns a +Open sheet listing the files under dir.
+// Files and directories beginning with . are skipped,
+// and at most maxFinderFiles are listed.
+func newFinder(w *Win, dir string) (*Sheet, error) {
+	f := &finder{dir: dir}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case path != dir && strings.HasPrefix(info.Name(), "."):
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		case info.IsDir():
+			return nil
+		}
+		if len(f.files) == maxFinderFiles {
+			return errFinderFull
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f.files = append(f.files, rel)
+		return nil
+	})
+	if err != nil && err != errFinderFull {
+		return nil, err
+	}
+	s := NewSheet(w, filepath.Join(dir, "+Open"))
+	s.finder = f
+	s.body.SetText(rope.New("\n" + strings.Join(f.match(""), "\n")))
+	return s, nil
+}
+
+var errFinderFull = errors.New("too many files")
+
+// finderUpdate lists the matches of the query line if it changed.
+// It returns whether the body changed.
+func finderUpdate(s *Sheet) bool {
+	b := s.body
+	end := b.text.Len()
+	if i := rope.IndexRune(b.text, '\n'); i >= 0 {
+		end = i
+	}
+	query := rope.Slice(b.text, 0, end).String()
+	if query == s.finder.query {
+		return false
+	}
+	s.finder.query = query
+	text := "\n" + strings.Join(s.finder.match(query), "\n")
+	b.Change(edit.Diffs{{At: [2]int64{end, b.text.Len()}, Text: rope.New(text)}})
+	return true
+}
+
+// finderRune opens the best match when return is typed on the query line.
+// It returns whether the rune was handled.
+func finderRune(s *Sheet, r rune) bool {
+	b := s.body
+	if r != '\n' || b.search != nil || b.win.mods[3] {
+		return false
+	}
+	i := rope.IndexRune(b.text, '\n')
+	if i >= 0 && b.dots[1].At[1] > i {
+		return false
+	}
+	finderUpdate(s)
+	ms := s.finder.match(s.finder.query)
+	if len(ms) == 0 {
+		return true
+	}
+	path := filepath.Join(s.finder.dir, ms[0])
+	if !focusSheet(b.win, path) {
+		if err := openSheet(b.win.Col, path); err != nil {
+			b.win.OutputString(err.Error() + "\n")
+		}
+	}
+	return true
+}
+
+// match returns at most maxFinderResults files matching the query, best first.
+func (f *finder) match(query string) []string {
+	type scored struct {
+		path  string
+		score int
+	}
+	var ms []scored
+	for _, p := range f.files {
+		if n, ok := fuzzyScore(query, p); ok {
+			ms = append(ms, scored{path: p, score: n})
+		}
+	}
+	sort.SliceStable(ms, func(i, j int) bool {
+		if ms[i].score != ms[j].score {
+			return ms[i].score < ms[j].score
+		}
+		return len(ms[i].path) < len(ms[j].path)
+	})
+	if len(ms) > maxFinderResults {
+		ms = ms[:maxFinderResults]
+	}
+	paths := make([]string, len(ms))
+	for i, m := range ms {
+		paths[i] = m.path
+	}
+	return paths
+}
+
+// fuzzyScore returns whether the runes of the query
+// appear in order in the path, and if so, a score; lower is better.
+// The score is the number of runes skipped between matched runes,
+// plus the length of the directory if the first match is not in the base name.
+// Matching ignores case unless the query has an upper case letter.
+func fuzzyScore(query, path string) (int, bool) {
+	fold := strings.IndexFunc(query, unicode.IsUpper) < 0
+	score, first := 0, -1
+	var at, skip int
+	for _, q := range query {
+		for {
+			r, w := utf8.DecodeRuneInString(path[at:])
+			if w == 0 {
+				return 0, false
+			}
+			at += w
+			if r == q || fold && unicode.ToLower(r) == q {
+				break
+			}
+			skip += w
+		}
+		if first < 0 {
+			first = at
+		} else {
+			score += skip
+		}
+		skip = 0
+	}
+	if dir := strings.LastIndexByte(path, filepath.Separator) + 1; first >= 0 && first <= dir {
+		score += dir
+	}
+	return score, true
+}
+
+// openFinder focuses the +Open sheet of the directory of s,
+// adding a new one to the column if there is none.
+func openFinder(c *Col, s *Sheet) error {
+	dir, err := abs(s, ".")
+	if err != nil {
+		return err
+	}
+	if focusSheet(c.win, filepath.Join(dir, "+Open")) {
+		return nil
+	}
+	f, err := newFinder(c.win, dir)
+	if err != nil {
+		return err
+	}
+	c.Add(f)
+	return nil
+}
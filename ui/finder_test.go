@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		query, path string
+		score       int
+		ok          bool
+	}{
+		{query: "", path: "a/b.go", score: 0, ok: true},
+		{query: "b", path: "a/b.go", score: 0, ok: true},
+		{query: "bgo", path: "a/b.go", score: 1, ok: true},
+		{query: "ab", path: "a/b.go", score: 3, ok: true},
+		{query: "B", path: "a/b.go", ok: false},
+		{query: "B", path: "a/B.go", score: 0, ok: true},
+		{query: "x", path: "a/b.go", ok: false},
+		{query: "bb", path: "a/b.go", ok: false},
+	}
+	for _, test := range tests {
+		score, ok := fuzzyScore(test.query, test.path)
+		if score != test.score || ok != test.ok {
+			t.Errorf("fuzzyScore(%q, %q)=%d,%v, want %d,%v",
+				test.query, test.path, score, ok, test.score, test.ok)
+		}
+	}
+}
+
+func TestCmd_OpenFinder(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	mkSubDir(dir, "ui")
+	mkSubDir(dir, ".git")
+	touch(dir, "main.go")
+	touch(dir, "ui/sheet.go")
+	touch(dir, "ui/sheet_test.go")
+	touch(dir, ".git/config")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, dir+"/")
+	)
+	c.Add(s)
+	if err := execCmd(c, s, "Open"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	f := findSheet(w, filepath.Join(dir, "+Open"))
+	if f == nil {
+		t.Fatalf("no +Open sheet")
+	}
+	if str, want := f.body.text.String(), "\nmain.go\nui/sheet.go\nui/sheet_test.go"; str != want {
+		t.Errorf("body=%q, want %q", str, want)
+	}
+
+	f.body.Change(edit.Diffs{{At: [2]int64{0, 0}, Text: rope.New("sht")}})
+	if !finderUpdate(f) {
+		t.Errorf("finderUpdate=false, want true")
+	}
+	if str, want := f.body.text.String(), "sht\nui/sheet.go\nui/sheet_test.go"; str != want {
+		t.Errorf("body=%q, want %q", str, want)
+	}
+	if finderUpdate(f) {
+		t.Errorf("finderUpdate=true with no change, want false")
+	}
+	if got := f.finder.match("test"); !reflect.DeepEqual(got, []string{"ui/sheet_test.go"}) {
+		t.Errorf("match(test)=%v, want [ui/sheet_test.go]", got)
+	}
+
+	// Return on the query line opens the best match.
+	setColFocus(c, f)
+	setDot(f.body, 1, 3, 3)
+	f.Rune('\n')
+	if findSheet(w, filepath.Join(dir, "ui", "sheet.go")) == nil {
+		t.Errorf("ui/sheet.go not opened")
+	}
+	if str, want := f.body.text.String(), "sht\nui/sheet.go\nui/sheet_test.go"; str != want {
+		t.Errorf("body=%q, want %q", str, want)
+	}
+
+	// Open focuses the existing +Open sheet.
+	n := len(c.rows)
+	if err := execCmd(c, s, "Open"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if len(c.rows) != n {
+		t.Errorf("len(c.rows)=%d, want %d", len(c.rows), n)
+	}
+}
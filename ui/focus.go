@@ -0,0 +1,64 @@
+package ui
+
+// focusRune handles the focus-moving runes typed with the control modifier:
+//
+//	ctrl-tab        focuses the next row, continuing with the next column
+//	ctrl-shift-tab  focuses the previous row
+//	ctrl-esc        focuses the body of the focused sheet if its tag is focused,
+//	                and its tag otherwise
+//
+// The rows include the tags of the columns.
+// Moving past the last row wraps to the first, and back.
+// It returns whether the rune was handled.
+func focusRune(w *Win, r rune) bool {
+	switch r {
+	case '\t':
+		if w.mods[1] {
+			focusNextRow(w, -1)
+		} else {
+			focusNextRow(w, 1)
+		}
+	case esc:
+		s := getSheet(w.Col.Row)
+		if s == nil {
+			return false
+		}
+		toggleSheetFocus(s)
+	default:
+		return false
+	}
+	closePopup(w)
+	return true
+}
+
+// focusNextRow focuses the row delta rows from the focused row
+// in the order of the columns and their rows.
+func focusNextRow(w *Win, delta int) {
+	type pos struct{ c, r int }
+	var rows []pos
+	var i int
+	for ci, c := range w.cols {
+		for ri, r := range c.rows {
+			if c == w.Col && r == c.Row {
+				i = len(rows)
+			}
+			rows = append(rows, pos{c: ci, r: ri})
+		}
+	}
+	i = (i + delta + len(rows)) % len(rows)
+	c := w.cols[rows[i].c]
+	setWinFocus(w, c)
+	setColFocus(c, c.rows[rows[i].r])
+}
+
+// toggleSheetFocus focuses the body of the sheet if its tag is focused,
+// and its tag otherwise.
+func toggleSheetFocus(s *Sheet) {
+	next := s.tag
+	if s.TextBox == s.tag {
+		next = s.body
+	}
+	s.TextBox.Focus(false)
+	s.TextBox = next
+	s.TextBox.Focus(true)
+}
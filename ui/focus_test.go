@@ -0,0 +1,58 @@
+package ui
+
+import "testing"
+
+func TestFocusRune(t *testing.T) {
+	var (
+		w  = newTestWin()
+		c0 = w.cols[0]
+		c1 = w.Add()
+		a  = NewSheet(w, "a")
+		b  = NewSheet(w, "b")
+		c  = NewSheet(w, "c")
+	)
+	c0.Add(a)
+	c0.Add(b)
+	c1.Add(c)
+	setWinFocus(w, c0)
+	setColFocus(c0, a)
+
+	w.mods[3] = true
+	for _, test := range []struct {
+		shift bool
+		col   *Col
+		row   Row
+	}{
+		{col: c0, row: b},
+		{col: c1, row: c1.rows[0]},
+		{col: c1, row: c},
+		{col: c0, row: c0.rows[0]},
+		{shift: true, col: c1, row: c},
+		{shift: true, col: c1, row: c1.rows[0]},
+	} {
+		w.mods[1] = test.shift
+		w.Rune('\t')
+		if w.Col != test.col || w.Col.Row != test.row {
+			t.Fatalf("focused column %d row %d, want column %d row %d",
+				colIndex(w.Col), focusedRow(w.Col), colIndex(test.col), rowIndex(test.col, test.row))
+		}
+	}
+	w.mods[1] = false
+
+	w.Rune('\t')
+	w.Rune(esc)
+	if c.TextBox != c.tag {
+		t.Errorf("after ctrl-esc, the body is focused, want the tag")
+	}
+	w.Rune(esc)
+	if c.TextBox != c.body {
+		t.Errorf("after ctrl-esc twice, the tag is focused, want the body")
+	}
+	w.mods[3] = false
+
+	for _, s := range []*Sheet{a, b, c} {
+		if text := s.body.text.String(); text != "" {
+			t.Errorf("%s body text=%q, want empty", s.Title(), text)
+		}
+	}
+}
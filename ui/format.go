@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// putFormat formats the text box with its formatter command,
+// which reads the text from its standard input
+// and writes the formatted text to its standard output,
+// before it is written to the file at path.
+// The text box is changed by the fewest whole lines possible,
+// so dot is kept unless its line changed.
+// If the command fails, the text box is not changed
+// and the error is returned,
+// with the command's mentions of its standard input
+// replaced by the path.
+// A missing formatter command is not an error.
+func putFormat(b *TextBox, path string) error {
+	if _, err := exec.LookPath(b.format[0]); err != nil {
+		return nil
+	}
+	old := b.text.String()
+	src, err := filter(b.format, filepath.Dir(path), old)
+	if err != nil {
+		return errors.New(strings.Replace(err.Error(), "<standard input>", path, -1))
+	}
+	b.Change(lineDiffs(old, src))
+	return nil
+}
+
+// filter returns the output of the command, run in the directory dir,
+// with src as its standard input.
+// If the command fails, the error is its standard error output, if any.
+func filter(cmd []string, dir, src string) (string, error) {
+	c := exec.Command(cmd[0], cmd[1:]...)
+	c.Dir = dir
+	c.Stdin = strings.NewReader(src)
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
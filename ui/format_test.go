@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestPutFormat(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file.txt")
+	write(path, "")
+
+	w := newTestWin()
+	s := NewSheet(w, path)
+	if err := s.Get(); err != nil {
+		t.Fatalf("Get()=%v, want nil", err)
+	}
+	s.body.format = []string{"sed", "s/a/A/"}
+	s.body.SetText(rope.New("abc\nxyz\nabc\n"))
+	setDot(s.body, 1, 5, 6)
+	if err := s.Put(); err != nil {
+		t.Fatalf("Put()=%v, want nil", err)
+	}
+	if got, want := read(path), "Abc\nxyz\nAbc\n"; got != want {
+		t.Errorf("wrote %q, want %q", got, want)
+	}
+	if got := s.body.dots[1].At; got != [2]int64{5, 6} {
+		t.Errorf("dot=%v, want [5 6]", got)
+	}
+
+	// A failing formatter does not change the text,
+	// and its error names the file.
+	s.body.format = []string{"sh", "-c", "echo '<standard input>:2: bad' >&2; exit 1"}
+	s.body.SetText(rope.New("abc\n"))
+	if err := s.Put(); err != nil {
+		t.Fatalf("Put()=%v, want nil", err)
+	}
+	if got, want := read(path), "abc\n"; got != want {
+		t.Errorf("wrote %q, want %q", got, want)
+	}
+	if got, want := w.outputBuffer.String(), path+":2: bad\n"; !strings.Contains(got, want) {
+		t.Errorf("output=%q, want %q", got, want)
+	}
+
+	// A missing formatter is not an error.
+	w.outputBuffer.Reset()
+	s.body.format = []string{"no-such-formatter"}
+	if err := s.Put(); err != nil {
+		t.Fatalf("Put()=%v, want nil", err)
+	}
+	if got := w.outputBuffer.String(); got != "" {
+		t.Errorf("output=%q, want empty", got)
+	}
+}
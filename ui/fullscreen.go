@@ -0,0 +1,10 @@
+package ui
+
+// Fullscreen returns whether the window is to be shown fullscreen.
+// It is toggled by the Fullscreen command;
+// the window's driver is responsible for showing it so,
+// after which the window is resized as usual.
+func (w *Win) Fullscreen() bool { return w.fullscreen }
+
+// SetFullscreen sets whether the window is to be shown fullscreen.
+func (w *Win) SetFullscreen(fullscreen bool) { w.fullscreen = fullscreen }
@@ -0,0 +1,265 @@
+package ui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// gitDiffText is appended to the first line of the tag of a sheet
+// whose file differs from the git HEAD commit.
+const gitDiffText = " Gdiff"
+
+// git returns the output of gitCmd with the arguments,
+// run in the directory dir.
+func git(dir string, args ...string) (string, error) {
+	cmd := append(append([]string{}, gitCmd...), args...)
+	return filter(cmd, dir, "")
+}
+
+// gitRoot returns the top-level directory
+// of the git work tree containing the directory.
+func gitRoot(dir string) (string, error) {
+	out, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return filepath.FromSlash(strings.TrimSpace(out)), nil
+}
+
+// gdiff implements the Gdiff command: Gdiff [args].
+// With no arguments, the changes of the sheet's file,
+// or of the sheet's directory, from the HEAD commit are shown.
+// Otherwise the arguments are passed to git diff.
+// The diff is shown in the +Gdiff sheet of the work tree,
+// in which looking anywhere on a line of a hunk
+// opens the file at the line.
+func gdiff(c *Col, s *Sheet, args string) error {
+	dir, err := abs(s, ".")
+	if err != nil {
+		return err
+	}
+	root, err := gitRoot(dir)
+	if err != nil {
+		return err
+	}
+	diffArgs := append([]string{"diff"}, strings.Fields(args)...)
+	if args == "" {
+		diffArgs = append(diffArgs, "HEAD", "--")
+		if s != nil && isFileSheet(s) {
+			diffArgs = append(diffArgs, filepath.Base(s.Title()))
+		} else {
+			diffArgs = append(diffArgs, ".")
+		}
+	}
+	out, err := git(dir, diffArgs...)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		return errors.New(msg("no differences"))
+	}
+	showScratch(c, filepath.Join(root, "+Gdiff"), out)
+	return nil
+}
+
+// gdiffClickText returns the path:line address
+// of the +Gdiff line containing the address,
+// and whether there is one.
+// Added and unchanged lines of a hunk are at their line in the new file,
+// and removed lines are at the line following them.
+func gdiffClickText(s *Sheet, addr [2]int64) (string, bool) {
+	if addr[0] < addr[1] || filepath.Base(s.Title()) != "+Gdiff" {
+		return "", false
+	}
+	text := s.body.text.String()
+	end := strings.IndexByte(text[addr[0]:], '\n')
+	if end < 0 {
+		end = len(text)
+	} else {
+		end += int(addr[0])
+	}
+	var path string
+	line := -1 // line number of the next hunk line; -1 if not in a hunk
+	sc := bufio.NewScanner(strings.NewReader(text[:end]))
+	for sc.Scan() {
+		l := sc.Text()
+		switch {
+		case strings.HasPrefix(l, "diff "):
+			path, line = "", -1
+		case line < 0 && strings.HasPrefix(l, "--- a/"):
+			path = l[len("--- a/"):]
+		case line < 0 && strings.HasPrefix(l, "+++ b/"):
+			path = l[len("+++ b/"):]
+		case strings.HasPrefix(l, "@@ "):
+			line = hunkStart(l)
+		case line >= 0 && !strings.HasPrefix(l, "-"):
+			line++
+		}
+	}
+	switch {
+	case path == "":
+		return "", false
+	case line < 0:
+		return path, true
+	}
+	// The last line scanned was the clicked line.
+	if l := text[strings.LastIndexByte(text[:end], '\n')+1 : end]; line > 0 && !strings.HasPrefix(l, "-") && !strings.HasPrefix(l, "@@ ") {
+		line--
+	}
+	return path + ":" + strconv.Itoa(line), true
+}
+
+// hunkStart returns the starting line in the new file
+// of a hunk header of the form @@ -a,b +c,d @@.
+func hunkStart(header string) int {
+	i := strings.Index(header, " +")
+	if i < 0 {
+		return 1
+	}
+	f := header[i+2:]
+	if j := strings.IndexAny(f, ", "); j >= 0 {
+		f = f[:j]
+	}
+	n, err := strconv.Atoi(f)
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// gblame implements the Gblame command.
+// The git blame of the sheet's file is shown
+// in the +Gblame sheet of its directory
+// as lines of the form path:line: commit (author date) text,
+// which can be looked at to open the file at the line.
+func gblame(c *Col, s *Sheet) error {
+	if !isFileSheet(s) {
+		return errors.New(msg("%s is not a file", s.Title()))
+	}
+	dir, name := filepath.Split(s.Title())
+	out, err := git(dir, "blame", "--date=short", "--", name)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	for i, l := range strings.SplitAfter(strings.TrimSuffix(out, "\n"), "\n") {
+		fmt.Fprintf(&b, "%s:%d: %s", name, i+1, blameLine(l))
+	}
+	b.WriteString("\n")
+	showScratch(c, filepath.Join(dir, "+Gblame"), b.String())
+	return nil
+}
+
+// blameLine returns the line of git blame output
+// without the line number that ends its annotation.
+func blameLine(l string) string {
+	i := strings.IndexByte(l, ')')
+	if i < 0 {
+		return l
+	}
+	ann := strings.TrimRight(l[:i], "0123456789")
+	return strings.TrimRight(ann, " ") + l[i:]
+}
+
+// gstatus implements the Gstatus command.
+// The short git status of the work tree
+// containing the sheet's directory
+// is shown in the +Gstatus sheet of the work tree,
+// in which looking anywhere on a line opens its file.
+func gstatus(c *Col, s *Sheet) error {
+	dir, err := abs(s, ".")
+	if err != nil {
+		return err
+	}
+	root, err := gitRoot(dir)
+	if err != nil {
+		return err
+	}
+	out, err := git(root, "status", "--short")
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		out = msg("nothing to commit") + "\n"
+	}
+	showScratch(c, filepath.Join(root, "+Gstatus"), out)
+	return nil
+}
+
+// gstatusClickText returns the path of the +Gstatus line
+// containing the address, and whether there is one.
+func gstatusClickText(s *Sheet, addr [2]int64) (string, bool) {
+	if addr[0] < addr[1] || filepath.Base(s.Title()) != "+Gstatus" {
+		return "", false
+	}
+	text := s.body.text.String()
+	start := strings.LastIndexByte(text[:addr[0]], '\n') + 1
+	line := text[start:]
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	if len(line) < 4 || line[2] != ' ' {
+		return "", false
+	}
+	path := line[3:]
+	if i := strings.Index(path, " -> "); i >= 0 {
+		path = path[i+len(" -> "):]
+	}
+	return path, true
+}
+
+// gcommit implements the Gcommit command: Gcommit message.
+// The changes of all tracked files of the work tree
+// containing the sheet's directory are committed with the message.
+// The output of git is shown in the +Gcommit sheet of the work tree,
+// and the tags of the open sheets are updated.
+func gcommit(c *Col, s *Sheet, message string) error {
+	if message == "" {
+		return errors.New(msg("usage: Gcommit message"))
+	}
+	dir, err := abs(s, ".")
+	if err != nil {
+		return err
+	}
+	root, err := gitRoot(dir)
+	if err != nil {
+		return err
+	}
+	out, err := git(root, "commit", "-a", "-m", message)
+	if err != nil {
+		return err
+	}
+	showScratch(c, filepath.Join(root, "+Gcommit"), out)
+	for _, col := range c.win.cols {
+		for _, r := range col.rows {
+			if sh := getSheet(r); sh != nil {
+				setGitText(sh)
+			}
+		}
+	}
+	return nil
+}
+
+// gitModified returns whether the file
+// differs from the HEAD commit of its git work tree.
+// A file not in a work tree is not modified.
+func gitModified(path string) bool {
+	dir, name := filepath.Split(path)
+	_, err := git(dir, "diff", "--quiet", "HEAD", "--", name)
+	if err, ok := err.(*exec.ExitError); ok {
+		return err.ExitCode() == 1
+	}
+	return false
+}
+
+// setGitText adds or removes gitDiffText
+// from the tag of the sheet
+// according to whether its file differs from the HEAD commit.
+func setGitText(s *Sheet) {
+	setTagText(s, gitDiffText, isFileSheet(s) && gitModified(s.Title()))
+}
@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+// gitTestRepo returns a new git work tree
+// with a.go committed.
+func gitTestRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	dir, err := filepath.EvalSymlinks(tmpdir())
+	if err != nil {
+		t.Fatalf("EvalSymlinks failed: %v", err)
+	}
+	write(filepath.Join(dir, "a.go"), "package a\n\nfunc F() {}\n\nfunc G() {}\n")
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "Tester"},
+		{"config", "user.email", "tester@example.com"},
+		{"config", "commit.gpgsign", "false"},
+		{"add", "a.go"},
+		{"commit", "-q", "-m", "first"},
+	} {
+		if _, err := git(dir, args...); err != nil {
+			os.RemoveAll(dir)
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+	}
+	return dir
+}
+
+func TestCmd_Gdiff(t *testing.T) {
+	dir := gitTestRepo(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.go")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, path)
+	)
+	c.Add(s)
+	if err := s.Get(); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if strings.Contains(s.tag.text.String(), gitDiffText) {
+		t.Errorf("unmodified tag=%q, want no %q", s.tag.text.String(), gitDiffText)
+	}
+	if err := execCmd(c, s, "Gdiff"); err == nil {
+		t.Errorf("Gdiff of an unmodified file succeeded, want an error")
+	}
+
+	s.body.SetText(rope.New("package a\n\nfunc F() {}\n\nfunc H() {}\n"))
+	if err := s.Put(); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if tag := s.tag.text.String(); !strings.HasSuffix(tag, gitDiffText) {
+		t.Errorf("modified tag=%q, want suffix %q", tag, gitDiffText)
+	}
+	if err := execCmd(c, s, "Gdiff"); err != nil {
+		t.Fatalf("Gdiff failed: %v", err)
+	}
+	d := findSheet(w, filepath.Join(dir, "+Gdiff"))
+	if d == nil {
+		t.Fatalf("no +Gdiff sheet")
+	}
+	text := d.body.text.String()
+	for _, test := range []struct {
+		line, want string
+	}{
+		{line: "+++ b/a.go", want: "a.go"},
+		{line: "@@ ", want: "a.go:2"},
+		{line: " func F() {}", want: "a.go:3"},
+		{line: "-func G() {}", want: "a.go:5"},
+		{line: "+func H() {}", want: "a.go:5"},
+	} {
+		i := strings.Index(text, "\n"+test.line)
+		if i < 0 {
+			t.Fatalf("no line %q in %q", test.line, text)
+		}
+		at := int64(i + 2)
+		if got, ok := gdiffClickText(d, [2]int64{at, at}); !ok || got != test.want {
+			t.Errorf("gdiffClickText(%q)=%q,%v, want %q,true", test.line, got, ok, test.want)
+		}
+	}
+
+	if err := execCmd(c, s, "Gstatus"); err != nil {
+		t.Fatalf("Gstatus failed: %v", err)
+	}
+	st := findSheet(w, filepath.Join(dir, "+Gstatus"))
+	if st == nil {
+		t.Fatalf("no +Gstatus sheet")
+	}
+	if got, ok := gstatusClickText(st, [2]int64{1, 1}); !ok || got != "a.go" {
+		t.Errorf("gstatusClickText=%q,%v, want a.go,true", got, ok)
+	}
+
+	if err := execCmd(c, s, "Gcommit"); err == nil {
+		t.Errorf("Gcommit with no message succeeded, want an error")
+	}
+	if err := execCmd(c, s, "Gcommit second commit"); err != nil {
+		t.Fatalf("Gcommit failed: %v", err)
+	}
+	if findSheet(w, filepath.Join(dir, "+Gcommit")) == nil {
+		t.Errorf("no +Gcommit sheet")
+	}
+	if tag := s.tag.text.String(); strings.Contains(tag, gitDiffText) {
+		t.Errorf("committed tag=%q, want no %q", tag, gitDiffText)
+	}
+	if out, err := git(dir, "log", "-1", "--format=%s"); err != nil || out != "second commit\n" {
+		t.Errorf("git log=%q,%v, want second commit", out, err)
+	}
+}
+
+func TestCmd_Gblame(t *testing.T) {
+	dir := gitTestRepo(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.go")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, path)
+	)
+	c.Add(s)
+	if err := s.Get(); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if err := execCmd(c, s, "Gblame"); err != nil {
+		t.Fatalf("Gblame failed: %v", err)
+	}
+	b := findSheet(w, filepath.Join(dir, "+Gblame"))
+	if b == nil {
+		t.Fatalf("no +Gblame sheet")
+	}
+	lines := strings.Split(strings.TrimSuffix(b.body.text.String(), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("+Gblame has %d lines, want 5: %q", len(lines), lines)
+	}
+	if l := lines[2]; !strings.HasPrefix(l, "a.go:3: ") || !strings.Contains(l, "(Tester ") || !strings.HasSuffix(l, ") func F() {}") {
+		t.Errorf("+Gblame line 3=%q, want a.go:3: commit (Tester date) func F() {}", l)
+	}
+	at := int64(len(lines[0]) + len(lines[1]) + 5)
+	txt, ok := grepClickText(b, [2]int64{at, at})
+	if !ok || txt != "a.go:3" {
+		t.Fatalf("grepClickText=%q,%v, want a.go:3,true", txt, ok)
+	}
+	if err := lookText(c, b, txt); err != nil {
+		t.Fatalf("lookText failed: %v", err)
+	}
+	if dot := s.body.dots[1].At; dot != [2]int64{11, 23} {
+		t.Errorf("dot=%v, want [11 23]", dot)
+	}
+}
@@ -0,0 +1,237 @@
+package ui
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// openFiles implements the Open command: Open pattern ...
+// Each pattern is expanded by expandTilde, expandBraces, and glob,
+// relative to the directory of the sheet, if any,
+// and each matching file is opened in the column.
+// If more than maxOpenFiles match,
+// the command must be executed twice to open them.
+// With no patterns, it opens a +Open sheet to find files (see finder).
+func openFiles(c *Col, s *Sheet, args string) error {
+	if args == "" {
+		return openFinder(c, s)
+	}
+	paths, err := expandPatterns(s, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New(msg("usage: Open pattern ..."))
+	}
+	w := c.win
+	key := "Open " + strings.Join(paths, " ")
+	if len(paths) > maxOpenFiles && w.confirm != key {
+		w.confirm = key
+		return errors.New(msg("%d files match; execute again to open them", len(paths)))
+	}
+	w.confirm = ""
+	for _, p := range paths {
+		if focusSheet(w, p) || focusSheet(w, ensureTrailingSlash(p)) {
+			continue
+		}
+		if err := openSheet(c, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OpenArgs opens each of the files and directories
+// given on the command line in its own sheet.
+// Relative paths are relative to the current directory,
+// and a path that does not exist is opened in an empty sheet,
+// to be created by Put.
+// The sheets are spread among up to maxArgCols columns,
+// which are added as needed.
+// Errors are shown in the Output sheet.
+func (w *Win) OpenArgs(paths []string) {
+	n := len(paths)
+	if n > maxArgCols {
+		n = maxArgCols
+	}
+	for len(w.cols) < n {
+		w.Add()
+	}
+	for i, p := range paths {
+		p, err := filepath.Abs(p)
+		if err != nil {
+			w.OutputString(err.Error() + "\n")
+			continue
+		}
+		c := w.cols[i%n]
+		if focusSheet(w, p) || focusSheet(w, ensureTrailingSlash(p)) {
+			continue
+		}
+		switch err := openSheet(c, p); {
+		case os.IsNotExist(err):
+			c.Add(NewSheet(w, p))
+		case err != nil:
+			w.OutputString(err.Error() + "\n")
+		}
+	}
+	if n > 0 {
+		setWinFocus(w, w.cols[0])
+	}
+}
+
+// expandPatterns returns the paths matching the space-separated patterns,
+// each expanded by expandTilde, expandBraces, and glob,
+// relative to the directory of the sheet, if any.
+// It is an error if a pattern matches no paths.
+func expandPatterns(s *Sheet, args string) ([]string, error) {
+	var paths []string
+	for _, arg := range strings.Fields(args) {
+		var n int
+		for _, p := range expandBraces(expandTilde(arg)) {
+			p, err := abs(s, p)
+			if err != nil {
+				return nil, err
+			}
+			ms, err := glob(p)
+			if err != nil {
+				return nil, err
+			}
+			n += len(ms)
+			paths = append(paths, ms...)
+		}
+		if n == 0 {
+			return nil, errors.New(msg("no files match %s", arg))
+		}
+	}
+	return paths, nil
+}
+
+// expandTilde returns the path with a leading ~
+// replaced by the home directory.
+func expandTilde(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return home + path[1:]
+}
+
+// expandBraces returns the expansions of the shell-style
+// brace alternatives in the string: a{b,c}d expands to abd and acd.
+// Braces with no comma are not expanded.
+func expandBraces(str string) []string {
+	open := -1
+	depth := 0
+	var commas []int
+	for i := 0; i < len(str); i++ {
+		switch str[i] {
+		case '{':
+			if depth == 0 {
+				open, commas = i, nil
+			}
+			depth++
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			if depth--; depth > 0 || len(commas) == 0 {
+				continue
+			}
+			var strs []string
+			prefix, suffix := str[:open], str[i+1:]
+			start := open + 1
+			for _, end := range append(commas, i) {
+				strs = append(strs, expandBraces(prefix+str[start:end]+suffix)...)
+				start = end + 1
+			}
+			return strs
+		}
+	}
+	return []string{str}
+}
+
+// glob returns the names of the files matching the absolute path pattern,
+// using filepath.Match syntax for each element of the path.
+// The element ** matches zero or more directories,
+// and, as in the shell, metacharacters do not match a leading dot.
+// Directories only match patterns with no metacharacters.
+func glob(pattern string) ([]string, error) {
+	if !hasMeta(pattern) {
+		if _, err := os.Stat(pattern); err != nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+	root := string(filepath.Separator)
+	elems := strings.Split(strings.TrimPrefix(pattern, root), root)
+	seen := make(map[string]bool)
+	if err := globDir(root, elems, seen); err != nil {
+		return nil, err
+	}
+	var paths []string
+	for p := range seen {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func globDir(dir string, elems []string, seen map[string]bool) error {
+	if len(elems) == 0 {
+		if st, err := os.Stat(dir); err == nil && !st.IsDir() {
+			seen[dir] = true
+		}
+		return nil
+	}
+	elem, rest := elems[0], elems[1:]
+	if !hasMeta(elem) {
+		return globDir(filepath.Join(dir, elem), rest, seen)
+	}
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	if elem == "**" {
+		if err := globDir(dir, rest, seen); err != nil {
+			return err
+		}
+		for _, fi := range fis {
+			if fi.IsDir() && !strings.HasPrefix(fi.Name(), ".") {
+				if err := globDir(filepath.Join(dir, fi.Name()), elems, seen); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for _, fi := range fis {
+		if strings.HasPrefix(fi.Name(), ".") && !strings.HasPrefix(elem, ".") {
+			continue
+		}
+		ok, err := filepath.Match(elem, fi.Name())
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := globDir(filepath.Join(dir, fi.Name()), rest, seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func hasMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[`)
+}
@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		str  string
+		want []string
+	}{
+		{str: "", want: []string{""}},
+		{str: "abc", want: []string{"abc"}},
+		{str: "a{b}c", want: []string{"a{b}c"}},
+		{str: "a{b,c}d", want: []string{"abd", "acd"}},
+		{str: "{a,b}{c,d}", want: []string{"ac", "ad", "bc", "bd"}},
+		{str: "a{b,c{d,e}}", want: []string{"ab", "acd", "ace"}},
+		{str: "a{,b}", want: []string{"a", "ab"}},
+		{str: "a}{b,c", want: []string{"a}{b,c"}},
+	}
+	for _, test := range tests {
+		if got := expandBraces(test.str); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("expandBraces(%q)=%q, want %q", test.str, got, test.want)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	mkSubDir(dir, "a")
+	mkSubDir(dir, "a/b")
+	mkSubDir(dir, ".git")
+	for _, f := range []string{"x.go", "y.c", "a/z.go", "a/b/w.go", ".git/h.go"} {
+		touch(dir, f)
+	}
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{pattern: "x.go", want: []string{"x.go"}},
+		{pattern: "a", want: []string{"a"}},
+		{pattern: "missing", want: nil},
+		{pattern: "*.go", want: []string{"x.go"}},
+		{pattern: "*", want: []string{"x.go", "y.c"}},
+		{pattern: "**/*.go", want: []string{"a/b/w.go", "a/z.go", "x.go"}},
+		{pattern: "a/**/*.go", want: []string{"a/b/w.go", "a/z.go"}},
+		{pattern: "*/*.go", want: []string{"a/z.go"}},
+	}
+	for _, test := range tests {
+		var want []string
+		for _, w := range test.want {
+			want = append(want, filepath.Join(dir, w))
+		}
+		got, err := glob(filepath.Join(dir, test.pattern))
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("glob(%q)=%q, %v, want %q, nil", test.pattern, got, err, want)
+		}
+	}
+}
+
+func TestOpenArgs(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	write(filepath.Join(dir, "a.txt"), "a\n")
+	write(filepath.Join(dir, "b.txt"), "b\n")
+	os.Mkdir(filepath.Join(dir, "d"), 0755)
+
+	w := newTestWin()
+	w.OpenArgs([]string{
+		filepath.Join(dir, "a.txt"),
+		filepath.Join(dir, "d"),
+		filepath.Join(dir, "b.txt"),
+		filepath.Join(dir, "new.txt"),
+	})
+	if len(w.cols) != 2 {
+		t.Fatalf("%d columns, want 2", len(w.cols))
+	}
+	if w.Col != w.cols[0] {
+		t.Errorf("focused column is not the first")
+	}
+	var titles [][]string
+	for _, c := range w.cols {
+		var ts []string
+		for _, r := range c.rows[1:] {
+			ts = append(ts, getSheet(r).Title())
+		}
+		titles = append(titles, ts)
+	}
+	want := [][]string{
+		{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")},
+		{filepath.Join(dir, "d") + "/", filepath.Join(dir, "new.txt")},
+	}
+	if !reflect.DeepEqual(titles, want) {
+		t.Errorf("titles=%v, want %v", titles, want)
+	}
+	if s := findSheet(w, filepath.Join(dir, "a.txt")); s == nil || s.body.text.String() != "a\n" {
+		t.Errorf("a.txt not read")
+	}
+}
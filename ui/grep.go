@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/eaburns/T/re1"
+)
+
+// grep implements the Grep command: Grep regexp [path ...]
+// The regexp is either delimited by / or ends at the first unescaped space.
+// Each file under the paths, by default the directory of the sheet,
+// is searched for lines matching the regexp,
+// and the matches are shown in the +Grep sheet of the directory
+// as lines of the form path:line: text.
+// Files and directories beginning with . are skipped, as are binary files.
+func grep(c *Col, s *Sheet, args string) error {
+	if args == "" {
+		return errors.New(msg("usage: Grep regexp [path ...]"))
+	}
+	delim, t := ' ', args
+	if args[0] == '/' {
+		delim, t = '/', args[1:]
+	}
+	re, rest, err := re1.New(t, re1.Opts{Delimiter: delim})
+	if err != nil {
+		return err
+	}
+	pat := t[:len(t)-len(rest)]
+	rest = strings.TrimPrefix(rest, string(delim))
+	dir, err := abs(s, ".")
+	if err != nil {
+		return err
+	}
+	paths := strings.Fields(rest)
+	if len(paths) == 0 {
+		paths = []string{dir}
+	}
+	var b strings.Builder
+	var n int
+	for _, p := range paths {
+		p, err := abs(s, expandTilde(p))
+		if err != nil {
+			return err
+		}
+		err = walkSource(p, func(path string) error {
+			return grepFile(&b, &n, re, path)
+		})
+		if err == errMaxGrep {
+			b.WriteString(msg("more than %d matches", maxGrepResults) + "\n")
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if n == 0 {
+		return errors.New(msg("no match for %s", pat))
+	}
+	showScratch(c, filepath.Join(dir, "+Grep"), b.String())
+	return nil
+}
+
+var errMaxGrep = errors.New("too many matches")
+
+// grepFile writes the lines of the file matching the regexp to b,
+// counting them in n.
+// It returns errMaxGrep if n exceeds maxGrepResults.
+func grepFile(b *strings.Builder, n *int, re *re1.Regexp, path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if isBinary(data) {
+		return nil
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, len(data)+1)
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		if m := re.Find(strings.NewReader(text)); m == nil || m[0] < 0 {
+			continue
+		}
+		if *n++; *n > maxGrepResults {
+			return errMaxGrep
+		}
+		fmt.Fprintf(b, "%s:%d: %s\n", path, line, text)
+	}
+	return sc.Err()
+}
+
+// grepClickText returns the path:line address
+// of the +Grep, +Gblame, or +Refs result line containing the address,
+// and whether there is one.
+func grepClickText(s *Sheet, addr [2]int64) (string, bool) {
+	if base := filepath.Base(s.Title()); addr[0] < addr[1] || base != "+Grep" && base != "+Gblame" && base != "+Refs" {
+		return "", false
+	}
+	text := s.body.text.String()
+	start := strings.LastIndexByte(text[:addr[0]], '\n') + 1
+	line := text[start:]
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	i := strings.Index(line, ": ")
+	if i < 0 {
+		return "", false
+	}
+	return line[:i], true
+}
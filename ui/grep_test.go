@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCmd_Grep(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	mkSubDir(dir, "sub")
+	mkSubDir(dir, ".git")
+	write(filepath.Join(dir, "a.go"), "package a\nfunc Foo() {}\n")
+	write(filepath.Join(dir, "sub", "b.go"), "// Foo bar\nvar x = Foo()\n")
+	write(filepath.Join(dir, ".git", "c"), "Foo\n")
+	write(filepath.Join(dir, "d.bin"), "Foo\x00")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, dir+"/")
+	)
+	c.Add(s)
+	if err := execCmd(c, s, "Grep /Foo\\(/"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	g := findSheet(w, filepath.Join(dir, "+Grep"))
+	if g == nil {
+		t.Fatalf("no +Grep sheet")
+	}
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "sub", "b.go")
+	want := a + ":2: func Foo() {}\n" +
+		b + ":2: var x = Foo()\n"
+	if str := g.body.text.String(); str != want {
+		t.Errorf("+Grep=%q, want %q", str, want)
+	}
+
+	// Looking anywhere on a result line opens the file at the line.
+	at := int64(len(a + ":2: func Foo() {}\n" + b + ":2: var"))
+	txt, ok := grepClickText(g, [2]int64{at, at})
+	if !ok || txt != b+":2" {
+		t.Fatalf("grepClickText=%q,%v, want %q,true", txt, ok, b+":2")
+	}
+	if err := lookText(c, g, txt); err != nil {
+		t.Fatalf("lookText failed: %v", err)
+	}
+	f := findSheet(w, b)
+	if f == nil {
+		t.Fatalf("%s not opened", b)
+	}
+	if c.Row != Row(f) {
+		t.Errorf("focused row is not %s", b)
+	}
+	if dot := f.body.dots[1].At; dot != [2]int64{11, 25} {
+		t.Errorf("dot=%v, want [11 25]", dot)
+	}
+
+	// Paths are relative to the sheet directory.
+	if err := execCmd(c, s, "Grep bar sub"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if str, want := g.body.text.String(), b+":1: // Foo bar\n"; str != want {
+		t.Errorf("+Grep=%q, want %q", str, want)
+	}
+
+	if err := execCmd(c, s, "Grep nothing"); err == nil {
+		t.Errorf("execCmd succeeded with no matches, want an error")
+	}
+	if err := execCmd(c, s, "Grep"); err == nil {
+		t.Errorf("execCmd succeeded with no regexp, want an error")
+	}
+}
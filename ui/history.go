@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// addSearch adds the pattern of a finished search to the search history,
+// moving it to the end if it is already there.
+func addSearch(w *Win, pat string) {
+	if pat == "" {
+		return
+	}
+	for i, p := range w.searches {
+		if p == pat {
+			w.searches = append(w.searches[:i], w.searches[i+1:]...)
+			break
+		}
+	}
+	w.searches = append(w.searches, pat)
+	if n := len(w.searches) - maxSearches; n > 0 {
+		w.searches = w.searches[n:]
+	}
+	w.searched = true
+}
+
+// searchHistory replaces the search pattern
+// with an older (dir < 0) or newer (dir > 0) pattern of the search history.
+// Moving past the newest pattern restores the typed pattern.
+func searchHistory(b *TextBox, dir int) {
+	s, hist := b.search, b.win.searches
+	if s.hist < 0 {
+		s.hist = len(hist)
+	}
+	if s.hist == len(hist) {
+		s.typed = s.pat
+	}
+	s.hist += dir
+	switch {
+	case s.hist < 0:
+		s.hist = 0
+		return
+	case s.hist >= len(hist):
+		s.hist = len(hist)
+		s.pat = s.typed
+	default:
+		s.pat = []rune(hist[s.hist])
+	}
+	if len(s.pat) == 0 {
+		setDot(b, 1, s.start[0], s.start[1])
+		return
+	}
+	search(b, searchStart(s))
+}
+
+// searchHistoryPath returns the path of the search history file.
+func searchHistoryPath() string {
+	if searchHistoryFile != "" {
+		return searchHistoryFile
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "T", "searches")
+}
+
+// readSearches reads the search history file, if it exists,
+// one pattern per line, oldest first.
+func readSearches(w *Win) error {
+	path := searchHistoryPath()
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var pats []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if s.Text() != "" {
+			pats = append(pats, s.Text())
+		}
+	}
+	if n := len(pats) - maxSearches; n > 0 {
+		pats = pats[n:]
+	}
+	w.searches = pats
+	return s.Err()
+}
+
+// writeSearches writes the search history file if the history changed.
+func writeSearches(w *Win) error {
+	path := searchHistoryPath()
+	if !w.searched || path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	text := strings.Join(w.searches, "\n") + "\n"
+	if err := ioutil.WriteFile(path, []byte(text), 0666); err != nil {
+		return err
+	}
+	w.searched = false
+	return nil
+}
@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestSearchHistory(t *testing.T) {
+	w := newTestWin()
+	b := NewTextBox(w, testTextStyles, testSize)
+	b.SetText(rope.New("one two three two one"))
+
+	for _, pat := range []string{"two", "one", "two"} {
+		ctrl(b, 'f')
+		typ(b, pat+"\n")
+	}
+	if want := []string{"one", "two"}; !reflect.DeepEqual(w.searches, want) {
+		t.Fatalf("searches=%q, want %q", w.searches, want)
+	}
+
+	setDot(b, 1, 0, 0)
+	ctrl(b, 'f')
+	typ(b, "thr")
+	b.Dir(0, -1)
+	if p, d := string(b.search.pat), b.dots[1].At; p != "two" || d != [2]int64{4, 7} {
+		t.Errorf("after up, pat=%q dot=%v, want two [4 7]", p, d)
+	}
+	b.Dir(0, -1)
+	b.Dir(0, -1)
+	if p, d := string(b.search.pat), b.dots[1].At; p != "one" || d != [2]int64{0, 3} {
+		t.Errorf("after up, pat=%q dot=%v, want one [0 3]", p, d)
+	}
+	b.Dir(0, 1)
+	b.Dir(0, 1)
+	if p, d := string(b.search.pat), b.dots[1].At; p != "thr" || d != [2]int64{8, 11} {
+		t.Errorf("after down, pat=%q dot=%v, want thr [8 11]", p, d)
+	}
+	b.Dir(1, 0)
+	if b.search != nil {
+		t.Errorf("still searching after right arrow")
+	}
+}
+
+func TestSearchHistoryFile(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	defer func(f string) { searchHistoryFile = f }(searchHistoryFile)
+	searchHistoryFile = filepath.Join(dir, "T", "searches")
+
+	w := newTestWin()
+	if err := readSearches(w); err != nil || len(w.searches) != 0 {
+		t.Fatalf("readSearches: searches=%q, err=%v, want none, nil", w.searches, err)
+	}
+	if err := writeSearches(w); err != nil {
+		t.Fatalf("writeSearches failed: %v", err)
+	}
+	if _, err := os.Stat(searchHistoryFile); !os.IsNotExist(err) {
+		t.Errorf("unchanged history was written")
+	}
+	addSearch(w, "a b")
+	addSearch(w, ":/x/")
+	w.Close()
+	data, err := ioutil.ReadFile(searchHistoryFile)
+	if err != nil || string(data) != "a b\n:/x/\n" {
+		t.Errorf("history file=%q, err=%v, want %q, nil", data, err, "a b\n:/x/\n")
+	}
+
+	w = newTestWin()
+	if err := readSearches(w); err != nil {
+		t.Fatalf("readSearches failed: %v", err)
+	}
+	if want := []string{"a b", ":/x/"}; !reflect.DeepEqual(w.searches, want) {
+		t.Errorf("searches=%q, want %q", w.searches, want)
+	}
+}
@@ -0,0 +1,160 @@
+package ui
+
+import (
+	"errors"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// imports implements the Imports command: Imports [+path|-path ...].
+// With no arguments, the imports of the text box
+// are organized by goimportsCmd.
+// Otherwise, each +path is added to the imports
+// and each -path is removed from them.
+// The text box is changed by the fewest whole lines possible,
+// so dot is kept unless its line changed.
+func imports(b *TextBox, path string, args string) error {
+	old := b.text.String()
+	src := old
+	if args == "" {
+		var err error
+		if src, err = goimports(filepath.Dir(path), src); err != nil {
+			return err
+		}
+	}
+	for _, arg := range strings.Fields(args) {
+		var err error
+		switch {
+		case strings.HasPrefix(arg, "+") && len(arg) > 1:
+			src, err = addImport(src, arg[1:])
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			src, err = removeImport(src, arg[1:])
+		default:
+			err = errors.New(msg("usage: Imports [+path|-path ...]"))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	b.Change(lineDiffs(old, src))
+	return nil
+}
+
+// goimports returns the source organized by goimportsCmd,
+// run in the directory dir.
+func goimports(dir, src string) (string, error) {
+	return filter(goimportsCmd, dir, src)
+}
+
+// addImport returns the Go source with the import path added.
+// The path is added to the last parenthesized import declaration,
+// or a new import declaration if there is none.
+func addImport(src, path string) (string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ImportsOnly)
+	if err != nil {
+		return "", err
+	}
+	quoted := strconv.Quote(path)
+	for _, imp := range f.Imports {
+		if imp.Path.Value == quoted {
+			return src, nil
+		}
+	}
+	var paren, last *ast.GenDecl
+	for _, d := range f.Decls {
+		if d, ok := d.(*ast.GenDecl); ok && d.Tok == token.IMPORT {
+			last = d
+			if d.Lparen.IsValid() {
+				paren = d
+			}
+		}
+	}
+	var at int
+	var ins string
+	switch {
+	case paren != nil:
+		at = fset.Position(paren.Rparen).Offset
+		ins = "\t" + quoted + "\n"
+		if src[at-1] != '\n' {
+			ins = "\n" + ins
+		}
+	case last != nil:
+		at = fset.Position(last.End()).Offset
+		ins = "\nimport " + quoted
+	default:
+		at = fset.Position(f.Name.End()).Offset
+		ins = "\n\nimport " + quoted
+	}
+	return formatSource(src[:at] + ins + src[at:])
+}
+
+// removeImport returns the Go source with the import path removed.
+func removeImport(src, path string) (string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ImportsOnly)
+	if err != nil {
+		return "", err
+	}
+	quoted := strconv.Quote(path)
+	for _, d := range f.Decls {
+		d, ok := d.(*ast.GenDecl)
+		if !ok || d.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range d.Specs {
+			spec := spec.(*ast.ImportSpec)
+			if spec.Path.Value != quoted {
+				continue
+			}
+			var start, end int
+			if d.Lparen.IsValid() {
+				start = fset.Position(spec.Pos()).Offset
+				end = fset.Position(spec.End()).Offset
+			} else {
+				start = fset.Position(d.Pos()).Offset
+				end = fset.Position(d.End()).Offset
+			}
+			// Remove the entire line if nothing else is on it.
+			ls := strings.LastIndexByte(src[:start], '\n') + 1
+			le := strings.IndexByte(src[end:], '\n')
+			if le < 0 {
+				le = len(src) - end
+			}
+			rest := strings.TrimSpace(src[end : end+le])
+			if strings.TrimSpace(src[ls:start]) == "" && (rest == "" || strings.HasPrefix(rest, "//")) {
+				start, end = ls, end+le
+				if end < len(src) {
+					end++
+				}
+			}
+			return formatSource(src[:start] + src[end:])
+		}
+	}
+	return "", errors.New(msg("%s is not imported", path))
+}
+
+// formatSource returns the Go source formatted by go/format,
+// which also sorts the imports.
+func formatSource(src string) (string, error) {
+	out, err := format.Source([]byte(src))
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// putImports organizes the imports of the text box before it is written.
+// A missing goimportsCmd is not an error.
+func putImports(b *TextBox, path string) error {
+	if _, err := exec.LookPath(goimportsCmd[0]); err != nil {
+		return nil
+	}
+	return imports(b, path, "")
+}
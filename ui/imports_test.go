@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestAddImport(t *testing.T) {
+	tests := []struct {
+		src, path, want string
+	}{
+		{
+			src:  "package p\n\nfunc f() {}\n",
+			path: "fmt",
+			want: "package p\n\nimport \"fmt\"\n\nfunc f() {}\n",
+		},
+		{
+			src:  "package p\n\nimport \"os\"\n",
+			path: "fmt",
+			want: "package p\n\nimport \"os\"\nimport \"fmt\"\n",
+		},
+		{
+			src:  "package p\n\nimport (\n\t\"os\"\n)\n",
+			path: "fmt",
+			want: "package p\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n",
+		},
+		{
+			src:  "package p\n\nimport (\n\t\"fmt\"\n)\n",
+			path: "fmt",
+			want: "package p\n\nimport (\n\t\"fmt\"\n)\n",
+		},
+	}
+	for _, test := range tests {
+		got, err := addImport(test.src, test.path)
+		if err != nil || got != test.want {
+			t.Errorf("addImport(%q, %q)=%q, %v, want %q, nil", test.src, test.path, got, err, test.want)
+		}
+	}
+}
+
+func TestRemoveImport(t *testing.T) {
+	tests := []struct {
+		src, path, want string
+	}{
+		{
+			src:  "package p\n\nimport \"fmt\"\n\nvar x = 1\n",
+			path: "fmt",
+			want: "package p\n\nvar x = 1\n",
+		},
+		{
+			src:  "package p\n\nimport (\n\t\"fmt\" // print\n\t\"os\"\n)\n",
+			path: "fmt",
+			want: "package p\n\nimport (\n\t\"os\"\n)\n",
+		},
+		{
+			src:  "package p\n\nimport (\n\tf \"fmt\"\n\t\"os\"\n)\n",
+			path: "fmt",
+			want: "package p\n\nimport (\n\t\"os\"\n)\n",
+		},
+	}
+	for _, test := range tests {
+		got, err := removeImport(test.src, test.path)
+		if err != nil || got != test.want {
+			t.Errorf("removeImport(%q, %q)=%q, %v, want %q, nil", test.src, test.path, got, err, test.want)
+		}
+	}
+
+	if _, err := removeImport("package p\n", "fmt"); err == nil {
+		t.Errorf("removeImport(not imported)=nil, want error")
+	}
+}
+
+func TestCmd_Imports(t *testing.T) {
+	defer func(cmd []string) { goimportsCmd = cmd }(goimportsCmd)
+	// gofmt stands in for goimports; it sorts imports too.
+	goimportsCmd = []string{"gofmt"}
+
+	w := newTestWin()
+	c := w.cols[0]
+	s := NewSheet(w, "/tmp/file.go")
+	c.Add(s)
+	const src = "package p\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n\nfunc f() {}\n"
+	s.body.SetText(rope.New(src))
+	dot := int64(len(src) - 3)
+	setDot(s.body, 1, dot, dot)
+
+	if err := execCmd(c, s, "Imports"); err != nil {
+		t.Fatalf("Imports failed: %v", err)
+	}
+	const want = "package p\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc f() {}\n"
+	if got := s.body.text.String(); got != want {
+		t.Errorf("Imports text=%q, want %q", got, want)
+	}
+	if got := s.body.dots[1].At; got != [2]int64{dot, dot} {
+		t.Errorf("Imports dot=%v, want %v", got, [2]int64{dot, dot})
+	}
+
+	if err := execCmd(c, s, "Imports -os +strings"); err != nil {
+		t.Fatalf("Imports -os +strings failed: %v", err)
+	}
+	const want2 = "package p\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n\nfunc f() {}\n"
+	if got := s.body.text.String(); got != want2 {
+		t.Errorf("Imports -os +strings text=%q, want %q", got, want2)
+	}
+
+	if err := execCmd(c, s, "Imports fmt"); err == nil {
+		t.Errorf("Imports fmt=nil, want usage error")
+	}
+}
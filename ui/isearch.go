@@ -0,0 +1,156 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/re1"
+)
+
+// An isearch is the state of an incremental, keyboard-driven search.
+//
+// A search is begun by typing control-f or control-g.
+// Runes typed during the search are appended to the search pattern,
+// and dot is moved to the first match of the pattern
+// at or after the point where the search began.
+// Typing control-f or control-g again moves to the next match.
+// Newline or escape ends the search, leaving dot at the match.
+//
+// If the search was begun with control-g,
+// instead of moving dot to the match,
+// dot is extended from its original start to the end of the match.
+//
+// A pattern beginning with : is an address (see package edit)
+// evaluated with dot set to the point where the search began.
+// Otherwise the pattern is matched literally.
+// During the search, alt-c toggles ignoring case,
+// and alt-e toggles matching the pattern as a regular expression.
+// The up and down arrow keys replace the pattern
+// with an older or newer pattern of the window's search history.
+type isearch struct {
+	pat    []rune
+	start  [2]int64 // dot when the search began
+	extend bool
+	fold   bool   // ignore case
+	regexp bool   // match pat as a regular expression
+	hist   int    // index of pat in the search history; -1 if typed
+	typed  []rune // the typed pattern while browsing the history
+}
+
+// ctrlRune handles a rune typed while the control modifier is held.
+// It returns whether the rune was handled.
+func ctrlRune(b *TextBox, r rune) bool {
+	if r >= 1 && r <= 26 {
+		// Some platforms deliver control runes, ^A=1, ^B=2, ….
+		r = 'a' + r - 1
+	}
+	switch r {
+	case 'f', 'F':
+		searchNext(b, false)
+	case 'g', 'G':
+		searchNext(b, true)
+	case 'n', 'N':
+		completeRune(b)
+	case 't', 'T':
+		snippetRune(b)
+	case '|', '\\', 0x1c:
+		pipeRune(b)
+	default:
+		return killRune(b, r)
+	}
+	return true
+}
+
+func searchNext(b *TextBox, extend bool) {
+	if b.search == nil {
+		b.search = &isearch{start: b.dots[1].At, extend: extend, hist: -1}
+		return
+	}
+	if extend {
+		b.search.extend = true
+	}
+	search(b, b.dots[1].At[1])
+}
+
+// searchRune handles a rune typed during a search.
+func searchRune(b *TextBox, r rune) {
+	s := b.search
+	if b.win.mods[2] {
+		switch r {
+		case 'c', 'C':
+			s.fold = !s.fold
+			search(b, searchStart(s))
+			return
+		case 'e', 'E':
+			s.regexp = !s.regexp
+			search(b, searchStart(s))
+			return
+		}
+	}
+	switch r {
+	case '\n', esc:
+		addSearch(b.win, string(s.pat))
+		if b.dots[1].At != s.start {
+			pushJump(b.win, jump{b: b, at: s.start})
+		}
+		b.search = nil
+	case '\b', del:
+		s.hist = -1
+		if len(s.pat) > 0 {
+			s.pat = s.pat[:len(s.pat)-1]
+		}
+		if len(s.pat) == 0 {
+			setDot(b, 1, s.start[0], s.start[1])
+			return
+		}
+		search(b, searchStart(s))
+	default:
+		s.hist = -1
+		s.pat = append(s.pat, r)
+		search(b, searchStart(s))
+	}
+}
+
+func searchStart(s *isearch) int64 {
+	if s.extend {
+		return s.start[1]
+	}
+	return s.start[0]
+}
+
+// search sets dot to the first match of the search pattern
+// at or after the given address.
+// If there is no match, dot is unchanged.
+func search(b *TextBox, from int64) {
+	s := b.search
+	if len(s.pat) == 0 {
+		return
+	}
+	pat := string(s.pat)
+	if strings.HasPrefix(pat, ":") {
+		pat = pat[1:]
+	} else {
+		if s.regexp {
+			pat = escapeDelim(pat, 0, '/')
+		} else {
+			pat = strings.Replace(re1.Escape(pat), "/", `\/`, -1)
+		}
+		if s.fold {
+			pat = re1.FoldCase(pat)
+		}
+		pat = "+/" + pat + "/"
+	}
+	m, err := edit.Addr([2]int64{from, from}, pat, b.text)
+	if err != nil {
+		return
+	}
+	if s.extend {
+		if s.start[0] < m[0] {
+			m[0] = s.start[0]
+		}
+		if s.start[1] > m[1] {
+			m[1] = s.start[1]
+		}
+	}
+	setDot(b, 1, m[0], m[1])
+}
@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestSearch(t *testing.T) {
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New("Hello, World!\nHello, again"))
+
+	ctrl(b, 'f')
+	typ(b, "Hel")
+	if d := b.dots[1].At; d != [2]int64{0, 3} {
+		t.Errorf("after typing Hel, dot=%v, want [0 3]", d)
+	}
+	typ(b, "lo, a")
+	if d := b.dots[1].At; d != [2]int64{14, 22} {
+		t.Errorf("after typing Hello, a, dot=%v, want [14 22]", d)
+	}
+	typ(b, "\b\b")
+	if d := b.dots[1].At; d != [2]int64{0, 6} {
+		t.Errorf("after deleting, dot=%v, want [0 6]", d)
+	}
+	ctrl(b, 'f')
+	if d := b.dots[1].At; d != [2]int64{14, 20} {
+		t.Errorf("after next, dot=%v, want [14 20]", d)
+	}
+	typ(b, "\n")
+	if b.search != nil {
+		t.Fatalf("still searching after newline")
+	}
+	typ(b, "x")
+	if s := b.text.String(); s != "Hello, World!\nx again" {
+		t.Errorf("text=%q, want %q", s, "Hello, World!\nx again")
+	}
+}
+
+func TestSearchFoldRegexp(t *testing.T) {
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New("a.b HELLO hello a/b axb"))
+
+	ctrl(b, 'f')
+	typ(b, "hello")
+	if d := b.dots[1].At; d != [2]int64{10, 15} {
+		t.Errorf("after typing hello, dot=%v, want [10 15]", d)
+	}
+	altKey(b, 'c')
+	if d := b.dots[1].At; d != [2]int64{4, 9} {
+		t.Errorf("after ignoring case, dot=%v, want [4 9]", d)
+	}
+	typ(b, "\n")
+
+	setDot(b, 1, 4, 4)
+	ctrl(b, 'f')
+	typ(b, "a.b")
+	if d := b.dots[1].At; d != [2]int64{0, 3} {
+		t.Errorf("after typing a.b, dot=%v, want [0 3]", d)
+	}
+	altKey(b, 'e')
+	if d := b.dots[1].At; d != [2]int64{16, 19} {
+		t.Errorf("after matching a regexp, dot=%v, want [16 19]", d)
+	}
+	ctrl(b, 'f')
+	if d := b.dots[1].At; d != [2]int64{20, 23} {
+		t.Errorf("after next, dot=%v, want [20 23]", d)
+	}
+	typ(b, "\n")
+}
+
+func TestSearchExtend(t *testing.T) {
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New("begin middle end end"))
+
+	ctrl(b, 'f')
+	typ(b, "mid\n")
+	if d := b.dots[1].At; d != [2]int64{6, 9} {
+		t.Fatalf("after search, dot=%v, want [6 9]", d)
+	}
+	ctrl(b, 'g')
+	typ(b, "end")
+	if d := b.dots[1].At; d != [2]int64{6, 16} {
+		t.Errorf("after extend, dot=%v, want [6 16]", d)
+	}
+	ctrl(b, 'g')
+	if d := b.dots[1].At; d != [2]int64{6, 20} {
+		t.Errorf("after extend next, dot=%v, want [6 20]", d)
+	}
+	typ(b, "\x1b")
+	if b.search != nil {
+		t.Errorf("still searching after escape")
+	}
+}
+
+func TestSearchAddress(t *testing.T) {
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New("one\ntwo\nthree\n"))
+
+	ctrl(b, 'f')
+	typ(b, ":2")
+	if d := b.dots[1].At; d != [2]int64{4, 8} {
+		t.Errorf("after :2, dot=%v, want [4 8]", d)
+	}
+	typ(b, "\n")
+	ctrl(b, 'g')
+	typ(b, ":+/ee/")
+	if d := b.dots[1].At; d != [2]int64{4, 13} {
+		t.Errorf("after extend to :+/ee/, dot=%v, want [4 13]", d)
+	}
+}
+
+func TestSearchEndsOnDir(t *testing.T) {
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New("abc"))
+	ctrl(b, 'f')
+	b.Dir(1, 0)
+	if b.search != nil {
+		t.Errorf("still searching after Dir")
+	}
+}
+
+func ctrl(b *TextBox, r rune) {
+	b.win.mods[3] = true
+	b.Rune(r)
+	b.win.mods[3] = false
+}
+
+func typ(b *TextBox, s string) {
+	for _, r := range s {
+		b.Rune(r)
+	}
+}
@@ -0,0 +1,125 @@
+package ui
+
+import (
+	"unicode"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+)
+
+// A killRing holds text deleted by the line-editing bindings,
+// shared by all of the text boxes of a window:
+//
+//	control-a  moves to the start of the line
+//	control-e  moves to the end of the line
+//	control-k  kills to the end of the line, or the newline at its end
+//	control-u  kills to the start of the line
+//	control-w  kills the word before dot, or dot if it is non-empty
+//	control-y  yanks the most recently killed text
+//	alt-y      after a yank, replaces the yanked text with the previous kill
+type killRing struct {
+	kills []rope.Rope // the killed text, most recent last
+
+	// The last yank, if it has not been followed by another change.
+	yankBox *TextBox
+	yankAt  [2]int64
+	yankN   int // number of kills before the most recent yanked
+}
+
+// killRune handles the line-editing control runes.
+// It returns whether the rune was handled.
+func killRune(b *TextBox, r rune) bool {
+	dot := b.dots[1].At
+	switch r {
+	case 'a', 'A':
+		at := lineStart(b, dot[0])
+		b.cursorCol = -1
+		setDot(b, 1, at, at)
+	case 'e', 'E':
+		at := lineEnd(b, dot[1])
+		b.cursorCol = -1
+		setDot(b, 1, at, at)
+	case 'k', 'K':
+		end := lineEnd(b, dot[1])
+		if end == dot[1] && end < b.text.Len() {
+			end++
+		}
+		kill(b, [2]int64{dot[0], end})
+	case 'u', 'U':
+		kill(b, [2]int64{lineStart(b, dot[0]), dot[1]})
+	case 'w', 'W':
+		if dot[0] == dot[1] {
+			dot[0] = wordBefore(b, dot[0])
+		}
+		kill(b, dot)
+	case 'y', 'Y':
+		yank(b, len(b.win.kills.kills)-1)
+	default:
+		return false
+	}
+	return true
+}
+
+// kill deletes the text and adds it to the kill ring.
+func kill(b *TextBox, at [2]int64) {
+	if at[0] == at[1] {
+		return
+	}
+	k := &b.win.kills
+	k.kills = append(k.kills, rope.Slice(b.text, at[0], at[1]))
+	if len(k.kills) > maxKills {
+		k.kills = k.kills[len(k.kills)-maxKills:]
+	}
+	k.yankBox = nil
+	b.Change(edit.Diffs{{At: at, Text: rope.Empty()}})
+	b.cursorCol = -1
+	setDot(b, 1, at[0], at[0])
+}
+
+// yank replaces dot with the ith kill.
+func yank(b *TextBox, i int) {
+	k := &b.win.kills
+	if i < 0 || i >= len(k.kills) {
+		return
+	}
+	at := b.dots[1].At
+	txt := k.kills[i]
+	b.Change(edit.Diffs{{At: at, Text: txt}})
+	end := at[0] + txt.Len()
+	b.cursorCol = -1
+	setDot(b, 1, end, end)
+	k.yankBox, k.yankAt, k.yankN = b, [2]int64{at[0], end}, i
+}
+
+// yankPop replaces the text of the last yank
+// with the kill before the one yanked.
+// It returns false if dot is not just after the last yank.
+func yankPop(b *TextBox) bool {
+	k := &b.win.kills
+	if k.yankBox != b || len(k.kills) == 0 || b.dots[1].At != [2]int64{k.yankAt[1], k.yankAt[1]} {
+		return false
+	}
+	i := k.yankN - 1
+	if i < 0 {
+		i = len(k.kills) - 1
+	}
+	setDot(b, 1, k.yankAt[0], k.yankAt[1])
+	yank(b, i)
+	return true
+}
+
+// wordBefore returns the address of the start
+// of the space-delimited word before the address.
+func wordBefore(b *TextBox, at int64) int64 {
+	rs := rope.NewReverseReader(rope.Slice(b.text, 0, at))
+	r, w, err := rs.ReadRune()
+	for err == nil && unicode.IsSpace(r) {
+		at -= int64(w)
+		r, w, err = rs.ReadRune()
+	}
+	for err == nil && !unicode.IsSpace(r) {
+		at -= int64(w)
+		r, w, err = rs.ReadRune()
+	}
+	return at
+}
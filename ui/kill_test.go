@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestKillRune(t *testing.T) {
+	const text = "one two\nthree four\n"
+	tests := []struct {
+		dot     [2]int64
+		keys    string
+		want    string
+		wantDot [2]int64
+	}{
+		{dot: [2]int64{10, 10}, keys: "a", want: text, wantDot: [2]int64{8, 8}},
+		{dot: [2]int64{10, 10}, keys: "e", want: text, wantDot: [2]int64{18, 18}},
+		{dot: [2]int64{4, 4}, keys: "k", want: "one \nthree four\n", wantDot: [2]int64{4, 4}},
+		{dot: [2]int64{7, 7}, keys: "k", want: "one twothree four\n", wantDot: [2]int64{7, 7}},
+		{dot: [2]int64{4, 4}, keys: "kk", want: "one three four\n", wantDot: [2]int64{4, 4}},
+		{dot: [2]int64{14, 14}, keys: "u", want: "one two\nfour\n", wantDot: [2]int64{8, 8}},
+		{dot: [2]int64{7, 7}, keys: "w", want: "one \nthree four\n", wantDot: [2]int64{4, 4}},
+		{dot: [2]int64{8, 8}, keys: "w", want: "one three four\n", wantDot: [2]int64{4, 4}},
+		{dot: [2]int64{1, 3}, keys: "w", want: "o two\nthree four\n", wantDot: [2]int64{1, 1}},
+		{dot: [2]int64{4, 4}, keys: "key", want: text, wantDot: [2]int64{7, 7}},
+		{dot: [2]int64{0, 0}, keys: "y", want: text, wantDot: [2]int64{0, 0}},
+	}
+	for _, test := range tests {
+		w := newTestWin()
+		b := NewTextBox(w, testTextStyles, testSize)
+		b.SetText(rope.New(text))
+		setDot(b, 1, test.dot[0], test.dot[1])
+		for _, r := range test.keys {
+			ctrl(b, r)
+		}
+		if got := b.text.String(); got != test.want {
+			t.Errorf("%v %q: text=%q, want %q", test.dot, test.keys, got, test.want)
+		}
+		if got := b.dots[1].At; got != test.wantDot {
+			t.Errorf("%v %q: dot=%v, want %v", test.dot, test.keys, got, test.wantDot)
+		}
+	}
+}
+
+func TestYankPop(t *testing.T) {
+	w := newTestWin()
+	b := NewTextBox(w, testTextStyles, testSize)
+	b.SetText(rope.New("one two three"))
+	setDot(b, 1, 13, 13)
+	ctrl(b, 'w')
+	ctrl(b, 'w')
+	ctrl(b, 'w')
+	if got := b.text.String(); got != "" {
+		t.Fatalf("text=%q, want empty", got)
+	}
+
+	ctrl(b, 'y')
+	if got := b.text.String(); got != "one " {
+		t.Errorf("after yank, text=%q, want %q", got, "one ")
+	}
+	altKey(b, 'y')
+	if got := b.text.String(); got != "two " {
+		t.Errorf("after yank pop, text=%q, want %q", got, "two ")
+	}
+	altKey(b, 'y')
+	if got := b.text.String(); got != "three" {
+		t.Errorf("after second yank pop, text=%q, want %q", got, "three")
+	}
+	altKey(b, 'y')
+	if got := b.text.String(); got != "one " {
+		t.Errorf("after wrapping yank pop, text=%q, want %q", got, "one ")
+	}
+
+	typ(b, "x")
+	altKey(b, 'y')
+	if got := b.text.String(); got != "one xy" {
+		t.Errorf("after typing, text=%q, want %q", got, "one xy")
+	}
+}
+
+func altKey(b *TextBox, r rune) {
+	b.win.mods[2] = true
+	b.Rune(r)
+	b.win.mods[2] = false
+}
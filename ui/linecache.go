@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// A cachedLine is the image of a line drawn by drawLine.
+type cachedLine struct {
+	img  image.RGBA
+	used int // the Draw of the text box that last used it
+}
+
+// drawLineCached is like drawLine,
+// but the images of the lines drawn recently are reused.
+// Lines with the cursor are always drawn.
+func drawLineCached(b *TextBox, img draw.Image, at int64, y0 fixed.Int26_6, l line) {
+	rgba, ok := img.(*image.RGBA)
+	if !ok || b.dots[1].At[0] == b.dots[1].At[1] && hasCursor(b.dots[1].At[0], at, l.n) {
+		drawLine(b, img, at, y0, l)
+		return
+	}
+	r := image.Rect(0, y0.Floor(), b.size.X, (y0 + l.h).Floor()).Add(img.Bounds().Min)
+	if !r.In(img.Bounds()) {
+		drawLine(b, img, at, y0, l)
+		return
+	}
+	key := lineKey(b, y0, l)
+	if c, ok := b.lineCache[string(key)]; ok && c.img.Rect.Size() == r.Size() {
+		c.used = b.draws
+		copyRGBA(rgba, r, &c.img)
+		return
+	}
+	drawLine(b, img, at, y0, l)
+	if b.lineCache == nil {
+		b.lineCache = make(map[string]*cachedLine)
+	}
+	c := &cachedLine{used: b.draws}
+	c.img = *image.NewRGBA(image.Rectangle{Max: r.Size()})
+	copyRGBA(&c.img, c.img.Rect, subImage(&image.RGBA{}, rgba, r))
+	b.lineCache[string(key)] = c
+}
+
+// trimLineCache removes the cached lines not used by the last Draw
+// if there are more than twice the displayed lines.
+func trimLineCache(b *TextBox) {
+	if len(b.lineCache) <= 2*len(b._lines) {
+		return
+	}
+	for k, c := range b.lineCache {
+		if c.used != b.draws {
+			delete(b.lineCache, k)
+		}
+	}
+}
+
+// clearLineCache removes all cached lines,
+// such as when the font or the colors change.
+func clearLineCache(b *TextBox) {
+	b.lineCache = nil
+	b.lineFaces = b.lineFaces[:0]
+}
+
+// copyRGBA copies src to the rectangle r of dst.
+func copyRGBA(dst *image.RGBA, r image.Rectangle, src *image.RGBA) {
+	n := 4 * r.Dx()
+	for y := 0; y < r.Dy(); y++ {
+		i := dst.PixOffset(r.Min.X, r.Min.Y+y)
+		j := src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y+y)
+		copy(dst.Pix[i:i+n], src.Pix[j:j+n])
+	}
+}
+
+// lineKey returns the encoding of everything that determines
+// the image of the line drawn by drawLine at y0.
+// It is reused by the next call.
+func lineKey(b *TextBox, y0 fixed.Int26_6, l line) []byte {
+	k := b.keyBuf[:0]
+	k = appendInt(k, int64(b.size.X))
+	k = appendInt(k, int64(b.xoff))
+	k = appendInt(k, int64(y0&63))
+	k = appendInt(k, int64(l.a))
+	k = appendInt(k, int64(l.h))
+	if l.brk {
+		k = append(k, 1)
+	} else {
+		k = append(k, 0)
+	}
+	k = appendColor(k, b.style.BG)
+	for _, s := range l.spans {
+		k = appendInt(k, int64(len(s.text)))
+		k = append(k, s.text...)
+		k = appendInt(k, int64(s.w))
+		k = appendColor(k, s.style.FG)
+		k = appendColor(k, s.style.BG)
+		k = appendColor(k, s.style.Underline)
+		k = appendInt(k, int64(lineFace(b, s)))
+	}
+	b.keyBuf = k
+	return k
+}
+
+// lineFace returns a number identifying the face of the span
+// among the faces of the lines of the text box.
+func lineFace(b *TextBox, s span) int {
+	for i, f := range b.lineFaces {
+		if f == s.style.Face {
+			return i
+		}
+	}
+	b.lineFaces = append(b.lineFaces, s.style.Face)
+	return len(b.lineFaces) - 1
+}
+
+func appendInt(k []byte, v int64) []byte {
+	for i := 0; i < 8; i++ {
+		k = append(k, byte(v>>(8*i)))
+	}
+	return k
+}
+
+func appendColor(k []byte, c color.Color) []byte {
+	if c == nil {
+		return append(k, 0)
+	}
+	r, g, b, a := c.RGBA()
+	k = append(k, 1)
+	k = appendInt(k, int64(r)<<32|int64(g))
+	return appendInt(k, int64(b)<<32|int64(a))
+}
@@ -0,0 +1,361 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/eaburns/T/edit"
+)
+
+// Listen listens for JSON-RPC 2.0 connections
+// on the Unix domain socket at path,
+// through which other programs can control the window.
+// The socket is removed when the window is closed.
+//
+// Each request and response is a JSON object on a single line.
+// Requests are handled on the next tick of the window.
+// The methods are:
+//
+//	sheets  {}                 returns the titles of the sheets
+//	open    {path}             opens or focuses the sheet of a file
+//	read    {sheet}            returns the title, text, and dot of a sheet
+//	edit    {sheet, edit}      performs an Edit language command on the body
+//	                           at dot and returns the new dot
+//	dot     {sheet, addr}      sets dot of the body to an Edit language address
+//	                           and returns it
+//	exec    {sheet, command}   executes a command as if clicked in the sheet
+//	type    {text}             types the text into the focused text box
+//	image   {}                 returns a PNG image of the window, base64 encoded
+//	preedit {text}             shows an input method composition; see Win.Preedit
+//	commit  {text}             commits an input method composition; see Win.Commit
+//	log     {}                 sends the events of the editor to the connection
+//
+// After log, the connection receives an event notification
+// each time a sheet is gotten or put, dot of the focused sheet moves,
+// or a command is executed; see editorEvent.
+// Events are dropped if the connection does not keep up.
+//
+// A sheet is named by its title;
+// if it is empty, the focused sheet is used.
+// Dot is a pair of byte offsets into the text of the body.
+func (w *Win) Listen(path string) error {
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	w.listener = &listener{
+		l:     l,
+		path:  path,
+		conns: make(map[net.Conn]bool),
+		logs:  make(map[net.Conn]chan editorEvent),
+		done:  make(chan struct{}),
+	}
+	go accept(w, w.listener)
+	return nil
+}
+
+// A listener accepts the connections of Listen.
+type listener struct {
+	l    net.Listener
+	path string
+
+	mu    sync.Mutex
+	conns map[net.Conn]bool
+	logs  map[net.Conn]chan editorEvent // events to write to connections that called log
+	done  chan struct{}                 // closed when the window is closed
+}
+
+// A connWriter writes the responses and notifications of a connection.
+type connWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (cw *connWriter) send(v interface{}) error {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	return cw.enc.Encode(v)
+}
+
+type rpcRequest struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error codes of JSON-RPC 2.0.
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+// An rpcCall is a request waiting to be handled by Tick.
+type rpcCall struct {
+	req   rpcRequest
+	reply chan rpcResponse
+}
+
+// stopListening closes the listener and its connections
+// and removes its socket.
+func stopListening(w *Win) {
+	ln := w.listener
+	if ln == nil {
+		return
+	}
+	w.listener = nil
+	close(ln.done)
+	ln.l.Close()
+	ln.mu.Lock()
+	for c := range ln.conns {
+		c.Close()
+	}
+	ln.mu.Unlock()
+	os.Remove(ln.path)
+}
+
+func accept(w *Win, ln *listener) {
+	for {
+		c, err := ln.l.Accept()
+		if err != nil {
+			return
+		}
+		ln.mu.Lock()
+		ln.conns[c] = true
+		ln.mu.Unlock()
+		go serveConn(w, ln, c)
+	}
+}
+
+func serveConn(w *Win, ln *listener, c net.Conn) {
+	defer func() {
+		ln.mu.Lock()
+		delete(ln.conns, c)
+		if ch := ln.logs[c]; ch != nil {
+			close(ch)
+			delete(ln.logs, c)
+		}
+		ln.mu.Unlock()
+		c.Close()
+	}()
+	r := bufio.NewReader(c)
+	cw := &connWriter{enc: json.NewEncoder(c)}
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return
+		}
+		var req rpcRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			resp := rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: rpcParseError, Message: err.Error()}}
+			if cw.send(resp) != nil {
+				return
+			}
+			continue
+		}
+		if req.Method == "log" {
+			subscribe(ln, c, cw)
+			resp := rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage("true")}
+			if req.ID != nil && cw.send(resp) != nil {
+				return
+			}
+			continue
+		}
+		call := rpcCall{req: req, reply: make(chan rpcResponse, 1)}
+		w.mu.Lock()
+		w.calls = append(w.calls, call)
+		w.mu.Unlock()
+		wake(w)
+		var resp rpcResponse
+		select {
+		case resp = <-call.reply:
+		case <-ln.done:
+			return
+		}
+		if req.ID != nil && cw.send(resp) != nil {
+			return
+		}
+	}
+}
+
+// handleCalls handles the requests received since the last tick.
+// It returns whether there were any.
+func handleCalls(w *Win) bool {
+	w.mu.Lock()
+	calls := w.calls
+	w.calls = nil
+	w.mu.Unlock()
+	for _, call := range calls {
+		call.reply <- handleCall(w, call.req)
+	}
+	return len(calls) > 0
+}
+
+func handleCall(w *Win, req rpcRequest) rpcResponse {
+	resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	method, ok := rpcMethods[req.Method]
+	if !ok {
+		resp.Error = &rpcError{Code: rpcMethodNotFound, Message: msg("unknown method %s", req.Method)}
+		return resp
+	}
+	var params rpcParams
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+			return resp
+		}
+	}
+	result, err := method(w, params)
+	if err == nil {
+		resp.Result, err = json.Marshal(result)
+	}
+	if err != nil {
+		resp.Error = &rpcError{Code: rpcServerError, Message: err.Error()}
+	}
+	return resp
+}
+
+// rpcParams are the parameters of all methods;
+// each method uses only some of them.
+type rpcParams struct {
+	Sheet   string `json:"sheet"`
+	Path    string `json:"path"`
+	Edit    string `json:"edit"`
+	Addr    string `json:"addr"`
+	Command string `json:"command"`
+	Text    string `json:"text"`
+}
+
+type rpcSheet struct {
+	Title string   `json:"title"`
+	Text  string   `json:"text"`
+	Dot   [2]int64 `json:"dot"`
+}
+
+var rpcMethods = map[string]func(*Win, rpcParams) (interface{}, error){
+	"sheets": func(w *Win, _ rpcParams) (interface{}, error) {
+		titles := []string{}
+		for _, c := range w.cols {
+			for _, r := range c.rows {
+				if s := getSheet(r); s != nil {
+					titles = append(titles, s.Title())
+				}
+			}
+		}
+		return titles, nil
+	},
+	"open": func(w *Win, p rpcParams) (interface{}, error) {
+		path, err := filepath.Abs(p.Path)
+		if err != nil {
+			return nil, err
+		}
+		if focusSheet(w, path) {
+			return nil, nil
+		}
+		return nil, openSheet(w.Col, path)
+	},
+	"read": func(w *Win, p rpcParams) (interface{}, error) {
+		_, s, err := rpcSheetCol(w, p.Sheet)
+		if err != nil {
+			return nil, err
+		}
+		return rpcSheet{Title: s.Title(), Text: s.body.text.String(), Dot: s.body.dots[1].At}, nil
+	},
+	"edit": func(w *Win, p rpcParams) (interface{}, error) {
+		_, s, err := rpcSheetCol(w, p.Sheet)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.body.Edit(p.Edit); err != nil {
+			return nil, err
+		}
+		return s.body.dots[1].At, nil
+	},
+	"dot": func(w *Win, p rpcParams) (interface{}, error) {
+		_, s, err := rpcSheetCol(w, p.Sheet)
+		if err != nil {
+			return nil, err
+		}
+		b := s.body
+		at, err := edit.Addr(b.dots[1].At, p.Addr, b.text)
+		if err != nil {
+			return nil, err
+		}
+		setDot(b, 1, at[0], at[1])
+		showAddr(b, at[0])
+		return at, nil
+	},
+	"exec": func(w *Win, p rpcParams) (interface{}, error) {
+		c, s, err := rpcSheetCol(w, p.Sheet)
+		if err != nil {
+			return nil, err
+		}
+		return nil, execCmd(c, s, p.Command)
+	},
+	"type": func(w *Win, p rpcParams) (interface{}, error) {
+		for _, r := range p.Text {
+			w.Rune(r)
+		}
+		return nil, nil
+	},
+	"image": func(w *Win, _ rpcParams) (interface{}, error) {
+		img := image.NewRGBA(image.Rectangle{Max: w.size})
+		drawRGBA(w, true, img)
+		// Drawing cleared the changes not yet drawn to the window's image.
+		w.redrawAll = true
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	},
+	"preedit": func(w *Win, p rpcParams) (interface{}, error) {
+		w.Preedit(p.Text)
+		return nil, nil
+	},
+	"commit": func(w *Win, p rpcParams) (interface{}, error) {
+		w.Commit(p.Text)
+		return nil, nil
+	},
+}
+
+// rpcSheetCol returns the sheet with the title and its column,
+// or if the title is empty, the focused sheet.
+func rpcSheetCol(w *Win, title string) (*Col, *Sheet, error) {
+	if title == "" {
+		if s := getSheet(w.Col.Row); s != nil {
+			return w.Col, s, nil
+		}
+		return nil, nil, errors.New(msg("no focused sheet"))
+	}
+	for _, c := range w.cols {
+		for _, r := range c.rows {
+			if s := getSheet(r); s != nil && s.Title() == title {
+				return c, s, nil
+			}
+		}
+	}
+	return nil, nil, errors.New(msg("no sheet %s", title))
+}
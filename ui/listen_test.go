@@ -0,0 +1,218 @@
+package ui
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestListen(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	w := newTestWin()
+	s := NewSheet(w, "/a/b.txt")
+	w.cols[0].Add(s)
+	s.body.SetText(rope.New("Hello, World"))
+
+	path := filepath.Join(dir, "sock")
+	if err := w.Listen(path); err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	c, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer c.Close()
+	r := bufio.NewReader(c)
+
+	// Handle requests as the ticks of a window would.
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+				handleCalls(w)
+			}
+		}
+	}()
+	call := func(req string) string {
+		t.Helper()
+		if _, err := c.Write([]byte(req + "\n")); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		c.SetReadDeadline(time.Now().Add(5 * time.Second))
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		return line
+	}
+
+	tests := []struct {
+		req, resp string
+	}{
+		{
+			`{"jsonrpc":"2.0","id":1,"method":"dot","params":{"addr":"/World/"}}`,
+			`{"jsonrpc":"2.0","id":1,"result":[7,12]}`,
+		},
+		{
+			`{"jsonrpc":"2.0","id":2,"method":"edit","params":{"sheet":"/a/b.txt","edit":"c/T/"}}`,
+			`{"jsonrpc":"2.0","id":2,"result":[7,8]}`,
+		},
+		{
+			`{"jsonrpc":"2.0","id":3,"method":"read"}`,
+			`{"jsonrpc":"2.0","id":3,"result":{"title":"/a/b.txt","text":"Hello, T","dot":[7,8]}}`,
+		},
+		{
+			`{"jsonrpc":"2.0","id":4,"method":"sheets"}`,
+			`{"jsonrpc":"2.0","id":4,"result":["/a/b.txt"]}`,
+		},
+		{
+			`{"jsonrpc":"2.0","id":5,"method":"exec","params":{"command":"Set tabwidth 3"}}`,
+			`{"jsonrpc":"2.0","id":5,"result":null}`,
+		},
+		{
+			`{"jsonrpc":"2.0","id":6,"method":"read","params":{"sheet":"nope"}}`,
+			`{"jsonrpc":"2.0","id":6,"error":{"code":-32000,"message":"no sheet nope"}}`,
+		},
+		{
+			`{"jsonrpc":"2.0","id":7,"method":"nope"}`,
+			`{"jsonrpc":"2.0","id":7,"error":{"code":-32601,"message":"unknown method nope"}}`,
+		},
+		{
+			`{"jsonrpc":"2.0","id":8,"method":"read","params":[1]}`,
+			`{"jsonrpc":"2.0","id":8,"error":{"code":-32602,"message":"json: cannot unmarshal array into Go value of type ui.rpcParams"}}`,
+		},
+		{
+			`{"jsonrpc":"2.0","id":9,"method":"preedit","params":{"text":"x"}}`,
+			`{"jsonrpc":"2.0","id":9,"result":null}`,
+		},
+		{
+			`{"jsonrpc":"2.0","id":10,"method":"read"}`,
+			`{"jsonrpc":"2.0","id":10,"result":{"title":"/a/b.txt","text":"Hello, T","dot":[7,8]}}`,
+		},
+		{
+			`{"jsonrpc":"2.0","id":11,"method":"commit","params":{"text":"!"}}`,
+			`{"jsonrpc":"2.0","id":11,"result":null}`,
+		},
+		{
+			`{"jsonrpc":"2.0","id":12,"method":"read"}`,
+			`{"jsonrpc":"2.0","id":12,"result":{"title":"/a/b.txt","text":"Hello, !","dot":[8,8]}}`,
+		},
+		{
+			`{"jsonrpc":"2.0","id":13,"method":"type","params":{"text":"?"}}`,
+			`{"jsonrpc":"2.0","id":13,"result":null}`,
+		},
+		{
+			`{"jsonrpc":"2.0","id":14,"method":"read"}`,
+			`{"jsonrpc":"2.0","id":14,"result":{"title":"/a/b.txt","text":"Hello, !?","dot":[9,9]}}`,
+		},
+		{
+			`{`,
+			`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"unexpected end of JSON input"}}`,
+		},
+	}
+	for _, test := range tests {
+		got := call(test.req)
+		// Compare the responses after normalizing their JSON.
+		var g, want interface{}
+		if err := json.Unmarshal([]byte(got), &g); err != nil {
+			t.Fatalf("bad response %s: %v", got, err)
+		}
+		json.Unmarshal([]byte(test.resp), &want)
+		gj, _ := json.Marshal(g)
+		wj, _ := json.Marshal(want)
+		if string(gj) != string(wj) {
+			t.Errorf("%s: got %s, want %s", test.req, got, test.resp)
+		}
+	}
+	close(stop)
+	<-stopped
+	if s.body.tabWidth != 3 {
+		t.Errorf("tabWidth=%d, want 3", s.body.tabWidth)
+	}
+
+	stopListening(w)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket exists after Close: %v", err)
+	}
+}
+
+func TestListenLog(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	w := newTestWin()
+	file := filepath.Join(dir, "a.txt")
+	s := NewSheet(w, file)
+	w.cols[0].Add(s)
+	s.body.SetText(rope.New("Hello, World"))
+
+	path := filepath.Join(dir, "sock")
+	if err := w.Listen(path); err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer stopListening(w)
+	c, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer c.Close()
+	r := bufio.NewReader(c)
+	read := func() string {
+		t.Helper()
+		c.SetReadDeadline(time.Now().Add(5 * time.Second))
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		return strings.TrimSpace(line)
+	}
+
+	if _, err := c.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"log"}` + "\n")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if got, want := read(), `{"jsonrpc":"2.0","id":1,"result":true}`; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	id := strconv.Itoa(s.id)
+	event := func(params string) string {
+		return `{"jsonrpc":"2.0","method":"event","params":` + params + `}`
+	}
+	if err := execCmd(w.cols[0], s, "Set tabwidth 3"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if got, want := read(), event(`{"type":"exec","sheet":"`+file+`","winid":`+id+`,"text":"Set tabwidth 3"}`); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if err := s.Put(); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if got, want := read(), event(`{"type":"put","sheet":"`+file+`","winid":`+id+`}`); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	setDot(s.body, 1, 7, 12)
+	logDot(w)
+	logDot(w)
+	setDot(s.body, 1, 0, 0)
+	logDot(w)
+	for _, want := range []string{
+		event(`{"type":"dot","sheet":"` + file + `","winid":` + id + `,"dot":[7,12]}`),
+		event(`{"type":"dot","sheet":"` + file + `","winid":` + id + `,"dot":[0,0]}`),
+	} {
+		if got := read(); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/re1"
+	"github.com/eaburns/T/rope"
+	"github.com/eaburns/T/syntax"
+)
+
+// lookAddr handles looking for an address in the text box.
+// It returns whether the text is an address.
+//
+// The addresses are:
+//
+//	/re/   the next match of the regular expression after dot
+//	?re?   the previous match of the regular expression before dot
+//	x/re/  every match of the regular expression in dot,
+//	       or in the whole text if dot is empty;
+//	       all are highlighted and dot is set to the first
+//	:addr  the edit address evaluated from dot (see package edit)
+//	'name  the mark set by the Mark command
+//
+// The closing delimiter of the regular expression is optional.
+// Regular expressions use the re1 syntax.
+// The closing delimiter may be followed by flags (see lookFlags).
+func lookAddr(b *TextBox, text string) (bool, error) {
+	var (
+		at  [2]int64
+		err error
+	)
+	text = lookFlags(text)
+	dot := b.dots[1].At
+	switch {
+	case len(text) > 1 && text[0] == '/':
+		at, err = edit.Addr(dot, ".+"+text, b.text)
+	case len(text) > 1 && text[0] == '?':
+		pat := strings.TrimSuffix(text[1:], "?")
+		at, err = edit.Addr(dot, ".-/"+escapeDelim(pat, '?', '/')+"/", b.text)
+	case len(text) > 2 && strings.HasPrefix(text, "x/"):
+		return true, lookAll(b, strings.TrimSuffix(text[2:], "/"))
+	case len(text) > 1 && text[0] == ':':
+		at, err = edit.Addr(dot, text[1:], b.text)
+	case len(text) > 1 && text[0] == '\'':
+		at, err = markAddr(b, text[1:])
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	b.cursorCol = -1
+	setDot(b, 1, at[0], at[1])
+	showAddr(b, at[0])
+	return true, nil
+}
+
+// lookAll highlights all matches of the regular expression
+// in dot, or in the whole text if dot is empty,
+// and sets dot to the first match.
+func lookAll(b *TextBox, pat string) error {
+	re, _, err := re1.New(pat, re1.Opts{Delimiter: '/'})
+	if err != nil {
+		return err
+	}
+	start, end := b.dots[1].At[0], b.dots[1].At[1]
+	if start == end {
+		start, end = 0, b.text.Len()
+	}
+	var hs []syntax.Highlight
+	for at := start; at <= end; {
+		m := re.FindInRope(b.text, at, end)
+		if m == nil || m[0] < 0 {
+			break
+		}
+		hs = append(hs, syntax.Highlight{At: [2]int64{m[0], m[1]}, Style: hiStyle("match")})
+		at = m[1]
+		if m[0] == m[1] {
+			_, w, err := rope.NewReader(rope.Slice(b.text, at, b.text.Len())).ReadRune()
+			if err != nil {
+				break
+			}
+			at += int64(w)
+		}
+	}
+	b.highlight = hs
+	dirtyLines(b)
+	if len(hs) == 0 {
+		return errors.New(msg("no match for %s", pat))
+	}
+	b.cursorCol = -1
+	setDot(b, 1, hs[0].At[0], hs[0].At[1])
+	showAddr(b, hs[0].At[0])
+	return nil
+}
+
+// lookFlags returns the look text of a regular expression,
+// /re/flags, ?re?flags, or x/re/flags,
+// with the regular expression rewritten as described by the flags.
+// Text without flags is returned unchanged.
+//
+// The flags are:
+//
+//	i  ignore case
+//	l  match the text literally, not as a regular expression
+func lookFlags(text string) string {
+	start := 1
+	if strings.HasPrefix(text, "x/") {
+		start = 2
+	}
+	if len(text) <= start || text[start-1] != '/' && text[start-1] != '?' {
+		return text
+	}
+	delim := text[start-1]
+	end := -1
+	for i := start; i < len(text); i++ {
+		if text[i] == '\\' {
+			i++
+		} else if text[i] == delim {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return text
+	}
+	pat, flags := text[start:end], text[end+1:]
+	if flags == "" || strings.Trim(flags, "il") != "" {
+		return text
+	}
+	if strings.ContainsRune(flags, 'l') {
+		d := string(delim)
+		pat = re1.Escape(strings.Replace(pat, `\`+d, d, -1))
+		if delim == '/' {
+			pat = strings.Replace(pat, d, `\`+d, -1)
+		}
+	}
+	if strings.ContainsRune(flags, 'i') {
+		pat = re1.FoldCase(pat)
+	}
+	return text[:start] + pat + string(delim)
+}
+
+// escapeDelim returns the text of a regular expression delimited by from
+// as one delimited by to.
+func escapeDelim(pat string, from, to rune) string {
+	var s strings.Builder
+	rs := []rune(pat)
+	for i := 0; i < len(rs); i++ {
+		switch {
+		case rs[i] == '\\' && i+1 < len(rs) && rs[i+1] == from:
+			i++
+			if e := re1.Escape(string(from)); len(e) > 1 {
+				s.WriteRune('\\') // from is a meta-character
+			}
+		case rs[i] == '\\' && i+1 < len(rs):
+			s.WriteRune(rs[i])
+			i++
+		case rs[i] == to:
+			s.WriteRune('\\')
+		}
+		s.WriteRune(rs[i])
+	}
+	return s.String()
+}
+
+// lookFileAddr handles looking for text of the form path:addr,
+// such as the file:line results of Grep or compiler errors.
+// If path is an existing file, relative to the directory of s,
+// it is focused or opened, and the edit address is looked up in its body.
+// A trailing : is ignored.
+// It returns whether the text is a file address.
+func lookFileAddr(c *Col, s *Sheet, text string) (bool, error) {
+	i := strings.IndexByte(text, ':')
+	if i <= 0 {
+		return false, nil
+	}
+	addr := strings.TrimSuffix(text[i+1:], ":")
+	if addr == "" {
+		return false, nil
+	}
+	path, err := abs(s, text[:i])
+	if err != nil {
+		return false, nil
+	}
+	if st, err := os.Stat(path); err != nil || st.IsDir() {
+		return false, nil
+	}
+	if !focusSheet(c.win, path) {
+		if err := openSheet(c, path); err != nil {
+			return true, err
+		}
+		focusSheet(c.win, path)
+	}
+	b := findSheet(c.win, path).body
+	setDot(b, 1, 0, 0)
+	_, err = lookAddr(b, ":"+addr)
+	return true, err
+}
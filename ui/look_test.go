@@ -0,0 +1,139 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestLookAddr(t *testing.T) {
+	const text = "a TODO b\nTODO c\nd todo/x TODO"
+	tests := []struct {
+		look    string
+		dot     [2]int64
+		wantOK  bool
+		wantErr bool
+		wantDot [2]int64
+		wantHi  [][2]int64
+	}{
+		{look: "TODO", dot: [2]int64{0, 0}, wantOK: false, wantDot: [2]int64{0, 0}},
+		{look: "/TODO/", dot: [2]int64{0, 0}, wantOK: true, wantDot: [2]int64{2, 6}},
+		{look: "/TODO/", dot: [2]int64{2, 6}, wantOK: true, wantDot: [2]int64{9, 13}},
+		{look: "/TODO", dot: [2]int64{9, 13}, wantOK: true, wantDot: [2]int64{25, 29}},
+		{look: "/TODO/", dot: [2]int64{25, 29}, wantOK: true, wantDot: [2]int64{2, 6}},
+		{look: "?TODO?", dot: [2]int64{25, 29}, wantOK: true, wantDot: [2]int64{9, 13}},
+		{look: "?todo\\/x?", dot: [2]int64{29, 29}, wantOK: true, wantDot: [2]int64{18, 24}},
+		{look: "?todo/x?", dot: [2]int64{29, 29}, wantOK: true, wantDot: [2]int64{18, 24}},
+		{look: ":2", dot: [2]int64{0, 0}, wantOK: true, wantDot: [2]int64{9, 16}},
+		{look: "/nope/", dot: [2]int64{3, 3}, wantOK: true, wantErr: true, wantDot: [2]int64{3, 3}},
+		{
+			look:    "x/TODO/",
+			dot:     [2]int64{0, 0},
+			wantOK:  true,
+			wantDot: [2]int64{2, 6},
+			wantHi:  [][2]int64{{2, 6}, {9, 13}, {25, 29}},
+		},
+		{
+			look:    "x/T[A-Z]*",
+			dot:     [2]int64{9, 24},
+			wantOK:  true,
+			wantDot: [2]int64{9, 13},
+			wantHi:  [][2]int64{{9, 13}},
+		},
+		{look: "x/nope/", dot: [2]int64{0, 0}, wantOK: true, wantErr: true, wantDot: [2]int64{0, 0}},
+		{look: "/todo/i", dot: [2]int64{0, 0}, wantOK: true, wantDot: [2]int64{2, 6}},
+		{look: "?Todo?i", dot: [2]int64{25, 29}, wantOK: true, wantDot: [2]int64{18, 22}},
+		{look: "/o\\/x/l", dot: [2]int64{0, 0}, wantOK: true, wantDot: [2]int64{21, 24}},
+		{look: "/O\\/X/li", dot: [2]int64{0, 0}, wantOK: true, wantDot: [2]int64{21, 24}},
+		{look: "?o/x?l", dot: [2]int64{29, 29}, wantOK: true, wantDot: [2]int64{21, 24}},
+		{look: "/T.DO/l", dot: [2]int64{0, 0}, wantOK: true, wantErr: true, wantDot: [2]int64{0, 0}},
+		{
+			look:    "x/todo/i",
+			dot:     [2]int64{0, 0},
+			wantOK:  true,
+			wantDot: [2]int64{2, 6},
+			wantHi:  [][2]int64{{2, 6}, {9, 13}, {18, 22}, {25, 29}},
+		},
+	}
+	for _, test := range tests {
+		b := NewTextBox(testWin, testTextStyles, testSize)
+		b.SetText(rope.New(text))
+		setDot(b, 1, test.dot[0], test.dot[1])
+		ok, err := lookAddr(b, test.look)
+		if ok != test.wantOK || (err != nil) != test.wantErr {
+			t.Errorf("lookAddr(%q)=%v, %v, want %v, error=%v", test.look, ok, err, test.wantOK, test.wantErr)
+			continue
+		}
+		if got := b.dots[1].At; got != test.wantDot {
+			t.Errorf("lookAddr(%q): dot=%v, want %v", test.look, got, test.wantDot)
+		}
+		var hi [][2]int64
+		for _, h := range b.highlight {
+			hi = append(hi, h.At)
+		}
+		if len(hi) != len(test.wantHi) {
+			t.Errorf("lookAddr(%q): highlight=%v, want %v", test.look, hi, test.wantHi)
+			continue
+		}
+		for i := range hi {
+			if hi[i] != test.wantHi[i] {
+				t.Errorf("lookAddr(%q): highlight=%v, want %v", test.look, hi, test.wantHi)
+				break
+			}
+		}
+	}
+}
+
+func TestLookText_Addr(t *testing.T) {
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, "")
+	)
+	c.Add(s)
+	s.body.SetText(rope.New("one two one"))
+	for _, want := range [][2]int64{{0, 3}, {8, 11}, {0, 3}} {
+		if err := lookText(c, s, "/one/"); err != nil {
+			t.Fatalf("lookText failed: %v", err)
+		}
+		if got := s.body.dots[1].At; got != want {
+			t.Errorf("dot=%v, want %v", got, want)
+		}
+	}
+}
+
+func TestCmd_JumpAddr(t *testing.T) {
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, "")
+	)
+	c.Add(s)
+	s.body.SetText(rope.New("one\ntwo\nthree\nfour\n"))
+	s.TextBox = s.tag
+	tests := []struct {
+		addr string
+		want [2]int64
+	}{
+		{addr: ":2", want: [2]int64{4, 8}},
+		{addr: ":$", want: [2]int64{19, 19}},
+		{addr: ":0", want: [2]int64{0, 0}},
+		{addr: ":/two/,/four/", want: [2]int64{4, 18}},
+		{addr: ":1,2", want: [2]int64{0, 8}},
+		{addr: " :3-1 ", want: [2]int64{4, 8}},
+	}
+	for _, test := range tests {
+		if err := execCmd(c, s, test.addr); err != nil {
+			t.Fatalf("execCmd(%q) failed: %v", test.addr, err)
+		}
+		if got := s.body.dots[1].At; got != test.want {
+			t.Errorf("execCmd(%q) dot=%v, want %v", test.addr, got, test.want)
+		}
+		if s.TextBox != s.body {
+			t.Errorf("execCmd(%q) did not focus the body", test.addr)
+		}
+	}
+	if err := execCmd(c, s, ":/none/"); err == nil {
+		t.Errorf("execCmd(:/none/) succeeded, want an error")
+	}
+}
@@ -0,0 +1,382 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eaburns/T/lsp"
+	"github.com/eaburns/T/rope"
+	"github.com/eaburns/T/syntax"
+	"github.com/eaburns/T/text"
+)
+
+// An lspServer is a language server
+// started for a command and root directory.
+type lspServer struct {
+	name   string        // the command and root directory
+	ready  chan struct{} // closed when the server started or failed to start
+	client *lsp.Client   // nil if the server failed to start
+}
+
+// An lspDoc is the file of a sheet open in a language server.
+//
+// The body text is sent to the server whenever it changes,
+// and the diagnostics published by the server are underlined.
+type lspDoc struct {
+	srv      *lspServer
+	path     string
+	language string
+	version  int
+	sent     rope.Rope // body text last sent to the server; nil if not opened
+	gen      int       // generation of the underlined diagnostics; -1 if none
+}
+
+// setLanguageServer closes the sheet's file in its language server, if any,
+// and opens it in the server of its file type in languageServers,
+// starting the server for the root directory of the file if needed.
+// A missing server command is not an error.
+func setLanguageServer(s *Sheet) {
+	closeLanguageServer(s)
+	path := s.Title()
+	for _, l := range languageServers {
+		ok, err := regexp.MatchString(l.regexp, path)
+		if err != nil {
+			s.win.OutputString(err.Error() + "\n")
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if _, err := exec.LookPath(l.cmd[0]); err != nil {
+			return
+		}
+		s.lsp = &lspDoc{
+			srv:      startServer(s.win, l.cmd, lspRoot(path)),
+			path:     path,
+			language: l.language,
+			gen:      -1,
+		}
+		return
+	}
+}
+
+// closeLanguageServer closes the sheet's file in its language server, if any.
+func closeLanguageServer(s *Sheet) {
+	if s.lsp == nil {
+		return
+	}
+	if cl := s.lsp.srv.get(); cl != nil && s.lsp.sent != nil {
+		cl.Close(s.lsp.path)
+	}
+	s.lsp = nil
+	s.body.diags = nil
+	dirtyLines(s.body)
+}
+
+// lspRoot returns the workspace root of the file:
+// the nearest directory containing one of lspRootFiles,
+// or the directory of the file if there is none.
+func lspRoot(path string) string {
+	return parentWith(filepath.Dir(path), lspRootFiles...)
+}
+
+// startServer returns the window's language server
+// for the command and root directory,
+// starting it if it is not running.
+// The server is started asynchronously;
+// an error starting it is written to the Output sheet.
+func startServer(w *Win, cmd []string, root string) *lspServer {
+	name := strings.Join(cmd, " ") + " " + root
+	if srv, ok := w.servers[name]; ok {
+		return srv
+	}
+	srv := &lspServer{name: name, ready: make(chan struct{})}
+	if w.servers == nil {
+		w.servers = make(map[string]*lspServer)
+	}
+	w.servers[name] = srv
+	go func() {
+		defer close(srv.ready)
+		cl, err := lsp.Start(cmd, root)
+		if err != nil {
+			w.OutputString(msg("%s: %s", name, err.Error()) + "\n")
+			return
+		}
+		srv.client = cl
+		cl.SetNotify(func() { wake(w) })
+		wake(w)
+	}()
+	return srv
+}
+
+// get returns the client of the server,
+// or nil if it is starting or failed to start.
+func (srv *lspServer) get() *lsp.Client {
+	select {
+	case <-srv.ready:
+		return srv.client
+	default:
+		return nil
+	}
+}
+
+// stopLanguageServers shuts down the window's language servers.
+func stopLanguageServers(w *Win) {
+	for name, srv := range w.servers {
+		<-srv.ready
+		if srv.client != nil {
+			srv.client.Shutdown(lspTimeout)
+		}
+		delete(w.servers, name)
+	}
+}
+
+// lspUpdate sends the body text to the language server if it changed,
+// and underlines the diagnostics of the file if they changed.
+// If the server cannot be sent the text,
+// the error is written to the Output sheet
+// and the file is no longer synchronized.
+// Nothing is sent while the window is quiet,
+// except by commands that use the server.
+// It returns whether the body must be redrawn.
+func lspUpdate(s *Sheet) bool {
+	cl := s.lsp.srv.get()
+	if cl == nil || s.win.quiet {
+		return false
+	}
+	if err := lspSync(cl, s); err != nil {
+		s.win.OutputString(msg("%s: %s", s.lsp.srv.name, err.Error()) + "\n")
+		closeLanguageServer(s)
+		return true
+	}
+	ds, gen := cl.Diagnostics(s.lsp.path)
+	if gen == s.lsp.gen {
+		return false
+	}
+	s.lsp.gen = gen
+	s.body.diags = diagHighlights(s.body.text.String(), ds)
+	dirtyLines(s.body)
+	return true
+}
+
+// lspSync sends the body text to the language server
+// if it changed since it was last sent.
+func lspSync(cl *lsp.Client, s *Sheet) error {
+	d := s.lsp
+	if d.sent == s.body.text {
+		return nil
+	}
+	var err error
+	d.version++
+	if d.sent == nil {
+		err = cl.Open(d.path, d.language, d.version, s.body.text.String())
+	} else {
+		err = cl.Change(d.path, d.version, s.body.text.String())
+	}
+	d.sent = s.body.text
+	return err
+}
+
+// diagHighlights returns underline highlights of the diagnostics,
+// sorted and non-overlapping.
+// Empty ranges are extended to the following rune.
+func diagHighlights(str string, ds []lsp.Diagnostic) []syntax.Highlight {
+	var his []syntax.Highlight
+	for _, d := range ds {
+		start := int64(lsp.Offset(str, d.Range.Start))
+		end := int64(lsp.Offset(str, d.Range.End))
+		if end <= start && start < int64(len(str)) {
+			_, w := utf8.DecodeRuneInString(str[start:])
+			end = start + int64(w)
+		}
+		if end <= start {
+			continue
+		}
+		color := warningUnderline
+		if d.Severity == lsp.Error || d.Severity == 0 {
+			color = errorUnderline
+		}
+		his = append(his, syntax.Highlight{At: [2]int64{start, end}, Style: text.Style{Underline: color}})
+	}
+	sort.SliceStable(his, func(i, j int) bool { return his[i].At[0] < his[j].At[0] })
+	var out []syntax.Highlight
+	for _, hi := range his {
+		if n := len(out); n > 0 && hi.At[0] < out[n-1].At[1] {
+			if hi.At[1] <= out[n-1].At[1] {
+				continue
+			}
+			hi.At[0] = out[n-1].At[1]
+		}
+		out = append(out, hi)
+	}
+	return out
+}
+
+// lspClient returns the language server client of the sheet,
+// waiting up to lspTimeout for the server to start,
+// after sending it the current body text.
+// It also returns the file path
+// and the position of the address in the body.
+func lspClient(s *Sheet, at int64) (*lsp.Client, string, lsp.Position, error) {
+	if s.lsp == nil {
+		return nil, "", lsp.Position{}, errors.New(msg("no language server for %s", s.Title()))
+	}
+	cl := s.lsp.srv.get()
+	if cl == nil {
+		select {
+		case <-s.lsp.srv.ready:
+			cl = s.lsp.srv.client
+		case <-time.After(lspTimeout):
+		}
+	}
+	if cl == nil {
+		return nil, "", lsp.Position{}, errors.New(msg("language server %s is not running", s.lsp.srv.name))
+	}
+	if err := lspSync(cl, s); err != nil {
+		return nil, "", lsp.Position{}, err
+	}
+	return cl, s.lsp.path, lsp.Pos(s.body.text.String(), int(at)), nil
+}
+
+// lspCall calls f, failing if it does not return within lspTimeout.
+func lspCall(f func() error) error {
+	done := make(chan error, 1)
+	go func() { done <- f() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(lspTimeout):
+		return errors.New(msg("language server timed out"))
+	}
+}
+
+// hover implements the Hover command.
+// The language server's description of the symbol at dot
+// is written to the Output sheet.
+func hover(s *Sheet) error {
+	cl, path, p, err := lspClient(s, s.body.dots[1].At[0])
+	if err != nil {
+		return err
+	}
+	var h string
+	err = lspCall(func() (err error) {
+		h, err = cl.Hover(path, p)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if h = strings.TrimSpace(h); h == "" {
+		return errors.New(msg("no information"))
+	}
+	s.win.OutputString(h + "\n")
+	return nil
+}
+
+// lspDefinition opens the definition of the symbol at dot
+// found by the sheet's language server,
+// with dot set to its start.
+// If the symbol has more than one definition,
+// they are written to the Output sheet as path:line lines.
+func lspDefinition(c *Col, s *Sheet) error {
+	cl, path, p, err := lspClient(s, s.body.dots[1].At[0])
+	if err != nil {
+		return err
+	}
+	var locs []lsp.Location
+	err = lspCall(func() (err error) {
+		locs, err = cl.Definition(path, p)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	switch len(locs) {
+	case 0:
+		return errors.New(msg("no definition"))
+	case 1:
+		return lspJump(c, s, locs[0])
+	}
+	var b strings.Builder
+	for _, l := range locs {
+		fmt.Fprintf(&b, "%s:%d\n", l.Path(), l.Range.Start.Line+1)
+	}
+	s.win.OutputString(b.String())
+	return nil
+}
+
+// lspJump opens the file of the location
+// and sets dot to the start of its range.
+func lspJump(c *Col, s *Sheet, loc lsp.Location) error {
+	path := loc.Path()
+	ok, err := lookFileAddr(c, s, fmt.Sprintf("%s:%d", path, loc.Range.Start.Line+1))
+	switch {
+	case err != nil:
+		return err
+	case !ok:
+		return errors.New(msg("no file %s", path))
+	}
+	b := findSheet(c.win, path).body
+	at := int64(lsp.Offset(b.text.String(), loc.Range.Start))
+	b.cursorCol = -1
+	setDot(b, 1, at, at)
+	showAddr(b, at)
+	return nil
+}
+
+// lspCompletions returns the texts of the completions
+// of the language server at the address of the sheet's body.
+func lspCompletions(s *Sheet, at int64) ([]string, error) {
+	cl, path, p, err := lspClient(s, at)
+	if err != nil {
+		return nil, err
+	}
+	var items []lsp.CompletionItem
+	err = lspCall(func() (err error) {
+		items, err = cl.Completion(path, p)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	texts := make([]string, len(items))
+	for i, it := range items {
+		texts[i] = it.Text()
+	}
+	return texts, nil
+}
+
+// diagnostics implements the Diags command.
+// The diagnostics of the file are written to the Output sheet
+// as lines of the form path:line: severity: message,
+// which can be looked at to open the file at the line.
+func diagnostics(s *Sheet) error {
+	cl, path, _, err := lspClient(s, 0)
+	if err != nil {
+		return err
+	}
+	ds, _ := cl.Diagnostics(path)
+	if len(ds) == 0 {
+		return errors.New(msg("no diagnostics"))
+	}
+	s.win.OutputString(formatDiags(path, ds))
+	return nil
+}
+
+// formatDiags returns the diagnostics of the file
+// as lines of the form path:line: severity: message.
+func formatDiags(path string, ds []lsp.Diagnostic) string {
+	var b strings.Builder
+	for _, d := range ds {
+		m := strings.Replace(strings.TrimSpace(d.Message), "\n", " ", -1)
+		fmt.Fprintf(&b, "%s:%d: %s: %s\n", path, d.Range.Start.Line+1, d.SeverityName(), m)
+	}
+	return b.String()
+}
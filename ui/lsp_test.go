@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/eaburns/T/lsp"
+	"github.com/eaburns/T/syntax"
+	"github.com/eaburns/T/text"
+)
+
+func TestDiagHighlights(t *testing.T) {
+	const str = "package a\nfunc f() {\n\tx\n}\n"
+	diag := func(sev, l0, c0, l1, c1 int) lsp.Diagnostic {
+		return lsp.Diagnostic{
+			Range:    lsp.Range{Start: lsp.Position{Line: l0, Character: c0}, End: lsp.Position{Line: l1, Character: c1}},
+			Severity: sev,
+		}
+	}
+	ds := []lsp.Diagnostic{
+		diag(lsp.Warning, 2, 1, 2, 2),
+		diag(lsp.Error, 1, 5, 1, 6),
+		// Overlaps the previous diagnostic.
+		diag(lsp.Error, 1, 5, 1, 8),
+		// Empty ranges underline the next rune.
+		diag(lsp.Hint, 0, 0, 0, 0),
+	}
+	want := []syntax.Highlight{
+		{At: [2]int64{0, 1}, Style: text.Style{Underline: warningUnderline}},
+		{At: [2]int64{15, 16}, Style: text.Style{Underline: errorUnderline}},
+		{At: [2]int64{16, 18}, Style: text.Style{Underline: errorUnderline}},
+		{At: [2]int64{22, 23}, Style: text.Style{Underline: warningUnderline}},
+	}
+	if got := diagHighlights(str, ds); !reflect.DeepEqual(got, want) {
+		t.Errorf("diagHighlights=%v, want %v", got, want)
+	}
+}
+
+func TestFormatDiags(t *testing.T) {
+	ds := []lsp.Diagnostic{
+		{Range: lsp.Range{Start: lsp.Position{Line: 2, Character: 1}}, Severity: lsp.Error, Message: "undefined: x"},
+		{Range: lsp.Range{Start: lsp.Position{Line: 0}}, Severity: lsp.Warning, Message: "two\nlines\n"},
+	}
+	want := "/a/b.go:3: error: undefined: x\n" +
+		"/a/b.go:1: warning: two lines\n"
+	if got := formatDiags("/a/b.go", ds); got != want {
+		t.Errorf("formatDiags=%q, want %q", got, want)
+	}
+}
+
+func TestLSPRoot(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	mkSubDir(dir, "mod")
+	mkSubDir(filepath.Join(dir, "mod"), "pkg")
+	touch(filepath.Join(dir, "mod"), "go.mod")
+	mkSubDir(dir, "other")
+
+	if got, want := lspRoot(filepath.Join(dir, "mod", "pkg", "a.go")), filepath.Join(dir, "mod"); got != want {
+		t.Errorf("lspRoot in a module=%q, want %q", got, want)
+	}
+	if got, want := lspRoot(filepath.Join(dir, "other", "a.go")), filepath.Join(dir, "other"); got != want {
+		t.Errorf("lspRoot outside a module=%q, want %q", got, want)
+	}
+}
+
+func TestCmd_LSPNoServer(t *testing.T) {
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, "/a/b.txt")
+	)
+	c.Add(s)
+	for _, cmd := range []string{"Hover", "Diags"} {
+		err := execCmd(c, s, cmd)
+		if want := "no language server for /a/b.txt"; err == nil || err.Error() != want {
+			t.Errorf("execCmd(%q)=%v, want %q", cmd, err, want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// A macroEvent is an input event of a recorded macro.
+type macroEvent struct {
+	button int // the button of a click event, or 0
+	replay func(*Win)
+}
+
+// recordEvent records an event if a macro is being recorded.
+// Events are not recorded while a macro is replayed.
+func recordEvent(w *Win, e macroEvent) {
+	if w.recording && !w.replaying {
+		w.recorded = append(w.recorded, e)
+	}
+}
+
+// toggleRecording implements the Record command.
+// The Rune, Dir, Mod, Move, and Click events of the window
+// are recorded until the next Record command.
+func toggleRecording(w *Win) {
+	if w.recording {
+		stopRecording(w)
+		return
+	}
+	w.recording = true
+	w.recorded = nil
+}
+
+// stopRecording ends a recording.
+// The recorded events become the macro replayed by Replay,
+// except for the press of a button not yet released,
+// which is the click executing Record.
+func stopRecording(w *Win) {
+	w.recording = false
+	w.macro = w.recorded
+	w.recorded = nil
+	held := map[int]bool{}
+	for i := len(w.macro) - 1; i >= 0; i-- {
+		switch b := w.macro[i].button; {
+		case b < 0:
+			held[-b] = true
+		case b > 0 && held[b]:
+			held[b] = false
+		case b > 0:
+			w.macro = w.macro[:i]
+		}
+	}
+}
+
+// replayMacro implements the Replay command: Replay [n].
+// The last recorded macro is replayed n times, by default once.
+func replayMacro(w *Win, args string) error {
+	n := 1
+	if args = strings.TrimSpace(args); args != "" {
+		var err error
+		if n, err = strconv.Atoi(args); err != nil || n <= 0 {
+			return errors.New(msg("usage: Replay [n]"))
+		}
+	}
+	switch {
+	case w.recording:
+		return errors.New(msg("cannot replay while recording"))
+	case w.replaying:
+		return errors.New(msg("already replaying"))
+	case len(w.macro) == 0:
+		return errors.New(msg("no macro recorded"))
+	}
+	w.replaying = true
+	defer func() { w.replaying = false }()
+	for i := 0; i < n; i++ {
+		for _, e := range w.macro {
+			e.replay(w)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package ui
+
+import "testing"
+
+func TestCmd_RecordReplay(t *testing.T) {
+	w := newTestWin()
+	c := w.cols[0]
+	s := NewSheet(w, "")
+	c.Add(s)
+	s.TextBox = s.body
+
+	if err := execCmd(c, s, "Replay"); err == nil || err.Error() != "no macro recorded" {
+		t.Errorf("Replay before Record=%v, want no macro recorded", err)
+	}
+	if err := execCmd(c, s, "Record"); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if !w.recording {
+		t.Fatalf("not recording after Record")
+	}
+	for _, r := range "ab" {
+		w.Rune(r)
+	}
+	w.Dir(-1, 0)
+	w.Rune('c')
+	w.Dir(1, 0)
+
+	// A click completed while recording is recorded,
+	// but the press of the click executing Record is not.
+	var clicks int
+	recordEvent(w, macroEvent{button: 1, replay: func(*Win) { clicks++ }})
+	recordEvent(w, macroEvent{button: -1, replay: func(*Win) { clicks++ }})
+	recordEvent(w, macroEvent{button: 2, replay: func(*Win) { t.Errorf("replayed the Record click") }})
+	if err := execCmd(c, s, "Replay"); err == nil {
+		t.Errorf("Replay while recording succeeded, want error")
+	}
+	if err := execCmd(c, s, "Record"); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if w.recording {
+		t.Fatalf("still recording after the second Record")
+	}
+	if got := s.body.text.String(); got != "acb" {
+		t.Fatalf("recorded text=%q, want acb", got)
+	}
+
+	if err := execCmd(c, s, "Replay 2"); err != nil {
+		t.Fatalf("Replay 2 failed: %v", err)
+	}
+	if got, want := s.body.text.String(), "acbacbacb"; got != want {
+		t.Errorf("replayed text=%q, want %q", got, want)
+	}
+	if clicks != 4 {
+		t.Errorf("replayed %d click events, want 4", clicks)
+	}
+	if len(w.recorded) != 0 || len(w.macro) != 7 {
+		t.Errorf("len(recorded)=%d, len(macro)=%d, want 0, 7", len(w.recorded), len(w.macro))
+	}
+	for _, bad := range []string{"Replay 0", "Replay x"} {
+		if err := execCmd(c, s, bad); err == nil || err.Error() != "usage: Replay [n]" {
+			t.Errorf("%s=%v, want usage", bad, err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eaburns/T/edit"
+)
+
+// A jump is a position in a sheet body
+// from which a look, address, or search moved away.
+type jump struct {
+	b  *TextBox
+	at [2]int64
+}
+
+// setMark implements the Mark command: Mark [name]
+// It sets the named mark of the body to dot.
+// With no name, it lists the marks of the body.
+// The mark is updated as the text changes,
+// and 'name looks up or executes a jump to it.
+func setMark(s *Sheet, name string) error {
+	b := s.body
+	if name == "" {
+		var names []string
+		for n := range b.marks {
+			names = append(names, n)
+		}
+		if len(names) == 0 {
+			return errors.New(msg("no marks"))
+		}
+		sort.Strings(names)
+		var str strings.Builder
+		for _, n := range names {
+			m := b.marks[n]
+			fmt.Fprintf(&str, "%s: #%d,#%d\n", n, m[0], m[1])
+		}
+		b.win.OutputString(str.String())
+		return nil
+	}
+	if strings.ContainsAny(name, " \t\n") {
+		return errors.New(msg("usage: Mark [name]"))
+	}
+	if b.marks == nil {
+		b.marks = make(map[string][2]int64)
+	}
+	b.marks[name] = b.dots[1].At
+	return nil
+}
+
+// markAddr returns the address of the named mark.
+func markAddr(b *TextBox, name string) ([2]int64, error) {
+	m, ok := b.marks[name]
+	if !ok {
+		return [2]int64{}, errors.New(msg("no mark %s", name))
+	}
+	return m, nil
+}
+
+// recordJump calls f, and if it moves the focus to another sheet
+// or moves dot of the focused sheet body,
+// it adds the position before the call to the jump list.
+func recordJump(w *Win, f func() error) error {
+	from, ok := focusedJump(w)
+	err := f()
+	if to, _ := focusedJump(w); ok && to != from {
+		pushJump(w, from)
+	}
+	return err
+}
+
+// focusedJump returns the position of the focused sheet body
+// and whether there is a focused sheet.
+func focusedJump(w *Win) (jump, bool) {
+	s := getSheet(w.Col.Row)
+	if s == nil {
+		return jump{}, false
+	}
+	return jump{b: s.body, at: s.body.dots[1].At}, true
+}
+
+// pushJump adds a position to the end of the jump list,
+// discarding the positions that Back moved away from.
+func pushJump(w *Win, j jump) {
+	w.back = append(w.back, j)
+	if n := len(w.back) - maxJumps; n > 0 {
+		w.back = w.back[n:]
+	}
+	w.forward = nil
+}
+
+// goBack moves to the last position of the jump list.
+func goBack(w *Win) error {
+	return goJump(w, &w.back, &w.forward, msg("no previous position"))
+}
+
+// goForward moves to the last position moved away from by goBack.
+func goForward(w *Win) error {
+	return goJump(w, &w.forward, &w.back, msg("no next position"))
+}
+
+// goJump moves to the last position of from that is in an open sheet,
+// adding the current position to to.
+func goJump(w *Win, from, to *[]jump, none string) error {
+	for len(*from) > 0 {
+		j := (*from)[len(*from)-1]
+		*from = (*from)[:len(*from)-1]
+		c, s := jumpSheet(w, j.b)
+		if s == nil {
+			continue
+		}
+		if cur, ok := focusedJump(w); ok {
+			*to = append(*to, cur)
+		}
+		setWinFocus(w, c)
+		setColFocus(c, s)
+		if s.TextBox != s.body {
+			s.TextBox.Focus(false)
+			s.TextBox = s.body
+			s.TextBox.Focus(true)
+		}
+		n := s.body.text.Len()
+		if j.at[1] > n {
+			j.at = [2]int64{n, n}
+		}
+		s.body.cursorCol = -1
+		setDot(s.body, 1, j.at[0], j.at[1])
+		showAddr(s.body, j.at[0])
+		return nil
+	}
+	return errors.New(none)
+}
+
+// jumpSheet returns the column and sheet with the body, if it is open.
+func jumpSheet(w *Win, b *TextBox) (*Col, *Sheet) {
+	for _, c := range w.cols {
+		for _, r := range c.rows {
+			if s := getSheet(r); s != nil && s.body == b {
+				return c, s
+			}
+		}
+	}
+	return nil, nil
+}
+
+// jumpRune handles the jump runes typed with the alt modifier:
+//
+//	alt-[  moves back to the previous position of the jump list
+//	alt-]  moves forward again
+//
+// It returns whether the rune was handled.
+func jumpRune(w *Win, r rune) bool {
+	var err error
+	switch r {
+	case '[':
+		err = goBack(w)
+	case ']':
+		err = goForward(w)
+	default:
+		return false
+	}
+	if err != nil {
+		w.OutputString(err.Error() + "\n")
+	}
+	return true
+}
+
+// updateJumps updates the marks of the text box
+// and its positions in the jump list for a change.
+func updateJumps(b *TextBox, diffs edit.Diffs) {
+	for n, m := range b.marks {
+		b.marks[n] = diffs.Update(m)
+	}
+	for _, js := range [][]jump{b.win.back, b.win.forward} {
+		for i := range js {
+			if js[i].b == b {
+				js[i].at = diffs.Update(js[i].at)
+			}
+		}
+	}
+}
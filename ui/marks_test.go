@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+)
+
+func TestMark(t *testing.T) {
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, "")
+	)
+	c.Add(s)
+	s.body.SetText(rope.New("one two three"))
+	if err := execCmd(c, s, "Mark"); err == nil {
+		t.Errorf("Mark with no marks succeeded, want an error")
+	}
+	setDot(s.body, 1, 4, 7)
+	if err := execCmd(c, s, "Mark m"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	s.body.Change(edit.Diffs{{At: [2]int64{0, 0}, Text: rope.New("zero ")}})
+	setDot(s.body, 1, 0, 0)
+	if err := execCmd(c, s, "'m"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if d := s.body.dots[1].At; d != [2]int64{9, 12} {
+		t.Errorf("dot=%v, want [9 12]", d)
+	}
+	if ok, err := lookAddr(s.body, "'x"); !ok || err == nil {
+		t.Errorf("lookAddr('x)=%v,%v, want true,error", ok, err)
+	}
+	if err := execCmd(c, s, "Mark"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if str, want := w.outputBuffer.String(), "m: #9,#12\n"; str != want {
+		t.Errorf("output=%q, want %q", str, want)
+	}
+}
+
+func TestBackForward(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file")
+	write(path, "a\nb\nc\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, dir+"/")
+	)
+	c.Add(s)
+	s.body.SetText(rope.New("file:2 file:3"))
+	setDot(s.body, 1, 1, 1)
+	if err := goBack(w); err == nil {
+		t.Errorf("goBack with no jumps succeeded, want an error")
+	}
+
+	// Looking at file:2 moves to another sheet.
+	if err := recordJump(w, func() error { return lookText(c, s, "file:2") }); err != nil {
+		t.Fatalf("lookText failed: %v", err)
+	}
+	f := findSheet(w, path)
+	if f == nil || c.Row != Row(f) {
+		t.Fatalf("file not focused")
+	}
+	// Executing :3 moves within the sheet.
+	if err := execCmd(c, f, ":3"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	// Edits before a jump position update it.
+	s.body.Change(edit.Diffs{{At: [2]int64{0, 0}, Text: rope.New("xx")}})
+
+	if err := execCmd(c, f, "Back"); err != nil {
+		t.Fatalf("Back failed: %v", err)
+	}
+	if d := f.body.dots[1].At; c.Row != Row(f) || d != [2]int64{2, 4} {
+		t.Errorf("after Back, dot=%v, want [2 4] in file", d)
+	}
+	w.mods[2] = true
+	w.Rune('[')
+	w.mods[2] = false
+	if d := s.body.dots[1].At; c.Row != Row(s) || d != [2]int64{3, 3} {
+		t.Errorf("after alt-[, dot=%v, want [3 3] in dir", d)
+	}
+	if err := goBack(w); err == nil {
+		t.Errorf("goBack at the start succeeded, want an error")
+	}
+	if err := goForward(w); err != nil {
+		t.Fatalf("goForward failed: %v", err)
+	}
+	if d := f.body.dots[1].At; c.Row != Row(f) || d != [2]int64{2, 4} {
+		t.Errorf("after Forward, dot=%v, want [2 4] in file", d)
+	}
+	if err := goForward(w); err != nil {
+		t.Fatalf("goForward failed: %v", err)
+	}
+	if d := f.body.dots[1].At; d != [2]int64{4, 6} {
+		t.Errorf("after Forward, dot=%v, want [4 6]", d)
+	}
+
+	// A search records the position where it began.
+	setDot(f.body, 1, 0, 0)
+	ctrl(f.body, 'f')
+	typ(f.body, "c\n")
+	if err := goBack(w); err != nil {
+		t.Fatalf("goBack failed: %v", err)
+	}
+	if d := f.body.dots[1].At; d != [2]int64{0, 0} {
+		t.Errorf("after Back from a search, dot=%v, want [0 0]", d)
+	}
+}
@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// msg returns the message for the format string
+// translated for the current locale, if there is a translation,
+// and formatted with fmt.Sprintf.
+func msg(format string, args ...interface{}) string {
+	if t, ok := messages[locale][format]; ok {
+		format = t
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// envLocale returns the locale of messages
+// named by the environment, or "" if there is none.
+//
+// The environment variables T_LANG, LC_ALL, LC_MESSAGES, and LANG
+// are consulted in that order.
+// A locale with a territory, for example de_CH.UTF-8,
+// falls back to its language, de, if it has no messages.
+func envLocale() string {
+	for _, v := range []string{"T_LANG", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		l := os.Getenv(v)
+		if l == "" {
+			continue
+		}
+		if i := strings.IndexAny(l, ".@"); i >= 0 {
+			l = l[:i]
+		}
+		if _, ok := messages[l]; ok {
+			return l
+		}
+		if i := strings.IndexAny(l, "_-"); i >= 0 {
+			l = l[:i]
+		}
+		if _, ok := messages[l]; ok {
+			return l
+		}
+		return ""
+	}
+	return ""
+}
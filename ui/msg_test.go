@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMsg(t *testing.T) {
+	defer func(l string) { locale = l }(locale)
+
+	locale = ""
+	if got, want := msg("no files match %s", "*.x"), "no files match *.x"; got != want {
+		t.Errorf("msg()=%q, want %q", got, want)
+	}
+	locale = "de"
+	if got, want := msg("no files match %s", "*.x"), "keine Dateien passen zu *.x"; got != want {
+		t.Errorf("msg()=%q, want %q", got, want)
+	}
+	if got, want := msg("untranslated %d", 5), "untranslated 5"; got != want {
+		t.Errorf("msg()=%q, want %q", got, want)
+	}
+}
+
+func TestEnvLocale(t *testing.T) {
+	vars := []string{"T_LANG", "LC_ALL", "LC_MESSAGES", "LANG"}
+	for _, v := range vars {
+		defer os.Setenv(v, os.Getenv(v))
+	}
+	tests := []struct {
+		env  [4]string
+		want string
+	}{
+		{want: ""},
+		{env: [4]string{"", "", "", "de_DE.UTF-8"}, want: "de"},
+		{env: [4]string{"", "", "", "fr_FR.UTF-8"}, want: ""},
+		{env: [4]string{"", "C", "", "de_DE.UTF-8"}, want: ""},
+		{env: [4]string{"", "", "de_CH@euro", "en_US"}, want: "de"},
+		{env: [4]string{"de", "en_US", "", ""}, want: "de"},
+	}
+	for _, test := range tests {
+		for i, v := range vars {
+			os.Setenv(v, test.env[i])
+		}
+		if got := envLocale(); got != test.want {
+			t.Errorf("envLocale() with %q=%q, want %q", test.env, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"os/exec"
+	"time"
+)
+
+// A finishedCmd is a command executed from a tag that finished.
+type finishedCmd struct {
+	text    string
+	elapsed time.Duration
+	err     error
+}
+
+// cmdFinished records the completion of a command executed from a tag,
+// to be notified by notifyFinished.
+// It may be called from any goroutine.
+func cmdFinished(w *Win, text string, start time.Time, err error) {
+	w.mu.Lock()
+	w.finished = append(w.finished, finishedCmd{text: text, elapsed: time.Since(start), err: err})
+	w.mu.Unlock()
+	wake(w)
+}
+
+// notifyFinished shows a desktop notification, by notifyCmd,
+// for each command that finished since the last tick
+// after running for at least notifyAfter,
+// if the window is not focused.
+func notifyFinished(w *Win) {
+	w.mu.Lock()
+	finished := w.finished
+	w.finished = nil
+	w.mu.Unlock()
+	if !w.unfocused || notifyAfter <= 0 || len(notifyCmd) == 0 {
+		return
+	}
+	for _, f := range finished {
+		if f.elapsed < notifyAfter {
+			continue
+		}
+		text := msg("%s finished", f.text)
+		if f.err != nil {
+			text = msg("%s failed: %s", f.text, f.err.Error())
+		}
+		args := append(append([]string{}, notifyCmd[1:]...), text)
+		cmd := exec.Command(notifyCmd[0], args...)
+		if err := cmd.Start(); err != nil {
+			w.OutputString(err.Error() + "\n")
+			continue
+		}
+		go cmd.Wait()
+	}
+}
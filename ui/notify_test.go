@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotifyFinished(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+	defer func(c []string, d time.Duration) { notifyCmd, notifyAfter = c, d }(notifyCmd, notifyAfter)
+	notifyCmd = []string{"sh", "-c", `echo "$1" >> ` + out, "sh"}
+	notifyAfter = time.Second
+
+	w := newTestWin()
+	long := time.Now().Add(-2 * time.Second)
+	cmdFinished(w, "focused", long, nil)
+	notifyFinished(w)
+
+	w.Focus(false)
+	cmdFinished(w, "short", time.Now(), nil)
+	cmdFinished(w, "mk", long, nil)
+	cmdFinished(w, "test", long, errors.New("exit status 1"))
+	notifyFinished(w)
+
+	// The notifications run concurrently, so their order may vary.
+	var got []string
+	for start := time.Now(); len(got) < 2 && time.Since(start) < 5*time.Second; {
+		data, _ := ioutil.ReadFile(out)
+		got = strings.SplitAfter(string(data), "\n")
+		got = got[:len(got)-1]
+		time.Sleep(time.Millisecond)
+	}
+	sort.Strings(got)
+	if want := []string{"mk finished\n", "test failed: exit status 1\n"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("notified %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"bufio"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/eaburns/T/rope"
+	"github.com/eaburns/T/syntax"
+)
+
+// selectedWord returns the text of dot
+// if it is the word selected by double-clicking, or "".
+func selectedWord(b *TextBox) string {
+	if b.word == "" {
+		return ""
+	}
+	dot := b.dots[1].At
+	if dot[1]-dot[0] != int64(len(b.word)) || rope.Slice(b.text, dot[0], dot[1]).String() != b.word {
+		return ""
+	}
+	return b.word
+}
+
+// occurrences returns highlights of the other occurrences
+// of the word selected by double-clicking
+// on the lines displayed in the text box.
+// Only whole words are highlighted.
+func occurrences(b *TextBox) []syntax.Highlight {
+	word := selectedWord(b)
+	if word == "" {
+		return nil
+	}
+	var str strings.Builder
+	visible := b.size.Y/b.style.Face.Metrics().Height.Ceil() + 1
+	rs := bufio.NewReader(rope.NewReader(rope.Slice(b.text, b.at, b.text.Len())))
+	for visible > 0 {
+		line, err := rs.ReadString('\n')
+		str.WriteString(line)
+		if err != nil {
+			break
+		}
+		visible--
+	}
+	var his []syntax.Highlight
+	style := hiStyle("occurrence")
+	s := str.String()
+	for i := 0; ; {
+		j := strings.Index(s[i:], word)
+		if j < 0 {
+			break
+		}
+		start, end := i+j, i+j+len(word)
+		at := b.at + int64(start)
+		before, _ := utf8.DecodeLastRuneInString(s[:start])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		if at != b.dots[1].At[0] &&
+			(start == 0 || !wordRune(before)) &&
+			(end == len(s) || !wordRune(after)) {
+			his = append(his, syntax.Highlight{At: [2]int64{at, at + int64(len(word))}, Style: style})
+		}
+		i = end
+	}
+	return his
+}
@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"image"
+	"reflect"
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestOccurrences(t *testing.T) {
+	const text = "foo bar\nfoobar foo_ foo\n(foo)"
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New(text))
+	b.now = fixedTime
+	pt := image.Point{A, H / 2}
+	b.Click(pt.Add(zp), 1)
+	b.Click(pt.Add(zp), -1)
+	b.Click(pt.Add(zp), 1)
+	b.Click(pt.Add(zp), -1)
+	if b.dots[1].At != [2]int64{0, 3} {
+		t.Fatalf("dot=%v, want [0 3]", b.dots[1].At)
+	}
+	b.lines()
+	var got [][2]int64
+	for _, h := range b.occurs {
+		got = append(got, h.At)
+	}
+	if want := [][2]int64{{20, 23}, {25, 28}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("occurrences=%v, want %v", got, want)
+	}
+
+	// Only the displayed lines are highlighted.
+	scrollDown(b, 1)
+	b.lines()
+	if len(b.occurs) != 2 {
+		t.Errorf("len(occurrences)=%d after scrolling, want 2", len(b.occurs))
+	}
+	scrollDown(b, 1)
+	b.lines()
+	if len(b.occurs) != 1 || b.occurs[0].At != [2]int64{25, 28} {
+		t.Errorf("occurrences=%v after scrolling, want [25 28]", b.occurs)
+	}
+
+	// Changing dot clears the highlights.
+	setDot(b, 1, 5, 5)
+	b.lines()
+	if b.word != "" || len(b.occurs) != 0 {
+		t.Errorf("word=%q, len(occurrences)=%d after moving dot, want \"\", 0", b.word, len(b.occurs))
+	}
+}
@@ -0,0 +1,157 @@
+package ui
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// An option is a setting of a text box
+// that can be changed by the Set command.
+type option struct {
+	name string
+	get  func(b *TextBox) string
+	set  func(b *TextBox, val string) error
+}
+
+// options are the options of the Set and Show options commands,
+// in the order they are shown.
+var options = []option{
+	boolOption("wrap", func(b *TextBox) bool { return !b.nowrap }, setWrap),
+	boolOption("elastic", func(b *TextBox) bool { return b.elastic }, setElastic),
+	{
+		name: "tabwidth",
+		get:  func(b *TextBox) string { return strconv.Itoa(b.tabWidth) },
+		set: func(b *TextBox, val string) error {
+			n, err := strconv.Atoi(val)
+			if err != nil || n <= 0 {
+				return errors.New(msg("%s wants a positive number", "tabwidth"))
+			}
+			b.tabWidth = n
+			dirtyLines(b)
+			return nil
+		},
+	},
+	boolOption("tabspaces", func(b *TextBox) bool { return b.tabSpaces }, func(b *TextBox, v bool) { b.tabSpaces = v }),
+	boolOption("autoindent", func(b *TextBox) bool { return b.autoIndent }, func(b *TextBox, v bool) { b.autoIndent = v }),
+	boolOption("showspace", func(b *TextBox) bool { return b.showSpace }, func(b *TextBox, v bool) { b.showSpace = v; dirtyLines(b) }),
+	boolOption("trimspace", func(b *TextBox) bool { return b.trimSpace }, func(b *TextBox, v bool) { b.trimSpace = v }),
+	boolOption("imports", func(b *TextBox) bool { return b.imports }, func(b *TextBox, v bool) { b.imports = v }),
+	{
+		name: "format",
+		get:  func(b *TextBox) string { return strings.Join(b.format, " ") },
+		set: func(b *TextBox, val string) error {
+			b.format = strings.Fields(val)
+			return nil
+		},
+	},
+	{
+		name: "comment",
+		get:  func(b *TextBox) string { return b.comment },
+		set: func(b *TextBox, val string) error {
+			b.comment = val
+			return nil
+		},
+	},
+}
+
+// boolOption returns an option with the value on or off.
+func boolOption(name string, get func(*TextBox) bool, set func(*TextBox, bool)) option {
+	return option{
+		name: name,
+		get: func(b *TextBox) string {
+			if get(b) {
+				return "on"
+			}
+			return "off"
+		},
+		set: func(b *TextBox, val string) error {
+			v, ok := parseOnOff(val)
+			if !ok {
+				return errors.New(msg("%s wants on or off", name))
+			}
+			set(b, v)
+			return nil
+		},
+	}
+}
+
+// parseOnOff returns the boolean value of on, off, true, or false.
+func parseOnOff(s string) (bool, bool) {
+	switch s {
+	case "on", "true":
+		return true, true
+	case "off", "false":
+		return false, true
+	}
+	return false, false
+}
+
+// setOption implements the Set command: Set [-g] option value.
+// The option of the sheet's body is set to the value,
+// or with -g, that of the bodies of all sheets of the window.
+// The value of the format and comment options is the rest of the line,
+// and it may be empty to unset them.
+func setOption(c *Col, s *Sheet, args string) error {
+	args = strings.TrimSpace(args)
+	global := false
+	if strings.HasPrefix(args, "-g ") || args == "-g" {
+		global = true
+		args = strings.TrimSpace(args[2:])
+	}
+	name, val := args, ""
+	if i := strings.IndexAny(args, " \t"); i >= 0 {
+		name, val = args[:i], strings.TrimSpace(args[i+1:])
+	}
+	if name == "" || !global && s == nil {
+		return errors.New(msg("usage: Set [-g] option value"))
+	}
+	opt, ok := findOption(name)
+	if !ok {
+		return errors.New(msg("unknown option %s", name))
+	}
+	if !global {
+		return opt.set(s.body, val)
+	}
+	w := c.win
+	for _, c := range w.cols {
+		for _, r := range c.rows[1:] {
+			if s, ok := r.(*Sheet); ok && s != w.output {
+				if err := opt.set(s.body, val); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func findOption(name string) (option, bool) {
+	for _, opt := range options {
+		if opt.name == name {
+			return opt, true
+		}
+	}
+	return option{}, false
+}
+
+// show implements the Show command: Show options.
+func show(s *Sheet, args string) error {
+	if strings.TrimSpace(args) != "options" {
+		return errors.New(msg("usage: Show options"))
+	}
+	if s != nil {
+		showOptions(s)
+	}
+	return nil
+}
+
+// showOptions shows the options of the sheet's body
+// in the Output sheet as Set commands.
+func showOptions(s *Sheet) {
+	var sb strings.Builder
+	for _, opt := range options {
+		sb.WriteString("Set " + opt.name + " " + opt.get(s.body) + "\n")
+	}
+	s.win.OutputString(sb.String())
+}
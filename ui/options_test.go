@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestCmd_Set(t *testing.T) {
+	var (
+		w  = newTestWin()
+		c  = w.cols[0]
+		s1 = NewSheet(w, "a.txt")
+		s2 = NewSheet(w, "b.txt")
+	)
+	c.Add(s1)
+	c.Add(s2)
+
+	for _, cmd := range []string{"Set tabwidth 3", "Set wrap off", "Set autoindent on", "Set format fmt -w 40", "Set comment #"} {
+		if err := execCmd(c, s1, cmd); err != nil {
+			t.Fatalf("%s failed: %v", cmd, err)
+		}
+	}
+	if s1.body.tabWidth != 3 || !s1.body.nowrap || !s1.body.autoIndent || s1.body.comment != "#" {
+		t.Errorf("tabWidth=%d, nowrap=%v, autoIndent=%v, comment=%q, want 3, true, true, #",
+			s1.body.tabWidth, s1.body.nowrap, s1.body.autoIndent, s1.body.comment)
+	}
+	if got := strings.Join(s1.body.format, " "); got != "fmt -w 40" {
+		t.Errorf("format=%q, want %q", got, "fmt -w 40")
+	}
+	if s2.body.tabWidth == 3 || s2.body.nowrap {
+		t.Errorf("b.txt tabWidth=%d, nowrap=%v, want unchanged", s2.body.tabWidth, s2.body.nowrap)
+	}
+
+	if err := execCmd(c, s1, "Set -g showspace on"); err != nil {
+		t.Fatalf("Set -g failed: %v", err)
+	}
+	if !s1.body.showSpace || !s2.body.showSpace {
+		t.Errorf("showSpace=%v, %v, want true, true", s1.body.showSpace, s2.body.showSpace)
+	}
+
+	for _, cmd := range []string{"Set", "Set nope 1", "Set wrap maybe", "Set tabwidth 0", "Show"} {
+		if err := execCmd(c, s1, cmd); err == nil {
+			t.Errorf("%s succeeded, want error", cmd)
+		}
+	}
+
+	if err := execCmd(c, s1, "Show options"); err != nil {
+		t.Fatalf("Show options failed: %v", err)
+	}
+	out := w.outputBuffer.String()
+	for _, want := range []string{"Set wrap off\n", "Set tabwidth 3\n", "Set autoindent on\n", "Set format fmt -w 40\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output=%q, want it to contain %q", out, want)
+		}
+	}
+}
+
+func TestAutoIndent(t *testing.T) {
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New("\t  x"))
+	b.autoIndent = true
+	setDot(b, 1, 4, 4)
+	b.Rune('\n')
+	if got, want := b.text.String(), "\t  x\n\t  "; got != want {
+		t.Errorf("text=%q, want %q", got, want)
+	}
+}
@@ -0,0 +1,171 @@
+package ui
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+)
+
+var (
+	checkboxRegexp = regexp.MustCompile(`^(\s*(?:[-+*]|\d+[.)]) \[)[ xX]\]`)
+	todoRegexp     = regexp.MustCompile(`^(\*+ )(TODO|DONE) `)
+)
+
+// headingMark returns the byte that marks headings
+// in the outline file at the given path:
+// * for org files, and # for markdown and all other files.
+func headingMark(path string) byte {
+	if strings.HasSuffix(path, ".org") {
+		return '*'
+	}
+	return '#'
+}
+
+// headingLevel returns the level of the heading on the line,
+// or 0 if the line is not a heading.
+func headingLevel(line string, h byte) int {
+	n := 0
+	for n < len(line) && line[n] == h {
+		n++
+	}
+	if n == 0 || n == len(line) || line[n] != ' ' {
+		return 0
+	}
+	return n
+}
+
+// dotLines returns the addresses of the lines spanned by dot,
+// not including their terminal newlines.
+func dotLines(b *TextBox) [][2]int64 {
+	dot := b.dots[1].At
+	start, err := edit.Addr([2]int64{dot[0], dot[0]}, "-0", b.text)
+	if err != nil {
+		return nil
+	}
+	end := dot[1]
+	if dot[0] < dot[1] {
+		end--
+	}
+	stop, err := edit.Addr([2]int64{end, end}, "+0", b.text)
+	if err != nil {
+		return nil
+	}
+	return splitLines(rope.Slice(b.text, start[0], stop[1]).String(), start[0])
+}
+
+// splitLines returns the addresses of the lines of str,
+// beginning at the address at,
+// not including their terminal newlines.
+func splitLines(str string, at int64) [][2]int64 {
+	var lines [][2]int64
+	for {
+		i := strings.IndexByte(str, '\n')
+		if i < 0 {
+			if len(str) > 0 {
+				lines = append(lines, [2]int64{at, at + int64(len(str))})
+			}
+			return lines
+		}
+		lines = append(lines, [2]int64{at, at + int64(i)})
+		at += int64(i) + 1
+		str = str[i+1:]
+	}
+}
+
+// changeLines applies a function to each line spanned by dot.
+// The function returns the replacement for the line.
+func changeLines(b *TextBox, f func(string) string) {
+	var diffs edit.Diffs
+	lines := dotLines(b)
+	for i := len(lines) - 1; i >= 0; i-- {
+		l := lines[i]
+		str := rope.Slice(b.text, l[0], l[1]).String()
+		if repl := f(str); repl != str {
+			diffs = append(diffs, edit.Diff{At: l, Text: rope.New(repl)})
+		}
+	}
+	b.Change(diffs)
+}
+
+// toggleCheck toggles task list checkboxes, - [ ] and - [x],
+// and org TODO and DONE headings on the lines spanned by dot.
+func toggleCheck(b *TextBox) {
+	changeLines(b, func(line string) string {
+		if m := checkboxRegexp.FindStringSubmatchIndex(line); m != nil {
+			mark := "x"
+			if line[m[3]] != ' ' {
+				mark = " "
+			}
+			return line[:m[3]] + mark + line[m[3]+1:]
+		}
+		if m := todoRegexp.FindStringSubmatch(line); m != nil {
+			state := "DONE"
+			if m[2] == "DONE" {
+				state = "TODO"
+			}
+			return m[1] + state + line[len(m[0])-1:]
+		}
+		return line
+	})
+}
+
+// shiftHeadings changes the level of headings
+// on the lines spanned by dot by delta.
+// The level of a heading is never less than 1.
+func shiftHeadings(b *TextBox, h byte, delta int) {
+	changeLines(b, func(line string) string {
+		n := headingLevel(line, h)
+		if n == 0 {
+			return line
+		}
+		m := n + delta
+		if m < 1 {
+			m = 1
+		}
+		return strings.Repeat(string(h), m) + line[n:]
+	})
+}
+
+// foldLevel folds the text so that only the headings
+// of level n or less are visible.
+// Text before the first heading remains visible.
+func foldLevel(b *TextBox, h byte, n int) {
+	var folds [][2]int64
+	start := int64(-1) // address of the newline ending the last visible line
+	hidden := false
+	text := b.text.String()
+	lines := splitLines(text, 0)
+	for i, l := range lines {
+		level := headingLevel(text[l[0]:l[1]], h)
+		switch {
+		case level > 0 && level <= n:
+			if hidden {
+				folds = append(folds, [2]int64{start, lines[i-1][1]})
+			}
+			start, hidden = l[1], false
+		case start >= 0:
+			hidden = true
+		}
+	}
+	if hidden {
+		folds = append(folds, [2]int64{start, lines[len(lines)-1][1]})
+	}
+	setFolds(b, folds)
+}
+
+// fold implements the Fold command: Fold [level].
+func fold(b *TextBox, h byte, args string) error {
+	n := 1
+	if args != "" {
+		var err error
+		if n, err = strconv.Atoi(args); err != nil || n < 1 {
+			return errors.New(msg("usage: Fold [level]"))
+		}
+	}
+	foldLevel(b, h, n)
+	return nil
+}
@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+)
+
+func TestToggleCheck(t *testing.T) {
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New("- [ ] a\n  * [x] b\n1. [ ] c\nd\n** TODO e\n"))
+	setDot(b, 1, 0, b.text.Len())
+	toggleCheck(b)
+	want := "- [x] a\n  * [ ] b\n1. [x] c\nd\n** DONE e\n"
+	if s := b.text.String(); s != want {
+		t.Errorf("text=%q, want %q", s, want)
+	}
+	if d := b.dots[1].At; d != [2]int64{0, b.text.Len()} {
+		t.Errorf("dot=%v, want [0 %d]", d, b.text.Len())
+	}
+
+	setDot(b, 1, 2, 2)
+	toggleCheck(b)
+	want = "- [ ] a\n  * [ ] b\n1. [x] c\nd\n** DONE e\n"
+	if s := b.text.String(); s != want {
+		t.Errorf("text=%q, want %q", s, want)
+	}
+}
+
+func TestShiftHeadings(t *testing.T) {
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New("# a\ntext\n## b\n#nope\n"))
+	setDot(b, 1, 0, b.text.Len())
+	shiftHeadings(b, '#', 1)
+	want := "## a\ntext\n### b\n#nope\n"
+	if s := b.text.String(); s != want {
+		t.Errorf("demote text=%q, want %q", s, want)
+	}
+	shiftHeadings(b, '#', -1)
+	shiftHeadings(b, '#', -1)
+	want = "# a\ntext\n# b\n#nope\n"
+	if s := b.text.String(); s != want {
+		t.Errorf("promote text=%q, want %q", s, want)
+	}
+}
+
+func TestFoldLevel(t *testing.T) {
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New("intro\n* a\ntext\n** b\nmore\n* c\nend"))
+	foldLevel(b, '*', 1)
+	want := [][2]int64{{9, 24}, {28, 32}}
+	if len(b.folds) != len(want) || b.folds[0] != want[0] || b.folds[1] != want[1] {
+		t.Fatalf("folds=%v, want %v", b.folds, want)
+	}
+	lines := b.lines()
+	if len(lines) != 3 {
+		t.Fatalf("len(lines)=%d, want 3", len(lines))
+	}
+	if n := lines[1].n; n != 19 {
+		t.Errorf("lines[1].n=%d, want 19", n)
+	}
+
+	// Edits move the folds.
+	b.Change([]edit.Diff{{At: [2]int64{0, 0}, Text: rope.New("x")}})
+	if b.folds[0] != [2]int64{10, 25} {
+		t.Errorf("after edit folds[0]=%v, want [10 25]", b.folds[0])
+	}
+
+	setFolds(b, nil)
+	if n := len(b.lines()); n != 7 {
+		t.Errorf("unfolded len(lines)=%d, want 7", n)
+	}
+}
@@ -0,0 +1,182 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+)
+
+// A pipe is a shell pipeline run on dot of a sheet's body
+// by executing |command.
+//
+// Its standard output replaces the piped text when it finishes,
+// and its standard error is written to the Output sheet.
+type pipe struct {
+	cmd   *exec.Cmd
+	text  string   // the text of the pipeline
+	at    [2]int64 // the address of the piped text
+	input string   // the piped text
+
+	mu     sync.Mutex
+	done   bool
+	out    bytes.Buffer
+	err    error
+	killed bool
+}
+
+// pipeDot implements executing |command in a sheet.
+// The text of dot of the body is piped through the command,
+// run by sh in the directory of the sheet,
+// and replaced by its output when it succeeds.
+// A pipe already running in the sheet is killed.
+//
+// With no command, a pipe running in the sheet is killed,
+// or if none is running, dot is piped through the last command.
+func pipeDot(s *Sheet, command string) error {
+	if command = strings.TrimSpace(command); command == "" {
+		if s.pipe != nil {
+			s.pipe.kill()
+			return nil
+		}
+		if command = s.win.lastPipe; command == "" {
+			return errors.New(msg("no pipe command"))
+		}
+	}
+	if s.pipe != nil {
+		s.pipe.kill()
+		s.pipe = nil
+	}
+	dir, err := abs(s, ".")
+	if err != nil {
+		return err
+	}
+	b := s.body
+	at := b.dots[1].At
+	p := &pipe{
+		cmd:   exec.Command("sh", "-c", command),
+		text:  command,
+		at:    at,
+		input: rope.Slice(b.text, at[0], at[1]).String(),
+	}
+	p.cmd.Dir = dir
+	p.cmd.Env = append(os.Environ(), sheetEnv(s)...)
+	p.cmd.Stdin = strings.NewReader(p.input)
+	// The pipes are os.Files, not io.Writers,
+	// so that Wait returns when the command is killed
+	// even if a process it started holds them open.
+	outr, outw, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	errr, errw, err := os.Pipe()
+	if err != nil {
+		outr.Close()
+		outw.Close()
+		return err
+	}
+	p.cmd.Stdout = outw
+	p.cmd.Stderr = errw
+	err = p.cmd.Start()
+	outw.Close()
+	errw.Close()
+	if err != nil {
+		outr.Close()
+		errr.Close()
+		return err
+	}
+	s.win.lastPipe = command
+	s.pipe = p
+	start := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		var buf [4096]byte
+		for {
+			n, err := outr.Read(buf[:])
+			p.mu.Lock()
+			p.out.Write(buf[:n])
+			p.mu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}()
+	go pipeOutput(&wg, s.win, errr)
+	go func() {
+		err := p.cmd.Wait()
+		p.mu.Lock()
+		killed := p.killed
+		p.mu.Unlock()
+		if !killed {
+			wg.Wait()
+		}
+		outr.Close()
+		errr.Close()
+		p.mu.Lock()
+		p.done = true
+		p.err = err
+		p.mu.Unlock()
+		cmdFinished(s.win, "|"+command, start, err)
+	}()
+	return nil
+}
+
+// pipeRune handles the key binding of piping dot:
+// like executing | alone in the sheet of the text box.
+func pipeRune(b *TextBox) {
+	_, s := jumpSheet(b.win, b)
+	if s == nil || s.TextBox != b {
+		return
+	}
+	if err := pipeDot(s, ""); err != nil {
+		b.win.OutputString(err.Error() + "\n")
+	}
+}
+
+// kill kills the pipe's command;
+// its output is discarded.
+func (p *pipe) kill() {
+	p.mu.Lock()
+	p.killed = true
+	p.mu.Unlock()
+	if p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+}
+
+// pipeUpdate replaces the piped text with the output of the pipe
+// if it finished successfully.
+// It returns whether the body changed.
+func pipeUpdate(s *Sheet) bool {
+	p := s.pipe
+	p.mu.Lock()
+	done, err, killed, out := p.done, p.err, p.killed, p.out.String()
+	p.mu.Unlock()
+	if !done {
+		return false
+	}
+	s.pipe = nil
+	b := s.body
+	switch {
+	case killed:
+		s.win.OutputString(msg("|%s killed", p.text) + "\n")
+		return false
+	case err != nil:
+		s.win.OutputString("|" + p.text + ": " + err.Error() + "\n")
+		return false
+	case p.at[1] > b.text.Len() || rope.Slice(b.text, p.at[0], p.at[1]).String() != p.input:
+		s.win.OutputString(msg("|%s: text changed while running", p.text) + "\n")
+		return false
+	}
+	b.Change(edit.Diffs{{At: p.at, Text: rope.New(out)}})
+	setDot(b, 1, p.at[0], p.at[0]+int64(len(out)))
+	return true
+}
@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eaburns/T/rope"
+)
+
+// waitPipe ticks the sheet until its pipe finishes.
+func waitPipe(t *testing.T, s *Sheet) {
+	t.Helper()
+	for deadline := time.Now().Add(10 * time.Second); s.pipe != nil; {
+		if time.Now().After(deadline) {
+			t.Fatalf("pipe did not finish")
+		}
+		time.Sleep(time.Millisecond)
+		s.Tick()
+	}
+}
+
+func TestCmd_Pipe(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	w := newTestWin()
+	c := w.cols[0]
+	s := NewSheet(w, filepath.Join(dir, "a.txt"))
+	c.Add(s)
+	s.body.SetText(rope.New("abc def ghi"))
+	setDot(s.body, 1, 4, 7)
+
+	if err := execCmd(c, s, "| tr a-z A-Z"); err != nil {
+		t.Fatalf("| tr a-z A-Z failed: %v", err)
+	}
+	waitPipe(t, s)
+	if got, want := s.body.text.String(), "abc DEF ghi"; got != want {
+		t.Errorf("text=%q, want %q", got, want)
+	}
+	if got, want := s.body.dots[1].At, [2]int64{4, 7}; got != want {
+		t.Errorf("dot=%v, want %v", got, want)
+	}
+
+	// | alone pipes through the last command.
+	setDot(s.body, 1, 0, 3)
+	if err := execCmd(c, s, "|"); err != nil {
+		t.Fatalf("| failed: %v", err)
+	}
+	waitPipe(t, s)
+	if got, want := s.body.text.String(), "ABC DEF ghi"; got != want {
+		t.Errorf("text=%q, want %q", got, want)
+	}
+
+	// Standard error goes to the Output sheet,
+	// and the text is unchanged if the command fails.
+	if err := execCmd(c, s, "|pwd; echo oops >&2; exit 1"); err != nil {
+		t.Fatalf("|pwd... failed: %v", err)
+	}
+	waitPipe(t, s)
+	if got, want := s.body.text.String(), "ABC DEF ghi"; got != want {
+		t.Errorf("text=%q, want %q", got, want)
+	}
+	if out := w.outputBuffer.String(); !strings.Contains(out, "oops\n") || !strings.Contains(out, "exit status 1") {
+		t.Errorf("output=%q, want oops and exit status 1", out)
+	}
+	w.outputBuffer.Reset()
+
+	// | alone kills a running pipe.
+	if err := execCmd(c, s, "|sleep 10"); err != nil {
+		t.Fatalf("|sleep 10 failed: %v", err)
+	}
+	if err := execCmd(c, s, "|"); err != nil {
+		t.Fatalf("| failed: %v", err)
+	}
+	waitPipe(t, s)
+	if got, want := w.outputBuffer.String(), "|sleep 10 killed\n"; got != want {
+		t.Errorf("output=%q, want %q", got, want)
+	}
+	if got, want := s.body.text.String(), "ABC DEF ghi"; got != want {
+		t.Errorf("text=%q, want %q", got, want)
+	}
+}
+
+func TestCmd_PipeChanged(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	w := newTestWin()
+	c := w.cols[0]
+	s := NewSheet(w, filepath.Join(dir, "a.txt"))
+	c.Add(s)
+	s.body.SetText(rope.New("abc"))
+	setDot(s.body, 1, 0, 3)
+	if err := execCmd(c, s, "|sleep 0.1; cat"); err != nil {
+		t.Fatalf("| failed: %v", err)
+	}
+	s.body.SetText(rope.New("xyz"))
+	waitPipe(t, s)
+	if got, want := s.body.text.String(), "xyz"; got != want {
+		t.Errorf("text=%q, want %q", got, want)
+	}
+	if got, want := w.outputBuffer.String(), "|sleep 0.1; cat: text changed while running\n"; got != want {
+		t.Errorf("output=%q, want %q", got, want)
+	}
+
+	w.lastPipe = ""
+	if err := execCmd(c, s, "|"); err == nil || err.Error() != "no pipe command" {
+		t.Errorf("| with no last command=%v, want no pipe command", err)
+	}
+}
@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	packageRegexp  = regexp.MustCompile(`(?m)^package\s`)
+	funcMainRegexp = regexp.MustCompile(`(?m)^func\s+main\s*\(\s*\)`)
+)
+
+// play runs a Go snippet with go run in a temporary directory.
+// The output of the program is written to the Output sheet.
+// The snippet is wrapped in a main package if it has no package clause,
+// and in a main function if it also has no main function.
+// Missing imports are added with goimportsCmd, if it is installed.
+func play(w *Win, snippet string) error {
+	dir, err := ioutil.TempDir("", "T-play")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	src := playSource(snippet)
+	if _, err := exec.LookPath(goimportsCmd[0]); err == nil {
+		if src, err = goimports(dir, src); err != nil {
+			return err
+		}
+	}
+	path := filepath.Join(dir, "main.go")
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), playTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "go", "run", path)
+	cmd.Dir = dir
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		stderr.Close()
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		stderr.Close()
+		stdout.Close()
+		return err
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go pipeOutput(&wg, w, stdout)
+	go pipeOutput(&wg, w, stderr)
+	wg.Wait()
+	return cmd.Wait()
+}
+
+// playSource returns the snippet wrapped in a main package
+// and main function as needed.
+func playSource(snippet string) string {
+	switch {
+	case packageRegexp.MatchString(snippet):
+		return snippet
+	case funcMainRegexp.MatchString(snippet):
+		return "package main\n\n" + snippet
+	default:
+		return "package main\n\nfunc main() {\n" + strings.TrimRight(snippet, "\n") + "\n}\n"
+	}
+}
@@ -0,0 +1,43 @@
+package ui
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestPlaySource(t *testing.T) {
+	tests := []struct {
+		snippet, want string
+	}{
+		{
+			snippet: "println(1)\n",
+			want:    "package main\n\nfunc main() {\nprintln(1)\n}\n",
+		},
+		{
+			snippet: "func main() {\n\tprintln(1)\n}\n",
+			want:    "package main\n\nfunc main() {\n\tprintln(1)\n}\n",
+		},
+		{
+			snippet: "package main\n\nfunc main() {}\n",
+			want:    "package main\n\nfunc main() {}\n",
+		},
+	}
+	for _, test := range tests {
+		if got := playSource(test.snippet); got != test.want {
+			t.Errorf("playSource(%q)=%q, want %q", test.snippet, got, test.want)
+		}
+	}
+}
+
+func TestPlay(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+	w := newTestWin()
+	if err := play(w, `print("hello")`); err != nil {
+		t.Fatalf("play failed: %v", err)
+	}
+	if got := w.outputBuffer.String(); got != "hello" {
+		t.Errorf("output=%q, want %q", got, "hello")
+	}
+}
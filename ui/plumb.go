@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"os/exec"
+	"regexp"
+	"runtime"
+)
+
+// plumb routes looked-at text to an external program
+// by the first of plumbRules whose regexp matches the text,
+// and returns whether one matched.
+// The program is run with the text as its final argument,
+// or the absolute path if the text names a file,
+// and is not waited for.
+func plumb(s *Sheet, text string) (bool, error) {
+	for _, r := range plumbRules {
+		ok, err := regexp.MatchString(r.regexp, text)
+		if err != nil {
+			return true, err
+		}
+		if !ok {
+			continue
+		}
+		arg := text
+		if path, err := abs(s, text); err == nil && fileExists(path) {
+			arg = path
+		}
+		cmd := r.cmd
+		if len(cmd) == 0 {
+			cmd = systemOpener()
+		}
+		args := append(append([]string{}, cmd[1:]...), arg)
+		c := exec.Command(cmd[0], args...)
+		if err := c.Start(); err != nil {
+			return true, err
+		}
+		go c.Wait()
+		return true, nil
+	}
+	return false, nil
+}
+
+// systemOpener returns the command that opens a URL or file
+// with the default program of the operating system.
+func systemOpener() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"open"}
+	case "windows":
+		// Not cmd /c start, since cmd.exe would interpret
+		// the &, |, ^, and % of the text as commands.
+		return []string{"rundll32", "url.dll,FileProtocolHandler"}
+	default:
+		return []string{"xdg-open"}
+	}
+}
@@ -0,0 +1,58 @@
+package ui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPlumb(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+	write(filepath.Join(dir, "a.doc"), "")
+	defer func(r []struct {
+		regexp string
+		cmd    []string
+	}) {
+		plumbRules = r
+	}(plumbRules)
+	plumbRules = []struct {
+		regexp string
+		cmd    []string
+	}{
+		{`^test:`, []string{"sh", "-c", `echo "$1" >> ` + out, "sh"}},
+		{`\.doc$`, []string{"sh", "-c", `echo "$1" >> ` + out, "sh"}},
+	}
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, filepath.Join(dir, "b.txt"))
+	)
+	c.Add(s)
+	for _, test := range []struct {
+		text, want string
+	}{
+		{"test:x", "test:x\n"},
+		{"a.doc", "test:x\n" + filepath.Join(dir, "a.doc") + "\n"},
+	} {
+		if err := lookText(c, s, test.text); err != nil {
+			t.Fatalf("lookText(%q) failed: %v", test.text, err)
+		}
+		var got string
+		for start := time.Now(); got != test.want && time.Since(start) < 5*time.Second; {
+			data, _ := ioutil.ReadFile(out)
+			got = string(data)
+			time.Sleep(time.Millisecond)
+		}
+		if got != test.want {
+			t.Errorf("after %q, plumbed %q, want %q", test.text, got, test.want)
+		}
+	}
+	if ok, err := plumb(s, "b.txt"); ok || err != nil {
+		t.Errorf("plumb(b.txt)=%v,%v, want false,nil", ok, err)
+	}
+}
@@ -0,0 +1,269 @@
+package ui
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"strings"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// A popup is a list of completions of the word before the cursor
+// of a text box, drawn by the window over the text below the cursor.
+//
+// The up and down arrow keys select a completion,
+// tab or newline replaces the word with it, and escape closes the popup.
+// Typing filters the completions by the word,
+// and the popup is closed when the cursor leaves the word.
+type popup struct {
+	b     *TextBox
+	start int64    // address of the start of the completed word
+	all   []string // all of the completions
+	items []string // the completions beginning with the word
+	sel   int      // index in items of the selected completion
+	top   int      // index in items of the first completion shown
+}
+
+// popupComplete implements the Complete command.
+// The completions of the word before dot are those of the sheet's
+// language server, if it has one, and otherwise the words of the body.
+// A single completion replaces the word;
+// otherwise the word is extended by their longest common prefix
+// and they are shown in a popup.
+func popupComplete(s *Sheet) error {
+	b := s.body
+	dot := b.dots[1].At[1]
+	start := wordStart(b, dot)
+	var all []string
+	if s.lsp != nil {
+		var err error
+		if all, err = lspCompletions(s, dot); err != nil {
+			return err
+		}
+	} else {
+		all = textWords(b, rope.Slice(b.text, start, dot).String())
+	}
+	prefix := rope.Slice(b.text, start, dot).String()
+	var items []string
+	for _, t := range all {
+		if strings.HasPrefix(t, prefix) {
+			items = append(items, t)
+		}
+	}
+	switch len(items) {
+	case 0:
+		return errors.New(msg("no completions"))
+	case 1:
+		replaceWord(b, start, items[0])
+		return nil
+	}
+	ext := items[0]
+	for _, t := range items[1:] {
+		ext = commonPrefix(ext, t)
+	}
+	if len(ext) > len(prefix) {
+		replaceWord(b, start, ext)
+	}
+	b.win.popup = &popup{b: b, start: start, all: all, items: items}
+	b.win.popupDirty = true
+	return nil
+}
+
+// completeRune handles the key binding of the Complete command.
+func completeRune(b *TextBox) {
+	_, s := jumpSheet(b.win, b)
+	if s == nil || s.TextBox != b {
+		return
+	}
+	if err := popupComplete(s); err != nil {
+		b.win.OutputString(err.Error() + "\n")
+	}
+}
+
+// replaceWord replaces the text from the address to dot
+// with the string, and sets dot to the end of it.
+func replaceWord(b *TextBox, start int64, str string) {
+	b.Change(edit.Diffs{{At: [2]int64{start, b.dots[1].At[1]}, Text: rope.New(str)}})
+	end := start + int64(len(str))
+	b.cursorCol = -1
+	setDot(b, 1, end, end)
+}
+
+// closePopup closes the window's popup, if any.
+func closePopup(w *Win) {
+	if w.popup != nil {
+		w.popup = nil
+		w.popupDirty = true
+	}
+}
+
+// popupRune handles a rune typed while the popup is open.
+// It returns whether the rune was handled.
+func popupRune(w *Win, r rune) bool {
+	p := w.popup
+	switch r {
+	case '\t', '\n':
+		replaceWord(p.b, p.start, p.items[p.sel])
+		closePopup(w)
+	case esc:
+		closePopup(w)
+	default:
+		return false
+	}
+	return true
+}
+
+// popupDir handles an up or down arrow key while the popup is open,
+// moving the selection.
+// It returns whether the key was handled.
+func popupDir(w *Win, x, y int) bool {
+	p := w.popup
+	if x != 0 || y != -1 && y != 1 {
+		return false
+	}
+	p.sel = (p.sel + y + len(p.items)) % len(p.items)
+	switch {
+	case p.sel < p.top:
+		p.top = p.sel
+	case p.sel >= p.top+maxPopupItems:
+		p.top = p.sel - maxPopupItems + 1
+	}
+	w.popupDirty = true
+	return true
+}
+
+// popupClick handles a button 1 press while the popup is open.
+// A click on a completion replaces the word with it.
+// It returns whether the click was handled.
+func popupClick(w *Win, pt image.Point) bool {
+	r, ok := popupRect(w)
+	if !ok || !pt.In(r) {
+		closePopup(w)
+		return false
+	}
+	p := w.popup
+	if i := p.top + (pt.Y-r.Min.Y)/w.lineHeight; i < len(p.items) {
+		replaceWord(p.b, p.start, p.items[i])
+	}
+	closePopup(w)
+	return true
+}
+
+// filterPopup updates the completions of the popup
+// for the word before the cursor,
+// closing it if the cursor left the word
+// or no completions begin with it.
+func filterPopup(w *Win) {
+	p := w.popup
+	b := p.b
+	dot := b.dots[1].At
+	if focusedTextBox(w) != b || dot[0] != dot[1] || dot[0] < p.start || dot[0] > b.text.Len() || wordStart(b, dot[0]) != p.start {
+		closePopup(w)
+		return
+	}
+	prefix := rope.Slice(b.text, p.start, dot[0]).String()
+	var items []string
+	for _, t := range p.all {
+		if strings.HasPrefix(t, prefix) && t != prefix {
+			items = append(items, t)
+		}
+	}
+	if len(items) == 0 {
+		closePopup(w)
+		return
+	}
+	if len(items) != len(p.items) {
+		p.sel, p.top = 0, 0
+	}
+	p.items = items
+	w.popupDirty = true
+}
+
+// popupRect returns the bounds of the popup in the window,
+// and whether it is shown:
+// whether the start of the completed word is displayed.
+// The popup is below the line of the word if it fits,
+// and otherwise above it.
+func popupRect(w *Win) (image.Rectangle, bool) {
+	p := w.popup
+	if p == nil {
+		return image.Rectangle{}, false
+	}
+	origin, ok := textBoxOrigin(w, p.b)
+	if !ok {
+		return image.Rectangle{}, false
+	}
+	pt, ok := addrPoint(p.b, p.start)
+	if !ok {
+		return image.Rectangle{}, false
+	}
+	pt = pt.Add(origin)
+	n := len(p.items)
+	if n > maxPopupItems {
+		n = maxPopupItems
+	}
+	var width fixed.Int26_6
+	for _, t := range p.items {
+		if dx := font.MeasureString(w.face, t); dx > width {
+			width = dx
+		}
+	}
+	size := image.Pt(width.Ceil()+2*textPadPx, n*w.lineHeight)
+	r := image.Rectangle{Min: pt, Max: pt.Add(size)}
+	if r.Max.Y > w.size.Y {
+		r = r.Sub(image.Pt(0, size.Y+w.lineHeight))
+	}
+	if r.Max.X > w.size.X {
+		r = r.Sub(image.Pt(r.Max.X-w.size.X, 0))
+	}
+	if r.Min.X < 0 {
+		r = r.Sub(image.Pt(r.Min.X, 0))
+	}
+	return r, true
+}
+
+// textBoxOrigin returns the window point
+// of the upper left of the text box of a sheet,
+// and whether the text box is in the window.
+func textBoxOrigin(w *Win, b *TextBox) (image.Point, bool) {
+	for i, c := range w.cols {
+		for j, r := range c.rows {
+			s := getSheet(r)
+			switch {
+			case s == nil:
+				continue
+			case s.tag == b:
+				return image.Pt(x0(w, i), y0(c, j)), true
+			case s.body == b:
+				return image.Pt(x0(w, i), y0(c, j)+s.tagH), true
+			}
+		}
+	}
+	return image.Point{}, false
+}
+
+// drawPopup draws the popup, if it is shown, over the window image.
+func drawPopup(w *Win, img draw.Image) {
+	r, ok := popupRect(w)
+	if !ok {
+		return
+	}
+	p := w.popup
+	r = r.Add(img.Bounds().Min)
+	fillRect(img, frameBG, r.Inset(-framePx))
+	fillRect(img, tagBG, r)
+	d := font.Drawer{Dst: img, Src: image.NewUniform(fg), Face: w.face}
+	ascent := w.face.Metrics().Ascent
+	for i := p.top; i < len(p.items) && i < p.top+maxPopupItems; i++ {
+		y := r.Min.Y + (i-p.top)*w.lineHeight
+		if i == p.sel {
+			fillRect(img, hiBG1, image.Rect(r.Min.X, y, r.Max.X, y+w.lineHeight))
+		}
+		d.Dot = fixed.Point26_6{X: fixed.I(r.Min.X + textPadPx), Y: fixed.I(y) + ascent}
+		d.DrawString(p.items[i])
+	}
+}
@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestPopupComplete(t *testing.T) {
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, "/a/b.txt")
+	)
+	c.Add(s)
+	s.body.SetText(rope.New("alpha alphabet alpine\nal"))
+	end := s.body.text.Len()
+	setDot(s.body, 1, end, end)
+
+	if err := execCmd(c, s, "Complete"); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if got, want := s.body.text.String(), "alpha alphabet alpine\nalp"; got != want {
+		t.Errorf("after Complete, text=%q, want %q", got, want)
+	}
+	if w.popup == nil {
+		t.Fatalf("no popup")
+	}
+	if want := []string{"alpha", "alphabet", "alpine"}; !reflect.DeepEqual(w.popup.items, want) {
+		t.Errorf("items=%v, want %v", w.popup.items, want)
+	}
+
+	w.Dir(0, 1)
+	w.Dir(0, 1)
+	w.Dir(0, 1)
+	if w.popup.sel != 0 {
+		t.Errorf("after 3 downs, sel=%d, want 0", w.popup.sel)
+	}
+	w.Dir(0, -1)
+	if w.popup.sel != 2 {
+		t.Errorf("after up, sel=%d, want 2", w.popup.sel)
+	}
+
+	// Typing filters the completions.
+	w.Rune('h')
+	if want := []string{"alpha", "alphabet"}; w.popup == nil || !reflect.DeepEqual(w.popup.items, want) {
+		t.Fatalf("after typing h, popup=%+v, want items %v", w.popup, want)
+	}
+	w.Dir(0, 1)
+	w.Rune('\t')
+	if got, want := s.body.text.String(), "alpha alphabet alpine\nalphabet"; got != want {
+		t.Errorf("after tab, text=%q, want %q", got, want)
+	}
+	if w.popup != nil {
+		t.Errorf("popup still open after tab")
+	}
+
+	// Leaving the word closes the popup.
+	s.body.SetText(rope.New("alpha alphabet\nal"))
+	setDot(s.body, 1, 17, 17)
+	if err := execCmd(c, s, "Complete"); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if w.popup == nil {
+		t.Fatalf("no popup")
+	}
+	w.Rune(' ')
+	if w.popup != nil {
+		t.Errorf("popup still open after typing a space")
+	}
+
+	s.body.SetText(rope.New("alpha alphabet\nalphab"))
+	setDot(s.body, 1, 21, 21)
+	if err := execCmd(c, s, "Complete"); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if got, want := s.body.text.String(), "alpha alphabet\nalphabet"; got != want || w.popup != nil {
+		t.Errorf("single completion, text=%q, popup=%v, want %q, nil", got, w.popup, want)
+	}
+	err := execCmd(c, s, "Complete")
+	if want := "no completions"; err == nil || err.Error() != want {
+		t.Errorf("Complete=%v, want %q", err, want)
+	}
+}
+
+func TestAddrPoint(t *testing.T) {
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New("ab\ncd"))
+	tests := []struct {
+		at int64
+		pt [2]int
+	}{
+		{0, [2]int{textPadPx, H}},
+		{1, [2]int{textPadPx + A, H}},
+		{4, [2]int{textPadPx + A, 2 * H}},
+		{5, [2]int{textPadPx + 2*A, 2 * H}},
+	}
+	for _, test := range tests {
+		pt, ok := addrPoint(b, test.at)
+		if !ok || pt.X != test.pt[0] || pt.Y != test.pt[1] {
+			t.Errorf("addrPoint(%d)=%v,%v, want %v,true", test.at, pt, ok, test.pt)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package ui
+
+import (
+	"image"
+	"image/draw"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+	"golang.org/x/image/math/fixed"
+)
+
+// Preedit handles an input method composition event.
+//
+// The text is the input method's current, uncommitted composition.
+// It is drawn over the focused text box at dot,
+// in the 1-click selection style and underlined,
+// but it is not part of the text until it is committed.
+// Each call replaces the text of the previous call.
+// An empty text cancels the composition.
+func (w *Win) Preedit(text string) {
+	if b := focusedTextBox(w); b != nil {
+		setPreedit(b, text)
+	}
+}
+
+// Commit handles an input method commit event.
+//
+// The text replaces dot, and the composition, if any, ends.
+func (w *Win) Commit(text string) {
+	w.alone = [4]bool{}
+	if b := focusedTextBox(w); b != nil {
+		commit(b, text)
+	}
+	releaseLatched(w)
+}
+
+func focusedTextBox(w *Win) *TextBox {
+	switch r := w.Col.Row.(type) {
+	case *Sheet:
+		return r.TextBox
+	case *TextBox:
+		return r
+	}
+	return nil
+}
+
+func setPreedit(b *TextBox, text string) {
+	if b.preedit == text {
+		return
+	}
+	b.preedit = text
+	dirtyDot(b, b.dots[1].At)
+}
+
+func commit(b *TextBox, text string) {
+	setPreedit(b, "")
+	if text == "" {
+		return
+	}
+	at := b.dots[1].At
+	b.Change(edit.Diffs{{At: at, Text: rope.New(text)}})
+	end := at[0] + int64(len(text))
+	b.cursorCol = -1
+	setDot(b, 1, end, end)
+}
+
+// drawPreedit draws the composition, if any, over the text at dot
+// and returns the area of the text box that it covers.
+func drawPreedit(b *TextBox, img draw.Image) image.Rectangle {
+	if b.preedit == "" {
+		return image.ZR
+	}
+	pt, ok := preeditPoint(b)
+	if !ok {
+		return image.ZR
+	}
+	style := b.style.Merge(b.dots[1].Style)
+	m := b.style.Face.Metrics()
+	x0 := fixed.I(pt.X)
+	x := x0
+	var prev rune
+	for _, r := range b.preedit {
+		x += kern(style, prev, r)
+		prev = r
+		adv, _ := style.Face.GlyphAdvance(r)
+		x += adv
+	}
+	h := (m.Height + m.Descent).Floor()
+	r := image.Rect(x0.Floor(), pt.Y, x.Ceil(), pt.Y+h).Intersect(image.Rectangle{Max: b.size})
+	fillRect(img, style.BG, r.Add(img.Bounds().Min))
+	x, prev = x0, 0
+	yb := fixed.I(pt.Y) + m.Ascent
+	for _, r := range b.preedit {
+		x += kern(style, prev, r)
+		prev = r
+		x += drawGlyph(img, style, x, yb, r)
+	}
+	u := image.Rect(r.Min.X, r.Max.Y-underlinePx, r.Max.X, r.Max.Y)
+	fillRect(img, style.FG, u.Add(img.Bounds().Min))
+	return r
+}
+
+// preeditPoint returns the upper left of the point dot,
+// where the composition is drawn, relative to the text box,
+// and whether it is displayed.
+func preeditPoint(b *TextBox) (image.Point, bool) {
+	m := b.style.Face.Metrics()
+	h := m.Height + m.Descent
+	at := b.dots[1].At[0]
+	if b.text.Len() == 0 {
+		return image.Pt(textPadPx, 0), true
+	}
+	lines := b.lines()
+	if n := len(lines); n > 0 && at == b.text.Len() && lastRune(&lines[n-1]) == '\n' {
+		// Dot is on the empty line after the text.
+		end, y := b.at, fixed.Int26_6(0)
+		for _, l := range lines {
+			end += l.n
+			y += l.h
+		}
+		return image.Pt(textPadPx-b.xoff, y.Floor()), end == at
+	}
+	pt, ok := addrPoint(b, at)
+	return image.Pt(pt.X, pt.Y-h.Floor()), ok
+}
@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"image"
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestPreedit(t *testing.T) {
+	w := newTestWin()
+	s := NewSheet(w, "")
+	w.cols[0].Add(s)
+	s.body.SetText(rope.New("ab"))
+	s.saved = s.body.text
+	setDot(s.body, 1, 1, 1)
+	s.TextBox = s.body
+	s.body.Focus(true)
+
+	w.Preedit("n")
+	w.Preedit("ni")
+	if got := s.body.text.String(); got != "ab" {
+		t.Errorf("after preedit, text=%q, want %q", got, "ab")
+	}
+	if s.body.preedit != "ni" {
+		t.Errorf("after preedit, preedit=%q, want %q", s.body.preedit, "ni")
+	}
+	if isModified(s) {
+		t.Errorf("after preedit, the sheet is modified")
+	}
+	w.Commit("你")
+	if got := s.body.text.String(); got != "a你b" {
+		t.Errorf("after commit, text=%q, want %q", got, "a你b")
+	}
+	if got := s.body.dots[1].At; got != [2]int64{4, 4} {
+		t.Errorf("after commit, dot=%v, want [4 4]", got)
+	}
+	if s.body.preedit != "" {
+		t.Errorf("after commit, preedit=%q, want empty", s.body.preedit)
+	}
+
+	w.Preedit("x")
+	w.Preedit("")
+	if got := s.body.text.String(); got != "a你b" {
+		t.Errorf("after cancel, text=%q, want %q", got, "a你b")
+	}
+	w.Commit("!")
+	if got := s.body.text.String(); got != "a你!b" {
+		t.Errorf("after commit without preedit, text=%q, want %q", got, "a你!b")
+	}
+
+	w.Preedit("x")
+	s.body.Focus(false)
+	if s.body.preedit != "" {
+		t.Errorf("after focus change, preedit=%q, want empty", s.body.preedit)
+	}
+}
+
+func TestPreeditDraw(t *testing.T) {
+	size := image.Pt(100, 100)
+	b := NewTextBox(testWin, testTextStyles, size)
+	b.SetText(rope.New("Hello\n"))
+	b.Focus(true)
+	setDot(b, 1, 1, 1)
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	b.Draw(true, img)
+	before := string(img.Pix)
+
+	for _, at := range []int64{1, 6} {
+		setDot(b, 1, at, at)
+		b.Draw(false, img)
+		before = string(img.Pix)
+
+		setPreedit(b, "xy")
+		b.Draw(false, img)
+		if string(img.Pix) == before {
+			t.Errorf("dot=%d: the image did not change after preedit", at)
+		}
+		full := image.NewRGBA(image.Rectangle{Max: size})
+		b.Draw(true, full)
+		if string(full.Pix) != string(img.Pix) {
+			t.Errorf("dot=%d: the redrawn image differs from the drawn preedit", at)
+		}
+
+		setPreedit(b, "")
+		b.Draw(false, img)
+		if string(img.Pix) != before {
+			t.Errorf("dot=%d: the image is not restored after canceling preedit", at)
+		}
+	}
+}
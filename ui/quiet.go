@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+)
+
+// quietText is appended to the first line of the column tags
+// while background activity is paused.
+const quietText = " Resume"
+
+// setQuiet pauses background activity if quiet is true,
+// and resumes it otherwise.
+// While paused, the cursor does not blink,
+// Watch commands are not rerun,
+// files changed on disk are not reloaded,
+// though Put still refuses to overwrite them,
+// modified sheets are not autosaved,
+// language servers are not sent changes,
+// and extensions that exit are not restarted.
+// Work that became due while paused is done on Resume.
+// While paused, the column tags show the Resume command.
+func setQuiet(w *Win, quiet bool) {
+	if w.quiet == quiet {
+		return
+	}
+	w.quiet = quiet
+	w.mu.Lock()
+	if quiet {
+		w.resume = make(chan struct{})
+	} else {
+		close(w.resume)
+		w.resume = nil
+	}
+	w.mu.Unlock()
+	for _, c := range w.cols {
+		setQuietText(c)
+	}
+}
+
+// closedChan is a closed channel.
+var closedChan = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+// resumed returns a channel that is closed
+// once the window is not quiet.
+// It is safe for concurrent calls.
+func resumed(w *Win) <-chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.resume == nil {
+		return closedChan
+	}
+	return w.resume
+}
+
+// setQuietText adds or removes quietText
+// from the tag of the column
+// according to whether its window is quiet.
+func setQuietText(c *Col) {
+	tag := c.rows[0].(*TextBox)
+	text := tag.text.String()
+	end := strings.IndexRune(text, '\n')
+	if end < 0 {
+		end = len(text)
+	}
+	has := strings.HasSuffix(text[:end], quietText)
+	switch {
+	case c.win.quiet && !has:
+		at := int64(end)
+		tag.Change(edit.Diffs{{At: [2]int64{at, at}, Text: rope.New(quietText)}})
+	case !c.win.quiet && has:
+		at := [2]int64{int64(end - len(quietText)), int64(end)}
+		tag.Change(edit.Diffs{{At: at, Text: rope.Empty()}})
+	}
+}
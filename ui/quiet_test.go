@@ -0,0 +1,205 @@
+package ui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestCmd_QuietResume(t *testing.T) {
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, "")
+	)
+	c.Add(s)
+	if err := execCmd(c, s, "Quiet"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if err := execCmd(c, s, "Quiet"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	c1 := w.Add()
+	for i, c := range w.cols {
+		if got, want := c.rows[0].(*TextBox).text.String(), colText[:len(colText)-1]+quietText+"\n"; got != want {
+			t.Errorf("column %d tag=%q, want %q", i, got, want)
+		}
+	}
+
+	b := s.body
+	b.Focus(true)
+	now := time.Now()
+	b.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		b.Tick()
+		if !b.showCursor {
+			t.Errorf("cursor blinked while quiet")
+		}
+		now = now.Add(blinkDuration)
+	}
+
+	if err := execCmd(c1, nil, "Resume"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	for i, c := range w.cols {
+		if got := c.rows[0].(*TextBox).text.String(); got != colText {
+			t.Errorf("column %d tag=%q, want %q", i, got, colText)
+		}
+	}
+	b.Tick()
+	if b.showCursor {
+		t.Errorf("cursor did not blink after Resume")
+	}
+}
+
+func TestQuietWatch(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	write(filepath.Join(dir, "a.txt"), "1\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, filepath.Join(dir, "a.txt"))
+	)
+	c.Add(s)
+	if err := execCmd(c, s, "Watch cat a.txt"); err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	b := findSheet(w, filepath.Join(dir, "+Watch"))
+	wait := func(want string) {
+		t.Helper()
+		for start := time.Now(); b.body.text.String() != want && time.Since(start) < 5*time.Second; {
+			b.Tick()
+			time.Sleep(time.Millisecond)
+		}
+		if got := b.body.text.String(); got != want {
+			t.Fatalf("+Watch=%q, want %q", got, want)
+		}
+	}
+	wait("$ cat a.txt\n1\n")
+
+	setQuiet(w, true)
+	write(filepath.Join(dir, "a.txt"), "2\n")
+	for start := time.Now(); time.Since(start) < 4*watchDelay; time.Sleep(time.Millisecond) {
+		b.Tick()
+	}
+	if got := b.body.text.String(); got != "$ cat a.txt\n1\n" {
+		t.Errorf("while quiet, +Watch=%q", got)
+	}
+	if d := w.NextTick(time.Now()); d >= 0 {
+		t.Errorf("while quiet, NextTick=%v, want none", d)
+	}
+
+	setQuiet(w, false)
+	wait("$ cat a.txt\n2\n")
+}
+
+func TestQuietAutosave(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	defer func(d string) { recoveryDir = d }(recoveryDir)
+	recoveryDir = filepath.Join(dir, "recover")
+	path := filepath.Join(dir, "a.txt")
+	write(path, "a\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, path)
+	)
+	c.Add(s)
+	if err := s.Get(); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	s.body.SetText(rope.New("b\n"))
+	setQuiet(w, true)
+	autosave(w)
+	file := recoveryFile(winRecoveryDir(w), path)
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("recovery file written while quiet: %v", err)
+	}
+	setQuiet(w, false)
+	autosave(w)
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("no recovery file after Resume: %v", err)
+	}
+}
+
+func TestQuietResumed(t *testing.T) {
+	w := newTestWin()
+	select {
+	case <-resumed(w):
+	default:
+		t.Errorf("resumed is not closed before Quiet")
+	}
+	setQuiet(w, true)
+	ch := resumed(w)
+	select {
+	case <-ch:
+		t.Errorf("resumed is closed while quiet")
+	default:
+	}
+	setQuiet(w, false)
+	select {
+	case <-ch:
+	default:
+		t.Errorf("resumed is not closed after Resume")
+	}
+}
+
+func TestQuietPut(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.txt")
+	write(path, "1\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, path)
+	)
+	c.Add(s)
+	defer stopWatchingFiles(w)
+	if err := s.Get(); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	watchFiles(w)
+
+	setQuiet(w, true)
+	write(path, "2\n")
+	for start := time.Now(); !s.conflict && time.Since(start) < 5*time.Second; time.Sleep(time.Millisecond) {
+		watchFiles(w)
+	}
+	if !s.conflict {
+		t.Fatalf("while quiet, the change of a.txt was not noticed")
+	}
+	if got := s.body.text.String(); got != "1\n" {
+		t.Errorf("while quiet, body=%q, want %q", got, "1\n")
+	}
+	if strings.Contains(s.tag.text.String(), conflictTagText) {
+		t.Errorf("while quiet, tag=%q, want no %q", s.tag.text.String(), conflictTagText)
+	}
+	s.body.SetText(rope.New("x\n"))
+	err := execCmd(c, s, "Put")
+	if want := path + " changed on disk; execute Put! to overwrite it"; err == nil || err.Error() != want {
+		t.Errorf("Put=%v, want %q", err, want)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "2\n" {
+		t.Errorf("a.txt=%q after Put, want %q", data, "2\n")
+	}
+
+	setQuiet(w, false)
+	watchFiles(w)
+	if got := s.body.text.String(); got != "x\n" {
+		t.Errorf("after Resume, body=%q, want %q", got, "x\n")
+	}
+	if !strings.Contains(s.tag.text.String(), conflictTagText) {
+		t.Errorf("after Resume, tag=%q, want it to contain %q", s.tag.text.String(), conflictTagText)
+	}
+}
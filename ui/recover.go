@@ -0,0 +1,267 @@
+package ui
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/eaburns/T/rope"
+)
+
+// recoveryPath returns the directory of the recovery files.
+func recoveryPath() string {
+	if recoveryDir != "" {
+		return recoveryDir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "T", "recover")
+}
+
+// numWins is the number of windows
+// that wrote recovery files in this process.
+var numWins int32
+
+// winRecoveryDir returns the directory of the recovery files of the window,
+// or "" if there is no recoveryPath.
+// It is a directory of recoveryPath named pid.n,
+// where pid is the process ID and n numbers the windows of the process,
+// so windows running at the same time do not share recovery files.
+func winRecoveryDir(w *Win) string {
+	base := recoveryPath()
+	if base == "" {
+		return ""
+	}
+	if w.recoverID == "" {
+		n := atomic.AddInt32(&numWins, 1)
+		w.recoverID = strconv.Itoa(os.Getpid()) + "." + strconv.Itoa(int(n))
+	}
+	return filepath.Join(base, w.recoverID)
+}
+
+// orphanedRecoveryDirs returns the window recovery directories
+// of processes that are no longer running.
+func orphanedRecoveryDirs() ([]string, error) {
+	base := recoveryPath()
+	if base == "" {
+		return nil, nil
+	}
+	infos, err := ioutil.ReadDir(base)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var dirs []string
+	for _, info := range infos {
+		pid, err := strconv.Atoi(strings.SplitN(info.Name(), ".", 2)[0])
+		if !info.IsDir() || err != nil || pid == os.Getpid() || processExists(pid) {
+			continue
+		}
+		dirs = append(dirs, filepath.Join(base, info.Name()))
+	}
+	return dirs, nil
+}
+
+// processExists returns whether a process with the ID is running.
+// A process that exited and whose ID was reused
+// is mistaken for a running one.
+func processExists(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		// On Windows, FindProcess fails if there is no process.
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM) || runtime.GOOS == "windows"
+}
+
+// recoveryFile returns the path of the recovery file of a file.
+func recoveryFile(dir, path string) string {
+	return filepath.Join(dir, url.PathEscape(path))
+}
+
+// autosave writes the body of each modified file sheet
+// that changed since it was last written
+// to a recovery file in the window's winRecoveryDir,
+// at most once every autosaveInterval.
+// Deleted modified sheets are saved too, while Undel can restore them.
+// The recovery files of sheets that are no longer modified are removed.
+// Nothing is written while the window is quiet.
+// A recovery file holds the path of the file on its first line,
+// followed by the text of the body.
+func autosave(w *Win) {
+	dir := winRecoveryDir(w)
+	if dir == "" || autosaveInterval <= 0 || w.quiet || time.Since(w.autosaveAt) < autosaveInterval {
+		return
+	}
+	w.autosaveAt = time.Now()
+	if w.autosaved == nil {
+		w.autosaved = make(map[string]rope.Rope)
+	}
+	keep := recoveryTexts(w)
+	for path, text := range keep {
+		if saved, ok := w.autosaved[path]; ok && saved == text {
+			continue
+		}
+		if err := writeRecoveryFile(dir, path, text); err != nil {
+			w.OutputString(err.Error() + "\n")
+			continue
+		}
+		w.autosaved[path] = text
+	}
+	for path := range w.autosaved {
+		if _, ok := keep[path]; !ok {
+			os.Remove(recoveryFile(dir, path))
+			delete(w.autosaved, path)
+		}
+	}
+}
+
+// recoveryTexts returns the texts to autosave by path:
+// the bodies of the modified file sheets,
+// and of the deleted ones that Undel can restore
+// unless their file is open again.
+func recoveryTexts(w *Win) map[string]rope.Rope {
+	texts := make(map[string]rope.Rope)
+	for _, d := range w.trash {
+		if isFileTitle(d.title) {
+			texts[d.title] = d.text
+		}
+	}
+	for _, s := range modifiedSheets(w) {
+		texts[s.Title()] = s.body.text
+	}
+	return texts
+}
+
+// removeRecoveryFiles removes the recovery files written by autosave.
+// It is called when the window is closed normally.
+func removeRecoveryFiles(w *Win) {
+	dir := winRecoveryDir(w)
+	if dir == "" {
+		return
+	}
+	for path := range w.autosaved {
+		os.Remove(recoveryFile(dir, path))
+	}
+	w.autosaved = nil
+	os.Remove(dir) // only if it is empty
+}
+
+// writeRecoveryFile writes the recovery file of the path.
+func writeRecoveryFile(dir, path string, text rope.Rope) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(recoveryFile(dir, path), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(path + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := text.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// checkRecovery reports recovery files left by windows
+// of processes that exited without closing them normally.
+func checkRecovery(w *Win) {
+	dirs, err := orphanedRecoveryDirs()
+	if err != nil {
+		return
+	}
+	var n int
+	for _, dir := range dirs {
+		infos, _ := ioutil.ReadDir(dir)
+		n += len(infos)
+	}
+	if n == 0 {
+		return
+	}
+	w.OutputString(msg("%d unsaved files can be recovered; execute Recover to restore them", n) + "\n")
+}
+
+// recoverFiles implements the Recover command.
+// The body of the sheet of the file of each recovery file
+// left by a process that is no longer running,
+// opened if it is not, is replaced with the recovered text,
+// and the recovery file is removed.
+// The sheet is left modified, so the file can be compared with Diff
+// and written with Put.
+func recoverFiles(c *Col) error {
+	dirs, err := orphanedRecoveryDirs()
+	if err != nil {
+		return err
+	}
+	var files []string
+	for _, dir := range dirs {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		if len(infos) == 0 {
+			os.Remove(dir)
+		}
+		for _, info := range infos {
+			files = append(files, filepath.Join(dir, info.Name()))
+		}
+	}
+	if len(files) == 0 {
+		return errors.New(msg("no recovery files"))
+	}
+	for _, file := range files {
+		path, text, err := readRecoveryFile(file)
+		if err != nil {
+			return err
+		}
+		s := findSheet(c.win, path)
+		if s == nil {
+			if err := openSheet(c, path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if s = findSheet(c.win, path); s == nil {
+				s = NewSheet(c.win, path)
+				c.Add(s)
+			}
+		}
+		focusSheet(c.win, path)
+		s.body.SetText(rope.New(text))
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+		os.Remove(filepath.Dir(file)) // only if it is empty
+		c.win.OutputString(msg("recovered %s", path) + "\n")
+	}
+	return nil
+}
+
+// readRecoveryFile returns the path and text of a recovery file.
+func readRecoveryFile(file string) (string, string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", "", err
+	}
+	text := string(data)
+	i := strings.IndexByte(text, '\n')
+	if i < 0 || !filepath.IsAbs(text[:i]) {
+		return "", "", errors.New(msg("%s: not a recovery file", file))
+	}
+	return text[:i], text[i+1:], nil
+}
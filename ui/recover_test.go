@@ -0,0 +1,149 @@
+package ui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+// deadPID is the process ID of no running process.
+const deadPID = "1073741823"
+
+func TestAutosaveRecover(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	defer func(d string) { recoveryDir = d }(recoveryDir)
+	recoveryDir = filepath.Join(dir, "recover")
+	path := filepath.Join(dir, "a.txt")
+	write(path, "a\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, path)
+	)
+	c.Add(s)
+	if err := s.Get(); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	s.body.SetText(rope.New("b\n"))
+	autosave(w)
+	file := recoveryFile(winRecoveryDir(w), path)
+	if data, err := ioutil.ReadFile(file); err != nil || string(data) != path+"\nb\n" {
+		t.Fatalf("recovery file=%q,%v, want %q", data, err, path+"\nb\n")
+	}
+
+	// The files of a running process are not recovered.
+	w1 := newTestWin()
+	if err := execCmd(w1.cols[0], nil, "Recover"); err == nil || err.Error() != "no recovery files" {
+		t.Errorf("Recover with the first window running=%v, want no recovery files", err)
+	}
+
+	// A new window recovers the file of a process that exited.
+	crashed := filepath.Join(recoveryDir, deadPID+".1")
+	if err := os.Rename(winRecoveryDir(w), crashed); err != nil {
+		t.Fatalf("failed to rename: %v", err)
+	}
+	file = recoveryFile(crashed, path)
+	w = newTestWin()
+	c = w.cols[0]
+	if err := execCmd(c, nil, "Recover"); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	s = findSheet(w, path)
+	if s == nil {
+		t.Fatalf("no sheet for %s", path)
+	}
+	if got := s.body.text.String(); got != "b\n" || !isModified(s) {
+		t.Errorf("body=%q, modified=%v, want %q, true", got, isModified(s), "b\n")
+	}
+	if _, err := os.Stat(crashed); !os.IsNotExist(err) {
+		t.Errorf("recovery directory not removed: %v", err)
+	}
+	err := execCmd(c, nil, "Recover")
+	if want := "no recovery files"; err == nil || err.Error() != want {
+		t.Errorf("Recover=%v, want %q", err, want)
+	}
+
+	// Saving removes the recovery file.
+	autosave(w)
+	file = recoveryFile(winRecoveryDir(w), path)
+	if _, err := os.Stat(file); err != nil {
+		t.Fatalf("no recovery file: %v", err)
+	}
+	if err := s.Put(); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	w.autosaveAt = w.autosaveAt.Add(-autosaveInterval)
+	autosave(w)
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("recovery file not removed after Put: %v", err)
+	}
+}
+
+func TestOrphanedRecoveryDirs(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	defer func(d string) { recoveryDir = d }(recoveryDir)
+	recoveryDir = dir
+	for _, name := range []string{
+		strconv.Itoa(os.Getpid()) + ".1",
+		strconv.Itoa(os.Getppid()) + ".1",
+		deadPID + ".1",
+		deadPID + ".2",
+		"other",
+	} {
+		mkSubDir(dir, name)
+	}
+	touch(dir, "1073741822.1")
+
+	got, err := orphanedRecoveryDirs()
+	if err != nil {
+		t.Fatalf("orphanedRecoveryDirs failed: %v", err)
+	}
+	want := []string{filepath.Join(dir, deadPID+".1"), filepath.Join(dir, deadPID+".2")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("orphanedRecoveryDirs()=%v, want %v", got, want)
+	}
+}
+
+func TestAutosaveDeleted(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	defer func(d string) { recoveryDir = d }(recoveryDir)
+	recoveryDir = filepath.Join(dir, "recover")
+	path := filepath.Join(dir, "a.txt")
+	write(path, "a\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, path)
+	)
+	c.Add(s)
+	if err := s.Get(); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	s.body.SetText(rope.New("b\n"))
+	if err := execCmd(c, s, "Del"); err != nil {
+		t.Fatalf("Del failed: %v", err)
+	}
+	autosave(w)
+	file := recoveryFile(winRecoveryDir(w), path)
+	if data, err := ioutil.ReadFile(file); err != nil || string(data) != path+"\nb\n" {
+		t.Fatalf("recovery file of the deleted sheet=%q,%v, want %q", data, err, path+"\nb\n")
+	}
+
+	// Once Undel cannot restore it, the recovery file is removed.
+	w.trash = nil
+	w.autosaveAt = w.autosaveAt.Add(-autosaveInterval)
+	autosave(w)
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("recovery file not removed: %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package ui
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// conflictTagText is appended to the first line of the tag of a sheet
+// whose file changed on disk while its body was modified.
+// Executing it writes the body over the changed file.
+const conflictTagText = " Put!"
+
+// A fileWatcher watches the directories of the files of file sheets
+// for changes made by other programs.
+type fileWatcher struct {
+	fs   *fsnotify.Watcher
+	win  *Win
+	dirs map[string]bool // watched directories
+	held map[string]bool // paths changed while the window was quiet
+
+	mu      sync.Mutex
+	changed map[string]bool // paths changed since the last tick
+}
+
+// watchFiles watches the directories of the window's file sheets,
+// and handles the changes of their files with fileChanged.
+// If the watcher cannot be created, files are not watched.
+// While the window is quiet, changed files only mark their sheets
+// as conflicting, so that Put does not overwrite the changes,
+// and they are handled by fileChanged once it resumes.
+func watchFiles(w *Win) {
+	if w.files == nil {
+		fs, err := fsnotify.NewWatcher()
+		if err != nil {
+			w.OutputString(err.Error() + "\n")
+			w.files = &fileWatcher{}
+			return
+		}
+		w.files = &fileWatcher{
+			fs:      fs,
+			win:     w,
+			dirs:    make(map[string]bool),
+			held:    make(map[string]bool),
+			changed: make(map[string]bool),
+		}
+		go w.files.run()
+	}
+	fw := w.files
+	if fw.fs == nil {
+		return
+	}
+	sheets := make(map[string]*Sheet)
+	dirs := make(map[string]bool)
+	for _, c := range w.cols {
+		for _, r := range c.rows {
+			if s := getSheet(r); s != nil && isFileSheet(s) {
+				sheets[s.Title()] = s
+				dirs[filepath.Dir(s.Title())] = true
+			}
+		}
+	}
+	for d := range dirs {
+		if !fw.dirs[d] && fw.fs.Add(d) == nil {
+			fw.dirs[d] = true
+		}
+	}
+	for d := range fw.dirs {
+		if !dirs[d] {
+			fw.fs.Remove(d)
+			delete(fw.dirs, d)
+		}
+	}
+
+	fw.mu.Lock()
+	changed := fw.changed
+	fw.changed = make(map[string]bool)
+	fw.mu.Unlock()
+	if w.quiet {
+		for path := range changed {
+			if s := sheets[path]; s != nil {
+				if _, ok := changedText(s); ok {
+					s.conflict = true
+				}
+			}
+			fw.held[path] = true
+		}
+		return
+	}
+	for path := range fw.held {
+		if s := sheets[path]; s != nil {
+			clearConflict(s)
+			fileChanged(s)
+		}
+		delete(fw.held, path)
+		delete(changed, path)
+	}
+	for path := range changed {
+		if s := sheets[path]; s != nil {
+			fileChanged(s)
+		}
+	}
+}
+
+// run records the changed paths until the watcher is closed.
+func (fw *fileWatcher) run() {
+	for {
+		select {
+		case ev, ok := <-fw.fs.Events:
+			if !ok {
+				return
+			}
+			if ev.Op == fsnotify.Chmod {
+				continue
+			}
+			fw.mu.Lock()
+			fw.changed[ev.Name] = true
+			fw.mu.Unlock()
+			wake(fw.win)
+		case _, ok := <-fw.fs.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// stopWatchingFiles stops watching the files of the window.
+func stopWatchingFiles(w *Win) {
+	if w.files != nil && w.files.fs != nil {
+		w.files.fs.Close()
+	}
+	w.files = nil
+}
+
+// fileChanged handles a change to the file of the sheet.
+// If the file differs from the text last read or written,
+// an unmodified body is reloaded,
+// keeping dot and the scroll position where the text is unchanged,
+// and a modified body is marked as conflicting:
+// conflictTagText is added to its tag, and Put fails.
+func fileChanged(s *Sheet) {
+	text, ok := changedText(s)
+	switch {
+	case !ok:
+		return
+	case isModified(s):
+		s.conflict = true
+		setTagText(s, conflictTagText, true)
+	default:
+		var saved string
+		if s.saved != nil {
+			saved = s.saved.String()
+		}
+		s.body.Change(lineDiffs(saved, text))
+		s.saved = s.body.text
+		setGitText(s)
+	}
+}
+
+// changedText returns the text of the file of the sheet
+// and whether it differs from the text last read or written.
+// If the file was removed or renamed, it is not changed;
+// the body is kept.
+func changedText(s *Sheet) (string, bool) {
+	data, err := ioutil.ReadFile(s.Title())
+	if err != nil {
+		return "", false
+	}
+	var saved string
+	if s.saved != nil {
+		saved = s.saved.String()
+	}
+	return string(data), string(data) != saved
+}
+
+// putConflict implements the Put! command,
+// writing the body over the file even if it changed on disk.
+func putConflict(s *Sheet) error {
+	s.conflict = false
+	setTagText(s, conflictTagText, false)
+	return s.Put()
+}
+
+// checkConflict returns an error if the file of the sheet
+// changed on disk while its body was modified.
+func checkConflict(s *Sheet) error {
+	if !s.conflict {
+		return nil
+	}
+	return errors.New(msg("%s changed on disk; execute Put! to overwrite it", s.Title()))
+}
+
+// clearConflict unmarks the sheet as conflicting,
+// after its body is read from its file.
+func clearConflict(s *Sheet) {
+	if s.conflict {
+		s.conflict = false
+		setTagText(s, conflictTagText, false)
+	}
+}
@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestFileChanged(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.txt")
+	write(path, "1\n2\n3\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, path)
+	)
+	c.Add(s)
+	if err := s.Get(); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	setDot(s.body, 1, 4, 5)
+
+	// An unmodified body is reloaded.
+	write(path, "0\n1\n2\n3\n")
+	fileChanged(s)
+	if got, want := s.body.text.String(), "0\n1\n2\n3\n"; got != want || isModified(s) {
+		t.Errorf("body=%q, modified=%v, want %q, false", got, isModified(s), want)
+	}
+	if dot := s.body.dots[1].At; dot != [2]int64{6, 7} {
+		t.Errorf("dot=%v, want [6 7]", dot)
+	}
+
+	// A modified body conflicts.
+	s.body.SetText(rope.New("x\n"))
+	write(path, "y\n")
+	fileChanged(s)
+	if got := s.body.text.String(); got != "x\n" {
+		t.Errorf("body=%q, want %q", got, "x\n")
+	}
+	if !strings.Contains(s.tag.text.String(), conflictTagText) {
+		t.Errorf("tag=%q, want it to contain %q", s.tag.text.String(), conflictTagText)
+	}
+	err := execCmd(c, s, "Put")
+	if want := path + " changed on disk; execute Put! to overwrite it"; err == nil || err.Error() != want {
+		t.Errorf("Put=%v, want %q", err, want)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "y\n" {
+		t.Errorf("a.txt=%q after Put, want %q", data, "y\n")
+	}
+	if err := execCmd(c, s, "Put!"); err != nil {
+		t.Fatalf("Put! failed: %v", err)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "x\n" {
+		t.Errorf("a.txt=%q after Put!, want %q", data, "x\n")
+	}
+	if strings.Contains(s.tag.text.String(), conflictTagText) {
+		t.Errorf("tag=%q after Put!, want no %q", s.tag.text.String(), conflictTagText)
+	}
+
+	// Writing the file does not reload it.
+	fileChanged(s)
+	if s.conflict || isModified(s) {
+		t.Errorf("conflict=%v, modified=%v after Put!, want false, false", s.conflict, isModified(s))
+	}
+}
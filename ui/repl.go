@@ -0,0 +1,319 @@
+package ui
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+)
+
+// A repl is an interpreter process attached to the body of a sheet.
+//
+// Typing newline in the body sends the line containing dot,
+// or the selected text if dot is not empty,
+// to the interpreter, with any leading prompt removed.
+// The interpreter's output is inserted below the sent text.
+//
+// Control-p and control-n replace the line containing dot
+// with the previous and next inputs from the history.
+// Tab completes the word before dot
+// from the words in the body.
+type repl struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	prompt *regexp.Regexp
+
+	history []string
+	hist    int   // index of the recalled input; len(history) if none
+	at      int64 // address at which to insert output
+
+	mu  sync.Mutex
+	out strings.Builder // output not yet inserted
+}
+
+// newRepl returns a new REPL sheet running the named interpreter.
+// Relative paths in the interpreter are relative to dir.
+func newRepl(w *Win, dir, name string) (*Sheet, error) {
+	cfg, ok := repls[name]
+	if !ok {
+		names := make([]string, 0, len(repls))
+		for n := range repls {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, errors.New(msg("usage: Repl %s", strings.Join(names, "|")))
+	}
+	return startRepl(w, dir, "+"+name, cfg.cmd, cfg.prompt, nil)
+}
+
+// newShell implements the Win command: Win [command].
+// It returns a new +win sheet of the directory
+// running the command by sh, or by default the winShell,
+// as a REPL sheet, like acme's win.
+func newShell(w *Win, dir, command string) (*Sheet, error) {
+	args := winShell
+	if command != "" {
+		args = []string{"sh", "-c", command}
+	}
+	return startRepl(w, dir, "+win", args, winPrompt, []string{"PS1=$ ", "TERM=dumb"})
+}
+
+// startRepl returns a new REPL sheet of the directory, with the name,
+// running the command with the environment variables added.
+func startRepl(w *Win, dir, name string, args []string, promptRegexp string, env []string) (*Sheet, error) {
+	prompt, err := regexp.Compile("(?m)^(?:" + promptRegexp + ")")
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	if err := cmd.Start(); err != nil {
+		stdin.Close()
+		return nil, err
+	}
+	s := NewSheet(w, filepath.Join(dir, name))
+	r := &repl{cmd: cmd, stdin: stdin, prompt: prompt}
+	s.repl = r
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var buf [4096]byte
+		var d outputDecoder
+		for {
+			n, err := pr.Read(buf[:])
+			r.mu.Lock()
+			r.out.Write(d.decode(buf[:n], err != nil))
+			r.mu.Unlock()
+			wake(w)
+			if err != nil {
+				return
+			}
+		}
+	}()
+	go func() {
+		err := cmd.Wait()
+		// The exit status follows all of the output,
+		// so wait for the reader to reach the end of the pipe.
+		pw.Close()
+		<-done
+		r.mu.Lock()
+		if err != nil {
+			r.out.WriteString(err.Error() + "\n")
+		}
+		r.mu.Unlock()
+		wake(w)
+	}()
+	return s, nil
+}
+
+// close kills the interpreter.
+func (r *repl) close() {
+	r.stdin.Close()
+	if r.cmd.Process != nil {
+		r.cmd.Process.Kill()
+	}
+}
+
+// replOutput inserts pending interpreter output into the body.
+// It returns whether any output was inserted.
+func replOutput(s *Sheet) bool {
+	r := s.repl
+	r.mu.Lock()
+	out := r.out.String()
+	r.out.Reset()
+	r.mu.Unlock()
+	if len(out) == 0 {
+		return false
+	}
+	b := s.body
+	if r.at > b.text.Len() {
+		r.at = b.text.Len()
+	}
+	follow := b.dots[1].At == [2]int64{r.at, r.at}
+	b.Change(edit.Diffs{{At: [2]int64{r.at, r.at}, Text: rope.New(out)}})
+	r.at += int64(len(out))
+	if follow {
+		setDot(b, 1, r.at, r.at)
+		showAddr(b, r.at)
+	}
+	return true
+}
+
+// replRune handles a rune typed in the body of a REPL sheet.
+// It returns whether the rune was handled.
+func replRune(s *Sheet, r rune) bool {
+	b := s.body
+	if b.win.mods[3] {
+		switch r {
+		case 'p', 'P', 0x10:
+			recall(s, -1)
+			return true
+		case 'n', 'N', 0x0e:
+			recall(s, 1)
+			return true
+		}
+		return false
+	}
+	switch {
+	case b.search != nil:
+		return false
+	case r == '\n':
+		return sendInput(s) == nil
+	case r == '\t' && b.dots[1].At[0] == b.dots[1].At[1]:
+		return complete(b)
+	}
+	return false
+}
+
+// sendInput sends the line containing dot,
+// or the text of dot if it is non-empty,
+// to the interpreter.
+func sendInput(s *Sheet) error {
+	b, r := s.body, s.repl
+	at := b.dots[1].At
+	if at[0] == at[1] {
+		var err error
+		if at, err = edit.Addr(at, "-+", b.text); err != nil {
+			return err
+		}
+	}
+	in := r.prompt.ReplaceAllString(rope.Slice(b.text, at[0], at[1]).String(), "")
+	in = strings.TrimSuffix(in, "\n")
+	end, err := edit.Addr([2]int64{at[1], at[1]}, "+0", b.text)
+	if err != nil {
+		return err
+	}
+	if n := b.text.Len(); end[1] == n && (n == 0 || rope.Slice(b.text, n-1, n).String() != "\n") {
+		b.Change(edit.Diffs{{At: [2]int64{n, n}, Text: rope.New("\n")}})
+		end[1]++
+	}
+	r.at = end[1]
+	setDot(b, 1, r.at, r.at)
+	if in != "" && (len(r.history) == 0 || r.history[len(r.history)-1] != in) {
+		r.history = append(r.history, in)
+	}
+	r.hist = len(r.history)
+	_, err = io.WriteString(r.stdin, in+"\n")
+	return err
+}
+
+// recall replaces the input on the line containing dot
+// with the previous (dir < 0) or next (dir > 0) input in the history.
+func recall(s *Sheet, dir int) {
+	b, r := s.body, s.repl
+	h := r.hist + dir
+	if h < 0 || h > len(r.history) {
+		return
+	}
+	r.hist = h
+	var in string
+	if h < len(r.history) {
+		in = r.history[h]
+	}
+	line, err := edit.Addr(b.dots[1].At, "-+", b.text)
+	if err != nil {
+		return
+	}
+	str := rope.Slice(b.text, line[0], line[1]).String()
+	if strings.HasSuffix(str, "\n") {
+		line[1]--
+		str = str[:len(str)-1]
+	}
+	if loc := r.prompt.FindStringIndex(str); loc != nil {
+		line[0] += int64(loc[1])
+	}
+	b.Change(edit.Diffs{{At: line, Text: rope.New(in)}})
+	end := line[0] + int64(len(in))
+	setDot(b, 1, end, end)
+}
+
+// complete extends the word before dot
+// by the longest common prefix
+// of the other words in the text beginning with it.
+// It returns whether the word was extended.
+func complete(b *TextBox) bool {
+	dot := b.dots[1].At[0]
+	start := wordStart(b, dot)
+	if start == dot {
+		return false
+	}
+	prefix := rope.Slice(b.text, start, dot).String()
+	words := textWords(b, prefix)
+	if len(words) == 0 {
+		return false
+	}
+	ext := words[0]
+	for _, w := range words[1:] {
+		ext = commonPrefix(ext, w)
+	}
+	if ext = ext[len(prefix):]; ext == "" {
+		return false
+	}
+	b.Change(edit.Diffs{{At: [2]int64{dot, dot}, Text: rope.New(ext)}})
+	setDot(b, 1, dot+int64(len(ext)), dot+int64(len(ext)))
+	return true
+}
+
+// wordStart returns the address of the start
+// of the word ending at the address.
+func wordStart(b *TextBox, at int64) int64 {
+	rr := rope.NewReverseReader(rope.Slice(b.text, 0, at))
+	for {
+		r, w, err := rr.ReadRune()
+		if err != nil || !isWordRune(r) {
+			return at
+		}
+		at -= int64(w)
+	}
+}
+
+// textWords returns the distinct words of the text
+// that begin with, and are longer than, the prefix,
+// in the order of their first occurrence.
+func textWords(b *TextBox, prefix string) []string {
+	var words []string
+	seen := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(b.text.String(), func(r rune) bool { return !isWordRune(r) }) {
+		if len(word) <= len(prefix) || !strings.HasPrefix(word, prefix) || seen[word] {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	return words
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func commonPrefix(a, b string) string {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	for i > 0 && i < len(a) && !utf8.RuneStart(a[i]) {
+		i--
+	}
+	return a[:i]
+}
@@ -0,0 +1,93 @@
+package ui
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestRepl(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat not found")
+	}
+	defer delete(repls, "test")
+	repls["test"] = struct {
+		cmd    []string
+		prompt string
+	}{[]string{"cat"}, "> "}
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+	)
+	if err := execCmd(c, nil, "Repl test"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	s := c.rows[len(c.rows)-1].(*Sheet)
+	defer s.repl.close()
+	s.body.Focus(true)
+
+	for _, r := range "> hello\n" {
+		s.Rune(r)
+	}
+	waitOutput(t, s, "> hello\nhello\n")
+
+	s.Rune('h')
+	s.Rune('\t')
+	if got, want := s.body.text.String(), "> hello\nhello\nhello"; got != want {
+		t.Errorf("after completion, text=%q, want %q", got, want)
+	}
+
+	s.body.SetText(rope.New("> x"))
+	setDot(s.body, 1, 3, 3)
+	w.mods[3] = true
+	s.Rune('p')
+	w.mods[3] = false
+	if got, want := s.body.text.String(), "> hello"; got != want {
+		t.Errorf("after recall, text=%q, want %q", got, want)
+	}
+}
+
+func waitOutput(t *testing.T, s *Sheet, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.Tick()
+		if s.body.text.String() == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("text=%q, want %q", s.body.text.String(), want)
+}
+
+func TestCmd_Win(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, filepath.Join(dir, "a.txt"))
+	)
+	c.Add(s)
+	if err := execCmd(c, s, "Win cat"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	sh := c.rows[len(c.rows)-1].(*Sheet)
+	defer sh.repl.close()
+	if got, want := sh.Title(), filepath.Join(dir, "+win"); got != want {
+		t.Errorf("title=%q, want %q", got, want)
+	}
+	sh.body.Focus(true)
+	for _, r := range "$ hello\n" {
+		sh.Rune(r)
+	}
+	waitOutput(t, sh, "$ hello\nhello\n")
+}
@@ -0,0 +1,253 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+)
+
+// A replacement is the result of a substitution on one file.
+type replacement struct {
+	path  string
+	sheet *Sheet // the open sheet of the file, or nil
+	old   rope.Rope
+	diffs edit.Diffs // the diffs changing old
+}
+
+// replace implements the Replace command: Replace /regexp/text/ [pattern ...]
+// The substitution is made globally in each file matching the patterns,
+// expanded as by Open, or in each open file sheet if there are no patterns.
+// The first execution shows the changes in a +Replace sheet;
+// executing the command again makes exactly those changes,
+// unless a file or sheet changed since, and lists the changed files.
+func replace(c *Col, s *Sheet, args string) error {
+	sub, pats, err := splitSubst(args)
+	if err != nil {
+		return err
+	}
+	w := c.win
+	key := "Replace " + args
+	if w.confirm != key {
+		rs, err := replacements(w, s, sub, pats)
+		if err != nil {
+			return err
+		}
+		if err := showReplace(c, s, rs); err != nil {
+			return err
+		}
+		w.confirm, w.replacing = key, rs
+		return nil
+	}
+	rs := w.replacing
+	w.confirm, w.replacing = "", nil
+	for _, r := range rs {
+		if len(r.diffs) > 0 && replacementChanged(w, r) {
+			return errors.New(msg("%s changed since the preview; execute Replace again", r.path))
+		}
+	}
+	var replaced []string
+	defer func() {
+		w.OutputString(msg("replaced in %d files", len(replaced)) + "\n")
+		for _, path := range replaced {
+			w.OutputString(path + "\n")
+		}
+	}()
+	for _, r := range rs {
+		if len(r.diffs) == 0 {
+			continue
+		}
+		if r.sheet != nil {
+			r.sheet.body.Change(r.diffs)
+		} else {
+			text, _ := r.diffs.Apply(r.old)
+			if err := writeFile(r.path, text); err != nil {
+				return err
+			}
+		}
+		replaced = append(replaced, r.path)
+	}
+	return nil
+}
+
+// replacementChanged returns whether the text replaced
+// differs from that of the preview:
+// the sheet was deleted or its body changed,
+// or the file changed or cannot be read.
+func replacementChanged(w *Win, r replacement) bool {
+	if r.sheet != nil {
+		return findSheet(w, r.path) != r.sheet || r.sheet.body.text != r.old
+	}
+	data, err := ioutil.ReadFile(r.path)
+	return err != nil || string(data) != r.old.String()
+}
+
+// splitSubst returns the delimited regexp and replacement text
+// at the start of args, including the delimiters,
+// and the remaining arguments.
+func splitSubst(args string) (string, string, error) {
+	if args == "" {
+		return "", "", errors.New(msg("usage: Replace /regexp/text/ [pattern ...]"))
+	}
+	var delim rune
+	var n int
+	var esc bool
+	for i, r := range args {
+		switch {
+		case i == 0:
+			delim = r
+		case esc:
+			esc = false
+		case r == '\\':
+			esc = true
+		case r == delim:
+			if n++; n == 2 {
+				j := i + len(string(r))
+				return args[:j], strings.TrimSpace(args[j:]), nil
+			}
+		}
+	}
+	return "", "", errors.New(msg("usage: Replace /regexp/text/ [pattern ...]"))
+}
+
+// replacements returns the result of making the substitution
+// in the files matching the patterns,
+// or in the open file sheets if there are no patterns.
+func replacements(w *Win, s *Sheet, sub, pats string) ([]replacement, error) {
+	var rs []replacement
+	if pats == "" {
+		for _, c := range w.cols {
+			for _, r := range c.rows {
+				if s := getSheet(r); s != nil && isFileSheet(s) {
+					rs = append(rs, replacement{path: s.Title(), sheet: s, old: s.body.text})
+				}
+			}
+		}
+	} else {
+		paths, err := expandPatterns(s, pats)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range paths {
+			if st, err := os.Stat(p); err == nil && st.IsDir() {
+				continue
+			}
+			if s := findSheet(w, p); s != nil {
+				rs = append(rs, replacement{path: p, sheet: s, old: s.body.text})
+				continue
+			}
+			data, err := ioutil.ReadFile(p)
+			if err != nil {
+				return nil, err
+			}
+			rs = append(rs, replacement{path: p, old: rope.New(string(data))})
+		}
+	}
+	for i := range rs {
+		diffs, err := edit.Edit([2]int64{}, ",s"+sub+"g", ioutil.Discard, rs[i].old)
+		if err != nil {
+			return nil, err
+		}
+		rs[i].diffs = diffs
+	}
+	return rs, nil
+}
+
+// isFileSheet returns whether the sheet is of a file,
+// as opposed to a directory, the Output sheet, or a +sheet.
+func isFileSheet(s *Sheet) bool {
+	return isFileTitle(s.Title())
+}
+
+// isFileTitle returns whether the title is that of a file sheet.
+func isFileTitle(title string) bool {
+	return filepath.IsAbs(title) &&
+		!strings.HasSuffix(title, "/") &&
+		!strings.HasPrefix(filepath.Base(title), "+")
+}
+
+// findSheet returns the sheet with the title, or nil.
+func findSheet(w *Win, title string) *Sheet {
+	for _, c := range w.cols {
+		for _, r := range c.rows {
+			if s := getSheet(r); s != nil && s.Title() == title {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// showReplace shows the changed lines of each replacement
+// in the +Replace sheet of the directory of s.
+func showReplace(c *Col, s *Sheet, rs []replacement) error {
+	dir, err := abs(s, ".")
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	var n int
+	for _, r := range rs {
+		if len(r.diffs) == 0 {
+			continue
+		}
+		n++
+		text, _ := r.diffs.Apply(r.old)
+		old := r.old.String()
+		ds := lineDiffs(old, text.String())
+		for i := len(ds) - 1; i >= 0; i-- {
+			d := ds[i]
+			line := strings.Count(old[:d.At[0]], "\n") + 1
+			fmt.Fprintf(&b, "%s:%d\n", r.path, line)
+			writeLines(&b, "-", old[d.At[0]:d.At[1]])
+			writeLines(&b, "+", d.Text.String())
+		}
+	}
+	if n == 0 {
+		return errors.New(msg("no matches"))
+	}
+	b.WriteString(msg("%d files change; execute again to replace", n) + "\n")
+
+	showScratch(c, filepath.Join(dir, "+Replace"), b.String())
+	return nil
+}
+
+// showScratch sets the text of the sheet with the title,
+// adding it to the column if it is not open, and focuses it.
+func showScratch(c *Col, title, text string) {
+	s := findSheet(c.win, title)
+	if s == nil {
+		s = NewSheet(c.win, title)
+		c.Add(s)
+	}
+	focusSheet(c.win, title)
+	s.body.SetText(rope.New(text))
+}
+
+// writeLines writes each line of text to b, preceded by prefix.
+func writeLines(b *strings.Builder, prefix, text string) {
+	if text == "" {
+		return
+	}
+	for _, l := range strings.SplitAfter(strings.TrimSuffix(text, "\n"), "\n") {
+		b.WriteString(prefix + strings.TrimSuffix(l, "\n") + "\n")
+	}
+}
+
+// writeFile writes the text to the existing file at path.
+func writeFile(path string, text rope.Rope) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := text.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
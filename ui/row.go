@@ -15,6 +15,7 @@ type Row interface {
 	// If dirty is true the element should redraw itself in its entirity.
 	// If dirty is false, the element need only redraw
 	// parts that have changed since the last call to Draw.
+	// Rows may be drawn concurrently, each to its own part of the image.
 	Draw(dirty bool, img draw.Image)
 
 	// Focus handles a focus state change.
@@ -25,11 +26,19 @@ type Row interface {
 	Resize(size image.Point)
 
 	// Tick returns whether the row need be redrawn.
-	// It is intended to be called at regular intervals
-	// in order to drive asynchronous events.
+	// It is called on each tick of the window
+	// in order to drive asynchronous events;
+	// ticks are not regular, see Win.NextTick.
 	Tick() bool
 
 	// Move handles mouse cursor moving events.
+	//
+	// While a mouse button pressed in the row is held,
+	// the moves are a drag, and they are sent to the row
+	// even if the point is outside of its bounds.
+	// A drag with button 1 extends the selection to the point,
+	// and a row that scrolls scrolls toward a point beyond it
+	// on the following ticks, until the button is released.
 	Move(pt image.Point)
 
 	// Click handles mouse button events.
@@ -51,6 +60,12 @@ type Row interface {
 	// 	+x is roll right.
 	Wheel(pt image.Point, x, y int)
 
+	// WheelPx handles precise scrolling events,
+	// such as two-finger scrolling on a touchpad.
+	// The x and y deltas are in pixels,
+	// with the same signs as the arguments of Wheel.
+	WheelPx(pt image.Point, x, y float64)
+
 	// Dir handles keyboard directional events.
 	//
 	// These events are generated by the arrow keys,
@@ -87,4 +102,20 @@ type Row interface {
 	// If the rune is positive, the event is a key press,
 	// if negative, a key release.
 	Rune(r rune)
+
+	// Copy copies the selection to the clipboard.
+	Copy() error
+
+	// Cut copies the selection to the clipboard and deletes it.
+	Cut() error
+
+	// Paste replaces the selection with the text of the clipboard.
+	Paste() error
+
+	// Close releases the resources of the row,
+	// such as its goroutines, files, and subprocesses.
+	// It is called when the row is deleted by the Del command
+	// and when the window is closed, but not when the row is moved.
+	// The row is not used after Close.
+	Close()
 }
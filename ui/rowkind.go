@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A NewRowFunc returns a new row of a kind registered with RegisterRow.
+// The argument is the text after the colon of the New command,
+// such as a path.
+type NewRowFunc func(w *Win, arg string) (Row, error)
+
+var (
+	rowKindsMu sync.Mutex
+	rowKinds   = make(map[string]NewRowFunc)
+)
+
+// RegisterRow registers a kind of row,
+// so that the command New kind:arg adds a row
+// returned by the function to the column.
+// Packages typically register their kinds in an init function.
+//
+// RegisterRow panics if the kind is empty,
+// contains a colon or a space,
+// or is already registered.
+func RegisterRow(kind string, f NewRowFunc) {
+	if kind == "" || strings.ContainsAny(kind, ": \t\n") {
+		panic("ui: bad row kind " + kind)
+	}
+	rowKindsMu.Lock()
+	defer rowKindsMu.Unlock()
+	if _, ok := rowKinds[kind]; ok {
+		panic("ui: row kind " + kind + " registered twice")
+	}
+	rowKinds[kind] = f
+}
+
+// RowKinds returns the registered kinds of rows, sorted.
+func RowKinds() []string {
+	rowKindsMu.Lock()
+	defer rowKindsMu.Unlock()
+	var kinds []string
+	for k := range rowKinds {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// newRow implements the New command: New kind:arg.
+func newRow(c *Col, args string) error {
+	kind, arg := args, ""
+	if i := strings.IndexByte(args, ':'); i >= 0 {
+		kind, arg = args[:i], args[i+1:]
+	}
+	if kind == "" {
+		return errors.New(msg("New: want kind:argument; kinds: %s", strings.Join(RowKinds(), " ")))
+	}
+	rowKindsMu.Lock()
+	f, ok := rowKinds[kind]
+	rowKindsMu.Unlock()
+	if !ok {
+		return errors.New(msg("New: unknown kind %s", kind))
+	}
+	r, err := f(c.win, arg)
+	if err != nil {
+		return err
+	}
+	c.Add(r)
+	return nil
+}
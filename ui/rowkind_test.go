@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+)
+
+type testRow struct {
+	*TextBox
+	arg    string
+	closed int
+}
+
+func (r *testRow) Close() { r.closed++ }
+
+func init() {
+	RegisterRow("testrow", func(w *Win, arg string) (Row, error) {
+		if arg == "bad" {
+			return nil, errors.New("bad argument")
+		}
+		return &testRow{TextBox: NewTextBox(w, testTextStyles, testSize), arg: arg}, nil
+	})
+}
+
+func TestNewRow(t *testing.T) {
+	w := newTestWin()
+	c := w.cols[0]
+	if err := execCmd(c, nil, "New testrow:/tmp/x.png"); err != nil {
+		t.Fatalf("New testrow:/tmp/x.png failed: %v", err)
+	}
+	r, ok := c.Row.(*testRow)
+	if !ok {
+		t.Fatalf("focused row is %T, want *testRow", c.Row)
+	}
+	if r.arg != "/tmp/x.png" {
+		t.Errorf("arg=%q, want /tmp/x.png", r.arg)
+	}
+	if len(c.rows) != 2 {
+		t.Errorf("%d rows, want 2", len(c.rows))
+	}
+
+	for _, cmd := range []string{"New", "New nosuchkind:x", "New testrow:bad"} {
+		if err := execCmd(c, nil, cmd); err == nil {
+			t.Errorf("%s succeeded, want error", cmd)
+		}
+	}
+	if len(c.rows) != 2 {
+		t.Errorf("%d rows after errors, want 2", len(c.rows))
+	}
+}
+
+func TestRegisterRowTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering testrow twice did not panic")
+		}
+	}()
+	RegisterRow("testrow", nil)
+}
+
+func TestRowKinds(t *testing.T) {
+	var found bool
+	for _, k := range RowKinds() {
+		found = found || k == "testrow"
+	}
+	if !found {
+		t.Errorf("RowKinds()=%v, want testrow", RowKinds())
+	}
+}
+
+func TestRowClose(t *testing.T) {
+	w := newTestWin()
+	w.Add()
+	var rows []*testRow
+	for _, c := range w.cols {
+		if err := execCmd(c, nil, "New testrow:x"); err != nil {
+			t.Fatalf("New testrow:x failed: %v", err)
+		}
+		rows = append(rows, c.Row.(*testRow))
+	}
+
+	if err := execCmd(w.cols[1], nil, "Del"); err != nil {
+		t.Fatalf("Del failed: %v", err)
+	}
+	if rows[0].closed != 0 || rows[1].closed != 1 {
+		t.Errorf("closed %d and %d times after Del of column 2, want 0 and 1",
+			rows[0].closed, rows[1].closed)
+	}
+
+	w.Close()
+	if rows[0].closed != 1 || rows[1].closed != 1 {
+		t.Errorf("closed %d and %d times after Close, want 1 and 1",
+			rows[0].closed, rows[1].closed)
+	}
+}
@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"errors"
+	"strconv"
+)
+
+// rowRune handles the row-arranging runes typed with the alt modifier:
+//
+//	alt-s    swaps the focused sheet with the sheet below it
+//	alt-r    rotates the sheets of the focused column down
+//	alt-R    rotates the sheets of the focused column up
+//	alt-1–9  moves the focused sheet to the numbered column
+//
+// It returns whether the rune was handled.
+func rowRune(w *Win, r rune) bool {
+	c := w.Col
+	s := getSheet(c.Row)
+	switch {
+	case r == 's' && s != nil:
+		swapRow(c, s, "")
+	case r == 'r':
+		rotateRows(c, "")
+	case r == 'R':
+		rotateRows(c, "-")
+	case r >= '1' && r <= '9' && s != nil:
+		moveToCol(c, s, string(r))
+	default:
+		return false
+	}
+	return true
+}
+
+// swapRow swaps the positions of the sheet
+// and the nth sheet of its column, counting from 1,
+// or the sheet below it if the argument is empty.
+// The sheets trade sizes along with positions.
+func swapRow(c *Col, s *Sheet, arg string) error {
+	i := rowIndex(c, s)
+	if i < 0 {
+		return nil
+	}
+	var j int
+	switch n, err := strconv.Atoi(arg); {
+	case arg == "" && i < len(c.rows)-1:
+		j = i + 1
+	case arg == "":
+		j = i - 1
+	case err != nil || n < 1 || n >= len(c.rows):
+		return errors.New(msg("usage: Swap [n]"))
+	default:
+		j = n
+	}
+	if j < 1 || j == i {
+		return nil
+	}
+	c.rows[i], c.rows[j] = c.rows[j], c.rows[i]
+	c.Resize(c.size)
+	return nil
+}
+
+// rotateRows rotates the sheets of the column by one position,
+// moving the last sheet to the top,
+// or the first sheet to the bottom if the argument is "-".
+// The sizes of the positions are unchanged.
+func rotateRows(c *Col, arg string) error {
+	rows := c.rows[1:]
+	if len(rows) < 2 {
+		return nil
+	}
+	switch arg {
+	case "":
+		last := rows[len(rows)-1]
+		copy(rows[1:], rows)
+		rows[0] = last
+	case "-":
+		first := rows[0]
+		copy(rows, rows[1:])
+		rows[len(rows)-1] = first
+	default:
+		return errors.New(msg("usage: Rotate [-]"))
+	}
+	c.Resize(c.size)
+	return nil
+}
+
+// moveToCol moves the sheet to the bottom of the nth column,
+// counting from 1, and focuses it.
+func moveToCol(c *Col, s *Sheet, arg string) error {
+	w := c.win
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 || n > len(w.cols) {
+		return errors.New(msg("usage: Move column"))
+	}
+	dst := w.cols[n-1]
+	if dst == c || rowIndex(c, s) < 0 {
+		return nil
+	}
+	c.Del(s)
+	dst.Add(s)
+	setWinFocus(w, dst)
+	return nil
+}
@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func titles(c *Col) []string {
+	var ts []string
+	for _, r := range c.rows[1:] {
+		ts = append(ts, getSheet(r).Title())
+	}
+	return ts
+}
+
+func TestCmd_SwapRotate(t *testing.T) {
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		a = NewSheet(w, "a")
+		b = NewSheet(w, "b")
+		d = NewSheet(w, "d")
+	)
+	c.Add(a)
+	c.Add(b)
+	c.Add(d)
+	heights := append([]float64{}, c.heights...)
+
+	tests := []struct {
+		s    *Sheet
+		cmd  string
+		want []string
+	}{
+		{s: a, cmd: "Swap", want: []string{"b", "a", "d"}},
+		{s: a, cmd: "Swap 3", want: []string{"b", "d", "a"}},
+		{s: a, cmd: "Swap", want: []string{"b", "a", "d"}},
+		{s: d, cmd: "Swap", want: []string{"b", "d", "a"}},
+		{s: a, cmd: "Rotate", want: []string{"a", "b", "d"}},
+		{s: a, cmd: "Rotate -", want: []string{"b", "d", "a"}},
+	}
+	for _, test := range tests {
+		if err := execCmd(c, test.s, test.cmd); err != nil {
+			t.Fatalf("%s: execCmd failed: %v", test.cmd, err)
+		}
+		if got := titles(c); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: rows=%v, want %v", test.cmd, got, test.want)
+		}
+	}
+	if !reflect.DeepEqual(c.heights, heights) {
+		t.Errorf("heights=%v, want unchanged %v", c.heights, heights)
+	}
+	for _, cmd := range []string{"Swap 0", "Swap 4", "Swap x", "Rotate x"} {
+		if err := execCmd(c, a, cmd); err == nil {
+			t.Errorf("%s: expected an error", cmd)
+		}
+	}
+}
+
+func TestCmd_Move(t *testing.T) {
+	var (
+		w  = newTestWin()
+		c0 = w.cols[0]
+		c1 = w.Add()
+		a  = NewSheet(w, "a")
+		b  = NewSheet(w, "b")
+	)
+	c0.Add(a)
+	c0.Add(b)
+	if err := execCmd(c0, a, "Move 2"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	if got := titles(c0); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("column 1 rows=%v, want [b]", got)
+	}
+	if got := titles(c1); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("column 2 rows=%v, want [a]", got)
+	}
+	if w.Col != c1 || c1.Row != a {
+		t.Errorf("moved sheet is not focused")
+	}
+	if err := execCmd(c1, a, "Move 3"); err == nil {
+		t.Errorf("Move 3: expected an error")
+	}
+}
+
+func TestRowRune(t *testing.T) {
+	var (
+		w  = newTestWin()
+		c0 = w.cols[0]
+		c1 = w.Add()
+		a  = NewSheet(w, "a")
+		b  = NewSheet(w, "b")
+	)
+	c0.Add(a)
+	c0.Add(b)
+	setWinFocus(w, c0)
+	setColFocus(c0, a)
+
+	w.mods[2] = true
+	w.Rune('s')
+	if got := titles(c0); !reflect.DeepEqual(got, []string{"b", "a"}) {
+		t.Errorf("after alt-s, rows=%v, want [b a]", got)
+	}
+	w.Rune('r')
+	if got := titles(c0); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("after alt-r, rows=%v, want [a b]", got)
+	}
+	w.Rune('2')
+	if got := titles(c1); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("after alt-2, column 2 rows=%v, want [a]", got)
+	}
+	w.mods[2] = false
+	if s := a.body.text.String(); s != "" {
+		t.Errorf("body text=%q, want empty", s)
+	}
+}
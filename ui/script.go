@@ -0,0 +1,394 @@
+package ui
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+)
+
+// maxScriptDepth is the most scripts that can be running
+// at once, calling each other with exec.
+const maxScriptDepth = 16
+
+// A script is a command defined in the scriptsFile.
+// It is executed like any other command, from any tag,
+// and its statements act on the sheet in which it was executed.
+//
+// Scripts are defined by def blocks:
+//
+//	# Upper converts dot to upper case.
+//	def Upper
+//	edit |tr a-z A-Z
+//	end
+//
+//	# Quote prefixes the lines of dot with its argument.
+//	def Quote
+//	edit x/^/ i/$1/
+//	end
+//
+// The statements of a script are, one per line:
+//
+//	addr address  set dot of the body to an address of the Edit language
+//	edit command  perform an Edit language command on the body at dot
+//	exec command  execute a command, which may itself be a script
+//	print text    write the text and a newline to the Output sheet
+//	set name text set the variable $name to the text
+//
+// and the blocks, each closed by its own end:
+//
+//	if address     run the statements up to else or end,
+//	               with dot set to the address,
+//	               if the address matches in the body;
+//	               otherwise run the statements after else
+//	while address  run the statements up to end,
+//	               with dot set to the address,
+//	               as long as the address matches in the body
+//
+// For example:
+//
+//	# Untab replaces each leading tab of the body with $1.
+//	def Untab
+//	addr 0
+//	while /^\t+/
+//	edit x/\t/ c/$1/
+//	end
+//	end
+//
+// Before a statement is performed, $1 through $9 are replaced by
+// the arguments of the script, $* by all of the arguments,
+// $dot by the text of dot, $file by the title of the sheet,
+// $name by the value of a variable set by the script,
+// and $$ by $.
+// Blank lines and lines beginning with # are ignored.
+// A script may not have the name of a built-in command.
+type script struct {
+	name  string
+	lines []*scriptLine
+}
+
+type scriptLine struct {
+	n         int // line number in the scripts file
+	stmt, arg string
+
+	// body and els are the statements of an if or while block;
+	// els are those after the else of an if.
+	body, els []*scriptLine
+	inElse    bool // whether else was read, while reading the block
+}
+
+// maxScriptLoop is the most times a while block is run
+// in one execution of its statement.
+const maxScriptLoop = 100000
+
+// scriptsPath returns the path of the scripts file.
+func scriptsPath() string {
+	if scriptsFile != "" {
+		return scriptsFile
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "T", "scripts")
+}
+
+// loadScripts reads the scripts of the scripts file, if it exists.
+func loadScripts(w *Win) error {
+	w.scripts = nil
+	path := scriptsPath()
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scripts, err := readScripts(f)
+	if err != nil {
+		return errors.New(path + ": " + err.Error())
+	}
+	w.scripts = scripts
+	return nil
+}
+
+// readScripts returns the scripts defined in a scripts file by name.
+func readScripts(r io.Reader) (map[string]*script, error) {
+	scripts := make(map[string]*script)
+	var cur *script
+	var blocks []*scriptLine // open if and while blocks of cur
+	add := func(l *scriptLine) {
+		if len(blocks) == 0 {
+			cur.lines = append(cur.lines, l)
+		} else if b := blocks[len(blocks)-1]; b.inElse {
+			b.els = append(b.els, l)
+		} else {
+			b.body = append(b.body, l)
+		}
+	}
+	s := bufio.NewScanner(r)
+	n := 0
+	for s.Scan() {
+		n++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		stmt, arg := splitCmd(line)
+		switch {
+		case stmt == "def" && cur != nil:
+			return nil, errors.New(msg("line %d: def inside def %s", n, cur.name))
+		case stmt == "def":
+			if arg == "" || strings.ContainsAny(arg, " \t") {
+				return nil, errors.New(msg("line %d: want def name", n))
+			}
+			if scripts[arg] != nil {
+				return nil, errors.New(msg("line %d: duplicate script %s", n, arg))
+			}
+			if builtinCmds[arg] {
+				return nil, errors.New(msg("line %d: script %s has the name of a built-in command", n, arg))
+			}
+			cur = &script{name: arg}
+		case stmt == "end" && cur == nil:
+			return nil, errors.New(msg("line %d: end without def", n))
+		case stmt == "end" && len(blocks) > 0:
+			blocks = blocks[:len(blocks)-1]
+		case stmt == "end":
+			scripts[cur.name] = cur
+			cur = nil
+		case cur == nil:
+			return nil, errors.New(msg("line %d: want def name", n))
+		case stmt == "else":
+			if len(blocks) == 0 || blocks[len(blocks)-1].stmt != "if" || blocks[len(blocks)-1].inElse {
+				return nil, errors.New(msg("line %d: else without if", n))
+			}
+			blocks[len(blocks)-1].inElse = true
+		case stmt == "if" || stmt == "while":
+			l := &scriptLine{n: n, stmt: stmt, arg: arg}
+			add(l)
+			blocks = append(blocks, l)
+		case stmt == "set":
+			if name, _ := splitCmd(arg); !isScriptVar(name) {
+				return nil, errors.New(msg("line %d: bad variable name %s", n, name))
+			}
+			add(&scriptLine{n: n, stmt: stmt, arg: arg})
+		case stmt == "addr" || stmt == "edit" || stmt == "exec" || stmt == "print":
+			add(&scriptLine{n: n, stmt: stmt, arg: arg})
+		default:
+			return nil, errors.New(msg("line %d: unknown statement %s", n, stmt))
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if cur != nil {
+		return nil, errors.New(msg("line %d: def %s without end", n, cur.name))
+	}
+	return scripts, nil
+}
+
+// isScriptVar returns whether the name can be set as a script variable:
+// a letter followed by letters and digits,
+// not beginning with the built-in variables dot or file.
+func isScriptVar(name string) bool {
+	if name == "" || strings.HasPrefix(name, "dot") || strings.HasPrefix(name, "file") {
+		return false
+	}
+	for i, r := range name {
+		if !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// reloadScripts implements the Scripts command.
+// It reads the scripts file again
+// and lists the names of its scripts in the Output sheet.
+func reloadScripts(w *Win) error {
+	if err := loadScripts(w); err != nil {
+		return err
+	}
+	var names []string
+	for name := range w.scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w.OutputString(strings.Join(names, " ") + "\n")
+	return nil
+}
+
+// runScript runs a script with the given arguments
+// in the column and sheet in which it was executed.
+// It stops at the first statement that fails.
+func runScript(c *Col, s *Sheet, sc *script, args string) error {
+	w := c.win
+	if w.running >= maxScriptDepth {
+		return errors.New(msg("%s: scripts nested too deeply", sc.name))
+	}
+	w.running++
+	defer func() { w.running-- }()
+	vars := make(map[string]string)
+	if l, err := runScriptLines(c, s, sc.lines, args, vars); err != nil {
+		return errors.New(msg("%s: line %d: %s", sc.name, l.n, err.Error()))
+	}
+	return nil
+}
+
+// runScriptLines runs the statements,
+// returning the statement that failed and its error, if any.
+func runScriptLines(c *Col, s *Sheet, lines []*scriptLine, args string, vars map[string]string) (*scriptLine, error) {
+	for _, l := range lines {
+		switch l.stmt {
+		case "if":
+			ok, err := scriptAddr(s, l, args, vars)
+			if err != nil {
+				return l, err
+			}
+			body := l.els
+			if ok {
+				body = l.body
+			}
+			if fl, err := runScriptLines(c, s, body, args, vars); err != nil {
+				return fl, err
+			}
+		case "while":
+			for i := 0; ; i++ {
+				if i == maxScriptLoop {
+					return l, errors.New(msg("while ran %d times", maxScriptLoop))
+				}
+				ok, err := scriptAddr(s, l, args, vars)
+				if err != nil {
+					return l, err
+				}
+				if !ok {
+					break
+				}
+				if fl, err := runScriptLines(c, s, l.body, args, vars); err != nil {
+					return fl, err
+				}
+			}
+		default:
+			if err := runScriptLine(c, s, l, args, vars); err != nil {
+				return l, err
+			}
+		}
+	}
+	return nil, nil
+}
+
+// scriptAddr evaluates the address of an if or while statement,
+// setting dot to it and returning true if it matches in the body.
+func scriptAddr(s *Sheet, l *scriptLine, args string, vars map[string]string) (bool, error) {
+	if s == nil {
+		return false, errors.New(msg("%s wants a sheet", l.stmt))
+	}
+	b := s.body
+	at, err := edit.Addr(b.dots[1].At, expandScriptVars(s, l.arg, args, vars), b.text)
+	switch {
+	case err == edit.ErrNoMatch:
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	setDot(b, 1, at[0], at[1])
+	return true, nil
+}
+
+func runScriptLine(c *Col, s *Sheet, l *scriptLine, args string, vars map[string]string) error {
+	arg := expandScriptVars(s, l.arg, args, vars)
+	switch l.stmt {
+	case "exec":
+		return execCmd(c, s, arg)
+	case "print":
+		c.win.OutputString(arg + "\n")
+		return nil
+	case "set":
+		name, val := splitCmd(l.arg)
+		vars[name] = expandScriptVars(s, val, args, vars)
+		return nil
+	}
+	if s == nil {
+		return errors.New(msg("%s wants a sheet", l.stmt))
+	}
+	b := s.body
+	switch l.stmt {
+	case "addr":
+		at, err := edit.Addr(b.dots[1].At, arg, b.text)
+		if err != nil {
+			return err
+		}
+		setDot(b, 1, at[0], at[1])
+		showAddr(b, at[0])
+	case "edit":
+		if _, err := b.Edit(arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandScriptVars returns the text with the variables of a script replaced.
+// Variables that are not set are left as they are.
+func expandScriptVars(s *Sheet, text, args string, vars map[string]string) string {
+	if !strings.Contains(text, "$") {
+		return text
+	}
+	fields := strings.Fields(args)
+	var sb strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '$' || i == len(text)-1 {
+			sb.WriteByte(text[i])
+			continue
+		}
+		switch rest := text[i+1:]; {
+		case rest[0] == '$':
+			sb.WriteByte('$')
+			i++
+		case rest[0] == '*':
+			sb.WriteString(args)
+			i++
+		case rest[0] >= '1' && rest[0] <= '9':
+			if n := int(rest[0] - '1'); n < len(fields) {
+				sb.WriteString(fields[n])
+			}
+			i++
+		case strings.HasPrefix(rest, "dot"):
+			if s != nil {
+				at := s.body.dots[1].At
+				sb.WriteString(rope.Slice(s.body.text, at[0], at[1]).String())
+			}
+			i += len("dot")
+		case strings.HasPrefix(rest, "file"):
+			if s != nil {
+				sb.WriteString(s.Title())
+			}
+			i += len("file")
+		default:
+			name := rest
+			if i := strings.IndexFunc(rest, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+			}); i >= 0 {
+				name = rest[:i]
+			}
+			if v, ok := vars[name]; ok && name != "" {
+				sb.WriteString(v)
+				i += len(name)
+				break
+			}
+			sb.WriteByte('$')
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,146 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestReadScripts(t *testing.T) {
+	scripts, err := readScripts(strings.NewReader("# comment\n\ndef A\n\tedit d\nend\ndef B\nend\n"))
+	if err != nil {
+		t.Fatalf("readScripts failed: %v", err)
+	}
+	if len(scripts) != 2 || len(scripts["A"].lines) != 1 || len(scripts["B"].lines) != 0 {
+		t.Errorf("scripts=%v, want A with 1 line and B with none", scripts)
+	}
+	tests := []struct {
+		text, err string
+	}{
+		{"edit d\n", "line 1: want def name"},
+		{"def\nend\n", "line 1: want def name"},
+		{"def A B\nend\n", "line 1: want def name"},
+		{"def A\ndef B\n", "line 2: def inside def A"},
+		{"def A\nend\ndef A\nend\n", "line 3: duplicate script A"},
+		{"end\n", "line 1: end without def"},
+		{"def A\nsed d\nend\n", "line 2: unknown statement sed"},
+		{"def A\nedit d\n", "line 2: def A without end"},
+		{"def Wrap\nend\n", "line 1: script Wrap has the name of a built-in command"},
+		{"def A\nelse\nend\n", "line 2: else without if"},
+		{"def A\nif /a/\nelse\nelse\nend\nend\n", "line 4: else without if"},
+		{"def A\nwhile /a/\nelse\nend\nend\n", "line 3: else without if"},
+		{"def A\nif /a/\nend\n", "line 3: def A without end"},
+		{"def A\nset 1x y\nend\n", "line 2: bad variable name 1x"},
+		{"def A\nset dot y\nend\n", "line 2: bad variable name dot"},
+	}
+	for _, test := range tests {
+		if _, err := readScripts(strings.NewReader(test.text)); err == nil || err.Error() != test.err {
+			t.Errorf("readScripts(%q)=%v, want %q", test.text, err, test.err)
+		}
+	}
+}
+
+func TestCmd_Script(t *testing.T) {
+	w := newTestWin()
+	c := w.cols[0]
+	s := NewSheet(w, "/a/b.txt")
+	c.Add(s)
+	s.body.SetText(rope.New("abc"))
+	setDot(s.body, 1, 1, 2)
+
+	var err error
+	w.scripts, err = readScripts(strings.NewReader(
+		"def Bracket\nedit c/[$dot]/\nend\n" +
+			"def Twice\nexec Bracket\nexec Bracket\nprint $file $2 $* $$1 $x\nend\n" +
+			"def Loop\nexec Loop\nend\n" +
+			"def Bad\naddr /nope/\nend\n"))
+	if err != nil {
+		t.Fatalf("readScripts failed: %v", err)
+	}
+	if err := execCmd(c, s, "Twice x y"); err != nil {
+		t.Fatalf("Twice failed: %v", err)
+	}
+	if got, want := s.body.text.String(), "a[[b]]c"; got != want {
+		t.Errorf("text=%q, want %q", got, want)
+	}
+	if got, want := w.outputBuffer.String(), "/a/b.txt y x y $1 $x\n"; got != want {
+		t.Errorf("output=%q, want %q", got, want)
+	}
+
+	err = execCmd(c, s, "Loop")
+	if err == nil || !strings.HasSuffix(err.Error(), "Loop: scripts nested too deeply") {
+		t.Errorf("Loop=%v, want scripts nested too deeply", err)
+	}
+	if w.running != 0 {
+		t.Errorf("running=%d after Loop, want 0", w.running)
+	}
+	if err := execCmd(c, s, "Bad"); err == nil || !strings.HasPrefix(err.Error(), "Bad: line 13: ") {
+		t.Errorf("Bad=%v, want an error on line 13", err)
+	}
+	if err := execCmd(c, nil, "Bracket"); err == nil || err.Error() != "Bracket: line 2: edit wants a sheet" {
+		t.Errorf("Bracket without a sheet=%v, want edit wants a sheet", err)
+	}
+}
+
+func TestCmd_ScriptBlocks(t *testing.T) {
+	w := newTestWin()
+	c := w.cols[0]
+	s := NewSheet(w, "/a/b.txt")
+	c.Add(s)
+
+	var err error
+	// Untab is the example of the script documentation.
+	w.scripts, err = readScripts(strings.NewReader(`
+# Untab replaces each leading tab of the body with $1.
+def Untab
+addr 0
+while /^\t+/
+edit x/\t/ c/$1/
+end
+end
+
+def Has
+if /$1/
+print yes $dot
+else
+print no $1
+end
+end
+
+def Forever
+while /a/
+end
+end
+
+def Bad
+if /a/,/b/-/c/x
+end
+end
+`))
+	if err != nil {
+		t.Fatalf("readScripts failed: %v", err)
+	}
+	s.body.SetText(rope.New("\ta\n\t\tb\nc\t\n"))
+	if err := execCmd(c, s, "Untab __"); err != nil {
+		t.Fatalf("Untab failed: %v", err)
+	}
+	if got, want := s.body.text.String(), "__a\n____b\nc\t\n"; got != want {
+		t.Errorf("text=%q, want %q", got, want)
+	}
+	if err := execCmd(c, s, "Has b"); err != nil {
+		t.Fatalf("Has b failed: %v", err)
+	}
+	if err := execCmd(c, s, "Has z"); err != nil {
+		t.Fatalf("Has z failed: %v", err)
+	}
+	if got, want := w.outputBuffer.String(), "yes b\nno z\n"; got != want {
+		t.Errorf("output=%q, want %q", got, want)
+	}
+	if err := execCmd(c, s, "Forever"); err == nil || err.Error() != "Forever: line 19: while ran 100000 times" {
+		t.Errorf("Forever=%v, want while ran 100000 times", err)
+	}
+	if err := execCmd(c, s, "Bad"); err == nil || err.Error() != "Bad: line 24: expected end-of-input" {
+		t.Errorf("Bad=%v, want expected end-of-input", err)
+	}
+}
@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"image/draw"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/eaburns/T/text"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxLigatureLen is the length in bytes
+// of the longest key of ligatureRunes.
+const maxLigatureLen = 3
+
+// isMark returns whether the rune is a combining mark.
+// Combining marks have no advance;
+// they are drawn over the preceding rune.
+func isMark(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me)
+}
+
+// drawShaped draws the glyph cluster at the start of str
+// with its left edge at x0 and its baseline at yb,
+// and returns the number of bytes of str drawn.
+//
+// A cluster is either a ligature, if ligatures are enabled,
+// or a rune followed by any combining marks.
+// If the face has a precomposed glyph for a rune and its marks,
+// the precomposed glyph is drawn.
+// Otherwise, each mark is drawn over the rune.
+func drawShaped(b *TextBox, img draw.Image, style text.Style, x0, yb fixed.Int26_6, str string) int {
+	if ligatures {
+		if lig, n := ligature(str); n > 0 && hasGlyph(style, lig) {
+			var w fixed.Int26_6
+			var prev rune
+			for _, r := range str[:n] {
+				w += kern(style, prev, r) + advance(b, style, 0, 0, r)
+				prev = r
+			}
+			adv, _ := style.Face.GlyphAdvance(lig)
+			drawGlyph(img, style, x0+(w-adv)/2, yb, lig)
+			return n
+		}
+	}
+
+	r, n := utf8.DecodeRuneInString(str)
+	end := n
+	for end < len(str) {
+		m, w := utf8.DecodeRuneInString(str[end:])
+		if !isMark(m) {
+			break
+		}
+		end += w
+	}
+	if end == n || isMark(r) {
+		drawGlyph(img, style, x0, yb, r)
+		return n
+	}
+	if c := []rune(norm.NFC.String(str[:end])); len(c) == 1 && hasGlyph(style, c[0]) {
+		drawGlyph(img, style, x0, yb, c[0])
+		return end
+	}
+	adv := drawGlyph(img, style, x0, yb, r)
+	for _, m := range str[n:end] {
+		// Marks with no advance are positioned by the font
+		// relative to the end of the preceding glyph.
+		// Others are centered over it.
+		x := x0 + adv
+		if madv, _ := style.Face.GlyphAdvance(m); madv > 0 {
+			x = x0 + (adv-madv)/2
+		}
+		drawGlyph(img, style, x, yb, m)
+	}
+	return end
+}
+
+// ligature returns the rune of the longest ligature
+// at the start of str and its length in bytes,
+// or 0 and 0 if there is none.
+func ligature(str string) (rune, int) {
+	for n := maxLigatureLen; n > 1; n-- {
+		if n > len(str) {
+			continue
+		}
+		if r, ok := ligatureRunes[str[:n]]; ok {
+			return r, n
+		}
+	}
+	return 0, 0
+}
+
+func hasGlyph(style text.Style, r rune) bool {
+	_, ok := style.Face.GlyphAdvance(r)
+	return ok
+}
@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"image"
+	"reflect"
+	"testing"
+
+	"github.com/eaburns/T/rope"
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestCombiningMarkLayout(t *testing.T) {
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New("e\u0301x"))
+	// The mark has no advance, so x follows e.
+	at, _ := atPoint(b, image.Pt(A+A/2, 0))
+	if at != 3 {
+		t.Errorf("atPoint(x)=%d, want 3", at)
+	}
+	if w := b.lines()[0].spans[0].w; w != fixed.I(2*A) {
+		t.Errorf("width=%v, want %v", w, fixed.I(2*A))
+	}
+}
+
+func TestDrawComposed(t *testing.T) {
+	styles := testTextStyles
+	styles[0].Face = truetype.NewFace(defaultFont, &truetype.Options{Size: 16})
+	draw := func(str string) *image.RGBA {
+		b := NewTextBox(testWin, styles, testSize)
+		b.SetText(rope.New(str))
+		img := image.NewRGBA(image.Rectangle{Max: testSize})
+		b.Draw(true, img)
+		return img
+	}
+	if !reflect.DeepEqual(draw("e\u0301"), draw("\u00E9")) {
+		t.Errorf("e+U+0301 was not drawn as U+00E9")
+	}
+}
+
+func TestLigature(t *testing.T) {
+	tests := []struct {
+		str string
+		r   rune
+		n   int
+	}{
+		{str: "", r: 0, n: 0},
+		{str: "-", r: 0, n: 0},
+		{str: "->x", r: '→', n: 2},
+		{str: "...", r: '…', n: 3},
+		{str: "..", r: 0, n: 0},
+		{str: "x->", r: 0, n: 0},
+	}
+	for _, test := range tests {
+		if r, n := ligature(test.str); r != test.r || n != test.n {
+			t.Errorf("ligature(%q)=%q, %d, want %q, %d", test.str, r, n, test.r, test.n)
+		}
+	}
+}
@@ -1,14 +1,18 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"image"
 	"image/draw"
+	"io/ioutil"
 	"math"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -17,6 +21,7 @@ import (
 	"github.com/eaburns/T/rope"
 	"github.com/eaburns/T/syntax"
 	"github.com/eaburns/T/text"
+	"github.com/golang/freetype/truetype"
 )
 
 // A Sheet is a tag and a body.
@@ -25,8 +30,25 @@ type Sheet struct {
 	tag           *TextBox
 	body          *TextBox
 	tagH, minTagH int
+	font          *truetype.Font // font of the sheet; nil for the default font
+	fontSize      int            // size of the font in points
 	size          image.Point
-	*TextBox      // the focus element: the tag or the body.
+	repl          *repl     // the interpreter of a REPL sheet; nil otherwise
+	finder        *finder   // the file list of a +Open sheet; nil otherwise
+	lsp           *lspDoc   // the file open in a language server; nil if none
+	build         *build    // the last build of a +Build sheet; nil otherwise
+	debug         *debugger // the debug session of a +Debug sheet; nil otherwise
+	watch         *watcher  // the file watcher of a +Watch sheet; nil otherwise
+	pipe          *pipe     // the running |command of the body; nil if none
+	saved         rope.Rope // body text when last read or written; nil if never
+	conflict      bool      // whether the file changed on disk while the body was modified
+	typed         string    // title for which the body's file type was last set
+	byTime        bool      // whether a directory body is sorted by modification time
+	noHidden      bool      // whether a directory body omits files beginning with .
+	id            int       // the winid of the sheet, unique in its window
+	*TextBox                // the focus element: the tag or the body.
+
+	imgs [2]image.RGBA // the images of the tag and body, reused by Draw
 }
 
 // NewSheet returns a new sheet.
@@ -48,14 +70,18 @@ func NewSheet(w *Win, title string) *Sheet {
 	tag := NewTextBox(w, tagTextStyles, image.ZP)
 	body := NewTextBox(w, bodyTextStyles, image.ZP)
 	s := &Sheet{
-		tag:     tag,
-		body:    body,
-		minTagH: w.lineHeight,
-		TextBox: body,
+		tag:      tag,
+		body:     body,
+		minTagH:  w.lineHeight,
+		fontSize: w.fontSize,
+		TextBox:  body,
 	}
+	w.sheets++
+	s.id = w.sheets
 	tag.setHighlighter(s)
-	tag.SetText(rope.New(tagText))
+	tag.SetText(rope.New(tagText + dirTagText(title)))
 	s.SetTitle(title)
+	s.typed = s.Title()
 	return s
 }
 
@@ -64,9 +90,20 @@ func (s *Sheet) Body() *TextBox { return s.body }
 
 // Tick handles tic events.
 func (s *Sheet) Tick() bool {
+	redraw0 := s.repl != nil && replOutput(s)
+	redraw0 = s.finder != nil && finderUpdate(s) || redraw0
+	redraw0 = s.lsp != nil && lspUpdate(s) || redraw0
+	redraw0 = s.build != nil && buildOutput(s) || redraw0
+	redraw0 = s.debug != nil && debugUpdate(s) || redraw0
+	redraw0 = s.watch != nil && watchUpdate(s) || redraw0
+	redraw0 = s.pipe != nil && pipeUpdate(s) || redraw0
+	if s.typed != s.Title() {
+		setFileType(s)
+		redraw0 = true
+	}
 	redraw1 := s.body.Tick()
 	redraw2 := s.tag.Tick()
-	return redraw1 || redraw2
+	return redraw0 || redraw1 || redraw2
 }
 
 // Draw draws the sheet.
@@ -76,11 +113,11 @@ func (s *Sheet) Draw(dirty bool, drawImg draw.Image) {
 	tagRect := img.Bounds()
 	tagRect.Max.X = drawSheetHandle(s, img)
 	tagRect.Max.Y = tagRect.Min.Y + s.tagH
-	s.tag.Draw(dirty, img.SubImage(tagRect).(*image.RGBA))
+	s.tag.Draw(dirty, subImage(&s.imgs[0], img, tagRect))
 
 	bodyRect := img.Bounds()
 	bodyRect.Min.Y = tagRect.Max.Y
-	s.body.Draw(dirty, img.SubImage(bodyRect).(*image.RGBA))
+	s.body.Draw(dirty, subImage(&s.imgs[1], img, bodyRect))
 }
 
 func drawSheetHandle(s *Sheet, img *image.RGBA) int {
@@ -90,6 +127,7 @@ func drawSheetHandle(s *Sheet, img *image.RGBA) int {
 	r.Max.Y = r.Min.Y + s.tagH
 	fillRect(img, tagBG, r)
 	fillRect(img, colBG, handle.Inset(pad))
+	addChanged(s.win, r)
 	return r.Min.X
 }
 
@@ -98,6 +136,60 @@ func (s *Sheet) HandleBounds() image.Rectangle {
 	return image.Rect(s.size.X-s.minTagH, 0, s.size.X, s.minTagH)
 }
 
+func setSheetFontSize(s *Sheet, size int) {
+	if size < minFontSize {
+		size = minFontSize
+	}
+	s.fontSize = size
+	face := newFace(s.font, s.win.dpi, size)
+	setFace(s.tag, face)
+	setFace(s.body, face)
+	s.minTagH = faceHeight(face)
+	s.Resize(s.size)
+}
+
+// setFont implements the Font command: Font [path] [size].
+// With no arguments, the sheet toggles
+// between the default font and fixedFont.
+// Otherwise, the sheet uses the TrueType font file at path, if given,
+// at the size in points, if given.
+func setFont(s *Sheet, args string) error {
+	fs := strings.Fields(args)
+	if len(fs) == 0 {
+		if s.font == nil {
+			s.font = fixedFont
+		} else {
+			s.font = nil
+		}
+		setSheetFontSize(s, s.fontSize)
+		return nil
+	}
+	if len(fs) > 2 {
+		return errors.New(msg("usage: Font [path] [size]"))
+	}
+	f, size := s.font, s.fontSize
+	for _, arg := range fs {
+		if n, err := strconv.Atoi(arg); err == nil {
+			size = n
+			continue
+		}
+		path, err := abs(s, arg)
+		if err != nil {
+			return err
+		}
+		ttf, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if f, err = truetype.Parse(ttf); err != nil {
+			return err
+		}
+	}
+	s.font = f
+	setSheetFontSize(s, size)
+	return nil
+}
+
 // Resize handles resize events.
 func (s *Sheet) Resize(size image.Point) {
 	s.size = size
@@ -143,6 +235,16 @@ func (s *Sheet) Wheel(pt image.Point, x, y int) {
 	}
 }
 
+// WheelPx handles precise scrolling events.
+func (s *Sheet) WheelPx(pt image.Point, x, y float64) {
+	if pt.Y < s.tagH {
+		s.tag.WheelPx(pt, x, y)
+	} else {
+		pt.Y -= s.tagH
+		s.body.WheelPx(pt, x, y)
+	}
+}
+
 // Click handles click events.
 func (s *Sheet) Click(pt image.Point, button int) (int, [2]int64) {
 	if button > 0 {
@@ -151,6 +253,9 @@ func (s *Sheet) Click(pt image.Point, button int) (int, [2]int64) {
 
 	if s.TextBox == s.body {
 		pt.Y -= s.tagH
+		if button > 0 && breakClick(s, pt, button) {
+			return button, [2]int64{}
+		}
 	}
 	return s.TextBox.Click(pt, button)
 }
@@ -177,6 +282,39 @@ func setSheetFocus(s *Sheet, pt image.Point, button int) bool {
 	return false
 }
 
+// Rune handles typed runes.
+func (s *Sheet) Rune(r rune) {
+	if s.repl != nil && s.TextBox == s.body && replRune(s, r) {
+		return
+	}
+	if s.finder != nil && s.TextBox == s.body && finderRune(s, r) {
+		return
+	}
+	s.TextBox.Rune(r)
+}
+
+// Close stops the interpreter, commands, debug session, and file watcher
+// of the sheet, and closes its file in the language server.
+func (s *Sheet) Close() {
+	if s.repl != nil {
+		s.repl.close()
+	}
+	closeLanguageServer(s)
+	if s.pipe != nil {
+		s.pipe.kill()
+	}
+	if s.build != nil {
+		s.build.kill()
+	}
+	if s.debug != nil {
+		endDebug(s)
+	}
+	if s.watch != nil {
+		s.watch.stop()
+		s.watch = nil
+	}
+}
+
 // Title returns the title of the sheet.
 // The title is the first space-terminated string in the tag,
 // or if the first rune of the tag is ' , it is the first ' terminated string
@@ -235,6 +373,48 @@ func (s *Sheet) SetTitle(title string) {
 	s.tag.Change([]edit.Diff{{At: [2]int64{0, 0}, Text: rope.New(title)}})
 }
 
+// dirTagText returns the text of the tagFile
+// in the directory of the file at the path, preceded by a space,
+// or "" if the path is not absolute or there is no such file.
+func dirTagText(path string) string {
+	if !filepath.IsAbs(path) {
+		return ""
+	}
+	dir := path
+	if !strings.HasSuffix(path, string(os.PathSeparator)) {
+		dir = filepath.Dir(path)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, tagFile))
+	if err != nil {
+		return ""
+	}
+	text := strings.TrimRight(string(data), "\n")
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	return " " + text
+}
+
+// setTagText appends the text to the first line of the sheet's tag
+// if on is true and it is not there,
+// and removes it from the first line if on is false.
+func setTagText(s *Sheet, tagText string, on bool) {
+	text := s.tag.text.String()
+	end := strings.IndexRune(text, '\n')
+	if end < 0 {
+		end = len(text)
+	}
+	i := strings.Index(text[:end], tagText)
+	switch {
+	case on && i < 0:
+		at := int64(end)
+		s.tag.Change(edit.Diffs{{At: [2]int64{at, at}, Text: rope.New(tagText)}})
+	case !on && i >= 0:
+		at := [2]int64{int64(i), int64(i + len(tagText))}
+		s.tag.Change(edit.Diffs{{At: at, Text: rope.Empty()}})
+	}
+}
+
 // Get loads the body of the sheet
 // with the contents of the file
 // at the path of the sheet's title.
@@ -262,6 +442,10 @@ func get(s *Sheet, f *os.File) error {
 	if err != nil {
 		return err
 	}
+	s.saved = s.body.text
+	setGitText(s)
+	clearConflict(s)
+	logEvent(s.win, s, "get", "")
 	if s.TextBox != s.body {
 		s.TextBox.Focus(false)
 		s.TextBox = s.body
@@ -277,18 +461,59 @@ func getText(s *Sheet, f *os.File) error {
 	}
 	s.body.setHighlighter(nil)
 	s.body.SetText(txt)
-	s.body.setHighlighter(syntaxHighlighter(s.win.dpi, s.Title()))
+	large := txt.Len() > largeFileSize
+	setFileType(s)
+	if large {
+		closeLanguageServer(s)
+	} else {
+		setLanguageServer(s)
+	}
+	var off []string
+	if large {
+		s.body.fileSettings.showSpace = false
+		s.body.fileSettings.imports = false
+		s.body.fileSettings.format = nil
+		off = append(off, msg("syntax highlighting"), msg("trailing space highlighting"), msg("imports on Put"), msg("formatting on Put"), msg("language server"))
+	}
+	if n := longestLine(txt); n > longLineLen && !s.body.nowrap {
+		setWrap(s.body, false)
+		off = append(off, msg("wrapping (%d-byte line)", n))
+	}
+	if len(off) > 0 {
+		s.win.OutputString(msg("%s: %d bytes; disabled %s", s.Title(), txt.Len(), strings.Join(off, ", ")) + "\n")
+	}
 	return nil
 }
 
+// longestLine returns the length in bytes of the longest line of the text.
+func longestLine(txt rope.Rope) int64 {
+	var max, n int64
+	rr := rope.NewReader(txt)
+	for {
+		c, err := rr.ReadByte()
+		if err != nil || c == '\n' {
+			if n > max {
+				max = n
+			}
+			if err != nil {
+				return max
+			}
+			n = 0
+			continue
+		}
+		n++
+	}
+}
+
 func getDir(s *Sheet, f *os.File) error {
 	s.SetTitle(ensureTrailingSlash(s.Title()))
-	txt, err := readFromDir("", f)
+	txt, err := readFromDir(s, "", f)
 	if err != nil {
 		return err
 	}
 	s.body.SetText(txt)
-	s.body.setHighlighter(syntaxHighlighter(s.win.dpi, s.Title()))
+	setFileType(s)
+	setTagText(s, dirRowTagText, true)
 	return nil
 }
 
@@ -299,15 +524,20 @@ func ensureTrailingSlash(p string) string {
 	return p
 }
 
-func readFromDir(prefix string, f *os.File) (rope.Rope, error) {
+// readFromDir returns the listing of the directory
+// sorted and filtered as set by the Sort and Hidden commands of the sheet.
+func readFromDir(s *Sheet, prefix string, f *os.File) (rope.Rope, error) {
 	txt := rope.Empty()
 	fis, err := f.Readdir(-1)
 	if err != nil {
 		return txt, err
 	}
-	sortFileInfos(fis)
+	sortFileInfos(fis, s.byTime)
 	for _, fi := range fis {
 		name := fi.Name()
+		if s.noHidden && strings.HasPrefix(name, ".") {
+			continue
+		}
 		if prefix != "" {
 			name = filepath.Join(prefix, name)
 		}
@@ -319,9 +549,13 @@ func readFromDir(prefix string, f *os.File) (rope.Rope, error) {
 	return txt, nil
 }
 
-func sortFileInfos(fis []os.FileInfo) {
+// sortFileInfos sorts directories first,
+// then by name or, if byTime, newest first.
+func sortFileInfos(fis []os.FileInfo, byTime bool) {
 	sort.Slice(fis, func(i, j int) bool {
 		switch {
+		case fis[i].IsDir() == fis[j].IsDir() && byTime && !fis[i].ModTime().Equal(fis[j].ModTime()):
+			return fis[i].ModTime().After(fis[j].ModTime())
 		case fis[i].IsDir() == fis[j].IsDir():
 			return fis[i].Name() < fis[j].Name()
 		case fis[i].IsDir():
@@ -332,21 +566,95 @@ func sortFileInfos(fis []os.FileInfo) {
 	})
 }
 
-func syntaxHighlighter(dpi float32, path string) updater {
-	for _, s := range syntaxHighlighting {
-		switch ok, err := regexp.MatchString(s.regexp, path); {
-		case err != nil:
+// setFileType sets the file settings and syntax highlighting
+// of the sheet's body for the type of the file named by its title.
+// Large files are not highlighted.
+func setFileType(s *Sheet) {
+	s.typed = s.Title()
+	setFileSettings(s.body, s.typed)
+	var h updater
+	if s.body.text.Len() <= largeFileSize {
+		h = syntaxHighlighter(s.win.dpi, s.body)
+	}
+	s.body.setHighlighter(h)
+}
+
+// syntaxHighlighter returns the highlighter of the text box's lexer
+// at the dpi, or nil if it has no lexer.
+func syntaxHighlighter(dpi float32, b *TextBox) updater {
+	if b.lexer == nil {
+		return nil
+	}
+	return &highlighter{b.lexer(dpi)}
+}
+
+// setFileSettings sets the text box's settings
+// to those of the first of fileTypes matching the path
+// or the interpreter of the #! line of its text.
+func setFileSettings(b *TextBox, path string) {
+	settings := defaultFileSettings
+	interp := shebang(b.text)
+	for _, t := range fileTypes {
+		ok, err := regexp.MatchString(t.regexp, path)
+		if err == nil && !ok && t.shebang != "" && interp != "" {
+			ok, err = regexp.MatchString(t.shebang, interp)
+		}
+		if err != nil {
 			fmt.Println(err.Error())
-		case ok:
-			return &highlighter{s.tok(dpi)}
+			continue
+		}
+		if ok {
+			settings = t.fileSettings
+			break
 		}
 	}
-	return nil
+	b.fileSettings = settings
+	if b.nowrap != settings.noWrap {
+		setWrap(b, !settings.noWrap)
+	}
+	dirtyLines(b)
+}
+
+// shebang returns the interpreter of the #! line beginning the text:
+// the base name of its first word, or its second word if the first is env.
+// It returns "" if the text does not begin with #!.
+func shebang(txt rope.Rope) string {
+	if txt.Len() < 2 || rope.Slice(txt, 0, 2).String() != "#!" {
+		return ""
+	}
+	end := rope.IndexRune(txt, '\n')
+	if end < 0 {
+		end = txt.Len()
+	}
+	fs := strings.Fields(rope.Slice(txt, 2, end).String())
+	if len(fs) == 0 {
+		return ""
+	}
+	if name := path.Base(fs[0]); name != "env" || len(fs) == 1 {
+		return name
+	}
+	return fs[1]
 }
 
 // Put writes the contents of the body of the sheet
 // to the file at the path of the sheet's title.
 func (s *Sheet) Put() error {
+	if err := checkConflict(s); err != nil {
+		return err
+	}
+	if s.body.trimSpace {
+		trimTrailingSpace(s.body)
+	}
+	if s.body.imports {
+		if err := putImports(s.body, s.Title()); err != nil {
+			s.body.win.OutputString(err.Error() + "\n")
+		}
+	}
+	if s.body.format != nil {
+		if err := putFormat(s.body, s.Title()); err != nil {
+			s.body.win.OutputString(err.Error() + "\n")
+		}
+	}
 	f, err := os.Create(s.Title())
 	if err != nil {
 		return err
@@ -355,5 +663,11 @@ func (s *Sheet) Put() error {
 		f.Close()
 		return err
 	}
-	return f.Close()
+	if err := f.Close(); err != nil {
+		return err
+	}
+	s.saved = s.body.text
+	setGitText(s)
+	logEvent(s.win, s, "put", "")
+	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/eaburns/T/rope"
@@ -97,6 +98,163 @@ func TestSheetGet_TextFile(t *testing.T) {
 	}
 }
 
+func TestSheetGet_TabSettings(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file.py")
+	write(path, "")
+
+	sh := NewSheet(testWin, path)
+	if err := sh.Get(); err != nil {
+		t.Fatalf("Get()=%v, want nil", err)
+	}
+	if sh.body.tabWidth != 4 || !sh.body.tabSpaces {
+		t.Errorf("tabWidth=%d, tabSpaces=%v, want 4, true", sh.body.tabWidth, sh.body.tabSpaces)
+	}
+}
+
+func TestSheetGet_Shebang(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "script")
+	write(path, "#!/usr/bin/env python3\nprint(1)\n")
+
+	sh := NewSheet(testWin, path)
+	if err := sh.Get(); err != nil {
+		t.Fatalf("Get()=%v, want nil", err)
+	}
+	if sh.body.tabWidth != 4 || !sh.body.tabSpaces || sh.body.comment != "#" {
+		t.Errorf("tabWidth=%d, tabSpaces=%v, comment=%q, want 4, true, #", sh.body.tabWidth, sh.body.tabSpaces, sh.body.comment)
+	}
+}
+
+func TestNewSheet_TagFile(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	write(filepath.Join(dir, tagFile), "Mk Test\n")
+	path := filepath.Join(dir, "a.go")
+
+	for _, title := range []string{path, dir + "/"} {
+		sh := NewSheet(testWin, title)
+		if got, want := sh.tag.text.String(), title+tagText+" Mk Test"; got != want {
+			t.Errorf("tag=%q, want %q", got, want)
+		}
+	}
+	other := tmpdir()
+	defer os.RemoveAll(other)
+	sh := NewSheet(testWin, filepath.Join(other, "b.go"))
+	if got := sh.tag.text.String(); strings.Contains(got, "Mk Test") {
+		t.Errorf("tag=%q, want no tag file text", got)
+	}
+}
+
+func TestShebang(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"", ""},
+		{"#", ""},
+		{"#!", ""},
+		{"#!/bin/sh\n", "sh"},
+		{"#! /usr/bin/python3 -u\nx", "python3"},
+		{"#!/usr/bin/env bash", "bash"},
+		{"#!/usr/bin/env", "env"},
+		{"x\n#!/bin/sh\n", ""},
+	}
+	for _, test := range tests {
+		if got := shebang(rope.New(test.text)); got != test.want {
+			t.Errorf("shebang(%q)=%q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestSheetRename_FileType(t *testing.T) {
+	sh := NewSheet(testWin, "a.txt")
+	if sh.body.highlighter != nil || sh.body.comment != "" {
+		t.Fatalf("a.txt: highlighter=%v, comment=%q, want nil, \"\"", sh.body.highlighter, sh.body.comment)
+	}
+	sh.SetTitle("a.go")
+	sh.Tick()
+	if sh.body.highlighter == nil || sh.body.comment != "//" || !sh.body.imports {
+		t.Errorf("a.go: highlighter=%v, comment=%q, imports=%v, want non-nil, //, true", sh.body.highlighter, sh.body.comment, sh.body.imports)
+	}
+	sh.SetTitle("a.csv")
+	sh.Tick()
+	if sh.body.highlighter != nil || !sh.body.nowrap {
+		t.Errorf("a.csv: highlighter=%v, nowrap=%v, want nil, true", sh.body.highlighter, sh.body.nowrap)
+	}
+}
+
+func TestSheetGet_LargeFile(t *testing.T) {
+	defer func(size, n int64) { largeFileSize, longLineLen = size, n }(largeFileSize, longLineLen)
+	largeFileSize, longLineLen = 16, 10
+
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	small := filepath.Join(dir, "small.go")
+	write(small, "package x\n")
+	large := filepath.Join(dir, "large.go")
+	write(large, "package x\n\nvar x = 1\n")
+	long := filepath.Join(dir, "long.go")
+	write(long, "package xyz123\n")
+
+	w := newTestWin()
+	sh := NewSheet(w, small)
+	if err := sh.Get(); err != nil {
+		t.Fatalf("Get()=%v, want nil", err)
+	}
+	if sh.body.highlighter == nil || sh.body.nowrap || !sh.body.imports {
+		t.Errorf("small file: highlighter=%v, nowrap=%v, imports=%v, want non-nil, false, true",
+			sh.body.highlighter, sh.body.nowrap, sh.body.imports)
+	}
+	if got := w.outputBuffer.String(); got != "" {
+		t.Errorf("small file: output=%q, want empty", got)
+	}
+
+	sh = NewSheet(w, large)
+	if err := sh.Get(); err != nil {
+		t.Fatalf("Get()=%v, want nil", err)
+	}
+	if sh.body.highlighter != nil || sh.body.nowrap || sh.body.imports {
+		t.Errorf("large file: highlighter=%v, nowrap=%v, imports=%v, want nil, false, false",
+			sh.body.highlighter, sh.body.nowrap, sh.body.imports)
+	}
+	if got := w.outputBuffer.String(); !strings.Contains(got, "syntax highlighting") {
+		t.Errorf("large file: output=%q, want a warning", got)
+	}
+
+	w.outputBuffer.Reset()
+	sh = NewSheet(w, long)
+	if err := sh.Get(); err != nil {
+		t.Fatalf("Get()=%v, want nil", err)
+	}
+	if sh.body.highlighter == nil || !sh.body.nowrap {
+		t.Errorf("long line: highlighter=%v, nowrap=%v, want non-nil, true",
+			sh.body.highlighter, sh.body.nowrap)
+	}
+	if got := w.outputBuffer.String(); !strings.Contains(got, "wrapping") {
+		t.Errorf("long line: output=%q, want a warning", got)
+	}
+}
+
+func TestLongestLine(t *testing.T) {
+	tests := []struct {
+		text string
+		want int64
+	}{
+		{text: "", want: 0},
+		{text: "\n\n", want: 0},
+		{text: "abc", want: 3},
+		{text: "a\nabc\nab\n", want: 3},
+		{text: "a\nabcd", want: 4},
+	}
+	for _, test := range tests {
+		if got := longestLine(rope.New(test.text)); got != test.want {
+			t.Errorf("longestLine(%q)=%d, want %d", test.text, got, test.want)
+		}
+	}
+}
+
 func TestSheetGet_Dir(t *testing.T) {
 	dir := tmpdir()
 	defer os.RemoveAll(dir)
@@ -0,0 +1,241 @@
+package ui
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/eaburns/T/syntax"
+	"github.com/eaburns/T/text"
+)
+
+// diffTagText is appended to the first line of the tag of a +Diff sheet.
+const diffTagText = " Hunk- Hunk+"
+
+// diffSeparator separates the two sides of a +Diff line.
+const diffSeparator = " │ "
+
+// A lineHunk is a range of lines of a text
+// replaced by a range of lines of another text.
+type lineHunk struct {
+	a0, a1 int // the replaced lines of the first text
+	b0, b1 int // the replacing lines of the second text
+}
+
+// sideDiff implements the Diff command: Diff [path].
+// With no path, the file of the sheet is compared with its body.
+// Otherwise the body is compared with the body of the sheet of the path,
+// if it is open, or the file.
+// The texts are shown side by side in the +Diff sheet of the directory,
+// with each line of one opposite its line of the other,
+// so both scroll together.
+// Removed, added, and changed lines are colored
+// with diffRemovedBG, diffAddedBG, and diffChangedBG.
+// The Hunk+ and Hunk- commands of the +Diff tag
+// move to the next and previous hunk of changed lines.
+func sideDiff(c *Col, s *Sheet, arg string) error {
+	var aTitle, bTitle, a, b string
+	if arg == "" {
+		if !isFileSheet(s) {
+			return errors.New(msg("no file %s", s.Title()))
+		}
+		data, err := ioutil.ReadFile(s.Title())
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		aTitle, a = s.Title(), string(data)
+		bTitle, b = s.Title()+" "+msg("(unsaved)"), s.body.text.String()
+	} else {
+		path, err := abs(s, arg)
+		if err != nil {
+			return err
+		}
+		aTitle, a = s.Title(), s.body.text.String()
+		bTitle = path
+		if o := findSheet(c.win, path); o != nil {
+			b = o.body.text.String()
+		} else {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			b = string(data)
+		}
+	}
+	hunks := lineHunks(a, b)
+	if len(hunks) == 0 {
+		return errors.New(msg("no differences"))
+	}
+	dir, err := abs(s, ".")
+	if err != nil {
+		return err
+	}
+	out, his, starts := sideBySide(aTitle, bTitle, a, b, hunks, s.body.tabWidth)
+	title := filepath.Join(dir, "+Diff")
+	showScratch(c, title, out)
+	d := findSheet(c.win, title)
+	setTagText(d, diffTagText, true)
+	d.body.diffLines = his
+	d.body.hunks = starts
+	setDot(d.body, 1, starts[0], starts[0])
+	showAddr(d.body, starts[0])
+	return nil
+}
+
+// nextHunk implements the Hunk+ and Hunk- commands,
+// moving dot of the +Diff sheet to the start of the next hunk,
+// or the previous hunk if dir is negative.
+// The first hunk follows the last.
+func nextHunk(s *Sheet, dir int) error {
+	b := s.body
+	if len(b.hunks) == 0 {
+		return errors.New(msg("no differences"))
+	}
+	at := b.dots[1].At[0]
+	var h int64
+	if dir < 0 {
+		h = b.hunks[len(b.hunks)-1]
+		for i := len(b.hunks) - 1; i >= 0; i-- {
+			if b.hunks[i] < at {
+				h = b.hunks[i]
+				break
+			}
+		}
+	} else {
+		h = b.hunks[0]
+		for _, x := range b.hunks {
+			if x > at {
+				h = x
+				break
+			}
+		}
+	}
+	setDot(b, 1, h, h)
+	showAddr(b, h)
+	return nil
+}
+
+// lineHunks returns the hunks of lines
+// changing the text a into the text b, in increasing order.
+func lineHunks(a, b string) []lineHunk {
+	diffs := lineDiffs(a, b)
+	if len(diffs) == 0 {
+		return nil
+	}
+	// lineDiffs replaces whole lines,
+	// so the addresses of the diffs are at the starts of lines of a.
+	line := make(map[int64]int)
+	var off int64
+	for i, l := range splitAfterLines(a) {
+		line[off] = i
+		off += int64(len(l))
+	}
+	line[off] = len(splitAfterLines(a))
+
+	var hunks []lineHunk
+	delta := 0 // lines of b minus lines of a before the hunk
+	for i := len(diffs) - 1; i >= 0; i-- {
+		d := diffs[i]
+		h := lineHunk{a0: line[d.At[0]], a1: line[d.At[1]]}
+		h.b0 = h.a0 + delta
+		h.b1 = h.b0 + len(splitAfterLines(d.Text.String()))
+		delta += (h.b1 - h.b0) - (h.a1 - h.a0)
+		hunks = append(hunks, h)
+	}
+	return hunks
+}
+
+// sideBySide returns the text of a +Diff sheet
+// showing the texts a and b side by side under their titles,
+// the highlights of its changed lines,
+// and the addresses of the starts of its hunks.
+// Tabs are expanded to tabWidth spaces.
+func sideBySide(aTitle, bTitle, a, b string, hunks []lineHunk, tabWidth int) (string, []syntax.Highlight, []int64) {
+	as, bs := splitAfterLines(a), splitAfterLines(b)
+	for i := range as {
+		as[i] = expandTabs(strings.TrimSuffix(as[i], "\n"), tabWidth)
+	}
+	for i := range bs {
+		bs[i] = expandTabs(strings.TrimSuffix(bs[i], "\n"), tabWidth)
+	}
+	width := utf8.RuneCountInString(aTitle)
+	for _, l := range as {
+		if n := utf8.RuneCountInString(l); n > width {
+			width = n
+		}
+	}
+
+	var sb strings.Builder
+	var his []syntax.Highlight
+	var starts []int64
+	add := func(l, r string, lbg, rbg *text.Style) {
+		start := int64(sb.Len())
+		sb.WriteString(l)
+		sb.WriteString(strings.Repeat(" ", width-utf8.RuneCountInString(l)))
+		mid := int64(sb.Len())
+		sb.WriteString(diffSeparator)
+		right := int64(sb.Len())
+		sb.WriteString(r)
+		end := int64(sb.Len())
+		sb.WriteByte('\n')
+		if lbg != nil {
+			his = append(his, syntax.Highlight{At: [2]int64{start, mid}, Style: *lbg})
+		}
+		if rbg != nil {
+			his = append(his, syntax.Highlight{At: [2]int64{right, end}, Style: *rbg})
+		}
+	}
+	removed := &text.Style{BG: diffRemovedBG}
+	added := &text.Style{BG: diffAddedBG}
+	changed := &text.Style{BG: diffChangedBG}
+
+	add(aTitle, bTitle, nil, nil)
+	ai, bi := 0, 0
+	for _, h := range hunks {
+		for ; ai < h.a0; ai, bi = ai+1, bi+1 {
+			add(as[ai], bs[bi], nil, nil)
+		}
+		starts = append(starts, int64(sb.Len()))
+		for ai < h.a1 || bi < h.b1 {
+			switch {
+			case ai < h.a1 && bi < h.b1:
+				add(as[ai], bs[bi], changed, changed)
+				ai, bi = ai+1, bi+1
+			case ai < h.a1:
+				add(as[ai], "", removed, nil)
+				ai++
+			default:
+				add("", bs[bi], nil, added)
+				bi++
+			}
+		}
+	}
+	for ; ai < len(as) && bi < len(bs); ai, bi = ai+1, bi+1 {
+		add(as[ai], bs[bi], nil, nil)
+	}
+	return sb.String(), his, starts
+}
+
+// expandTabs returns the line with its tabs replaced by spaces
+// to the next multiple of tabWidth columns.
+func expandTabs(line string, tabWidth int) string {
+	if !strings.Contains(line, "\t") || tabWidth <= 0 {
+		return line
+	}
+	var sb strings.Builder
+	col := 0
+	for _, r := range line {
+		if r != '\t' {
+			sb.WriteRune(r)
+			col++
+			continue
+		}
+		n := tabWidth - col%tabWidth
+		sb.WriteString(strings.Repeat(" ", n))
+		col += n
+	}
+	return sb.String()
+}
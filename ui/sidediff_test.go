@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestSideBySide(t *testing.T) {
+	a, b := "a\nb\nc\nz\n", "a\nx\ny\nb\nz\n"
+	hunks := lineHunks(a, b)
+	if want := []lineHunk{{1, 1, 1, 3}, {2, 3, 4, 4}}; !reflect.DeepEqual(hunks, want) {
+		t.Fatalf("lineHunks=%v, want %v", hunks, want)
+	}
+	text, his, starts := sideBySide("A", "B", a, b, hunks, 8)
+	want := "A │ B\n" +
+		"a │ a\n" +
+		"  │ x\n" +
+		"  │ y\n" +
+		"b │ b\n" +
+		"c │ \n" +
+		"z │ z\n"
+	if text != want {
+		t.Errorf("text=%q, want %q", text, want)
+	}
+	var at [][2]int64
+	for _, h := range his {
+		at = append(at, h.At)
+	}
+	if want := [][2]int64{{22, 23}, {30, 31}, {40, 41}}; !reflect.DeepEqual(at, want) {
+		t.Errorf("highlights at %v, want %v", at, want)
+	}
+	if want := []int64{16, 40}; !reflect.DeepEqual(starts, want) {
+		t.Errorf("starts=%v, want %v", starts, want)
+	}
+}
+
+func TestExpandTabs(t *testing.T) {
+	if got := expandTabs("a\tbc\td", 4); got != "a   bc  d" {
+		t.Errorf("expandTabs=%q, want %q", got, "a   bc  d")
+	}
+}
+
+func TestCmd_Diff(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.txt")
+	write(path, "1\n2\n3\n4\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, path)
+	)
+	c.Add(s)
+	if err := s.Get(); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	err := execCmd(c, s, "Diff")
+	if want := "no differences"; err == nil || err.Error() != want {
+		t.Errorf("Diff=%v, want %q", err, want)
+	}
+
+	s.body.SetText(rope.New("0\n2\n3\n4\n5\n"))
+	if err := execCmd(c, s, "Diff"); err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	d := findSheet(w, filepath.Join(dir, "+Diff"))
+	if d == nil {
+		t.Fatalf("no +Diff sheet")
+	}
+	if !strings.Contains(d.tag.text.String(), diffTagText) {
+		t.Errorf("tag=%q, want it to contain %q", d.tag.text.String(), diffTagText)
+	}
+	text := d.body.text.String()
+	first := int64(strings.Index(text, "\n1 ") + 1)
+	last := int64(strings.LastIndex(text[:strings.Index(text, "│ 5")], "\n") + 1)
+	if d.body.dots[1].At != [2]int64{first, first} {
+		t.Errorf("dot=%v, want %d", d.body.dots[1].At, first)
+	}
+	for _, test := range []struct {
+		cmd  string
+		want int64
+	}{
+		{"Hunk+", last},
+		{"Hunk+", first},
+		{"Hunk-", last},
+		{"Hunk-", first},
+	} {
+		if err := execCmd(c, d, test.cmd); err != nil {
+			t.Fatalf("%s failed: %v", test.cmd, err)
+		}
+		if dot := d.body.dots[1].At; dot != [2]int64{test.want, test.want} {
+			t.Errorf("after %s, dot=%v, want %d", test.cmd, dot, test.want)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package ui
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+)
+
+// A placeholder is a tab stop of an expanded snippet.
+type placeholder struct {
+	n  int      // the number of the stop; 0 is the final stop
+	at [2]int64 // the address of its default text
+}
+
+// snippetsPath returns the directory of the snippet files.
+func snippetsPath() string {
+	if snippetsDir != "" {
+		return snippetsDir
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "T", "snippets")
+}
+
+// expandSnippet implements the Snip command: Snip [name].
+// The named snippet is inserted in place of dot,
+// or with no name, the snippet named by the word before dot
+// replaces the word.
+//
+// Snippets are read from the files in snippetsPath
+// named for the extension of the sheet's file, such as go.snippets,
+// and from _.snippets, for all files.
+// A snippet begins with a line of the form snippet name,
+// followed by the lines of its text, each indented by a tab.
+// The text may contain tab stops of the form $n or ${n:default};
+// \$ is a literal $.
+// Each line after the first is indented like the line of dot.
+// Dot is set to the first stop,
+// and tab moves to the next, ending at $0 or the end of the snippet.
+func expandSnippet(s *Sheet, name string) error {
+	b := s.body
+	at := b.dots[1].At
+	if name == "" {
+		at[0] = wordStart(b, at[1])
+		at[1] = b.dots[1].At[1]
+		if name = rope.Slice(b.text, at[0], at[1]).String(); name == "" {
+			return errors.New(msg("no snippet name"))
+		}
+	}
+	snips, err := readSnippets(s.Title())
+	if err != nil {
+		return err
+	}
+	text, ok := snips[name]
+	if !ok {
+		return errors.New(msg("no snippet %s", name))
+	}
+	start := lineStart(b, at[0])
+	line := rope.Slice(b.text, start, at[0]).String()
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	insertSnippet(b, at, strings.Replace(text, "\n", "\n"+indent, -1))
+	return nil
+}
+
+// snippetRune handles the key binding of the Snip command.
+func snippetRune(b *TextBox) {
+	_, s := jumpSheet(b.win, b)
+	if s == nil || s.TextBox != b {
+		return
+	}
+	if err := expandSnippet(s, ""); err != nil {
+		b.win.OutputString(err.Error() + "\n")
+	}
+}
+
+// insertSnippet replaces the address with the text of a snippet
+// and sets dot to its first tab stop.
+func insertSnippet(b *TextBox, at [2]int64, snippet string) {
+	text, stops := parseSnippet(snippet)
+	b.Change(edit.Diffs{{At: at, Text: rope.New(text)}})
+	for i := range stops {
+		stops[i].at[0] += at[0]
+		stops[i].at[1] += at[0]
+	}
+	b.snippet = stops
+	if !nextStop(b) {
+		end := at[0] + int64(len(text))
+		setDot(b, 1, end, end)
+	}
+}
+
+// nextStop sets dot to the next tab stop of the expanded snippet,
+// removing it.
+// It returns whether there was a stop.
+func nextStop(b *TextBox) bool {
+	if len(b.snippet) == 0 {
+		return false
+	}
+	st := b.snippet[0]
+	if b.snippet = b.snippet[1:]; len(b.snippet) == 0 {
+		b.snippet = nil
+	}
+	b.cursorCol = -1
+	setDot(b, 1, st.at[0], st.at[1])
+	return true
+}
+
+// parseSnippet returns the text of the snippet with its tab stops
+// replaced by their default text, and the stops, in the order visited:
+// increasing numbers followed by 0.
+// Only the first of stops with the same number is visited.
+func parseSnippet(snippet string) (string, []placeholder) {
+	var b strings.Builder
+	var stops []placeholder
+	seen := make(map[int]bool)
+	for i := 0; i < len(snippet); {
+		switch {
+		case strings.HasPrefix(snippet[i:], `\$`):
+			b.WriteByte('$')
+			i += 2
+			continue
+		case snippet[i] != '$':
+			b.WriteByte(snippet[i])
+			i++
+			continue
+		}
+		n, def, size := parseStop(snippet[i:])
+		if size == 0 {
+			b.WriteByte('$')
+			i++
+			continue
+		}
+		at := int64(b.Len())
+		b.WriteString(def)
+		if !seen[n] {
+			seen[n] = true
+			stops = append(stops, placeholder{n: n, at: [2]int64{at, at + int64(len(def))}})
+		}
+		i += size
+	}
+	sort.SliceStable(stops, func(i, j int) bool {
+		if stops[i].n == 0 || stops[j].n == 0 {
+			return stops[j].n == 0 && stops[i].n != 0
+		}
+		return stops[i].n < stops[j].n
+	})
+	return b.String(), stops
+}
+
+// parseStop parses a tab stop of the form $n or ${n:default}
+// at the start of the string,
+// returning its number, default text, and size,
+// or a size of 0 if there is none.
+func parseStop(str string) (int, string, int) {
+	if strings.HasPrefix(str, "${") {
+		end := strings.IndexByte(str, '}')
+		if end < 0 {
+			return 0, "", 0
+		}
+		num, def := str[2:end], ""
+		if i := strings.IndexByte(num, ':'); i >= 0 {
+			num, def = num[:i], num[i+1:]
+		}
+		n, err := strconv.Atoi(num)
+		if err != nil || n < 0 {
+			return 0, "", 0
+		}
+		return n, def, end + 1
+	}
+	end := 1
+	for end < len(str) && '0' <= str[end] && str[end] <= '9' {
+		end++
+	}
+	if end == 1 {
+		return 0, "", 0
+	}
+	n, _ := strconv.Atoi(str[1:end])
+	return n, "", end
+}
+
+// readSnippets returns the snippets for the file by name.
+// Snippets for the file type take precedence over those for all files.
+func readSnippets(path string) (map[string]string, error) {
+	dir := snippetsPath()
+	snips := make(map[string]string)
+	if dir == "" {
+		return snips, nil
+	}
+	files := []string{"_.snippets"}
+	if ext := strings.TrimPrefix(filepath.Ext(path), "."); ext != "" {
+		files = append(files, ext+".snippets")
+	}
+	for _, file := range files {
+		if err := readSnippetFile(filepath.Join(dir, file), snips); err != nil {
+			return nil, err
+		}
+	}
+	return snips, nil
+}
+
+// readSnippetFile adds the snippets of the file, if it exists, to the map.
+func readSnippetFile(path string, snips map[string]string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var name string
+	var lines []string
+	add := func() {
+		for len(lines) > 0 && lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		if name != "" {
+			snips[name] = strings.Join(lines, "\n")
+		}
+		name, lines = "", nil
+	}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		l := sc.Text()
+		switch {
+		case strings.HasPrefix(l, "snippet "):
+			add()
+			if fs := strings.Fields(l); len(fs) > 1 {
+				name = fs[1]
+			}
+		case name != "" && strings.HasPrefix(l, "\t"):
+			lines = append(lines, l[1:])
+		case name != "" && l == "":
+			lines = append(lines, "")
+		default:
+			add()
+		}
+	}
+	add()
+	return sc.Err()
+}
@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestParseSnippet(t *testing.T) {
+	tests := []struct {
+		snippet, text string
+		stops         []placeholder
+	}{
+		{"x", "x", nil},
+		{"$0 \\$1 $", " $1 $", []placeholder{{0, [2]int64{0, 0}}}},
+		{
+			"for ${1:i} := 0; $1 < ${2:n}; $1++ {\n\t$0\n}",
+			"for i := 0;  < n; ++ {\n\t\n}",
+			[]placeholder{{1, [2]int64{4, 5}}, {2, [2]int64{15, 16}}, {0, [2]int64{24, 24}}},
+		},
+		{"$2 $10 $1", "  ", []placeholder{{1, [2]int64{2, 2}}, {2, [2]int64{0, 0}}, {10, [2]int64{1, 1}}}},
+		{"${x} ${1:a", "${x} ${1:a", nil},
+	}
+	for _, test := range tests {
+		text, stops := parseSnippet(test.snippet)
+		if text != test.text || !reflect.DeepEqual(stops, test.stops) {
+			t.Errorf("parseSnippet(%q)=%q,%v, want %q,%v", test.snippet, text, stops, test.text, test.stops)
+		}
+	}
+}
+
+func TestCmd_Snip(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	defer func(d string) { snippetsDir = d }(snippetsDir)
+	snippetsDir = dir
+	write(filepath.Join(dir, "_.snippets"), "# comment\nsnippet hi\n\thello\nsnippet if\n\tall files\n")
+	write(filepath.Join(dir, "go.snippets"), "snippet if greeting\n\tif ${1:ok} {\n\t\t$0\n\t}\n\n\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, "/a/b.go")
+	)
+	c.Add(s)
+	s.body.SetText(rope.New("\tif"))
+	setDot(s.body, 1, 3, 3)
+	if err := execCmd(c, s, "Snip"); err != nil {
+		t.Fatalf("Snip failed: %v", err)
+	}
+	if got, want := s.body.text.String(), "\tif ok {\n\t\t\n\t}"; got != want {
+		t.Errorf("text=%q, want %q", got, want)
+	}
+	if dot := s.body.dots[1].At; dot != [2]int64{4, 6} {
+		t.Errorf("dot=%v, want [4 6]", dot)
+	}
+	s.body.Rune('x')
+	s.body.Rune('\t')
+	if got, want := s.body.text.String(), "\tif x {\n\t\t\n\t}"; got != want {
+		t.Errorf("text=%q, want %q", got, want)
+	}
+	if dot := s.body.dots[1].At; dot != [2]int64{10, 10} {
+		t.Errorf("dot=%v, want [10 10]", dot)
+	}
+	// After the last stop, tab is typed.
+	s.body.Rune('\t')
+	if got, want := s.body.text.String(), "\tif x {\n\t\t\t\n\t}"; got != want {
+		t.Errorf("text=%q, want %q", got, want)
+	}
+
+	s.body.SetText(rope.New("say "))
+	setDot(s.body, 1, 4, 4)
+	if err := execCmd(c, s, "Snip hi"); err != nil {
+		t.Fatalf("Snip hi failed: %v", err)
+	}
+	if got, want := s.body.text.String(), "say hello"; got != want {
+		t.Errorf("text=%q, want %q", got, want)
+	}
+	if dot := s.body.dots[1].At; dot != [2]int64{9, 9} {
+		t.Errorf("dot=%v, want [9 9]", dot)
+	}
+	err := execCmd(c, s, "Snip nope")
+	if want := "no snippet nope"; err == nil || err.Error() != want {
+		t.Errorf("Snip nope=%v, want %q", err, want)
+	}
+}
@@ -0,0 +1,368 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eaburns/T/lsp"
+	"github.com/eaburns/T/re1"
+	"github.com/eaburns/T/rope"
+)
+
+// A symbol is the definition of a name in a file.
+type symbol struct {
+	path string // absolute path of the file
+	addr string // edit address of the definition in the file
+}
+
+// definition implements the Def command: Def [name].
+// With no arguments, the definition of the identifier at dot
+// is found by the sheet's language server, if it has one,
+// and otherwise by the symbol index of the sheet's directory.
+// With a name, it is found by the symbol index.
+// A single definition is opened, with dot set to it;
+// more than one are written to the Output sheet as path:addr lines.
+//
+// The symbol index is the ctags tags file
+// of the sheet's directory or its nearest parent, if there is one.
+// Otherwise it is the package-level declarations of the Go files
+// under the module root of the sheet's directory.
+func definition(c *Col, s *Sheet, name string) error {
+	if name == "" && s.lsp != nil {
+		if err := lspDefinition(c, s); err == nil {
+			return nil
+		}
+	}
+	if name == "" {
+		if name = identAt(s.body); name == "" {
+			return errors.New(msg("no identifier"))
+		}
+	}
+	dir, err := abs(s, ".")
+	if err != nil {
+		return err
+	}
+	syms, err := findSymbols(dir, name)
+	if err != nil {
+		return err
+	}
+	switch len(syms) {
+	case 0:
+		return errors.New(msg("no definition of %s", name))
+	case 1:
+		ok, err := lookFileAddr(c, s, syms[0].path+":"+syms[0].addr)
+		if !ok && err == nil {
+			err = errors.New(msg("no file %s", syms[0].path))
+		}
+		return err
+	}
+	var b strings.Builder
+	for _, sym := range syms {
+		fmt.Fprintf(&b, "%s:%s\n", sym.path, sym.addr)
+	}
+	s.win.OutputString(b.String())
+	return nil
+}
+
+// references implements the Refs command: Refs [name].
+// With no arguments, the references to the identifier at dot
+// are found by the sheet's language server, if it has one,
+// and otherwise they are the occurrences of the identifier as a word
+// in the files under the module root of the sheet's directory.
+// With a name, they are the occurrences of the name.
+// The references are shown in the +Refs sheet of the directory
+// as lines of the form path:line: text,
+// which can be looked at to open the file at the line.
+func references(c *Col, s *Sheet, name string) error {
+	dir, err := abs(s, ".")
+	if err != nil {
+		return err
+	}
+	if name == "" && s.lsp != nil {
+		if out, err := lspReferences(s); err == nil {
+			showScratch(c, filepath.Join(dir, "+Refs"), out)
+			return nil
+		}
+	}
+	if name == "" {
+		if name = identAt(s.body); name == "" {
+			return errors.New(msg("no identifier"))
+		}
+	}
+	re, err := regexp.Compile(`\b` + regexp.QuoteMeta(name) + `\b`)
+	if err != nil {
+		return err
+	}
+	root := parentWith(dir, "go.mod", ".git")
+	var b strings.Builder
+	var n int
+	err = walkSource(root, func(path string) error {
+		data, err := ioutil.ReadFile(path)
+		if err != nil || isBinary(data) {
+			return err
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			if !re.MatchString(line) {
+				continue
+			}
+			if n++; n > maxGrepResults {
+				return errMaxGrep
+			}
+			fmt.Fprintf(&b, "%s:%d: %s\n", path, i+1, line)
+		}
+		return nil
+	})
+	if err == errMaxGrep {
+		b.WriteString(msg("more than %d matches", maxGrepResults) + "\n")
+	} else if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.New(msg("no references to %s", name))
+	}
+	showScratch(c, filepath.Join(dir, "+Refs"), b.String())
+	return nil
+}
+
+// lspReferences returns the references to the symbol at dot
+// found by the sheet's language server,
+// as lines of the form path:line: text.
+func lspReferences(s *Sheet) (string, error) {
+	cl, path, p, err := lspClient(s, s.body.dots[1].At[0])
+	if err != nil {
+		return "", err
+	}
+	var locs []lsp.Location
+	err = lspCall(func() (err error) {
+		locs, err = cl.References(path, p)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(locs) == 0 {
+		return "", errors.New(msg("no references"))
+	}
+	sort.SliceStable(locs, func(i, j int) bool {
+		if locs[i].URI != locs[j].URI {
+			return locs[i].URI < locs[j].URI
+		}
+		return locs[i].Range.Start.Line < locs[j].Range.Start.Line
+	})
+	var b strings.Builder
+	lines := make(map[string][]string)
+	for _, l := range locs {
+		path := l.Path()
+		if _, ok := lines[path]; !ok {
+			data, _ := ioutil.ReadFile(path)
+			lines[path] = strings.Split(string(data), "\n")
+		}
+		var text string
+		if n := l.Range.Start.Line; n < len(lines[path]) {
+			text = lines[path][n]
+		}
+		fmt.Fprintf(&b, "%s:%d: %s\n", path, l.Range.Start.Line+1, text)
+	}
+	return b.String(), nil
+}
+
+// identAt returns the text of dot if it is not empty,
+// and otherwise the identifier surrounding dot.
+func identAt(b *TextBox) string {
+	dot := b.dots[1].At
+	if dot[0] < dot[1] {
+		return rope.Slice(b.text, dot[0], dot[1]).String()
+	}
+	start, end := dot[0], dot[1]
+	rr := rope.NewReverseReader(rope.Slice(b.text, 0, start))
+	for {
+		r, w, err := rr.ReadRune()
+		if err != nil || !isWordRune(r) {
+			break
+		}
+		start -= int64(w)
+	}
+	fr := rope.NewReader(rope.Slice(b.text, end, b.text.Len()))
+	for {
+		r, w, err := fr.ReadRune()
+		if err != nil || !isWordRune(r) {
+			break
+		}
+		end += int64(w)
+	}
+	return rope.Slice(b.text, start, end).String()
+}
+
+// findSymbols returns the definitions of the name
+// in the symbol index of the directory,
+// those in the directory itself first.
+func findSymbols(dir, name string) ([]symbol, error) {
+	var syms []symbol
+	var err error
+	if tagsDir := parentWith(dir, "tags"); fileExists(filepath.Join(tagsDir, "tags")) {
+		syms, err = tagSymbols(filepath.Join(tagsDir, "tags"), name)
+	} else {
+		syms, err = goSymbols(parentWith(dir, "go.mod"), name)
+	}
+	sort.SliceStable(syms, func(i, j int) bool {
+		return filepath.Dir(syms[i].path) == dir && filepath.Dir(syms[j].path) != dir
+	})
+	return syms, err
+}
+
+// tagSymbols returns the definitions of the name in a ctags tags file.
+// Each line of the file is a name, a path relative to the file,
+// and an ex address, a line number or a /^line$/ search pattern,
+// separated by tabs.
+func tagSymbols(tags, name string) ([]symbol, error) {
+	f, err := os.Open(tags)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var syms []symbol
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fs := strings.SplitN(sc.Text(), "\t", 4)
+		if len(fs) < 3 || fs[0] != name {
+			continue
+		}
+		addr := tagAddr(fs[2])
+		if addr == "" {
+			continue
+		}
+		path := fs[1]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(tags), path)
+		}
+		syms = append(syms, symbol{path: path, addr: addr})
+	}
+	return syms, sc.Err()
+}
+
+// tagAddr returns the edit address of a ctags ex address,
+// or "" if it is not a line number or a search pattern.
+// The text of a search pattern is matched literally.
+func tagAddr(ex string) string {
+	ex = strings.TrimSuffix(ex, `;"`)
+	if _, err := strconv.Atoi(ex); err == nil {
+		return ex
+	}
+	if len(ex) < 2 || ex[0] != '/' && ex[0] != '?' || ex[len(ex)-1] != ex[0] {
+		return ""
+	}
+	pat := ex[1 : len(ex)-1]
+	var start, end string
+	if strings.HasPrefix(pat, "^") {
+		start, pat = "^", pat[1:]
+	}
+	if strings.HasSuffix(pat, "$") && !strings.HasSuffix(pat, `\$`) {
+		end, pat = "$", pat[:len(pat)-1]
+	}
+	pat = strings.NewReplacer(`\\`, `\`, `\/`, `/`, `\?`, `?`, `\$`, `$`).Replace(pat)
+	pat = strings.Replace(re1.Escape(pat), "/", `\/`, -1)
+	return "/" + start + pat + end + "/"
+}
+
+// goSymbols returns the definitions of the name
+// among the package-level declarations of the Go files
+// under the directory.
+func goSymbols(dir, name string) ([]symbol, error) {
+	var syms []symbol
+	fset := token.NewFileSet()
+	err := walkSource(dir, func(path string) error {
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil // skip files that don't parse
+		}
+		add := func(id *ast.Ident) {
+			if id.Name == name {
+				line := fset.Position(id.Pos()).Line
+				syms = append(syms, symbol{path: path, addr: strconv.Itoa(line)})
+			}
+		}
+		for _, d := range f.Decls {
+			switch d := d.(type) {
+			case *ast.FuncDecl:
+				add(d.Name)
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						add(spec.Name)
+					case *ast.ValueSpec:
+						for _, id := range spec.Names {
+							add(id)
+						}
+					}
+				}
+			}
+		}
+		return nil
+	})
+	return syms, err
+}
+
+// walkSource calls f with the path of each regular file under the directory.
+// Files and directories beginning with . are skipped.
+func walkSource(dir string, f func(path string) error) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case path != dir && strings.HasPrefix(info.Name(), "."):
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		case info.IsDir() || !info.Mode().IsRegular():
+			return nil
+		}
+		return f(path)
+	})
+}
+
+// isBinary returns whether the file contents appear to be binary:
+// whether there is a NUL byte in the first 8KB.
+func isBinary(data []byte) bool {
+	if len(data) > 8192 {
+		data = data[:8192]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// parentWith returns the nearest of the directory and its parents
+// containing a file with one of the names,
+// or the directory if there is none.
+func parentWith(dir string, names ...string) string {
+	for d := dir; ; d = filepath.Dir(d) {
+		for _, n := range names {
+			if fileExists(filepath.Join(d, n)) {
+				return d
+			}
+		}
+		if filepath.Dir(d) == d {
+			return dir
+		}
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,121 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCmd_Def(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	mkSubDir(dir, "sub")
+	write(filepath.Join(dir, "go.mod"), "module x\n")
+	a := filepath.Join(dir, "sub", "a.go")
+	write(a, "package sub\n\nvar x = Foo()\n")
+	b := filepath.Join(dir, "b.go")
+	write(b, "package x\n\ntype T int\n\nfunc Foo() int { return 1 }\n\nfunc (T) Bar() {}\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, a)
+	)
+	c.Add(s)
+	if err := s.Get(); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	at := int64(strings.Index(s.body.text.String(), "Foo") + 1)
+	setDot(s.body, 1, at, at)
+	if err := execCmd(c, s, "Def"); err != nil {
+		t.Fatalf("Def failed: %v", err)
+	}
+	f := findSheet(w, b)
+	if f == nil {
+		t.Fatalf("%s not opened", b)
+	}
+	if c.Row != Row(f) {
+		t.Errorf("focused row is not %s", b)
+	}
+	if dot := f.body.dots[1].At; dot != [2]int64{23, 51} {
+		t.Errorf("dot=%v, want [23 51]", dot)
+	}
+	if len(w.back) != 1 || w.back[0].b != s.body {
+		t.Errorf("Def did not record a jump")
+	}
+
+	if err := execCmd(c, s, "Def Bar"); err != nil {
+		t.Fatalf("Def Bar failed: %v", err)
+	}
+	if dot := f.body.dots[1].At; dot != [2]int64{52, 70} {
+		t.Errorf("dot=%v, want [52 70]", dot)
+	}
+	if err := execCmd(c, s, "Def Baz"); err == nil {
+		t.Errorf("Def Baz succeeded, want an error")
+	}
+
+	// A tags file is used instead of the Go declarations.
+	write(filepath.Join(dir, "tags"), "!_TAG_FILE_FORMAT\t2\n"+
+		"Foo\tsub/a.go\t/^var x = Foo()$/;\"\tv\n")
+	if err := execCmd(c, s, "Def Foo"); err != nil {
+		t.Fatalf("Def Foo failed: %v", err)
+	}
+	if dot := s.body.dots[1].At; dot != [2]int64{13, 26} {
+		t.Errorf("dot=%v, want [13 26]", dot)
+	}
+}
+
+func TestCmd_Refs(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	write(filepath.Join(dir, "go.mod"), "module x\n")
+	a := filepath.Join(dir, "a.go")
+	write(a, "package x\n\nvar x = Foo()\n\nvar y = FooBar()\n")
+	b := filepath.Join(dir, "b.go")
+	write(b, "package x\n\nfunc Foo() int { return 1 }\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, a)
+	)
+	c.Add(s)
+	if err := s.Get(); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	at := int64(strings.Index(s.body.text.String(), "Foo"))
+	setDot(s.body, 1, at, at)
+	if err := execCmd(c, s, "Refs"); err != nil {
+		t.Fatalf("Refs failed: %v", err)
+	}
+	r := findSheet(w, filepath.Join(dir, "+Refs"))
+	if r == nil {
+		t.Fatalf("no +Refs sheet")
+	}
+	want := a + ":3: var x = Foo()\n" +
+		b + ":3: func Foo() int { return 1 }\n"
+	if got := r.body.text.String(); got != want {
+		t.Errorf("+Refs=%q, want %q", got, want)
+	}
+	if err := execCmd(c, s, "Refs Baz"); err == nil {
+		t.Errorf("Refs Baz succeeded, want an error")
+	}
+}
+
+func TestTagAddr(t *testing.T) {
+	tests := []struct {
+		ex, want string
+	}{
+		{`12;"`, "12"},
+		{`/^func Foo() {$/;"`, `/^func Foo\(\) {$/`},
+		{`/^a\/b\\c*$/`, `/^a\/b\\c\*$/`},
+		{`?^x$?`, `/^x$/`},
+		{`bad`, ""},
+	}
+	for _, test := range tests {
+		if got := tagAddr(test.ex); got != test.want {
+			t.Errorf("tagAddr(%q)=%q, want %q", test.ex, got, test.want)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package ui
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
@@ -17,11 +19,11 @@ import (
 	"github.com/eaburns/T/rope"
 	"github.com/eaburns/T/syntax"
 	"github.com/eaburns/T/text"
+	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
 )
 
 const (
-	blinkDuration       = 500 * time.Millisecond
 	dragScrollDuration  = 20 * time.Millisecond
 	wheelScrollDuration = 20 * time.Millisecond
 	doubleClickDuration = 500 * time.Millisecond
@@ -34,6 +36,35 @@ type TextBox struct {
 	text rope.Rope
 	at   int64 // address of the first rune in the window
 
+	nowrap bool // whether long lines extend off the right edge instead of wrapping
+	xoff   int  // horizontal scroll offset in pixels; only used if nowrap
+	widest int  // width of the widest displayed line; only used if nowrap
+
+	search *isearch // the current keyboard search; nil if not searching
+	vi     *viState // the state of vi-like modal editing; nil if not modal
+
+	fileSettings
+	trailing []syntax.Highlight // highlighted trailing whitespace; only used if showSpace
+
+	word   string             // the word selected by double-clicking; "" if none
+	occurs []syntax.Highlight // other displayed occurrences of word
+
+	diags []syntax.Highlight // underlined diagnostics of the language server
+
+	breaks    []int64            // addresses of the starts of lines with breakpoints
+	debugLine []syntax.Highlight // the line where the debugged program stopped
+
+	diffLines []syntax.Highlight // changed lines of a +Diff sheet
+	hunks     []int64            // addresses of the starts of the hunks of a +Diff sheet
+
+	folds [][2]int64          // sorted, non-overlapping ranges of text that are not displayed
+	marks map[string][2]int64 // named marks set by the Mark command
+
+	snippet []placeholder // the tab stops of an expanded snippet not yet visited
+
+	elastic bool                    // whether tabs are elastic tabstops
+	stops   map[int64]fixed.Int26_6 // x of the tab stop after the tab at each address; only used if elastic
+
 	focus      bool
 	showCursor bool
 	blinkTime  time.Time
@@ -47,6 +78,7 @@ type TextBox struct {
 	dragTextBox    image.Rectangle // bounding-box of the dragAt glyph
 	dragScrollTime time.Time       // time when dragging off screen scrolls
 	wheelTime      time.Time       // time when we will consider the next wheel
+	wheelPx        [2]float64      // precise scrolling not yet scrolled, x and y
 
 	style       text.Style
 	dots        [4]syntax.Highlight // cursor for unused, click 1, click 2, and click 3.
@@ -54,13 +86,28 @@ type TextBox struct {
 	syntax      []syntax.Highlight  // syntax highlighting
 	highlighter updater             // syntax highlighter
 
+	preedit string // the uncommitted input method composition, drawn at dot
+
 	dirty  bool
 	_lines []line
 	now    func() time.Time
+	filled int // y of the background below the text when last drawn
+
+	// The lines when last drawn, moved instead of drawn after scrolling.
+	drawn    []line
+	drawnAt  int64 // at when last drawn
+	drawnX   int   // xoff when last drawn
+	drawnDot int64 // address of the cursor when last drawn, or -1 if none
+
+	lineCache map[string]*cachedLine // images of recently drawn lines by lineKey
+	lineFaces []font.Face            // faces of the lines, numbered by lineKey
+	keyBuf    []byte                 // the last lineKey
+	draws     int                    // number of Draws that drew lines
 }
 
 type line struct {
 	dirty bool
+	brk   bool // whether the line was drawn with a breakpoint
 	n     int64
 	a, h  fixed.Int26_6
 	spans []span
@@ -90,8 +137,9 @@ func NewTextBox(w *Win, styles [4]text.Style, size image.Point) *TextBox {
 			{Style: styles[2]},
 			{Style: styles[3]},
 		},
-		cursorCol: -1,
-		now:       func() time.Time { return time.Now() },
+		cursorCol:    -1,
+		fileSettings: fileSettings{tabWidth: defaultTabWidth},
+		now:          func() time.Time { return time.Now() },
 	}
 	return b
 }
@@ -113,6 +161,7 @@ func (b *TextBox) SetText(text rope.Rope) {
 		b.dots[i].At = [2]int64{}
 	}
 	b.highlight = nil
+	b.preedit = ""
 	if b.highlighter != nil {
 		b.syntax = b.highlighter.Update(nil, nil, b.text)
 	}
@@ -195,6 +244,32 @@ func (b *TextBox) Change(diffs edit.Diffs) {
 	for i := range b.highlight {
 		b.highlight[i].At = diffs.Update(b.highlight[i].At)
 	}
+	for i := range b.diags {
+		b.diags[i].At = diffs.Update(b.diags[i].At)
+	}
+	for i := range b.debugLine {
+		b.debugLine[i].At = diffs.Update(b.debugLine[i].At)
+	}
+	for i := range b.diffLines {
+		b.diffLines[i].At = diffs.Update(b.diffLines[i].At)
+	}
+	for i, h := range b.hunks {
+		b.hunks[i] = diffs.Update([2]int64{h, h})[0]
+	}
+	for i := range b.snippet {
+		b.snippet[i].at = diffs.Update(b.snippet[i].at)
+	}
+	for i, br := range b.breaks {
+		b.breaks[i] = diffs.Update([2]int64{br, br})[0]
+	}
+	updateJumps(b, diffs)
+	folds := b.folds[:0]
+	for _, f := range b.folds {
+		if f = diffs.Update(f); f[0] < f[1] {
+			folds = append(folds, f)
+		}
+	}
+	b.folds = folds
 }
 
 // Copy copies the selected text into the system clipboard.
@@ -223,6 +298,9 @@ func (b *TextBox) Cut() error {
 	return nil
 }
 
+// Close does nothing; a text box has no resources to release.
+func (b *TextBox) Close() {}
+
 // Resize handles a resize event.
 // The text box must always be redrawn after being resized.
 func (b *TextBox) Resize(size image.Point) {
@@ -234,11 +312,15 @@ func (b *TextBox) Resize(size image.Point) {
 func (b *TextBox) Focus(focus bool) {
 	b.focus = focus
 	b.showCursor = focus
+	if !focus {
+		b.preedit = ""
+	}
 	dirtyDot(b, b.dots[1].At)
 	if focus {
 		b.blinkTime = b.now().Add(blinkDuration)
 	} else {
 		b.button = 0
+		b.search = nil
 	}
 }
 
@@ -251,33 +333,82 @@ func (b *TextBox) Focus(focus bool) {
 func (b *TextBox) Tick() bool {
 	now := b.now()
 	redraw := b.dirty
-	if b.focus && b.dots[1].At[0] == b.dots[1].At[1] && !b.blinkTime.After(now) {
+	if b.focus && cursorBlink && !reducedMotion && !b.win.quiet && !b.win.unfocused &&
+		b.dots[1].At[0] == b.dots[1].At[1] && !b.blinkTime.After(now) {
 		b.blinkTime = now.Add(blinkDuration)
 		b.showCursor = !b.showCursor
 		dirtyDot(b, b.dots[1].At)
 	}
-	if b.button == 1 &&
-		!b.dragScrollTime.After(now) {
-		var ymax fixed.Int26_6
-		atMax := b.at
-		for _, l := range b.lines() {
-			ymax += l.h
-			atMax += l.n
-		}
-		switch {
-		case b.pt.Y < 0:
-			scrollUp(b, 1)
-			b.Move(b.pt)
-			b.dragScrollTime = now.Add(dragScrollDuration)
-		case b.pt.Y >= ymax.Floor() && atMax < b.text.Len():
-			scrollDown(b, 1)
-			b.Move(b.pt)
-			b.dragScrollTime = now.Add(dragScrollDuration)
-		}
+	if b.focus && (b.win.quiet || !cursorBlink) && !b.showCursor {
+		b.showCursor = true
+		dirtyDot(b, b.dots[1].At)
+	}
+	if b.button == 1 && !b.dragScrollTime.After(now) && dragScroll(b) {
+		b.dragScrollTime = now.Add(dragScrollDuration)
 	}
 	return redraw
 }
 
+// dragBeyond returns how far in pixels the point of a drag
+// is beyond the text that can be scrolled into view:
+// negative above or left of the text box,
+// and positive below or right of it.
+// Horizontally, it is 0 unless the lines do not wrap.
+func dragBeyond(b *TextBox) (dx, dy int) {
+	var ymax fixed.Int26_6
+	atMax := b.at
+	for _, l := range b.lines() {
+		ymax += l.h
+		atMax += l.n
+	}
+	switch {
+	case b.pt.Y < 0 && b.at > 0:
+		dy = b.pt.Y
+	case b.pt.Y >= ymax.Floor() && atMax < b.text.Len():
+		dy = b.pt.Y - ymax.Floor() + 1
+	}
+	if b.nowrap {
+		switch maxx := b.size.X - 2*textPadPx; {
+		case b.pt.X < 0 && b.xoff > 0:
+			dx = b.pt.X
+		case b.pt.X >= maxx && b.widest > b.xoff+maxx:
+			dx = b.pt.X - maxx + 1
+		}
+	}
+	return dx, dy
+}
+
+// dragScroll scrolls a line, or the height of a line horizontally,
+// toward the point of a drag beyond the text box,
+// extends the selection to the point,
+// and returns whether it scrolled.
+func dragScroll(b *TextBox) bool {
+	dx, dy := dragBeyond(b)
+	if dx == 0 && dy == 0 {
+		return false
+	}
+	at, xoff := b.at, b.xoff
+	switch {
+	case dy < 0:
+		scrollUp(b, 1)
+	case dy > 0:
+		scrollDown(b, 1)
+	}
+	switch h := faceHeight(b.style.Face); {
+	case dx < 0:
+		scrollX(b, -h)
+	case dx > 0:
+		scrollX(b, h)
+	}
+	if b.at == at && b.xoff == xoff {
+		return false
+	}
+	// The glyph under the point changed.
+	b.dragTextBox = image.ZR
+	b.Move(b.pt.Add(image.Pt(textPadPx, 0)))
+	return true
+}
+
 // Move handles the event of the mouse cursor moving to a point
 // and returns whether the text box image needs to be redrawn.
 func (b *TextBox) Move(pt image.Point) {
@@ -286,12 +417,19 @@ func (b *TextBox) Move(pt image.Point) {
 	if b.button <= 0 || b.button >= len(b.dots) || pt.In(b.dragTextBox) {
 		return
 	}
+	xoff := b.xoff
 	b.dragAt, b.dragTextBox = atPoint(b, pt)
 	if b.clickAt <= b.dragAt {
 		setDot(b, b.button, b.clickAt, b.dragAt)
 	} else {
 		setDot(b, b.button, b.dragAt, b.clickAt)
 	}
+	// The point of the drag is visible,
+	// so keep setDot from scrolling back to the start of dot.
+	if b.xoff != xoff {
+		b.xoff = xoff
+		dirtyLines(b)
+	}
 }
 
 // Wheel handles the event of the mouse wheel rolling
@@ -311,7 +449,181 @@ func (b *TextBox) Wheel(_ image.Point, x, y int) {
 		scrollDown(b, 1)
 	case y > 0:
 		scrollUp(b, 1)
+	case x < 0:
+		scrollX(b, -b.size.X/8)
+	case x > 0:
+		scrollX(b, b.size.X/8)
+	}
+}
+
+// WheelPx handles precise scrolling events.
+// Vertical scrolling is by whole lines,
+// once the deltas add up to the height of a line.
+func (b *TextBox) WheelPx(_ image.Point, x, y float64) {
+	b.wheelPx[0] += x
+	b.wheelPx[1] += y
+	if dx := int(b.wheelPx[0]); dx != 0 {
+		b.wheelPx[0] -= float64(dx)
+		scrollX(b, dx)
+	}
+	h := float64(faceHeight(b.style.Face))
+	for ; b.wheelPx[1] >= h; b.wheelPx[1] -= h {
+		scrollUp(b, 1)
+	}
+	for ; b.wheelPx[1] <= -h; b.wheelPx[1] += h {
+		scrollDown(b, 1)
+	}
+}
+
+// setWrap sets whether long lines are wrapped.
+// If they are not wrapped, they can be scrolled horizontally.
+func setWrap(b *TextBox, wrap bool) {
+	b.nowrap = !wrap
+	b.xoff = 0
+	dirtyLines(b)
+	showCol(b)
+}
+
+func setFace(b *TextBox, face font.Face) {
+	b.style.Face = face
+	b.dots[0].Style.Face = face
+	clearLineCache(b)
+	dirtyLines(b)
+}
+
+// setColors sets the text box's background color,
+// and its foreground and selection colors to the current theme's.
+func setColors(b *TextBox, bg color.Color) {
+	b.style.FG, b.style.BG = fg, bg
+	b.dots[0].Style.FG, b.dots[0].Style.BG = fg, bg
+	b.dots[1].Style.BG = hiBG1
+	b.dots[2].Style.BG = hiBG2
+	b.dots[3].Style.BG = hiBG3
+	clearLineCache(b)
+	dirtyLines(b)
+}
+
+func setElastic(b *TextBox, elastic bool) {
+	b.elastic = elastic
+	dirtyLines(b)
+}
+
+// setFolds sets the ranges of text that are not displayed.
+func setFolds(b *TextBox, folds [][2]int64) {
+	b.folds = folds
+	for _, f := range folds {
+		if f[0] < b.at && b.at <= f[1] {
+			at, err := edit.Addr([2]int64{f[0], f[0]}, "-0", b.text)
+			if err == nil {
+				b.at = at[0]
+			}
+		}
+	}
+	dirtyLines(b)
+}
+
+// foldEnd returns the end of the fold beginning at the address,
+// or the address itself if no fold begins there.
+func foldEnd(b *TextBox, at int64) int64 {
+	for _, f := range b.folds {
+		if f[0] == at {
+			return f[1]
+		}
 	}
+	return at
+}
+
+// setTabs sets the tab width and whether the tab key inserts spaces
+// from the arguments of a Tab command: [width] [spaces|tabs].
+// With no arguments, the current settings are written to the Output sheet.
+func setTabs(b *TextBox, args string) error {
+	fs := strings.Fields(args)
+	if len(fs) == 0 {
+		mode := "tabs"
+		if b.tabSpaces {
+			mode = "spaces"
+		}
+		b.win.OutputString(fmt.Sprintf("Tab %d %s\n", b.tabWidth, mode))
+		return nil
+	}
+	width, spaces := b.tabWidth, b.tabSpaces
+	for _, f := range fs {
+		switch n, err := strconv.Atoi(f); {
+		case f == "spaces":
+			spaces = true
+		case f == "tabs":
+			spaces = false
+		case err == nil && n > 0:
+			width = n
+		default:
+			return errors.New(msg("usage: Tab [width] [spaces|tabs]"))
+		}
+	}
+	b.tabSpaces = spaces
+	if width != b.tabWidth {
+		b.tabWidth = width
+		dirtyLines(b)
+	}
+	return nil
+}
+
+// scrollX scrolls horizontally by dx pixels.
+// It doesn't scroll right unless a displayed line
+// extends past the right edge.
+func scrollX(b *TextBox, dx int) {
+	if !b.nowrap {
+		return
+	}
+	b.lines() // compute b.widest
+	if dx > 0 && b.widest <= b.xoff+b.size.X-2*textPadPx {
+		return
+	}
+	xoff := b.xoff + dx
+	if xoff < 0 {
+		xoff = 0
+	}
+	if xoff != b.xoff {
+		b.xoff = xoff
+		dirtyLines(b)
+	}
+}
+
+// showCol scrolls horizontally to make the cursor visible.
+func showCol(b *TextBox) {
+	if !b.nowrap {
+		return
+	}
+	dot := b.dots[1].At[0]
+	bol, err := edit.Addr([2]int64{dot, dot}, "-0", b.text)
+	if err != nil {
+		return
+	}
+	var prev rune
+	var x fixed.Int26_6
+	at := bol[0]
+	rr := rope.NewReader(rope.Slice(b.text, bol[0], dot))
+	for {
+		r, w, err := rr.ReadRune()
+		if err != nil {
+			break
+		}
+		x += kern(b.style, prev, r)
+		x += advance(b, b.style, at, x, r)
+		at += int64(w)
+		prev = r
+	}
+	maxx := b.size.X - 2*textPadPx
+	switch cx := x.Floor(); {
+	case cx < b.xoff:
+		if b.xoff = cx - maxx/4; b.xoff < 0 {
+			b.xoff = 0
+		}
+	case cx+cursorWidthPx > b.xoff+maxx:
+		b.xoff = cx - maxx*3/4
+	default:
+		return
+	}
+	dirtyLines(b)
 }
 
 // Click handles a mouse button press or release event.
@@ -327,6 +639,7 @@ func (b *TextBox) Wheel(_ image.Point, x, y int) {
 func (b *TextBox) Click(pt image.Point, button int) (int, [2]int64) {
 	pt.X -= textPadPx
 	b.pt = pt
+	b.search = nil
 	switch {
 	case b.button > 0 && button > 0:
 		// b.button/button mouse chord; ignore it for now.
@@ -335,13 +648,10 @@ func (b *TextBox) Click(pt image.Point, button int) (int, [2]int64) {
 	case b.button > 0 && button == -b.button:
 		return unclick(b)
 
-	case b.button == 0 && button == 1 && b.win.mods[2]:
-		button = 2
-
-	case b.button == 0 && button == 1 && b.win.mods[3]:
-		button = 3
+	case b.button == 0 && button > 0:
+		button = modButton(b.win, button)
 
-	case b.button != 1 && button == -1: // mod-button unclick
+	case b.button > 0 && button < 0: // mod-button unclick
 		return unclick(b)
 	}
 	if button > 0 {
@@ -376,28 +686,16 @@ func click(b *TextBox, button int) {
 	}
 }
 
-var delim = [][2]rune{
-	{'(', ')'},
-	{'{', '}'},
-	{'[', ']'},
-	{'<', '>'},
-	{'«', '»'},
-	{'\'', '\''},
-	{'"', '"'},
-	{'`', '`'},
-	{'“', '”'},
-}
-
 func doubleClick(b *TextBox) {
 	prev := prevRune(b)
-	for _, ds := range delim {
+	for _, ds := range clickDelims {
 		if ds[0] == prev {
 			selectForwardDelim(b, ds[0], ds[1])
 			return
 		}
 	}
 	cur := curRune(b)
-	for _, ds := range delim {
+	for _, ds := range clickDelims {
 		if ds[1] == cur {
 			selectReverseDelim(b, ds[1], ds[0])
 			return
@@ -409,6 +707,8 @@ func doubleClick(b *TextBox) {
 	}
 	if wordRune(cur) {
 		selectWord(b)
+		b.word = rope.Slice(b.text, b.dots[1].At[0], b.dots[1].At[1]).String()
+		dirtyLines(b)
 		return
 	}
 }
@@ -508,7 +808,7 @@ func selectWord(b *TextBox) {
 }
 
 func wordRune(r rune) bool {
-	return unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_'
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || strings.ContainsRune(wordRunes, r)
 }
 
 // Dir handles a keyboard directional event
@@ -528,6 +828,11 @@ func wordRune(r rune) bool {
 //
 // Dir only handles key press events, not key releases.
 func (b *TextBox) Dir(x, y int) {
+	if b.search != nil && x == 0 && (y == -1 || y == 1) {
+		searchHistory(b, y)
+		return
+	}
+	b.search = nil
 	switch {
 	case x == -1:
 		at := leftRight(b, "-")
@@ -619,6 +924,28 @@ func cursorCol(b *TextBox) int {
 	return n
 }
 
+// tabStop returns the number of columns from dot to the next tab stop.
+func tabStop(b *TextBox) int {
+	var line []rune
+	rr := rope.NewReverseReader(rope.Slice(b.text, 0, b.dots[1].At[0]))
+	for {
+		r, _, err := rr.ReadRune()
+		if err != nil || r == '\n' {
+			break
+		}
+		line = append(line, r)
+	}
+	var col int
+	for i := len(line) - 1; i >= 0; i-- {
+		if line[i] == '\t' {
+			col += b.tabWidth - col%b.tabWidth
+		} else {
+			col++
+		}
+	}
+	return b.tabWidth - col%b.tabWidth
+}
+
 func scrollUp(b *TextBox, delta int) {
 	if b.at == 0 {
 		return
@@ -689,6 +1016,24 @@ const (
 // If the rune is positive, the event is a key press,
 // if negative, a key release.
 func (b *TextBox) Rune(r rune) {
+	if b.win.mods[3] && ctrlRune(b, r) {
+		return
+	}
+	if b.win.mods[2] && (r == 'y' || r == 'Y') && yankPop(b) {
+		return
+	}
+	b.win.kills.yankBox = nil
+	b.preedit = ""
+	if b.search != nil {
+		searchRune(b, r)
+		return
+	}
+	if b.vi != nil && viRune(b, r) {
+		return
+	}
+	if r == '\t' && nextStop(b) {
+		return
+	}
 	switch r {
 	case '\b':
 		if b.dots[1].At[0] == b.dots[1].At[1] {
@@ -702,29 +1047,55 @@ func (b *TextBox) Rune(r rune) {
 		} else {
 			ed(b, ".d")
 		}
+	case '\t':
+		if b.tabSpaces {
+			ed(b, ".c/"+strings.Repeat(" ", tabStop(b)))
+		} else {
+			ed(b, ".c/\t")
+		}
 	case '/':
 		ed(b, ".c/\\/")
 	case '\n':
-		ed(b, ".c/\\n")
+		if b.autoIndent {
+			ed(b, ".c/\\n"+lineIndent(b, b.dots[1].At[0]))
+		} else {
+			ed(b, ".c/\\n")
+		}
 	default:
 		ed(b, ".c/"+string([]rune{r}))
 	}
 	setDot(b, 1, b.dots[1].At[1], b.dots[1].At[1])
 }
 
+// lineIndent returns the leading spaces and tabs
+// of the line containing the address, up to the address.
+func lineIndent(b *TextBox, at int64) string {
+	line := rope.Slice(b.text, lineStart(b, at), at).String()
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
 // Draw draws the text box to the image with the upper-left of the box at 0,0.
+// The area of the image that changed is added to the window's changed area.
 func (b *TextBox) Draw(dirty bool, img draw.Image) {
 	size := img.Bounds().Size()
+	var changed image.Rectangle
 	if dirty || size != b.size {
 		b.size = size
+		b.filled = size.Y
 		dirtyLines(b)
+		changed = image.Rectangle{Max: size}
 	}
 	if !b.dirty {
 		return
 	}
 	b.dirty = false
+	b.draws++
+	defer func() { addChanged(b.win, changed.Add(img.Bounds().Min)) }()
 	at := b.at
 	lines := b.lines()
+	if rgba, ok := img.(*image.RGBA); ok && changed.Empty() {
+		changed = scrollLines(b, rgba, lines)
+	}
 	var y fixed.Int26_6
 	for i := range lines {
 		l := &lines[i]
@@ -734,7 +1105,9 @@ func (b *TextBox) Draw(dirty bool, img draw.Image) {
 			continue
 		}
 		at1 := at + l.n
-		drawLine(b, img, at, y, *l)
+		l.brk = hasBreak(b, at, at1)
+		drawLineCached(b, img, at, y, *l)
+		changed = changed.Union(image.Rect(0, y.Floor(), size.X, (y + l.h).Ceil()))
 		l.dirty = false
 		y += l.h
 		at = at1
@@ -742,13 +1115,26 @@ func (b *TextBox) Draw(dirty bool, img draw.Image) {
 	if y.Floor() < size.Y {
 		r := image.Rect(0, y.Floor(), size.X, size.Y)
 		fillRect(img, b.style.BG, r.Add(img.Bounds().Min))
+		if y.Floor() < b.filled {
+			// The text got shorter.
+			changed = changed.Union(image.Rect(0, y.Floor(), size.X, b.filled))
+		}
+	}
+	b.filled = y.Floor()
+	trimLineCache(b)
+	b.drawn = append(b.drawn[:0], lines...)
+	b.drawnAt, b.drawnX, b.drawnDot = b.at, b.xoff, -1
+	if b.dots[1].At[0] == b.dots[1].At[1] {
+		b.drawnDot = b.dots[1].At[0]
 	}
+	changed = changed.Union(drawPreedit(b, img))
 
 	// Draw a cursor for empty text.
 	if b.text.Len() == 0 {
 		m := b.style.Face.Metrics()
 		h := m.Height + m.Descent
-		drawCursor(b, img, fixed.I(textPadPx), 0, h)
+		drawCursor(b, img, fixed.I(textPadPx), 0, h, 0)
+		changed = changed.Union(image.Rect(0, 0, size.X, h.Ceil()))
 		return
 	}
 	// Draw a cursor just after the last line of text.
@@ -762,18 +1148,23 @@ func (b *TextBox) Draw(dirty bool, img draw.Image) {
 		lastRune(lastLine) == '\n' {
 		m := b.style.Face.Metrics()
 		h := m.Height + m.Descent
-		drawCursor(b, img, fixed.I(textPadPx), y, y+h)
+		drawCursor(b, img, fixed.I(textPadPx), y, y+h, 0)
+		changed = changed.Union(image.Rect(0, y.Floor(), size.X, (y + h).Ceil()))
 	}
 }
 
 func drawLine(b *TextBox, img draw.Image, at int64, y0 fixed.Int26_6, l line) {
 	var prevRune rune
-	x0 := fixed.I(textPadPx)
+	x0 := fixed.I(textPadPx - b.xoff)
 	yb, y1 := y0+l.a, y0+l.h
 
-	// leading padding
+	// leading padding, marking breakpoints
 	pad := image.Rect(0, y0.Floor(), textPadPx, y1.Floor())
-	fillRect(img, b.style.BG, pad.Add(img.Bounds().Min))
+	padBG := b.style.BG
+	if l.brk {
+		padBG = breakpointBG
+	}
+	fillRect(img, padBG, pad.Add(img.Bounds().Min))
 
 	for i, s := range l.spans {
 		x1 := x0 + s.w
@@ -781,23 +1172,24 @@ func drawLine(b *TextBox, img draw.Image, at int64, y0 fixed.Int26_6, l line) {
 		bbox := image.Rect(x0.Floor(), y0.Floor(), x1.Floor(), y1.Floor())
 		fillRect(img, s.style.BG, bbox.Add(img.Bounds().Min))
 
-		for _, r := range s.text {
-			if prevRune != 0 {
-				x0 += s.style.Face.Kern(prevRune, r)
-			}
+		var drawn int // bytes of s.text already drawn
+		for j, r := range s.text {
+			x0 += kern(s.style, prevRune, r)
 			prevRune = r
-			var adv fixed.Int26_6
-			if r == '\t' || r == '\n' {
-				adv = advance(b, s.style, x0-fixed.I(textPadPx), r)
-			} else {
-				adv = drawGlyph(img, s.style, x0, yb, r)
+			if r != '\t' && r != '\n' && j >= drawn {
+				drawn = j + drawShaped(b, img, s.style, x0, yb, s.text[j:])
 			}
+			adv := advance(b, s.style, at, x0-fixed.I(textPadPx-b.xoff), r)
 			if b.dots[1].At[0] == b.dots[1].At[1] && b.dots[1].At[0] == at {
-				drawCursor(b, img, x0, y0, y1)
+				drawCursor(b, img, x0, y0, y1, adv)
 			}
 			x0 += adv
 			at += int64(utf8.RuneLen(r))
 		}
+		if s.style.Underline != nil {
+			u := image.Rect(bbox.Min.X, bbox.Max.Y-underlinePx, bbox.Max.X, bbox.Max.Y)
+			fillRect(img, s.style.Underline, u.Add(img.Bounds().Min))
+		}
 		x0 = x1
 		if i < len(l.spans)-1 && l.spans[i+1].style.Face != s.style.Face {
 			prevRune = 0
@@ -808,40 +1200,189 @@ func drawLine(b *TextBox, img draw.Image, at int64, y0 fixed.Int26_6, l line) {
 	r := image.Rect(x0.Floor(), y0.Floor(), img.Bounds().Size().X, y1.Floor())
 	fillRect(img, b.style.BG, r.Add(img.Bounds().Min))
 
+	if b.xoff > 0 {
+		// Text scrolled off the left may have been drawn over the padding.
+		fillRect(img, padBG, pad.Add(img.Bounds().Min))
+	}
+
 	if b.dots[1].At[0] == b.dots[1].At[1] &&
 		at == b.dots[1].At[0] &&
 		at == b.text.Len() &&
 		prevRune != '\n' {
-		drawCursor(b, img, x0, y0, y1)
+		drawCursor(b, img, x0, y0, y1, 0)
+	}
+}
+
+// scrollLines moves the pixels of the lines drawn by the last Draw
+// that are displayed again after scrolling to their new place
+// and marks them as not dirty, so that only the lines
+// scrolled into view are drawn.
+// It returns the area of the image that changed.
+func scrollLines(b *TextBox, img *image.RGBA, lines []line) image.Rectangle {
+	if b.at == b.drawnAt || b.xoff != b.drawnX || len(b.drawn) == 0 || b.preedit != "" {
+		return image.ZR
+	}
+	if _, ok := popupRect(b.win); ok {
+		// The popup may be drawn over the lines.
+		return image.ZR
+	}
+	// The lines moved by the same number of pixels, d,
+	// are moved together from the first to the last.
+	var d, first, last fixed.Int26_6
+	var moved bool
+	old, oldAt, oldY := 0, b.drawnAt, fixed.Int26_6(0)
+	at, y := b.at, fixed.Int26_6(0)
+	for i := range lines {
+		l := &lines[i]
+		for old < len(b.drawn) && oldAt < at {
+			oldAt += b.drawn[old].n
+			oldY += b.drawn[old].h
+			old++
+		}
+		if old < len(b.drawn) && oldAt == at &&
+			sameLine(b.drawn[old], *l) &&
+			b.drawn[old].brk == hasBreak(b, at, at+l.n) &&
+			!hasCursor(b.drawnDot, at, l.n) &&
+			!(b.dots[1].At[0] == b.dots[1].At[1] && hasCursor(b.dots[1].At[0], at, l.n)) &&
+			y != oldY && (y-oldY)&63 == 0 &&
+			(!moved || y-oldY == d) {
+			if !moved {
+				moved, d, first = true, y-oldY, y
+			}
+			last = y + l.h
+			l.dirty = false
+		}
+		at += l.n
+		y += l.h
+	}
+	if !moved {
+		return image.ZR
+	}
+	r := image.Rect(0, first.Floor(), b.size.X, last.Floor()).Intersect(image.Rectangle{Max: b.size})
+	dy := d.Floor()
+	n := 4 * r.Dx()
+	move := func(y int) {
+		i := img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y+y)
+		j := img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y+y-dy)
+		copy(img.Pix[i:i+n], img.Pix[j:j+n])
+	}
+	if dy < 0 {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			move(y)
+		}
+	} else {
+		for y := r.Max.Y - 1; y >= r.Min.Y; y-- {
+			move(y)
+		}
+	}
+	return r
+}
+
+// sameLine returns whether the lines are drawn the same,
+// but for breakpoints and the cursor.
+func sameLine(l0, l1 line) bool {
+	if l0.n != l1.n || l0.a != l1.a || l0.h != l1.h || len(l0.spans) != len(l1.spans) {
+		return false
+	}
+	for i, s0 := range l0.spans {
+		s1 := l1.spans[i]
+		if s0.w != s1.w || s0.text != s1.text || s0.style != s1.style {
+			return false
+		}
 	}
+	return true
+}
+
+// hasCursor returns whether a cursor at the address
+// is drawn on the line of n bytes at the address at.
+func hasCursor(dot, at, n int64) bool {
+	return dot >= 0 && at <= dot && dot <= at+n
 }
 
 func drawGlyph(img draw.Image, style text.Style, x0, yb fixed.Int26_6, r rune) fixed.Int26_6 {
 	pt := fixed.Point26_6{X: x0, Y: yb}
-	dr, m, mp, adv, ok := style.Face.Glyph(pt, r)
+	// Cached masks are drawn without holding the lock of the face,
+	// so glyphs are drawn concurrently with other rows.
+	dr, m, mp, adv, ok := text.Glyph(style.Face, pt, r)
 	if !ok {
-		dr, m, mp, adv, _ = style.Face.Glyph(pt, unicode.ReplacementChar)
+		dr, m, mp, adv, _ = text.Glyph(style.Face, pt, unicode.ReplacementChar)
 	}
 	dr = dr.Add(img.Bounds().Min)
+	if lcd, ok := m.(text.LCDMask); ok {
+		text.DrawLCD(img, dr, style.FG, lcd, mp)
+		return adv
+	}
+	if rgba, ok := img.(*image.RGBA); ok {
+		if a, ok := m.(*image.Alpha); ok {
+			drawAlphaMask(rgba, dr, style.FG, a, mp)
+			return adv
+		}
+	}
 	fg := image.NewUniform(style.FG)
 	draw.DrawMask(img, dr, fg, image.ZP, m, mp, draw.Over)
 	return adv
 }
 
-func drawCursor(b *TextBox, img draw.Image, x, y0, y1 fixed.Int26_6) {
-	if !b.showCursor {
+// drawCursor draws the cursor in the cursorShape.
+// The advance is the width of the glyph under the cursor
+// used by the block and underline shapes,
+// or 0 for the width of a space.
+func drawCursor(b *TextBox, img draw.Image, x, y0, y1, adv fixed.Int26_6) {
+	if b.preedit != "" {
+		// The composition is drawn in place of the cursor.
 		return
 	}
+	hollow := false
+	if !b.showCursor {
+		if !hollowCursor || !b.win.unfocused || focusedTextBox(b.win) != b {
+			return
+		}
+		hollow = true
+	}
+	if adv <= 0 {
+		adv, _ = b.style.Face.GlyphAdvance(' ')
+	}
 	x0 := x.Floor()
 	r := image.Rect(x0, y0.Floor(), x0+cursorWidthPx, y1.Floor())
-	fillRect(img, b.style.FG, r.Add(img.Bounds().Min))
+	switch cursorShape {
+	case "block":
+		r.Max.X = x0 + adv.Ceil()
+	case "underline":
+		r = image.Rect(x0, y1.Floor()-cursorWidthPx, x0+adv.Ceil(), y1.Floor())
+	}
+	r = r.Add(img.Bounds().Min)
+	switch {
+	case hollow:
+		fillRect(img, b.style.FG, image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+1))
+		fillRect(img, b.style.FG, image.Rect(r.Min.X, r.Max.Y-1, r.Max.X, r.Max.Y))
+		fillRect(img, b.style.FG, image.Rect(r.Min.X, r.Min.Y, r.Min.X+1, r.Max.Y))
+		fillRect(img, b.style.FG, image.Rect(r.Max.X-1, r.Min.Y, r.Max.X, r.Max.Y))
+	case cursorShape == "block":
+		cr, cg, cb, _ := b.style.FG.RGBA()
+		c := color.NRGBA64{R: uint16(cr), G: uint16(cg), B: uint16(cb), A: 0x6000}
+		draw.Draw(img, r, image.NewUniform(c), image.ZP, draw.Over)
+	default:
+		fillRect(img, b.style.FG, r)
+	}
 }
 
 func fillRect(img draw.Image, c color.Color, r image.Rectangle) {
+	if rgba, ok := img.(*image.RGBA); ok {
+		fillRGBA(rgba, c, r)
+		return
+	}
 	draw.Draw(img, r, image.NewUniform(c), image.ZP, draw.Src)
 }
 
 func atPoint(b *TextBox, pt image.Point) (int64, image.Rectangle) {
+	off := image.Pt(b.xoff, 0)
+	at, r := atTextPoint(b, pt.Add(off))
+	return at, r.Sub(off)
+}
+
+// atTextPoint is like atPoint, but the point is relative to the
+// start of the text, not accounting for horizontal scrolling.
+func atTextPoint(b *TextBox, pt image.Point) (int64, image.Rectangle) {
 	lines := b.lines()
 	if len(lines) == 0 {
 		m := b.style.Face.Metrics()
@@ -898,7 +1439,7 @@ func atPoint(b *TextBox, pt image.Point) (int64, image.Rectangle) {
 	x1 = x0
 	for _, r := range s.text {
 		x0 += kern(s.style, prevRune, r)
-		x1 = x0 + advance(b, s.style, x0, r)
+		x1 = x0 + advance(b, s.style, at, x0, r)
 		if x1.Floor() > pt.X {
 			break
 		}
@@ -911,6 +1452,41 @@ func atPoint(b *TextBox, pt image.Point) (int64, image.Rectangle) {
 	return at, rect
 }
 
+// addrPoint returns the point at the lower left
+// of the glyph at the address, relative to the text box,
+// and whether the address is displayed.
+func addrPoint(b *TextBox, at int64) (image.Point, bool) {
+	if at < b.at {
+		return image.Point{}, false
+	}
+	lines := b.lines()
+	a := b.at
+	var y fixed.Int26_6
+	for i, l := range lines {
+		if at >= a+l.n && i < len(lines)-1 {
+			a += l.n
+			y += l.h
+			continue
+		}
+		if at > a+l.n {
+			break
+		}
+		var x fixed.Int26_6
+	spans:
+		for _, s := range l.spans {
+			for _, r := range s.text {
+				if a >= at {
+					break spans
+				}
+				x += advance(b, s.style, a, x, r)
+				a += int64(utf8.RuneLen(r))
+			}
+		}
+		return image.Pt(textPadPx-b.xoff+x.Floor(), (y + l.h).Floor()), true
+	}
+	return image.Point{}, false
+}
+
 func lastRune(l *line) rune {
 	if len(l.spans) == 0 {
 		return utf8.RuneError
@@ -937,6 +1513,13 @@ func setDot(b *TextBox, i int, start, end int64) {
 	if dirtyDot(b, b.dots[i].At) {
 		showAddr(b, b.dots[i].At[0])
 	}
+	if i == 1 {
+		showCol(b)
+		if b.word != "" && selectedWord(b) == "" {
+			b.word = ""
+			dirtyLines(b)
+		}
+	}
 }
 
 func showAddr(b *TextBox, at int64) {
@@ -1002,7 +1585,17 @@ func reset(b *TextBox) {
 	maxx := b.size.X - 2*textPadPx
 	var y fixed.Int26_6
 	var txt strings.Builder
-	stack := [][]syntax.Highlight{b.syntax, b.highlight, {b.dots[1]}, {b.dots[2]}, {b.dots[3]}}
+	b.widest = 0
+	b.stops = nil
+	if b.elastic {
+		b.stops = elasticStops(b)
+	}
+	b.trailing = nil
+	if b.showSpace {
+		b.trailing = trailingSpace(b)
+	}
+	b.occurs = occurrences(b)
+	stack := [][]syntax.Highlight{b.syntax, b.diffLines, b.diags, b.debugLine, b.highlight, b.occurs, b.trailing, {b.dots[1]}, {b.dots[2]}, {b.dots[3]}}
 	for at < b.text.Len() && y < fixed.I(b.size.Y) {
 		var prevRune rune
 		var x0, x fixed.Int26_6
@@ -1010,6 +1603,17 @@ func reset(b *TextBox) {
 		line := line{dirty: true, a: m.Ascent, h: m.Height + m.Descent}
 		style, stack, next := nextTextStyle(b.style, stack, at)
 		for {
+			if end := foldEnd(b, at); end > at {
+				n := end - at
+				rs.Discard(int(n))
+				at += n
+				line.n += n
+				if next >= 0 && next <= at {
+					appendSpan(&line, x0, x, style, &txt)
+					x0 = x
+					style, stack, next = nextTextStyle(b.style, stack, at)
+				}
+			}
 			r, w, err := rs.ReadRune()
 			if err != nil {
 				break
@@ -1019,15 +1623,27 @@ func reset(b *TextBox) {
 				txt.WriteRune(r)
 				at++
 				line.n++
-				x = fixed.I(maxx)
+				if x.Ceil() > b.widest {
+					b.widest = x.Ceil()
+				}
+				x = fixed.I(maxx + b.xoff)
 				break
 			}
-			adv := advance(b, style, x, r)
-			if (x + adv).Ceil() >= maxx {
+			adv := advance(b, style, at, x, r)
+			if !b.nowrap && (x+adv).Ceil() >= maxx {
 				x = fixed.I(maxx)
 				rs.UnreadRune()
 				break
 			}
+			if b.nowrap && (x+adv).Ceil() >= maxx+b.xoff {
+				// The rest of the line is off the right edge.
+				n := int64(w) + skipLine(rs)
+				at += n
+				line.n += n
+				x = fixed.I(maxx + b.xoff)
+				b.widest = maxx + b.xoff + 1
+				break
+			}
 			txt.WriteRune(r)
 			x += adv
 			at += int64(w)
@@ -1043,6 +1659,9 @@ func reset(b *TextBox) {
 			}
 		}
 		appendSpan(&line, x0, x, style, &txt)
+		if x.Ceil() > b.widest {
+			b.widest = x.Ceil()
+		}
 		if y += line.h; y > fixed.I(b.size.Y) {
 			break
 		}
@@ -1050,6 +1669,22 @@ func reset(b *TextBox) {
 	}
 }
 
+// skipLine reads through the next newline or the end of input
+// and returns the number of bytes read.
+func skipLine(rs *bufio.Reader) int64 {
+	var n int64
+	for {
+		r, w, err := rs.ReadRune()
+		if err != nil {
+			return n
+		}
+		n += int64(w)
+		if r == '\n' {
+			return n
+		}
+	}
+}
+
 func appendSpan(line *line, x0, x fixed.Int26_6, style text.Style, text *strings.Builder) {
 	m := style.Face.Metrics()
 	line.a = max(line.a, m.Ascent)
@@ -1063,7 +1698,7 @@ func appendSpan(line *line, x0, x fixed.Int26_6, style text.Style, text *strings
 }
 
 func kern(style text.Style, prev, cur rune) fixed.Int26_6 {
-	if prev == 0 {
+	if prev == 0 || isMark(cur) {
 		return 0
 	}
 	return style.Face.Kern(prev, cur)
@@ -1100,22 +1735,28 @@ func nextTextStyle(def text.Style, stack [][]syntax.Highlight, at int64) (text.S
 	return style, stack, next
 }
 
-func advance(b *TextBox, style text.Style, x fixed.Int26_6, r rune) fixed.Int26_6 {
+func advance(b *TextBox, style text.Style, at int64, x fixed.Int26_6, r rune) fixed.Int26_6 {
 	switch r {
 	case '\n':
-		return fixed.I(b.size.X-2*textPadPx) - x
+		return fixed.I(b.size.X-2*textPadPx+b.xoff) - x
 	case '\t':
+		if stop, ok := b.stops[at]; ok && stop > x {
+			return stop - x
+		}
 		spaceWidth, ok := b.style.Face.GlyphAdvance(' ')
 		if !ok {
 			return 0
 		}
-		tabWidth := spaceWidth.Mul(fixed.I(8))
+		tabWidth := spaceWidth.Mul(fixed.I(b.tabWidth))
 		adv := tabWidth - (x % tabWidth)
 		if adv < spaceWidth {
 			adv += tabWidth
 		}
 		return adv
 	default:
+		if isMark(r) {
+			return 0
+		}
 		adv, ok := style.Face.GlyphAdvance(r)
 		if !ok {
 			adv, _ = style.Face.GlyphAdvance(unicode.ReplacementChar)
@@ -1123,3 +1764,14 @@ func advance(b *TextBox, style text.Style, x fixed.Int26_6, r rune) fixed.Int26_
 		return adv
 	}
 }
+
+// modButton returns the button that a press of the button acts as
+// with the currently held modifier keys.
+func modButton(w *Win, button int) int {
+	for _, m := range modButtons {
+		if m.button == button && w.mods[m.mod] {
+			return m.as
+		}
+	}
+	return button
+}
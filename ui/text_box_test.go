@@ -2,6 +2,7 @@ package ui
 
 import (
 	"bytes"
+	"fmt"
 	"image"
 	"image/color"
 	"image/png"
@@ -19,6 +20,7 @@ import (
 	"github.com/golang/freetype/truetype"
 	"golang.org/x/image/font/basicfont"
 	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
 )
 
 var (
@@ -37,12 +39,14 @@ var (
 
 func newTestWin() *Win {
 	w := &Win{
+		resizing:   -1,
 		face:       basicfont.Face7x13,
 		lineHeight: H,
 		clipboard:  clipboard.NewMem(),
 	}
 	c := NewCol(w)
 	w.cols = []*Col{c}
+	w.widths = []float64{1.0}
 	w.Col = c
 	return w
 }
@@ -644,6 +648,30 @@ func TestDoubleClick1(t *testing.T) {
 	}
 }
 
+func TestDoubleClickWordRunes(t *testing.T) {
+	defer func(rs string) { wordRunes = rs }(wordRunes)
+	const text = "see ui/text_box.go:12 now"
+	pt := image.Point{8 * A, H / 2}
+	for _, test := range []struct {
+		wordRunes string
+		want      [2]int64
+	}{
+		{wordRunes: "_", want: [2]int64{7, 15}},
+		{wordRunes: "_-./:", want: [2]int64{4, 21}},
+	} {
+		wordRunes = test.wordRunes
+		b := NewTextBox(testWin, testTextStyles, testSize)
+		b.SetText(rope.New(text))
+		b.now = fixedTime
+		b.Click(pt.Add(zp), 1)
+		b.Click(pt.Add(zp), -1)
+		b.Click(pt.Add(zp), 1)
+		if b.dots[1].At != test.want {
+			t.Errorf("wordRunes=%q: got dot=%v, want dot=%v", test.wordRunes, b.dots[1].At, test.want)
+		}
+	}
+}
+
 func TestDir1(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -873,6 +901,127 @@ func TestWheelDown(t *testing.T) {
 	}
 }
 
+func TestWheelPx(t *testing.T) {
+	text := rope.New(lines500)
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(text)
+	b.at = 10
+
+	b.WheelPx(image.ZP, 0, -float64(H)/2)
+	if b.at != 10 {
+		t.Fatalf("WheelPx -H/2, at=%d, wanted 10", b.at)
+	}
+	b.WheelPx(image.ZP, 0, -float64(H)/2)
+	if b.at != 11 {
+		t.Fatalf("WheelPx -H/2 -H/2, at=%d, wanted 11", b.at)
+	}
+	b.WheelPx(image.ZP, 0, -3*float64(H))
+	if b.at != 14 {
+		t.Fatalf("WheelPx -3H, at=%d, wanted 14", b.at)
+	}
+	b.WheelPx(image.ZP, 0, 2*float64(H)+1)
+	if b.at != 12 {
+		t.Errorf("WheelPx 2H+1, at=%d, wanted 12", b.at)
+	}
+}
+
+func TestNoWrap(t *testing.T) {
+	long := strings.Repeat("a", 40)
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New(long + "\nb"))
+	if n := len(b.lines()); n != 3 {
+		t.Fatalf("wrapped len(lines)=%d, want 3", n)
+	}
+
+	setWrap(b, false)
+	lines := b.lines()
+	if len(lines) != 2 {
+		t.Fatalf("unwrapped len(lines)=%d, want 2", len(lines))
+	}
+	if lines[0].n != 41 {
+		t.Errorf("unwrapped lines[0].n=%d, want 41", lines[0].n)
+	}
+}
+
+func TestWheelRight(t *testing.T) {
+	long := strings.Repeat("a", 40)
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New(long + "\nb"))
+	var now time.Time
+	b.now = func() time.Time {
+		n := now
+		now = now.Add(wheelScrollDuration)
+		return n
+	}
+
+	b.Wheel(image.ZP, 1, 0)
+	if b.xoff != 0 {
+		t.Errorf("wrapped WheelRight, xoff=%d, want 0", b.xoff)
+	}
+
+	setWrap(b, false)
+	b.Wheel(image.ZP, 1, 0)
+	if want := testSize.X / 8; b.xoff != want {
+		t.Fatalf("WheelRight, xoff=%d, want %d", b.xoff, want)
+	}
+	b.clickTime = now.Add(-doubleClickDuration)
+	b.Click(image.Pt(textPadPx, 0), 1)
+	b.Click(image.Pt(textPadPx, 0), -1)
+	if want := int64(testSize.X / 8 / A); b.dots[1].At != [2]int64{want, want} {
+		t.Errorf("click after WheelRight, dot=%v, want %v", b.dots[1].At, [2]int64{want, want})
+	}
+
+	for i := 0; i < 10; i++ {
+		b.Wheel(image.ZP, 1, 0)
+	}
+	if max := 40*A - (testSize.X - 2*textPadPx) + testSize.X/8; b.xoff > max {
+		t.Errorf("WheelRight past the end, xoff=%d, want <= %d", b.xoff, max)
+	}
+
+	b.Wheel(image.ZP, -100, 0)
+	for i := 0; i < 10; i++ {
+		b.Wheel(image.ZP, -1, 0)
+	}
+	if b.xoff != 0 {
+		t.Errorf("WheelLeft, xoff=%d, want 0", b.xoff)
+	}
+}
+
+func TestNoWrapShowsCursor(t *testing.T) {
+	long := strings.Repeat("a", 40)
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New(long + "\nb"))
+	setWrap(b, false)
+
+	setDot(b, 1, 40, 40)
+	x := 40*A - b.xoff
+	if x < 0 || x+cursorWidthPx > testSize.X-2*textPadPx {
+		t.Errorf("cursor x=%d is not visible", x)
+	}
+
+	setDot(b, 1, 0, 0)
+	if b.xoff != 0 {
+		t.Errorf("cursor at 0, xoff=%d, want 0", b.xoff)
+	}
+}
+
+func TestTabWidth(t *testing.T) {
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New("\tx"))
+	if adv := advance(b, b.style, 0, 0, '\t'); adv != fixed.I(8*A) {
+		t.Errorf("default tab advance=%v, want %v", adv, fixed.I(8*A))
+	}
+	if err := setTabs(b, "3"); err != nil {
+		t.Fatalf("setTabs failed: %v", err)
+	}
+	if adv := advance(b, b.style, 1, fixed.I(A), '\t'); adv != fixed.I(2*A) {
+		t.Errorf("tab advance=%v, want %v", adv, fixed.I(2*A))
+	}
+	if at, _ := atPoint(b, image.Pt(3*A+1, 1)); at != 1 {
+		t.Errorf("atPoint(after tab)=%d, want 1", at)
+	}
+}
+
 func TestDragScrollUp(t *testing.T) {
 	text := rope.New(lines500)
 	b := NewTextBox(testWin, testTextStyles, testSize)
@@ -936,6 +1085,56 @@ func TestDragScrollDown(t *testing.T) {
 	}
 }
 
+func TestDragScrollExtendsSelection(t *testing.T) {
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New(lines500))
+	var now time.Time
+	b.now = func() time.Time {
+		n := now
+		now = now.Add(dragScrollDuration)
+		return n
+	}
+	b.Focus(true)
+	b.Click(image.Pt(A/2, H/2), 1)
+	b.Move(image.Pt(A/2, b.size.Y+H))
+	end := b.dots[1].At[1]
+	for i := 0; i < 3; i++ {
+		b.Tick()
+	}
+	if b.at == 0 {
+		t.Fatalf("at=0, want scrolled")
+	}
+	if b.dots[1].At[0] != 0 || b.dots[1].At[1] <= end {
+		t.Errorf("dot=%v, want [0, >%d]", b.dots[1].At, end)
+	}
+}
+
+func TestDragScrollHorizontal(t *testing.T) {
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New(strings.Repeat("x", 100)))
+	setWrap(b, false)
+	var now time.Time
+	b.now = func() time.Time {
+		n := now
+		now = now.Add(dragScrollDuration)
+		return n
+	}
+	b.Focus(true)
+	b.Click(image.Pt(A/2, H/2), 1)
+	b.Move(image.Pt(b.size.X+A, H/2))
+	b.Tick()
+	if b.xoff == 0 {
+		t.Fatalf("xoff=0, want scrolled right")
+	}
+	b.Move(image.Pt(-A, H/2))
+	for i := 0; i < 10; i++ {
+		b.Tick()
+	}
+	if b.xoff != 0 {
+		t.Errorf("xoff=%d, want scrolled back to 0", b.xoff)
+	}
+}
+
 func TestPageUp(t *testing.T) {
 	text := rope.New(lines500)
 	b := NewTextBox(testWin, testTextStyles, testSize)
@@ -1281,6 +1480,67 @@ func TestDrawCursorOnLineAfterLastLine(t *testing.T) {
 	goldenImageTest(img, t)
 }
 
+func TestDrawScroll(t *testing.T) {
+	size := image.Pt(100, 10*H)
+	var text strings.Builder
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&text, "line %d\n", i)
+	}
+	b := NewTextBox(testWin, testTextStyles, size)
+	b.SetText(rope.New(text.String()))
+	b.dots[1].At = [2]int64{50, 50}
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	b.Draw(true, img)
+
+	for _, scroll := range []int{3, -1, 12, -5, -20} {
+		if scroll > 0 {
+			scrollDown(b, scroll)
+		} else {
+			scrollUp(b, -scroll)
+		}
+		b.Draw(false, img)
+
+		want := image.NewRGBA(image.Rectangle{Max: size})
+		b.Draw(true, want)
+		if !bytes.Equal(img.Pix, want.Pix) {
+			t.Errorf("scroll %d: image differs from a full redraw", scroll)
+		}
+		copy(img.Pix, want.Pix)
+	}
+}
+
+func TestDrawLineCache(t *testing.T) {
+	size := image.Pt(100, 10*H)
+	b := NewTextBox(testWin, testTextStyles, size)
+	b.SetText(rope.New("a\nb\nc\nd\n"))
+	b.dots[1].At = [2]int64{0, 1} // no cursor
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	b.Draw(true, img)
+	if len(b.lineCache) != 4 {
+		t.Fatalf("%d cached lines, want 4", len(b.lineCache))
+	}
+
+	b.SetText(rope.New("a\nx\nc\nd\n"))
+	b.dots[1].At = [2]int64{0, 1}
+	b.Draw(true, img)
+	if len(b.lineCache) != 5 {
+		t.Errorf("%d cached lines after changing a line, want 5", len(b.lineCache))
+	}
+	want := image.NewRGBA(image.Rectangle{Max: size})
+	b2 := NewTextBox(testWin, testTextStyles, size)
+	b2.SetText(b.text)
+	b2.dots[1].At = [2]int64{0, 1}
+	b2.Draw(true, want)
+	if !bytes.Equal(img.Pix, want.Pix) {
+		t.Errorf("image differs from drawing without the cache")
+	}
+
+	setColors(b, color.White)
+	if len(b.lineCache) != 0 {
+		t.Errorf("%d cached lines after setColors, want 0", len(b.lineCache))
+	}
+}
+
 func TestBlank(t *testing.T) {
 	size := image.Pt(100, 100)
 	b := NewTextBox(testWin, testTextStyles, size)
@@ -1307,6 +1567,82 @@ func TestCursorBlink(t *testing.T) {
 	goldenImageTest(img, t)
 }
 
+func TestCursorShape(t *testing.T) {
+	defer func(shape string) { cursorShape = shape }(cursorShape)
+	black := color.RGBA{A: 0xFF}
+	white := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	tests := []struct {
+		shape string
+		pts   map[image.Point]color.RGBA
+	}{
+		{"bar", map[image.Point]color.RGBA{
+			{textPadPx + 1, 2}:                 black,
+			{textPadPx + cursorWidthPx + 1, 2}: white,
+		}},
+		{"underline", map[image.Point]color.RGBA{
+			{textPadPx + 1, H - 1}: black,
+			{textPadPx + 1, 2}:     white,
+		}},
+	}
+	for _, test := range tests {
+		cursorShape = test.shape
+		b := NewTextBox(testWin, testTextStyles, testSize)
+		b.Focus(true)
+		img := image.NewRGBA(image.Rectangle{Max: testSize})
+		b.Draw(true, img)
+		for pt, want := range test.pts {
+			if got := img.RGBAAt(pt.X, pt.Y); got != want {
+				t.Errorf("%s: pixel %v=%v, want %v", test.shape, pt, got, want)
+			}
+		}
+	}
+
+	cursorShape = "block"
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.Focus(true)
+	img := image.NewRGBA(image.Rectangle{Max: testSize})
+	b.Draw(true, img)
+	if got := img.RGBAAt(textPadPx+A/2, 2); got == black || got == white {
+		t.Errorf("block: pixel=%v, want translucent", got)
+	}
+}
+
+func TestCursorUnfocusedWin(t *testing.T) {
+	w := newTestWin()
+	b := w.cols[0].rows[0].(*TextBox)
+	b.SetText(rope.New(""))
+	b.style, b.dots[0].Style = testTextStyle1, testTextStyle1
+	w.Focus(false)
+	img := image.NewRGBA(image.Rectangle{Max: testSize})
+	b.Draw(true, img)
+	black := color.RGBA{A: 0xFF}
+	if got := img.RGBAAt(textPadPx, 2); got != black {
+		t.Errorf("outline pixel=%v, want %v", got, black)
+	}
+	if got := img.RGBAAt(textPadPx+1, 2); got == black {
+		t.Errorf("inner pixel=%v, want not %v", got, black)
+	}
+}
+
+func TestCursorNoBlink(t *testing.T) {
+	defer func(blink bool) { cursorBlink = blink }(cursorBlink)
+	cursorBlink = false
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	var now time.Time
+	b.now = func() time.Time {
+		n := now
+		now = now.Add(blinkDuration)
+		return n
+	}
+	b.Focus(true)
+	for i := 0; i < 3; i++ {
+		b.Tick()
+		if !b.showCursor {
+			t.Fatalf("tick %d: cursor hidden", i)
+		}
+	}
+}
+
 func goldenImageTest(img image.Image, t *testing.T) {
 	var (
 		goldenFile = "testdata/" + t.Name() + "_golden.png"
@@ -1324,12 +1660,36 @@ func goldenImageTest(img image.Image, t *testing.T) {
 		ioutil.WriteFile(newFile, got, 0666)
 		t.Fatalf(err.Error())
 	}
-	if !bytes.Equal(got, want) {
+	// Compare the pixels, since the encoding of a PNG
+	// differs between versions of the encoder.
+	wantImg, err := png.Decode(bytes.NewReader(want))
+	if err != nil {
+		ioutil.WriteFile(newFile, got, 0666)
+		t.Fatalf("%s: %v", goldenFile, err)
+	}
+	if !samePixels(img, wantImg) {
 		ioutil.WriteFile(newFile, got, 0666)
 		t.Errorf("%s does not match %s\n", newFile, goldenFile)
 	}
 }
 
+func samePixels(a, b image.Image) bool {
+	if a.Bounds() != b.Bounds() {
+		return false
+	}
+	r := a.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			ar, ag, ab, aa := a.At(x, y).RGBA()
+			br, bg, bb, ba := b.At(x, y).RGBA()
+			if ar != br || ag != bg || ab != bb || aa != ba {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func TestCopy_Empty(t *testing.T) {
 	w := newTestWin()
 	b := NewTextBox(w, testTextStyles, testSize)
@@ -1479,3 +1839,32 @@ func TestPaste(t *testing.T) {
 		})
 	}
 }
+
+func TestModButton(t *testing.T) {
+	defer func(m []struct{ mod, button, as int }) { modButtons = m }(modButtons)
+	modButtons = append(modButtons, struct{ mod, button, as int }{mod: 1, button: 3, as: 2})
+
+	tests := []struct {
+		mod, button, want int
+	}{
+		{mod: 0, button: 1, want: 1},
+		{mod: 2, button: 1, want: 2},
+		{mod: 3, button: 1, want: 3},
+		{mod: 1, button: 1, want: 1},
+		{mod: 1, button: 3, want: 2},
+		{mod: 0, button: 3, want: 3},
+	}
+	for _, test := range tests {
+		w := newTestWin()
+		b := NewTextBox(w, testTextStyles, testSize)
+		b.SetText(rope.New("Hello"))
+		w.mods[test.mod] = test.mod > 0
+		if got, _ := b.Click(image.Pt(textPadPx, 0), test.button); got != test.want {
+			t.Errorf("mod %d button %d: got %d, want %d", test.mod, test.button, got, test.want)
+		}
+		w.mods[test.mod] = false
+		if got, _ := b.Click(image.Pt(textPadPx, 0), -1); test.want > 1 && got != -test.want {
+			t.Errorf("mod %d button %d: release got %d, want %d", test.mod, test.button, got, -test.want)
+		}
+	}
+}
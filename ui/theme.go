@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// setWinTheme implements the Theme command: Theme [name].
+// With no name, the theme names are shown in the Output sheet.
+// Otherwise, the colors of the named theme are set
+// and all text boxes of the window are redrawn with them.
+func setWinTheme(w *Win, args string) error {
+	fs := strings.Fields(args)
+	switch len(fs) {
+	case 0:
+		var names []string
+		for name := range themes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		w.OutputString(strings.Join(names, " ") + "\n")
+		return nil
+	case 1:
+	default:
+		return errors.New(msg("usage: Theme [name]"))
+	}
+	if err := setTheme(fs[0]); err != nil {
+		return err
+	}
+	setSheetColors := func(s *Sheet) {
+		setColors(s.tag, tagBG)
+		setColors(s.body, bodyBG)
+	}
+	for _, c := range w.cols {
+		setColors(c.rows[0].(*TextBox), colBG)
+		for _, r := range c.rows[1:] {
+			if s, ok := r.(*Sheet); ok && s != w.output {
+				setSheetColors(s)
+			}
+		}
+	}
+	if w.output != nil {
+		setSheetColors(w.output)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package ui
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestCmd_Theme(t *testing.T) {
+	defer setTheme("default")
+	w := newTestWin()
+	w.output = NewSheet(w, "Output")
+	c := w.cols[0]
+	s := NewSheet(w, "a.txt")
+	c.Add(s)
+
+	if err := execCmd(c, nil, "Theme dark"); err != nil {
+		t.Fatalf("Theme dark failed: %v", err)
+	}
+	dark := themes["dark"]
+	if bodyBG != dark.bodyBG || occurrenceBG != dark.occurrenceBG {
+		t.Errorf("bodyBG=%v, occurrenceBG=%v, want %v, %v", bodyBG, occurrenceBG, dark.bodyBG, dark.occurrenceBG)
+	}
+	for _, test := range []struct {
+		name string
+		b    *TextBox
+		bg   color.Color
+	}{
+		{"column", c.rows[0].(*TextBox), dark.colBG},
+		{"tag", s.tag, dark.tagBG},
+		{"body", s.body, dark.bodyBG},
+		{"output tag", w.output.tag, dark.tagBG},
+	} {
+		if test.b.style.FG != dark.fg || test.b.style.BG != test.bg || test.b.dots[1].Style.BG != dark.hiBG1 {
+			t.Errorf("%s style=%v, want the dark theme's", test.name, test.b.style)
+		}
+	}
+
+	// Missing colors are those of the light theme.
+	if err := execCmd(c, nil, "Theme high-contrast"); err != nil {
+		t.Fatalf("Theme high-contrast failed: %v", err)
+	}
+	if occurrenceBG != lightTheme.occurrenceBG {
+		t.Errorf("occurrenceBG=%v, want %v", occurrenceBG, lightTheme.occurrenceBG)
+	}
+
+	if err := execCmd(c, nil, "Theme none"); err == nil {
+		t.Errorf("Theme none succeeded, want error")
+	}
+	if err := execCmd(c, nil, "Theme"); err != nil {
+		t.Errorf("Theme failed: %v", err)
+	}
+	if got, want := w.outputBuffer.String(), "dark default high-contrast light\n"; got != want {
+		t.Errorf("output=%q, want %q", got, want)
+	}
+}
@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"bufio"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+	"github.com/eaburns/T/syntax"
+)
+
+// trailingSpace returns highlights of the trailing whitespace
+// on the lines displayed in the text box.
+func trailingSpace(b *TextBox) []syntax.Highlight {
+	var his []syntax.Highlight
+	style := hiStyle("trailing")
+	visible := b.size.Y/b.style.Face.Metrics().Height.Ceil() + 1
+	rs := bufio.NewReader(rope.NewReader(rope.Slice(b.text, b.at, b.text.Len())))
+	at, start := b.at, int64(-1)
+	for visible > 0 {
+		r, w, err := rs.ReadRune()
+		if err != nil || r == '\n' {
+			if start >= 0 {
+				his = append(his, syntax.Highlight{At: [2]int64{start, at}, Style: style})
+			}
+			if err != nil {
+				break
+			}
+			start = -1
+			visible--
+		} else if r != ' ' && r != '\t' {
+			start = -1
+		} else if start < 0 {
+			start = at
+		}
+		at += int64(w)
+	}
+	return his
+}
+
+// trimTrailingSpace removes trailing whitespace from all lines.
+func trimTrailingSpace(b *TextBox) {
+	var diffs edit.Diffs
+	str := b.text.String()
+	lines := splitLines(str, 0)
+	for i := len(lines) - 1; i >= 0; i-- {
+		l := lines[i]
+		end := l[1]
+		for end > l[0] && (str[end-1] == ' ' || str[end-1] == '\t') {
+			end--
+		}
+		if end < l[1] {
+			diffs = append(diffs, edit.Diff{At: [2]int64{end, l[1]}, Text: rope.Empty()})
+		}
+	}
+	b.Change(diffs)
+}
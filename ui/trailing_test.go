@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestTrailingSpace(t *testing.T) {
+	b := NewTextBox(testWin, testTextStyles, testSize)
+	b.SetText(rope.New("a  \nb\n \t\nc d\ne "))
+	b.showSpace = true
+	b.lines()
+	want := [][2]int64{{1, 3}, {6, 8}, {14, 15}}
+	if len(b.trailing) != len(want) {
+		t.Fatalf("trailing=%v, want %v", b.trailing, want)
+	}
+	for i, hi := range b.trailing {
+		if hi.At != want[i] {
+			t.Errorf("trailing[%d]=%v, want %v", i, hi.At, want[i])
+		}
+	}
+
+	b.showSpace = false
+	dirtyLines(b)
+	b.lines()
+	if b.trailing != nil {
+		t.Errorf("trailing=%v, want nil", b.trailing)
+	}
+}
+
+func TestPutTrimsTrailingSpace(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	for _, test := range []struct {
+		name, want string
+	}{
+		{name: "file.go", want: "a\n\nb"},
+		{name: "file.md", want: "a  \n\t\nb "},
+	} {
+		path := filepath.Join(dir, test.name)
+		write(path, "")
+		s := NewSheet(testWin, path)
+		if err := s.Get(); err != nil {
+			t.Fatalf("Get()=%v, want nil", err)
+		}
+		s.body.SetText(rope.New("a  \n\t\nb "))
+		if err := s.Put(); err != nil {
+			t.Fatalf("Put()=%v, want nil", err)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+		if string(data) != test.want {
+			t.Errorf("%s: wrote %q, want %q", test.name, data, test.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package ui
+
+import (
+	"errors"
+
+	"github.com/eaburns/T/rope"
+)
+
+// A deletedSheet is a modified sheet that was deleted.
+type deletedSheet struct {
+	title       string
+	text, saved rope.Rope
+	dot         [2]int64
+}
+
+// isModified returns whether the body of the sheet
+// has changed since it was last read from or written to its file.
+func isModified(s *Sheet) bool {
+	if s.saved == nil {
+		return s.body.text.Len() > 0
+	}
+	return s.body.text != s.saved
+}
+
+// trashSheet adds the sheet to the window's deleted sheets
+// if it is modified and not a REPL sheet.
+// Only the maxTrash most recently deleted sheets are kept.
+// Those of files are also autosaved to recovery files.
+func trashSheet(w *Win, s *Sheet) {
+	if s.repl != nil || !isModified(s) {
+		return
+	}
+	w.trash = append(w.trash, deletedSheet{
+		title: s.Title(),
+		text:  s.body.text,
+		saved: s.saved,
+		dot:   s.body.dots[1].At,
+	})
+	if len(w.trash) > maxTrash {
+		w.trash = w.trash[len(w.trash)-maxTrash:]
+	}
+}
+
+// undel implements the Undel command.
+// It restores the most recently deleted modified sheet to the column.
+func undel(c *Col) error {
+	w := c.win
+	if len(w.trash) == 0 {
+		return errors.New(msg("no deleted sheets"))
+	}
+	d := w.trash[len(w.trash)-1]
+	w.trash = w.trash[:len(w.trash)-1]
+	s := NewSheet(w, d.title)
+	s.body.SetText(d.text)
+	setFileType(s)
+	s.saved = d.saved
+	setDot(s.body, 1, d.dot[0], d.dot[1])
+	c.Add(s)
+	return nil
+}
@@ -0,0 +1,487 @@
+package ui
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+)
+
+type viMode int
+
+const (
+	viNormal viMode = iota
+	viInsert
+	viVisual
+)
+
+// A viState is the state of vi-like modal editing in a text box.
+//
+// In insert mode, runes are inserted as usual
+// until escape returns to normal mode.
+// In normal mode, runes are commands:
+//
+//	h j k l w b e 0 ^ $ G gg  move the cursor, with an optional count
+//	d c y  followed by a motion delete, change, or copy the text moved over
+//	dd cc yy  delete, change, or copy whole lines
+//	x X D C Y  are dl, dh, d$, c$, and yy
+//	i a I A o O  enter insert mode
+//	p P  paste after or before the cursor
+//	v  enters visual mode
+//	/  begins an incremental search
+//
+// In visual mode, motions extend dot from where visual mode began,
+// and d, x, c, and y operate on dot.
+// Deleted and copied text is stored in the clipboard.
+//
+// Since the cursor is between runes, all motions are exclusive:
+// de deletes through the end of a word, and d$ through the end of a line.
+type viState struct {
+	mode   viMode
+	count  int   // pending count; 0 if none
+	op     rune  // pending operator: d, c, or y; 0 if none
+	opN    int   // count given before the pending operator
+	g      bool  // whether a g is pending
+	anchor int64 // start of the visual selection
+}
+
+// setVi turns vi-like modal editing on or off.
+func setVi(b *TextBox, on bool) {
+	if on {
+		b.vi = &viState{mode: viNormal}
+	} else {
+		b.vi = nil
+	}
+}
+
+// viRune handles a rune typed in a text box with modal editing.
+// It returns whether the rune was handled.
+func viRune(b *TextBox, r rune) bool {
+	v := b.vi
+	if v.mode == viInsert {
+		if r != esc {
+			return false
+		}
+		// As in vi, the cursor moves back over the last inserted rune.
+		v.mode = viNormal
+		if at := b.dots[1].At[0]; at > lineStart(b, at) {
+			at = prevAddr(b, at)
+			setDot(b, 1, at, at)
+		}
+		return true
+	}
+	if r == esc {
+		if v.mode == viVisual {
+			setDot(b, 1, b.dots[1].At[0], b.dots[1].At[0])
+		}
+		*v = viState{mode: viNormal}
+		return true
+	}
+	if r >= '1' && r <= '9' || r == '0' && v.count > 0 {
+		v.count = v.count*10 + int(r-'0')
+		return true
+	}
+	if r == 'g' && !v.g {
+		v.g = true
+		return true
+	}
+	count := v.count
+	v.count = 0
+	n := max1(count)
+	switch {
+	case v.g:
+		v.g = false
+		if r != 'g' {
+			v.op = 0
+			return true
+		}
+		// gg is G, but to the first line if there is no count.
+		r, n = 'G', count
+		if n == 0 {
+			n = -1
+		}
+	case r == 'G':
+		n = count
+	}
+
+	if v.op == 'c' && r == 'w' {
+		r = 'e' // as in vi, cw changes only through the end of the word
+	}
+	cur := viCursor(b)
+	if at, linewise, ok := viMotion(b, r, cur, n); ok {
+		switch {
+		case v.op != 0:
+			for i := 1; i < v.opN; i++ {
+				at, _, _ = viMotion(b, r, at, n)
+			}
+			viOperate(b, v.op, viRange(b, cur, at, linewise), linewise)
+			v.op = 0
+		case v.mode == viVisual:
+			viSelect(b, at)
+		default:
+			b.cursorCol = -1
+			setDot(b, 1, at, at)
+		}
+		return true
+	}
+
+	if v.mode == viVisual {
+		switch r {
+		case 'd', 'x', 'c', 'y':
+			if r == 'x' {
+				r = 'd'
+			}
+			viOperate(b, r, b.dots[1].At, false)
+			if v.mode == viVisual {
+				v.mode = viNormal
+			}
+		case 'v':
+			v.mode = viNormal
+			setDot(b, 1, cur, cur)
+		}
+		return true
+	}
+
+	switch r {
+	case 'd', 'c', 'y':
+		if v.op == r {
+			end := cur
+			for i := 1; i < n*max1(v.opN); i++ {
+				end = lineEnd(b, end) + 1
+			}
+			viOperate(b, r, viRange(b, cur, end, true), true)
+			v.op = 0
+			return true
+		}
+		v.op, v.opN = r, n
+		return true
+	case 'x', 'X', 'D', 'C', 'Y':
+		sh := viShorthands[r]
+		v.op, v.opN, v.count = rune(sh[0]), 1, count
+		return viRune(b, rune(sh[1]))
+	}
+	v.op = 0
+
+	switch r {
+	case 'i':
+		viInsertAt(b, cur)
+	case 'a':
+		if cur < lineEnd(b, cur) {
+			cur = nextAddr(b, cur)
+		}
+		viInsertAt(b, cur)
+	case 'I':
+		viInsertAt(b, firstNonBlank(b, cur))
+	case 'A':
+		viInsertAt(b, lineEnd(b, cur))
+	case 'o':
+		at := lineEnd(b, cur)
+		b.Change(edit.Diffs{{At: [2]int64{at, at}, Text: rope.New("\n")}})
+		viInsertAt(b, at+1)
+	case 'O':
+		at := lineStart(b, cur)
+		b.Change(edit.Diffs{{At: [2]int64{at, at}, Text: rope.New("\n")}})
+		viInsertAt(b, at)
+	case 'p', 'P':
+		viPut(b, cur, r == 'p', n)
+	case 'v':
+		v.mode, v.anchor = viVisual, cur
+	case '/':
+		ctrlRune(b, 'f')
+	}
+	return true
+}
+
+// viShorthands maps normal mode commands
+// to the operator and motion for which they are shorthand.
+var viShorthands = map[rune]string{
+	'x': "dl",
+	'X': "dh",
+	'D': "d$",
+	'C': "c$",
+	'Y': "yy",
+}
+
+func max1(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// viCursor returns the cursor address:
+// the end of dot that moves in visual mode,
+// and otherwise the start of dot.
+func viCursor(b *TextBox) int64 {
+	dot := b.dots[1].At
+	if b.vi.mode == viVisual && dot[0] == b.vi.anchor {
+		return dot[1]
+	}
+	return dot[0]
+}
+
+// viSelect sets dot to span from the visual anchor to the address.
+func viSelect(b *TextBox, at int64) {
+	a := b.vi.anchor
+	if at < a {
+		a, at = at, a
+	}
+	setDot(b, 1, a, at)
+}
+
+// viRange returns the range between two addresses,
+// extended to whole lines, including the final newline, if linewise.
+func viRange(b *TextBox, a0, a1 int64, linewise bool) [2]int64 {
+	if a1 < a0 {
+		a0, a1 = a1, a0
+	}
+	if linewise {
+		a0 = lineStart(b, a0)
+		if a1 = lineEnd(b, a1); a1 < b.text.Len() {
+			a1++
+		}
+	}
+	return [2]int64{a0, a1}
+}
+
+// viOperate applies an operator to the range of text.
+func viOperate(b *TextBox, op rune, at [2]int64, linewise bool) {
+	b.win.clipboard.Store(rope.Slice(b.text, at[0], at[1]))
+	switch op {
+	case 'y':
+		setDot(b, 1, at[0], at[0])
+	case 'd':
+		b.Change(edit.Diffs{{At: at, Text: rope.Empty()}})
+		setDot(b, 1, at[0], at[0])
+	case 'c':
+		if linewise && at[1] > at[0] && rope.Slice(b.text, at[1]-1, at[1]).String() == "\n" {
+			at[1]-- // keep the line
+		}
+		b.Change(edit.Diffs{{At: at, Text: rope.Empty()}})
+		viInsertAt(b, at[0])
+	}
+}
+
+// viPut pastes the clipboard n times after or before the cursor.
+// Text ending in newline is pasted on the following or preceding line.
+func viPut(b *TextBox, cur int64, after bool, n int) {
+	r, err := b.win.clipboard.Fetch()
+	if err != nil || r.Len() == 0 {
+		return
+	}
+	str := r.String()
+	at := cur
+	switch {
+	case strings.HasSuffix(str, "\n") && after:
+		if at = lineEnd(b, cur); at < b.text.Len() {
+			at++
+		} else {
+			str = "\n" + strings.TrimSuffix(str, "\n")
+		}
+	case strings.HasSuffix(str, "\n"):
+		at = lineStart(b, cur)
+	case after && cur < lineEnd(b, cur):
+		at = nextAddr(b, cur)
+	}
+	str = strings.Repeat(str, n)
+	b.Change(edit.Diffs{{At: [2]int64{at, at}, Text: rope.New(str)}})
+	setDot(b, 1, at, at)
+}
+
+func viInsertAt(b *TextBox, at int64) {
+	b.vi.mode = viInsert
+	b.cursorCol = -1
+	setDot(b, 1, at, at)
+}
+
+// viMotion returns the address after moving n times from at,
+// and whether the motion is linewise.
+// It returns false if the rune is not a motion.
+func viMotion(b *TextBox, r rune, at int64, n int) (int64, bool, bool) {
+	switch r {
+	case 'h':
+		for i := 0; i < n && at > lineStart(b, at); i++ {
+			at = prevAddr(b, at)
+		}
+	case 'l', ' ':
+		for i := 0; i < n && at < lineEnd(b, at); i++ {
+			at = nextAddr(b, at)
+		}
+	case 'j', 'k':
+		col := runeCount(b, lineStart(b, at), at)
+		for i := 0; i < n; i++ {
+			if r == 'j' {
+				if end := lineEnd(b, at); end < b.text.Len() {
+					at = end + 1
+				}
+			} else if start := lineStart(b, at); start > 0 {
+				at = lineStart(b, start-1)
+			}
+		}
+		at = lineStart(b, at)
+		for end := lineEnd(b, at); col > 0 && at < end; col-- {
+			at = nextAddr(b, at)
+		}
+		return at, true, true
+	case 'w':
+		for i := 0; i < n; i++ {
+			at = wordForward(b, at)
+		}
+	case 'e':
+		for i := 0; i < n; i++ {
+			at = wordEnd(b, at)
+		}
+	case 'b':
+		for i := 0; i < n; i++ {
+			at = wordBack(b, at)
+		}
+	case '0':
+		at = lineStart(b, at)
+	case '^':
+		at = firstNonBlank(b, at)
+	case '$':
+		for i := 1; i < n && lineEnd(b, at) < b.text.Len(); i++ {
+			at = lineEnd(b, at) + 1
+		}
+		at = lineEnd(b, at)
+	case 'G':
+		switch {
+		case n == 0:
+			at = lastLine(b)
+		case n < 0:
+			at = 0
+		default:
+			line, err := edit.Addr([2]int64{}, strconv.Itoa(n), b.text)
+			if err != nil {
+				line = [2]int64{lastLine(b)}
+			}
+			at = line[0]
+		}
+		return firstNonBlank(b, at), true, true
+	default:
+		return 0, false, false
+	}
+	return at, false, true
+}
+
+// lastLine returns the address of the start of the last line,
+// not counting the empty line after a final newline.
+func lastLine(b *TextBox) int64 {
+	n := b.text.Len()
+	if n > 0 && rope.Slice(b.text, n-1, n).String() == "\n" {
+		n--
+	}
+	return lineStart(b, n)
+}
+
+func lineStart(b *TextBox, at int64) int64 {
+	return rope.LastIndexFunc(rope.Slice(b.text, 0, at), func(r rune) bool { return r == '\n' }) + 1
+}
+
+func lineEnd(b *TextBox, at int64) int64 {
+	if i := rope.IndexRune(rope.Slice(b.text, at, b.text.Len()), '\n'); i >= 0 {
+		return at + i
+	}
+	return b.text.Len()
+}
+
+func firstNonBlank(b *TextBox, at int64) int64 {
+	at = lineStart(b, at)
+	end := lineEnd(b, at)
+	if i := rope.IndexFunc(rope.Slice(b.text, at, end), func(r rune) bool { return r != ' ' && r != '\t' }); i >= 0 {
+		return at + i
+	}
+	return end
+}
+
+func nextAddr(b *TextBox, at int64) int64 {
+	_, w, err := rope.NewReader(rope.Slice(b.text, at, b.text.Len())).ReadRune()
+	if err != nil {
+		return at
+	}
+	return at + int64(w)
+}
+
+func prevAddr(b *TextBox, at int64) int64 {
+	_, w, err := rope.NewReverseReader(rope.Slice(b.text, 0, at)).ReadRune()
+	if err != nil {
+		return at
+	}
+	return at - int64(w)
+}
+
+func runeCount(b *TextBox, from, to int64) int {
+	return len([]rune(rope.Slice(b.text, from, to).String()))
+}
+
+// runeClass returns the class of a rune for word motions:
+// 0 for space, 1 for word runes, and 2 for other runes.
+func runeClass(r rune) int {
+	switch {
+	case unicode.IsSpace(r):
+		return 0
+	case isWordRune(r):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// wordForward returns the address of the start of the next word.
+func wordForward(b *TextBox, at int64) int64 {
+	rs := rope.NewReader(rope.Slice(b.text, at, b.text.Len()))
+	r, w, err := rs.ReadRune()
+	if err != nil {
+		return at
+	}
+	class := runeClass(r)
+	for err == nil && class != 0 && runeClass(r) == class {
+		at += int64(w)
+		r, w, err = rs.ReadRune()
+	}
+	for err == nil && runeClass(r) == 0 {
+		at += int64(w)
+		r, w, err = rs.ReadRune()
+	}
+	return at
+}
+
+// wordEnd returns the address of the end of the next word.
+func wordEnd(b *TextBox, at int64) int64 {
+	rs := rope.NewReader(rope.Slice(b.text, at, b.text.Len()))
+	r, w, err := rs.ReadRune()
+	for err == nil && runeClass(r) == 0 {
+		at += int64(w)
+		r, w, err = rs.ReadRune()
+	}
+	if err != nil {
+		return at
+	}
+	class := runeClass(r)
+	for err == nil && runeClass(r) == class {
+		at += int64(w)
+		r, w, err = rs.ReadRune()
+	}
+	return at
+}
+
+// wordBack returns the address of the start of the previous word.
+func wordBack(b *TextBox, at int64) int64 {
+	rs := rope.NewReverseReader(rope.Slice(b.text, 0, at))
+	r, w, err := rs.ReadRune()
+	for err == nil && runeClass(r) == 0 {
+		at -= int64(w)
+		r, w, err = rs.ReadRune()
+	}
+	if err != nil {
+		return at
+	}
+	class := runeClass(r)
+	for err == nil && runeClass(r) == class {
+		at -= int64(w)
+		r, w, err = rs.ReadRune()
+	}
+	return at
+}
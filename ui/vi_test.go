@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestVi(t *testing.T) {
+	const text = "one two three\n  four five\nsix\n"
+	tests := []struct {
+		keys    string
+		want    string
+		wantDot [2]int64
+	}{
+		{keys: "", want: text, wantDot: [2]int64{0, 0}},
+		{keys: "w", want: text, wantDot: [2]int64{4, 4}},
+		{keys: "2w", want: text, wantDot: [2]int64{8, 8}},
+		{keys: "e", want: text, wantDot: [2]int64{3, 3}},
+		{keys: "$", want: text, wantDot: [2]int64{13, 13}},
+		{keys: "$b", want: text, wantDot: [2]int64{8, 8}},
+		{keys: "lllj", want: text, wantDot: [2]int64{17, 17}},
+		{keys: "j0", want: text, wantDot: [2]int64{14, 14}},
+		{keys: "j^", want: text, wantDot: [2]int64{16, 16}},
+		{keys: "G", want: text, wantDot: [2]int64{26, 26}},
+		{keys: "Ggg", want: text, wantDot: [2]int64{0, 0}},
+		{keys: "2G", want: text, wantDot: [2]int64{16, 16}},
+		{keys: "x", want: "ne two three\n  four five\nsix\n", wantDot: [2]int64{0, 0}},
+		{keys: "3x", want: " two three\n  four five\nsix\n", wantDot: [2]int64{0, 0}},
+		{keys: "dw", want: "two three\n  four five\nsix\n", wantDot: [2]int64{0, 0}},
+		{keys: "d2w", want: "three\n  four five\nsix\n", wantDot: [2]int64{0, 0}},
+		{keys: "wD", want: "one \n  four five\nsix\n", wantDot: [2]int64{4, 4}},
+		{keys: "dd", want: "  four five\nsix\n", wantDot: [2]int64{0, 0}},
+		{keys: "j2dd", want: "one two three\n", wantDot: [2]int64{14, 14}},
+		{keys: "dj", want: "six\n", wantDot: [2]int64{0, 0}},
+		{keys: "cwONE\x1b", want: "ONE two three\n  four five\nsix\n", wantDot: [2]int64{2, 2}},
+		{keys: "ccx\x1b", want: "x\n  four five\nsix\n", wantDot: [2]int64{0, 0}},
+		{keys: "iX\x1b", want: "Xone two three\n  four five\nsix\n", wantDot: [2]int64{0, 0}},
+		{keys: "aX\x1b", want: "oXne two three\n  four five\nsix\n", wantDot: [2]int64{1, 1}},
+		{keys: "AX\x1b", want: "one two threeX\n  four five\nsix\n", wantDot: [2]int64{13, 13}},
+		{keys: "jIX\x1b", want: "one two three\n  Xfour five\nsix\n", wantDot: [2]int64{16, 16}},
+		{keys: "oX\x1b", want: "one two three\nX\n  four five\nsix\n", wantDot: [2]int64{14, 14}},
+		{keys: "OX\x1b", want: "X\none two three\n  four five\nsix\n", wantDot: [2]int64{0, 0}},
+		{keys: "ywP", want: "one one two three\n  four five\nsix\n", wantDot: [2]int64{0, 0}},
+		{keys: "yyjp", want: "one two three\n  four five\none two three\nsix\n", wantDot: [2]int64{26, 26}},
+		{keys: "ddp", want: "  four five\none two three\nsix\n", wantDot: [2]int64{12, 12}},
+		{keys: "Gyyp", want: text + "six\n", wantDot: [2]int64{30, 30}},
+		{keys: "vee", want: text, wantDot: [2]int64{0, 7}},
+		{keys: "wvwd", want: "one three\n  four five\nsix\n", wantDot: [2]int64{4, 4}},
+		{keys: "vjd", want: "  four five\nsix\n", wantDot: [2]int64{0, 0}},
+		{keys: "ve\x1b", want: text, wantDot: [2]int64{0, 0}},
+		{keys: "zq\n\t", want: text, wantDot: [2]int64{0, 0}},
+	}
+	for _, test := range tests {
+		b := NewTextBox(testWin, testTextStyles, testSize)
+		b.SetText(rope.New(text))
+		setVi(b, true)
+		typ(b, test.keys)
+		if got := b.text.String(); got != test.want {
+			t.Errorf("%q: text=%q, want %q", test.keys, got, test.want)
+		}
+		if got := b.dots[1].At; got != test.wantDot {
+			t.Errorf("%q: dot=%v, want %v", test.keys, got, test.wantDot)
+		}
+	}
+}
+
+func TestCmd_Vi(t *testing.T) {
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, "")
+	)
+	c.Add(s)
+	if err := execCmd(c, s, "Vi"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	typ(s.body, "ihi\x1bx")
+	if got := s.body.text.String(); got != "h" {
+		t.Errorf("text=%q, want %q", got, "h")
+	}
+	if err := execCmd(c, s, "Vi"); err != nil {
+		t.Fatalf("execCmd failed: %v", err)
+	}
+	typ(s.body, "x")
+	if got := s.body.text.String(); got != "hx" {
+		t.Errorf("text=%q, want %q", got, "hx")
+	}
+}
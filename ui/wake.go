@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"time"
+)
+
+// tickRate is the interval between ticks while work is pending
+// that cannot wake the window, such as a debug session.
+const tickRate = 20 * time.Millisecond
+
+// unfocusedTickRate is the tickRate while the window is not focused,
+// so that a window in the background uses little CPU.
+const unfocusedTickRate = 250 * time.Millisecond
+
+// TickRate returns the shortest interval between ticks,
+// which is longer while the window is not focused.
+func (w *Win) TickRate() time.Duration {
+	if w.unfocused {
+		return unfocusedTickRate
+	}
+	return tickRate
+}
+
+// SetWake sets the function called when background work,
+// such as a running command or a language server,
+// has results for the next tick.
+// It is called from any goroutine and must not block.
+func (w *Win) SetWake(f func()) {
+	w.mu.Lock()
+	w.wakeFunc = f
+	w.mu.Unlock()
+}
+
+// wake calls the function set by SetWake, if any.
+func wake(w *Win) {
+	w.mu.Lock()
+	f := w.wakeFunc
+	w.mu.Unlock()
+	if f != nil {
+		f()
+	}
+}
+
+// NextTick returns how long after now the window next needs a tick,
+// unless an event arrives or it is woken before,
+// or a negative duration if it needs none.
+// Ticks are needed for animations, such as the blinking cursor
+// and scrolling while dragging, and for timed work, such as autosave.
+func (w *Win) NextTick(now time.Time) time.Duration {
+	next := time.Duration(-1)
+	at := func(t time.Time) {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		if next < 0 || d < next {
+			next = d
+		}
+	}
+	if winRecoveryDir(w) != "" && autosaveInterval > 0 && !w.quiet && len(recoveryTexts(w)) > 0 {
+		at(w.autosaveAt.Add(autosaveInterval))
+	}
+	for _, c := range w.cols {
+		for _, r := range c.rows {
+			s := getSheet(r)
+			if s == nil {
+				continue
+			}
+			for _, b := range [...]*TextBox{s.tag, s.body} {
+				textBoxTick(b, at)
+			}
+			if dbg := s.debug; dbg != nil {
+				dbg.mu.Lock()
+				ended := dbg.ended
+				dbg.mu.Unlock()
+				if !ended {
+					at(now.Add(w.TickRate()))
+				}
+			}
+			if wt := s.watch; wt != nil && !w.quiet {
+				wt.mu.Lock()
+				changed := wt.changed
+				wt.mu.Unlock()
+				if !changed.IsZero() {
+					at(changed.Add(watchDelay))
+				}
+			}
+		}
+	}
+	return next
+}
+
+// textBoxTick calls at with the time of the next tick
+// needed by the text box, if any.
+func textBoxTick(b *TextBox, at func(time.Time)) {
+	if b.button == 1 {
+		if dx, dy := dragBeyond(b); dx != 0 || dy != 0 {
+			at(b.dragScrollTime)
+		}
+	}
+	if b.focus && cursorBlink && !reducedMotion && !b.win.quiet && !b.win.unfocused &&
+		b.dots[1].At[0] == b.dots[1].At[1] {
+		at(b.blinkTime)
+	}
+}
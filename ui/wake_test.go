@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/eaburns/T/rope"
+)
+
+func TestSetWake(t *testing.T) {
+	w := newTestWin()
+	var n int
+	w.SetWake(func() { n++ })
+	w.OutputString("x")
+	cmdFinished(w, "x", time.Now(), nil)
+	if n != 2 {
+		t.Errorf("woken %d times, want 2", n)
+	}
+}
+
+func TestNextTick(t *testing.T) {
+	defer func(d time.Duration) { autosaveInterval = d }(autosaveInterval)
+	autosaveInterval = 0
+
+	w := newTestWin()
+	s := NewSheet(w, "/a/b.txt")
+	w.cols[0].Add(s)
+	s.tag.Focus(false)
+	s.body.Focus(false)
+	now := time.Now()
+	if d := w.NextTick(now); d >= 0 {
+		t.Errorf("idle NextTick=%v, want negative", d)
+	}
+
+	s.body.button = 1
+	s.body.dragScrollTime = now.Add(-time.Second)
+	if d := w.NextTick(now); d >= 0 {
+		t.Errorf("dragging in the text NextTick=%v, want negative", d)
+	}
+	s.body.SetText(rope.New("1\n2\n"))
+	s.body.at = 2
+	s.body.pt = image.Pt(0, -1)
+	if d := w.NextTick(now); d != 0 {
+		t.Errorf("dragging above the text NextTick=%v, want 0", d)
+	}
+	s.body.button = 0
+
+	if !cursorBlink || reducedMotion {
+		return
+	}
+	s.body.Focus(true)
+	s.body.blinkTime = now.Add(100 * time.Millisecond)
+	if d := w.NextTick(now); d != 100*time.Millisecond {
+		t.Errorf("blinking NextTick=%v, want 100ms", d)
+	}
+}
+
+func TestTickRateUnfocused(t *testing.T) {
+	w := newTestWin()
+	s := NewSheet(w, "/a/b.txt")
+	w.cols[0].Add(s)
+	if r := w.TickRate(); r != tickRate {
+		t.Errorf("focused TickRate=%v, want %v", r, tickRate)
+	}
+	w.Focus(false)
+	if r := w.TickRate(); r != unfocusedTickRate {
+		t.Errorf("unfocused TickRate=%v, want %v", r, unfocusedTickRate)
+	}
+
+	// Even if the body has the focus, its cursor does not blink.
+	s.body.Focus(true)
+	if d := w.NextTick(time.Now()); d >= 0 {
+		t.Errorf("unfocused NextTick=%v, want negative", d)
+	}
+}
@@ -0,0 +1,142 @@
+package ui
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// A watcher reruns the command of a +Watch sheet
+// when a watched file changes.
+type watcher struct {
+	fs      *fsnotify.Watcher
+	win     *Win
+	dir     string // directory in which the command is run
+	command string
+
+	mu      sync.Mutex
+	changed time.Time // time of the last change not yet run; zero if none
+	err     error     // error watching the files not yet shown; nil if none
+}
+
+// runWatch implements the Watch command: Watch [-p path]... command.
+// The command is run by sh in the sheet's directory
+// and its output is shown in the +Watch sheet of the directory.
+// Whenever a file in the directory, or in the paths if any, changes,
+// the command is killed if it is running and run again
+// once no more changes occur for watchDelay.
+// Files beginning with . are not watched.
+// Deleting the +Watch sheet stops watching.
+func runWatch(c *Col, s *Sheet, args string) error {
+	dir, err := abs(s, ".")
+	if err != nil {
+		return err
+	}
+	var paths []string
+	for args = strings.TrimSpace(args); strings.HasPrefix(args, "-p "); {
+		rest := strings.TrimSpace(args[len("-p "):])
+		i := strings.IndexAny(rest, " \t")
+		if i < 0 {
+			return errors.New(msg("usage: Watch [-p path]... command"))
+		}
+		path, err := abs(s, rest[:i])
+		if err != nil {
+			return err
+		}
+		paths = append(paths, path)
+		args = strings.TrimSpace(rest[i:])
+	}
+	if args == "" {
+		return errors.New(msg("usage: Watch [-p path]... command"))
+	}
+	if len(paths) == 0 {
+		paths = []string{dir}
+	}
+	fs, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		if err := fs.Add(p); err != nil {
+			fs.Close()
+			return err
+		}
+	}
+	w, err := runInSheet(c, filepath.Join(dir, "+Watch"), dir, args)
+	if err != nil {
+		fs.Close()
+		return err
+	}
+	if w.watch != nil {
+		w.watch.stop()
+	}
+	w.watch = &watcher{fs: fs, win: c.win, dir: dir, command: args}
+	go w.watch.run()
+	return nil
+}
+
+// run records the changes of the watched files
+// until the watcher is stopped.
+func (wt *watcher) run() {
+	for {
+		select {
+		case ev, ok := <-wt.fs.Events:
+			if !ok {
+				return
+			}
+			if ev.Op == fsnotify.Chmod || strings.HasPrefix(filepath.Base(ev.Name), ".") {
+				continue
+			}
+			wt.mu.Lock()
+			wt.changed = time.Now()
+			wt.mu.Unlock()
+			wake(wt.win)
+		case err, ok := <-wt.fs.Errors:
+			if !ok {
+				return
+			}
+			wt.mu.Lock()
+			wt.err = err
+			wt.mu.Unlock()
+			wake(wt.win)
+		}
+	}
+}
+
+// stop stops watching the files.
+func (wt *watcher) stop() {
+	wt.fs.Close()
+}
+
+// watchUpdate reruns the command of a +Watch sheet
+// if a watched file changed at least watchDelay ago.
+// While the window is quiet, changes are kept until it resumes.
+// It returns whether the sheet must be redrawn.
+func watchUpdate(s *Sheet) bool {
+	if s.win.quiet {
+		return false
+	}
+	wt := s.watch
+	wt.mu.Lock()
+	changed, err := wt.changed, wt.err
+	rerun := !changed.IsZero() && time.Since(changed) >= watchDelay
+	if rerun {
+		wt.changed = time.Time{}
+	}
+	wt.err = nil
+	wt.mu.Unlock()
+	if err != nil {
+		appendOutput(s.body, err.Error()+"\n")
+	}
+	if !rerun {
+		return err != nil
+	}
+	if _, err := runInSheet(s.win.Col, s.Title(), wt.dir, wt.command); err != nil {
+		appendOutput(s.body, err.Error()+"\n")
+	}
+	return true
+}
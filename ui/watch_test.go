@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCmd_Watch(t *testing.T) {
+	dir := tmpdir()
+	defer os.RemoveAll(dir)
+	write(filepath.Join(dir, "a.txt"), "1\n")
+
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, filepath.Join(dir, "a.txt"))
+	)
+	c.Add(s)
+	if err := execCmd(c, s, "Watch cat a.txt"); err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	b := findSheet(w, filepath.Join(dir, "+Watch"))
+	if b == nil {
+		t.Fatalf("no +Watch sheet")
+	}
+	wait := func(want string) {
+		t.Helper()
+		for start := time.Now(); b.body.text.String() != want && time.Since(start) < 5*time.Second; {
+			b.Tick()
+			time.Sleep(time.Millisecond)
+		}
+		if got := b.body.text.String(); got != want {
+			t.Fatalf("+Watch=%q, want %q", got, want)
+		}
+	}
+	wait("$ cat a.txt\n1\n")
+
+	write(filepath.Join(dir, "a.txt"), "2\n")
+	wait("$ cat a.txt\n2\n")
+
+	if err := execCmd(c, b, "Del"); err != nil {
+		t.Fatalf("Del failed: %v", err)
+	}
+	write(filepath.Join(dir, "a.txt"), "3\n")
+	time.Sleep(2 * watchDelay)
+	b.Tick()
+	if got := b.body.text.String(); got != "$ cat a.txt\n2\n" {
+		t.Errorf("after Del, +Watch=%q", got)
+	}
+}
+
+func TestCmd_WatchUsage(t *testing.T) {
+	var (
+		w = newTestWin()
+		c = w.cols[0]
+		s = NewSheet(w, "/a/b.txt")
+	)
+	c.Add(s)
+	for _, cmd := range []string{"Watch", "Watch -p /a", "Watch -p /a -p /b"} {
+		err := execCmd(c, s, cmd)
+		if err == nil || !strings.HasPrefix(err.Error(), "usage: Watch") {
+			t.Errorf("%s=%v, want usage error", cmd, err)
+		}
+	}
+}
@@ -1,15 +1,17 @@
-// Package ui is the user interface of the editor.
 package ui
 
 import (
 	"image"
 	"image/draw"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/eaburns/T/clipboard"
 	"github.com/eaburns/T/edit"
 	"github.com/eaburns/T/rope"
+	"github.com/eaburns/T/text"
 	"github.com/golang/freetype/truetype"
 	"golang.org/x/image/font"
 )
@@ -25,38 +27,120 @@ type Win struct {
 	dpi        float32
 	lineHeight int
 	mods       [4]bool // currently held modifier keys
+	sticky     bool    // whether modifiers pressed alone latch
+	latched    [4]bool // modifiers latched until the next click or rune
+	alone      [4]bool // modifiers pressed with no other event since
+	deadKeys   bool    // whether the accents of deadKeys are dead keys
+	dead       rune    // the pending dead key or 0
+	quiet      bool    // whether background activity is paused
+	exts       []*extension
+	stopExts   chan struct{} // closed to stop the extensions
+	extsDone   sync.WaitGroup
 	clipboard  clipboard.Clipboard
 	face       font.Face // default font face
+	fontSize   int       // size of face in points
 	output     *Sheet
+	confirm    string               // command to execute again to confirm it
+	replacing  []replacement        // the replacements previewed by Replace
+	exiting    bool                 // whether the window should be closed
+	autosaved  map[string]rope.Rope // text of the recovery file of each path
+	autosaveAt time.Time            // time of the last autosave
+	recoverID  string               // name of the directory of winRecoveryDir
+	files      *fileWatcher         // watcher of the files of file sheets; nil until the first tick
+	unfocused  bool                 // whether the window is not focused
+	fullscreen bool                 // whether the window is to be shown fullscreen
+	trash      []deletedSheet       // deleted modified sheets, oldest first
+	kills      killRing
+	searches   []string              // search history, oldest first
+	searched   bool                  // whether searches changed since it was read
+	lastPipe   string                // the last |command
+	back       []jump                // positions moved away from, oldest first
+	forward    []jump                // positions moved back from, oldest first
+	servers    map[string]*lspServer // language servers by command and root directory
+	popup      *popup                // the completion popup; nil if none
+	popupDirty bool                  // whether the popup changed since it was drawn
+	popupDrawn image.Rectangle       // bounds of the popup when it was last drawn
+	changed    image.Rectangle       // area of the image changed by Draw so far
+	changedMu  sync.Mutex            // guards changed while the columns are drawn
+	drawnX     []int                 // x of the columns when last drawn
+	colImgs    []image.RGBA          // the images of the columns, reused by Draw
+	drawBuf    *image.RGBA           // image drawn to by Draw for non-RGBA images
+	redrawAll  bool                  // whether the next Draw must redraw everything
+	recording  bool                  // whether a macro is being recorded
+	replaying  bool                  // whether a macro is being replayed
+	recorded   []macroEvent          // events recorded since the Record command
+	macro      []macroEvent          // the last recorded macro
+	scripts    map[string]*script    // scripts of the scripts file by name
+	running    int                   // number of scripts running
+	listener   *listener             // the listener of Listen, or nil
+	sheets     int                   // number of sheets created, the last winid
+	dotSheet   *Sheet                // sheet of the last dot event
+	dotAt      [2]int64              // dot of the last dot event
 
 	mu           sync.Mutex
 	outputBuffer strings.Builder
+	finished     []finishedCmd // commands finished since the last tick
+	calls        []rpcCall     // requests received since the last tick
+	resume       chan struct{} // closed on Resume; nil unless quiet
+	wakeFunc     func()        // set by SetWake; nil if none
 }
 
 // NewWin returns a new window.
 func NewWin(dpi float32) *Win {
-	face := truetype.NewFace(defaultFont, &truetype.Options{
-		Size: float64(defaultFontSize),
-		DPI:  float64(dpi * (72.0 / 96.0)),
-	})
-	h := (face.Metrics().Height + face.Metrics().Descent).Ceil()
+	configErr := configFromEnv()
+	face := newFace(nil, dpi, defaultFontSize)
 	w := &Win{
 		resizing:   -1,
 		dpi:        dpi,
 		face:       face,
-		lineHeight: h,
+		fontSize:   defaultFontSize,
+		lineHeight: faceHeight(face),
 		clipboard:  clipboard.New(),
 	}
 	w.cols = []*Col{NewCol(w)}
 	w.widths = []float64{1.0}
 	w.Col = w.cols[0]
 	w.output = NewSheet(w, "Output")
+	if configErr != nil {
+		w.OutputString(configErr.Error() + "\n")
+	}
+	if err := startExtensions(w); err != nil {
+		w.OutputString(err.Error() + "\n")
+	}
+	if err := readSearches(w); err != nil {
+		w.OutputString(err.Error() + "\n")
+	}
+	if err := loadScripts(w); err != nil {
+		w.OutputString(err.Error() + "\n")
+	}
+	checkRecovery(w)
 	return w
 }
 
+// Close closes the rows of the window,
+// stops its extensions and language servers,
+// saves its search history, and removes its recovery files.
+func (w *Win) Close() {
+	for _, c := range w.cols {
+		for _, r := range c.rows[1:] {
+			r.Close()
+		}
+	}
+	stopExtensions(w)
+	stopLanguageServers(w)
+	stopDebug(w)
+	removeRecoveryFiles(w)
+	stopWatchingFiles(w)
+	stopListening(w)
+	if err := writeSearches(w); err != nil {
+		w.OutputString(err.Error() + "\n")
+	}
+}
+
 // Add adds a new column to the window and returns it.
 func (w *Win) Add() *Col {
 	col := NewCol(w)
+	setQuietText(col)
 	f := 0.5
 	if n := len(w.widths); n > 1 {
 		f = (w.widths[n-2] + w.widths[n-1]) / 2.0
@@ -93,7 +177,14 @@ func (w *Win) Del(c *Col) {
 
 // Tick handles tick events.
 func (w *Win) Tick() bool {
-	var redraw bool
+	autosave(w)
+	watchFiles(w)
+	notifyFinished(w)
+	redraw := w.popupDirty
+	if handleCalls(w) {
+		redraw = true
+	}
+	logDot(w)
 	if showOutput(w) {
 		redraw = true
 	}
@@ -138,23 +229,130 @@ func showOutput(w *Win) bool {
 	return true
 }
 
-// Draw draws the window.
-func (w *Win) Draw(dirty bool, drawImg draw.Image) {
-	img := drawImg.(*image.RGBA)
+// Draw draws the window and returns the rectangle of the image that changed.
+// If dirty is true, everything is redrawn and the rectangle is the image bounds.
+// If the size of the image differs from the window's, the window is resized.
+//
+// Drawing into an *image.RGBA is fastest.
+// Other images are drawn by copying the changed rectangle
+// from an *image.RGBA kept by the window.
+func (w *Win) Draw(dirty bool, drawImg draw.Image) image.Rectangle {
+	if img, ok := drawImg.(*image.RGBA); ok {
+		w.drawBuf = nil
+		return drawRGBA(w, dirty, img)
+	}
+	b := drawImg.Bounds()
+	if w.drawBuf == nil || w.drawBuf.Bounds() != b {
+		w.drawBuf = image.NewRGBA(b)
+		dirty = true
+	}
+	r := drawRGBA(w, dirty, w.drawBuf)
+	draw.Draw(drawImg, r, w.drawBuf, r.Min, draw.Src)
+	return r
+}
+
+func drawRGBA(w *Win, dirty bool, img *image.RGBA) image.Rectangle {
+	if w.redrawAll {
+		w.redrawAll = false
+		dirty = true
+	}
 	if w.size != img.Bounds().Size() {
 		w.Resize(img.Bounds().Size())
 	}
-	for i, c := range w.cols {
+	// Redraw everything when the popup moves to clear it.
+	if r, _ := popupRect(w); r != w.popupDrawn {
+		w.popupDrawn = r
+		dirty = true
+	}
+	w.changed = image.ZR
+	if colsMoved(w) {
+		// The frames moved.
+		addChanged(w, img.Bounds())
+	}
+	if len(w.colImgs) != len(w.cols) {
+		w.colImgs = make([]image.RGBA, len(w.cols))
+	}
+	for i := range w.cols[:len(w.cols)-1] {
 		r := img.Bounds()
-		r.Min.X = img.Bounds().Min.X + x0(w, i)
-		r.Max.X = img.Bounds().Min.X + x1(w, i)
-		c.Draw(dirty, img.SubImage(r).(*image.RGBA))
-		if i < len(w.cols)-1 {
-			r.Min.X = r.Max.X
-			r.Max.X += framePx
-			fillRect(img, frameBG, r)
+		r.Min.X = img.Bounds().Min.X + x1(w, i)
+		r.Max.X = r.Min.X + framePx
+		fillRect(img, frameBG, r)
+	}
+	// Starting goroutines allocates,
+	// so columns are only drawn concurrently if more than one changed.
+	if dirty || changedCols(w) > 1 {
+		drawColsConcurrently(w, dirty, img)
+	} else {
+		for i, c := range w.cols {
+			c.Draw(dirty, subImage(&w.colImgs[i], img, colRect(w, img, i)))
 		}
 	}
+	if r, ok := popupRect(w); ok {
+		drawPopup(w, img)
+		addChanged(w, r.Inset(-framePx).Add(img.Bounds().Min))
+	}
+	w.popupDirty = false
+	if dirty {
+		return img.Bounds()
+	}
+	return w.changed.Intersect(img.Bounds())
+}
+
+// drawColsConcurrently draws the columns concurrently
+// to disjoint parts of img.
+func drawColsConcurrently(w *Win, dirty bool, img *image.RGBA) {
+	var wg sync.WaitGroup
+	for i, c := range w.cols {
+		wg.Add(1)
+		go func(i int, c *Col) {
+			defer wg.Done()
+			c.Draw(dirty, subImage(&w.colImgs[i], img, colRect(w, img, i)))
+		}(i, c)
+	}
+	wg.Wait()
+}
+
+// colRect returns the rectangle of img to which the ith column is drawn.
+func colRect(w *Win, img *image.RGBA, i int) image.Rectangle {
+	r := img.Bounds()
+	r.Min.X = img.Bounds().Min.X + x0(w, i)
+	r.Max.X = img.Bounds().Min.X + x1(w, i)
+	return r
+}
+
+// changedCols returns the number of columns with changed rows.
+func changedCols(w *Win) int {
+	var n int
+	for _, c := range w.cols {
+		if changedRows(c) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// colsMoved returns whether the x of the left of any column changed
+// since it was last called, and records the current xs.
+func colsMoved(w *Win) bool {
+	moved := len(w.drawnX) != len(w.cols)
+	if moved {
+		w.drawnX = make([]int, len(w.cols))
+	}
+	for i := range w.cols {
+		if x := x0(w, i); w.drawnX[i] != x {
+			w.drawnX[i] = x
+			moved = true
+		}
+	}
+	return moved
+}
+
+// addChanged adds a rectangle of the image being drawn
+// to the area returned by Draw.
+func addChanged(w *Win, r image.Rectangle) {
+	w.changedMu.Lock()
+	w.changed = w.changed.Union(r)
+	w.changedMu.Unlock()
 }
 
 // Resize handles resize events.
@@ -180,6 +378,7 @@ func (w *Win) Resize(size image.Point) {
 
 // Move handles mouse move events.
 func (w *Win) Move(pt image.Point) {
+	recordEvent(w, macroEvent{replay: func(w *Win) { w.Move(pt) }})
 	if w.resizing >= 0 {
 		// Center the pointer horizontally on the handle.
 		x := pt.X + w.cols[w.resizing].HandleBounds().Dx()/2
@@ -216,7 +415,14 @@ func resizeCol(w *Win, x int) {
 }
 
 // Wheel handles mouse wheel events.
+// If the control modifier is held,
+// rolling zooms the font of the sheet under the mouse,
+// or of the whole window if shift is also held.
 func (w *Win) Wheel(pt image.Point, x, y int) {
+	if w.mods[3] && y != 0 {
+		zoom(w, pt, y)
+		return
+	}
 	for i, c := range w.cols {
 		if pt.X < x1(w, i) {
 			pt.X -= x0(w, i)
@@ -226,8 +432,22 @@ func (w *Win) Wheel(pt image.Point, x, y int) {
 	}
 }
 
+// WheelPx handles precise scrolling events,
+// such as two-finger scrolling on a touchpad.
+// The x and y deltas are in pixels, with the same signs as for Wheel.
+func (w *Win) WheelPx(pt image.Point, x, y float64) {
+	for i, c := range w.cols {
+		if pt.X < x1(w, i) {
+			pt.X -= x0(w, i)
+			c.WheelPx(pt, x, y)
+			return
+		}
+	}
+}
+
 // Click handles click events.
 func (w *Win) Click(pt image.Point, button int) {
+	recordEvent(w, macroEvent{button: button, replay: func(w *Win) { w.Click(pt, button) }})
 	if w.resizing >= 0 && button == -1 {
 		w.resizing = -1
 		return
@@ -247,11 +467,19 @@ func (w *Win) Click(pt image.Point, button int) {
 		}
 	}
 
+	if w.popup != nil && button == 1 && popupClick(w, pt) {
+		return
+	}
 	if button > 0 {
+		closePopup(w)
 		setWinFocusPt(w, pt)
 	}
+	w.alone = [4]bool{}
 	pt.X -= x0(w, focusedCol(w))
 	w.Col.Click(pt, button)
+	if button < 0 {
+		releaseLatched(w)
+	}
 }
 
 func setWinFocusPt(w *Win, pt image.Point) {
@@ -265,23 +493,147 @@ func setWinFocusPt(w *Win, pt image.Point) {
 
 // Focus handles focus change events.
 func (w *Win) Focus(focus bool) {
+	w.unfocused = !focus
 	if !focus {
 		w.mods = [4]bool{}
+		w.latched = [4]bool{}
+		w.alone = [4]bool{}
 	}
 	w.Col.Focus(focus)
 }
 
+// Title returns the title of the window:
+// the file name of the focused sheet,
+// followed by * if the file is modified,
+// and the name of the editor.
+func (w *Win) Title() string {
+	s := getSheet(w.Col.Row)
+	if s == nil || s.Title() == "" {
+		return "T"
+	}
+	name := filepath.Base(s.Title())
+	if isFileSheet(s) && isModified(s) {
+		name += "*"
+	}
+	return name + " - T"
+}
+
+// SetStickyMods sets whether modifier keys are sticky.
+//
+// A sticky modifier that is pressed and released
+// without any intervening click or typing
+// remains held until after the next click or typed rune.
+// This allows modifier combinations to be entered
+// without holding multiple keys simultaneously.
+// Pressing a latched modifier again releases it.
+func (w *Win) SetStickyMods(sticky bool) {
+	w.sticky = sticky
+	if !sticky {
+		releaseLatched(w)
+	}
+}
+
 // Mod handles modifier key state change events.
 func (w *Win) Mod(m int) {
+	recordEvent(w, macroEvent{replay: func(w *Win) { w.Mod(m) }})
 	switch {
 	case m > 0 && m < len(w.mods):
 		w.mods[m] = true
+		w.alone[m] = !w.latched[m]
+		w.latched[m] = false
 	case m < 0 && -m < len(w.mods):
+		if w.sticky && w.alone[-m] {
+			w.alone[-m] = false
+			w.latched[-m] = true
+			return
+		}
 		w.mods[-m] = false
 	}
 	w.Col.Mod(m)
 }
 
+// Dir handles keyboard directional events.
+func (w *Win) Dir(x, y int) {
+	recordEvent(w, macroEvent{replay: func(w *Win) { w.Dir(x, y) }})
+	w.alone = [4]bool{}
+	if w.popup != nil && popupDir(w, x, y) {
+		return
+	}
+	w.Col.Dir(x, y)
+	if w.popup != nil {
+		filterPopup(w)
+	}
+	releaseLatched(w)
+}
+
+// Rune handles typing events.
+func (w *Win) Rune(r rune) {
+	recordEvent(w, macroEvent{replay: func(w *Win) { w.Rune(r) }})
+	w.alone = [4]bool{}
+	w.confirm = "" // editing dismisses a confirmation
+	if w.mods[2] && (rowRune(w, r) || jumpRune(w, r)) {
+		releaseLatched(w)
+		return
+	}
+	if w.mods[3] && focusRune(w, r) {
+		releaseLatched(w)
+		return
+	}
+	if w.popup != nil && popupRune(w, r) {
+		releaseLatched(w)
+		return
+	}
+	for _, r := range deadKey(w, r) {
+		w.Col.Rune(r)
+	}
+	if w.popup != nil {
+		filterPopup(w)
+	}
+	releaseLatched(w)
+}
+
+func releaseLatched(w *Win) {
+	for m := range w.latched {
+		if !w.latched[m] {
+			continue
+		}
+		w.latched[m] = false
+		w.mods[m] = false
+		w.Col.Mod(-m)
+	}
+}
+
+// Copy handles copy events,
+// such as the platform's copy shortcut or menu item,
+// copying the selection of the focused row to the clipboard.
+func (w *Win) Copy() error {
+	recordEvent(w, macroEvent{replay: func(w *Win) { w.Copy() }})
+	w.alone = [4]bool{}
+	return w.Col.Copy()
+}
+
+// Cut handles cut events,
+// copying the selection of the focused row to the clipboard
+// and deleting it.
+func (w *Win) Cut() error {
+	recordEvent(w, macroEvent{replay: func(w *Win) { w.Cut() }})
+	w.alone = [4]bool{}
+	w.confirm = ""
+	closePopup(w)
+	return w.Col.Cut()
+}
+
+// Paste handles paste events,
+// replacing the selection of the focused row
+// with the text of the clipboard.
+func (w *Win) Paste() error {
+	recordEvent(w, macroEvent{replay: func(w *Win) { w.Paste() }})
+	w.alone = [4]bool{}
+	w.confirm = ""
+	closePopup(w)
+	return w.Col.Paste()
+}
+
 // OutputString appends a string to the Output sheet
 // and ensures that the Output sheet is visible.
 // It is safe for concurrent calls.
@@ -289,6 +641,7 @@ func (w *Win) OutputString(str string) {
 	w.mu.Lock()
 	w.outputBuffer.WriteString(str)
 	w.mu.Unlock()
+	wake(w)
 }
 
 // OutputBytes appends bytes to the Output sheet
@@ -298,6 +651,107 @@ func (w *Win) OutputBytes(data []byte) {
 	w.mu.Lock()
 	w.outputBuffer.Write(data)
 	w.mu.Unlock()
+	wake(w)
+}
+
+// zoom increases the font size if y < 0 (roll up)
+// and decreases it otherwise.
+func zoom(w *Win, pt image.Point, y int) {
+	d := 1
+	if y > 0 {
+		d = -1
+	}
+	if w.mods[1] {
+		setWinFontSize(w, w.fontSize+d)
+		return
+	}
+	for i, c := range w.cols {
+		if pt.X >= x1(w, i) {
+			continue
+		}
+		for j, r := range c.rows {
+			if pt.Y < y1(c, j) {
+				if s, ok := r.(*Sheet); ok {
+					setSheetFontSize(s, s.fontSize+d)
+				}
+				return
+			}
+		}
+		return
+	}
+}
+
+// setWinFontSize sets the font size of the window and all of its sheets.
+func setWinFontSize(w *Win, size int) {
+	if size < minFontSize {
+		size = minFontSize
+	}
+	w.fontSize = size
+	w.face = newFace(nil, w.dpi, size)
+	w.lineHeight = faceHeight(w.face)
+	for _, c := range w.cols {
+		setFace(c.rows[0].(*TextBox), w.face)
+		for _, r := range c.rows[1:] {
+			if s, ok := r.(*Sheet); ok {
+				setSheetFontSize(s, size)
+			}
+		}
+	}
+	if w.output != nil {
+		setSheetFontSize(w.output, size)
+	}
+	w.Resize(w.size)
+}
+
+// SetDPI sets the resolution of the window in dots per inch,
+// as when it moves to a monitor with a different resolution.
+// The fonts of the window and its sheets are re-derived
+// at their sizes in points.
+func (w *Win) SetDPI(dpi float32) {
+	if dpi == w.dpi || dpi <= 0 {
+		return
+	}
+	w.dpi = dpi
+	w.face = newFace(nil, dpi, w.fontSize)
+	w.lineHeight = faceHeight(w.face)
+	setDPI := func(s *Sheet) {
+		setSheetFontSize(s, s.fontSize)
+		if s.body.highlighter != nil {
+			s.body.setHighlighter(syntaxHighlighter(dpi, s.body))
+		}
+	}
+	for _, c := range w.cols {
+		setFace(c.rows[0].(*TextBox), w.face)
+		for _, r := range c.rows[1:] {
+			if s, ok := r.(*Sheet); ok && s != w.output {
+				setDPI(s)
+			}
+		}
+	}
+	if w.output != nil {
+		setDPI(w.output)
+	}
+	w.Resize(w.size)
+}
+
+// newFace returns a face of the given size
+// using the font, or the default font if the font is nil,
+// and the fallbacks of the default font.
+func newFace(f *truetype.Font, dpi float32, size int) font.Face {
+	if fonts == nil {
+		loadFonts()
+	}
+	opts := text.Options{Hinting: fontHinting, Subpixel: subpixelText}
+	// Faces are shared by the sheets, which are drawn concurrently.
+	if f == nil || f == fonts[0] {
+		return text.SyncFace(text.FallbackFaceOptions(fonts, dpi, size, opts))
+	}
+	return text.SyncFace(text.FallbackFaceOptions(append([]*truetype.Font{f}, fonts[1:]...), dpi, size, opts))
+}
+
+// faceHeight returns the pixel height of a line of text in the face.
+func faceHeight(face font.Face) int {
+	return (face.Metrics().Height + face.Metrics().Descent).Ceil()
 }
 
 func x0(w *Win, i int) int {
@@ -0,0 +1,305 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/T/edit"
+	"github.com/eaburns/T/rope"
+)
+
+func TestWinTitle(t *testing.T) {
+	w := newTestWin()
+	if got := w.Title(); got != "T" {
+		t.Errorf("Title()=%q, want T", got)
+	}
+	s := NewSheet(w, "/a/b.go")
+	w.cols[0].Add(s)
+	s.body.SetText(rope.New("x"))
+	s.saved = s.body.text
+	if got, want := w.Title(), "b.go - T"; got != want {
+		t.Errorf("Title()=%q, want %q", got, want)
+	}
+	s.body.SetText(rope.New("y"))
+	if got, want := w.Title(), "b.go* - T"; got != want {
+		t.Errorf("Title()=%q, want %q", got, want)
+	}
+	d := NewSheet(w, "/a/+Build")
+	w.cols[0].Add(d)
+	d.body.SetText(rope.New("y"))
+	if got, want := w.Title(), "+Build - T"; got != want {
+		t.Errorf("Title()=%q, want %q", got, want)
+	}
+}
+
+func TestStickyMods(t *testing.T) {
+	w := newTestWin()
+	w.SetStickyMods(true)
+
+	w.Mod(2)
+	w.Mod(-2)
+	if !w.mods[2] {
+		t.Fatalf("modifier 2 is not latched after press and release")
+	}
+	w.Click(image.Pt(0, 0), 1)
+	if !w.mods[2] {
+		t.Errorf("modifier 2 released before the click completed")
+	}
+	w.Click(image.Pt(0, 0), -1)
+	if w.mods[2] {
+		t.Errorf("modifier 2 still latched after the click")
+	}
+
+	w.Mod(3)
+	w.Mod(-3)
+	w.Mod(3)
+	w.Mod(-3)
+	if w.mods[3] {
+		t.Errorf("modifier 3 still latched after pressing it again")
+	}
+
+	w.Mod(1)
+	w.Rune('x')
+	w.Mod(-1)
+	if w.mods[1] {
+		t.Errorf("modifier 1 latched after being used with a rune")
+	}
+}
+
+func TestNonStickyMods(t *testing.T) {
+	w := newTestWin()
+	w.Mod(2)
+	w.Mod(-2)
+	if w.mods[2] {
+		t.Errorf("modifier 2 latched with sticky modifiers disabled")
+	}
+}
+
+func TestZoomSheet(t *testing.T) {
+	w := newTestWin()
+	w.Resize(image.Pt(200, 200))
+	c := w.cols[0]
+	s := NewSheet(w, "")
+	c.Add(s)
+	s.fontSize = 11
+
+	pt := image.Pt(10, y0(c, 1)+5)
+	w.Wheel(pt, 0, -1)
+	if s.fontSize != 11 {
+		t.Fatalf("fontSize=%d after wheel without control, want 11", s.fontSize)
+	}
+
+	w.mods[3] = true
+	w.Wheel(pt, 0, -1)
+	w.Wheel(pt, 0, -1)
+	w.Wheel(pt, 0, 1)
+	w.mods[3] = false
+	if s.fontSize != 12 {
+		t.Errorf("fontSize=%d, want 12", s.fontSize)
+	}
+	if s.body.style.Face == w.face || s.tag.style.Face != s.body.style.Face {
+		t.Errorf("sheet face was not changed")
+	}
+	if h := faceHeight(s.body.style.Face); s.minTagH != h {
+		t.Errorf("minTagH=%d, want %d", s.minTagH, h)
+	}
+}
+
+func TestZoomWin(t *testing.T) {
+	w := newTestWin()
+	w.Resize(image.Pt(200, 200))
+	c := w.cols[0]
+	s := NewSheet(w, "")
+	c.Add(s)
+	w.fontSize = 11
+
+	w.mods[1], w.mods[3] = true, true
+	w.Wheel(image.Pt(10, 10), 0, -1)
+	w.mods[1], w.mods[3] = false, false
+	if w.fontSize != 12 || s.fontSize != 12 {
+		t.Errorf("w.fontSize=%d, s.fontSize=%d, want 12, 12", w.fontSize, s.fontSize)
+	}
+	if bg := c.rows[0].(*TextBox); bg.style.Face != w.face {
+		t.Errorf("column background face was not changed")
+	}
+	if h := faceHeight(w.face); w.lineHeight != h {
+		t.Errorf("lineHeight=%d, want %d", w.lineHeight, h)
+	}
+}
+
+func TestSetDPI(t *testing.T) {
+	w := newTestWin()
+	w.Resize(image.Pt(200, 200))
+	c := w.cols[0]
+	s := NewSheet(w, "")
+	c.Add(s)
+	w.dpi, w.fontSize = 72, 11
+	s.fontSize = 14
+
+	w.SetDPI(144)
+	if w.dpi != 144 {
+		t.Errorf("dpi=%v, want 144", w.dpi)
+	}
+	if s.fontSize != 14 {
+		t.Errorf("fontSize=%d, want 14", s.fontSize)
+	}
+	if want := newFace(nil, 144, 14); faceHeight(s.body.style.Face) != faceHeight(want) {
+		t.Errorf("sheet face height=%d, want %d", faceHeight(s.body.style.Face), faceHeight(want))
+	}
+	if bg := c.rows[0].(*TextBox); bg.style.Face != w.face {
+		t.Errorf("column background face was not changed")
+	}
+	if h := faceHeight(newFace(nil, 144, 11)); w.lineHeight != h {
+		t.Errorf("lineHeight=%d, want %d", w.lineHeight, h)
+	}
+}
+
+func TestCmd_Fullscreen(t *testing.T) {
+	w := newTestWin()
+	c := w.cols[0]
+	for _, want := range []bool{true, false} {
+		if err := execCmd(c, nil, "Fullscreen"); err != nil {
+			t.Fatalf("Fullscreen failed: %v", err)
+		}
+		if w.Fullscreen() != want {
+			t.Errorf("Fullscreen()=%v, want %v", w.Fullscreen(), want)
+		}
+	}
+}
+
+func TestWinDrawChanged(t *testing.T) {
+	w := newTestWin()
+	s := NewSheet(w, "/a/b.txt")
+	w.cols[0].Add(s)
+	s.body.SetText(rope.New("1\n2\n3\n4\n5\n"))
+	img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	if r := w.Draw(true, img); r != img.Bounds() {
+		t.Fatalf("Draw(true)=%v, want %v", r, img.Bounds())
+	}
+	w.Draw(false, img)
+
+	r := w.Draw(false, img)
+	if r.Dy() > s.tagH {
+		t.Errorf("unchanged Draw=%v, want at most the sheet handle", r)
+	}
+
+	s.body.Change(edit.Diffs{{At: [2]int64{0, 1}, Text: rope.New("x")}})
+	r = w.Draw(false, img)
+	if r.Empty() || r.Dy() > img.Bounds().Dy()/2 {
+		t.Errorf("Draw after a change=%v, want only a few lines", r)
+	}
+}
+
+func TestWinCopyCutPaste(t *testing.T) {
+	w := newTestWin()
+	s := NewSheet(w, "/a/b.txt")
+	w.cols[0].Add(s)
+	s.TextBox = s.body
+	s.body.SetText(rope.New("Hello, World"))
+	s.body.dots[1].At = [2]int64{0, 5}
+	if err := w.Cut(); err != nil {
+		t.Fatalf("Cut()=%v", err)
+	}
+	s.body.dots[1].At = [2]int64{7, 7}
+	if err := w.Paste(); err != nil {
+		t.Fatalf("Paste()=%v", err)
+	}
+	if got, want := s.body.text.String(), ", WorldHello"; got != want {
+		t.Errorf("text=%q, want %q", got, want)
+	}
+	s.body.dots[1].At = [2]int64{0, 1}
+	if err := w.Copy(); err != nil {
+		t.Fatalf("Copy()=%v", err)
+	}
+	if got, err := w.clipboard.Fetch(); err != nil || got.String() != "," {
+		t.Errorf("clipboard=%q, %v, want \",\"", got, err)
+	}
+}
+
+func TestWinDrawConcurrent(t *testing.T) {
+	w := newTestWin()
+	w.Add()
+	for i, c := range w.cols {
+		for j := 0; j < 3; j++ {
+			s := NewSheet(w, fmt.Sprintf("/%d/%d.txt", i, j))
+			c.Add(s)
+			s.body.SetText(rope.New(strings.Repeat(s.Title()+"\n", 10)))
+		}
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 800, 600))
+	w.Draw(true, img)
+
+	// Each row is drawn as if it were drawn alone.
+	for i, c := range w.cols {
+		for j, r := range c.rows[1:] {
+			rect := image.Rect(x0(w, i), y0(c, j+1), x1(w, i), y1(c, j+1))
+			want := image.NewRGBA(rect)
+			r.Draw(true, want)
+			got := img.SubImage(rect).(*image.RGBA)
+			for y := rect.Min.Y; y < rect.Max.Y; y++ {
+				for x := rect.Min.X; x < rect.Max.X; x++ {
+					if got.RGBAAt(x, y) != want.RGBAAt(x, y) {
+						t.Fatalf("column %d row %d differs at %d,%d", i, j+1, x, y)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestWinDrawAllocs(t *testing.T) {
+	w := newTestWin()
+	s := NewSheet(w, "/a/b.txt")
+	w.cols[0].Add(s)
+	s.body.SetText(rope.New(strings.Repeat("Hello, World!\n", 100)))
+	img := image.NewRGBA(image.Rect(0, 0, 800, 600))
+	w.Draw(true, img)
+	allocs := testing.AllocsPerRun(100, func() {
+		s.body.showCursor = !s.body.showCursor
+		dirtyDot(s.body, s.body.dots[1].At)
+		w.Draw(false, img)
+	})
+	if allocs != 0 {
+		t.Errorf("Draw allocated %v times per frame, want 0", allocs)
+	}
+}
+
+func BenchmarkWinDraw(b *testing.B) {
+	w := newTestWin()
+	s := NewSheet(w, "/a/b.txt")
+	w.cols[0].Add(s)
+	s.body.SetText(rope.New(strings.Repeat("Hello, World!\n", 100)))
+	img := image.NewRGBA(image.Rect(0, 0, 800, 600))
+	w.Draw(true, img)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.body.showCursor = !s.body.showCursor
+		dirtyDot(s.body, s.body.dots[1].At)
+		w.Draw(false, img)
+	}
+}
+
+func TestWinDrawNRGBA(t *testing.T) {
+	w := newTestWin()
+	s := NewSheet(w, "/test.txt")
+	w.cols[0].Add(s)
+	s.body.SetText(rope.New(strings.Repeat("Hello, World\n", 10)))
+
+	want := image.NewRGBA(image.Rect(0, 0, 800, 600))
+	w.Draw(true, want)
+	got := image.NewNRGBA(image.Rect(0, 0, 800, 600))
+	if r := w.Draw(false, got); r != got.Bounds() {
+		t.Errorf("first Draw changed %v, want %v", r, got.Bounds())
+	}
+	for y := 0; y < 600; y++ {
+		for x := 0; x < 800; x++ {
+			if c := color.RGBAModel.Convert(got.At(x, y)); c != want.At(x, y) {
+				t.Fatalf("pixel %d,%d is %v, want %v", x, y, c, want.At(x, y))
+			}
+		}
+	}
+}